@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"launay-dot-one/middlewares"
+	"launay-dot-one/models"
+	adminsvc "launay-dot-one/services/admin"
+	"launay-dot-one/utils"
+)
+
+type AdminController struct {
+	svc    adminsvc.Service
+	logger *logrus.Logger
+}
+
+func NewAdminController(svc adminsvc.Service, logger *logrus.Logger) *AdminController {
+	return &AdminController{svc: svc, logger: logger}
+}
+
+func (ac *AdminController) RegisterRoutes(r *gin.Engine) {
+	grp := r.Group("/admin", middlewares.AuthMiddleware(), middlewares.RequireRole(models.RoleAdmin))
+	{
+		grp.GET("/users", ac.ListUsers)
+		grp.GET("/users/:user_id", ac.GetUser)
+		grp.POST("/users/:user_id/suspend", ac.Suspend)
+		grp.POST("/users/:user_id/ban", ac.Ban)
+		grp.DELETE("/users/:user_id/restrictions", ac.LiftRestrictions)
+		grp.POST("/users/:user_id/force-password-reset", ac.ForcePasswordReset)
+		grp.POST("/users/:user_id/revoke-sessions", ac.RevokeSessions)
+		grp.PUT("/users/:user_id/role", ac.UpdateRole)
+
+		grp.DELETE("/guilds/:guild_id", ac.DeleteGuild)
+
+		grp.GET("/stats", ac.Stats)
+		grp.GET("/audit-logs", ac.ListAuditLog)
+	}
+}
+
+// reasonPayload is the optional body shared by simple admin actions.
+type reasonPayload struct {
+	Reason string `json:"reason"`
+}
+
+// bindReason accepts an empty body as "no reason given".
+func bindReason(c *gin.Context) (string, bool) {
+	var body reasonPayload
+	if c.Request.ContentLength == 0 {
+		return "", true
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return "", false
+	}
+	return body.Reason, true
+}
+
+func (ac *AdminController) respondServiceError(c *gin.Context, op string, err error) {
+	ac.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, adminsvc.ErrSelfAction),
+		errors.Is(err, adminsvc.ErrInvalidRole),
+		errors.Is(err, adminsvc.ErrInvalidKind),
+		errors.Is(err, adminsvc.ErrExpiryRequired):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid request", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, "Admin action failed", err.Error())
+	}
+}
+
+// ListUsers handles GET /admin/users?q=&page=&limit=
+func (ac *AdminController) ListUsers(c *gin.Context) {
+	page, limit := utils.Pagination(c, 50, 200)
+	out, err := ac.svc.ListUsers(c.Request.Context(), c.Query("q"), page, limit)
+	if err != nil {
+		ac.respondServiceError(c, "ListUsers", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Users fetched", out)
+}
+
+// GetUser handles GET /admin/users/:user_id
+func (ac *AdminController) GetUser(c *gin.Context) {
+	out, err := ac.svc.GetUser(c.Request.Context(), c.Param("user_id"))
+	if err != nil {
+		ac.logger.Error("GetUser error: ", err)
+		utils.RespondError(c, http.StatusNotFound, "User not found", err.Error())
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "User fetched", out)
+}
+
+// Suspend handles POST /admin/users/:user_id/suspend
+//
+//	body: { "reason": "...", "expires_at": "2025-01-01T00:00:00Z" }
+func (ac *AdminController) Suspend(c *gin.Context) {
+	ac.restrict(c, models.RestrictionSuspension)
+}
+
+// Ban handles POST /admin/users/:user_id/ban
+//
+//	body: { "reason": "...", "expires_at": null }
+func (ac *AdminController) Ban(c *gin.Context) {
+	ac.restrict(c, models.RestrictionBan)
+}
+
+func (ac *AdminController) restrict(c *gin.Context, kind models.RestrictionKind) {
+	var body struct {
+		Reason    string     `json:"reason" binding:"required"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	rs, err := ac.svc.Restrict(
+		c.Request.Context(),
+		c.GetString("user_id"), c.Param("user_id"),
+		kind, body.Reason, body.ExpiresAt,
+	)
+	if err != nil {
+		ac.respondServiceError(c, "Restrict", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "User restricted", rs)
+}
+
+// LiftRestrictions handles DELETE /admin/users/:user_id/restrictions
+func (ac *AdminController) LiftRestrictions(c *gin.Context) {
+	reason, ok := bindReason(c)
+	if !ok {
+		return
+	}
+	if err := ac.svc.LiftRestrictions(c.Request.Context(), c.GetString("user_id"), c.Param("user_id"), reason); err != nil {
+		ac.respondServiceError(c, "LiftRestrictions", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Restrictions lifted", nil)
+}
+
+// ForcePasswordReset handles POST /admin/users/:user_id/force-password-reset
+func (ac *AdminController) ForcePasswordReset(c *gin.Context) {
+	reason, ok := bindReason(c)
+	if !ok {
+		return
+	}
+	if err := ac.svc.ForcePasswordReset(c.Request.Context(), c.GetString("user_id"), c.Param("user_id"), reason); err != nil {
+		ac.respondServiceError(c, "ForcePasswordReset", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Password reset required", nil)
+}
+
+// RevokeSessions handles POST /admin/users/:user_id/revoke-sessions
+func (ac *AdminController) RevokeSessions(c *gin.Context) {
+	reason, ok := bindReason(c)
+	if !ok {
+		return
+	}
+	if err := ac.svc.RevokeSessions(c.Request.Context(), c.GetString("user_id"), c.Param("user_id"), reason); err != nil {
+		ac.respondServiceError(c, "RevokeSessions", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Sessions revoked", nil)
+}
+
+// UpdateRole handles PUT /admin/users/:user_id/role
+//
+//	body: { "role": "admin", "reason": "..." }
+func (ac *AdminController) UpdateRole(c *gin.Context) {
+	var body struct {
+		Role   string `json:"role" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	if err := ac.svc.UpdateRole(c.Request.Context(), c.GetString("user_id"), c.Param("user_id"), body.Role, body.Reason); err != nil {
+		ac.respondServiceError(c, "UpdateRole", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Role updated", nil)
+}
+
+// DeleteGuild handles DELETE /admin/guilds/:guild_id
+func (ac *AdminController) DeleteGuild(c *gin.Context) {
+	reason, ok := bindReason(c)
+	if !ok {
+		return
+	}
+	if err := ac.svc.DeleteGuild(c.Request.Context(), c.GetString("user_id"), c.Param("guild_id"), reason); err != nil {
+		ac.respondServiceError(c, "DeleteGuild", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Guild deleted", nil)
+}
+
+// Stats handles GET /admin/stats
+func (ac *AdminController) Stats(c *gin.Context) {
+	st, err := ac.svc.Stats(c.Request.Context())
+	if err != nil {
+		ac.respondServiceError(c, "Stats", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Stats fetched", st)
+}
+
+// ListAuditLog handles GET /admin/audit-logs?actor_id=&target_id=&page=&limit=
+func (ac *AdminController) ListAuditLog(c *gin.Context) {
+	page, limit := utils.Pagination(c, 50, 200)
+	out, err := ac.svc.ListAuditLog(c.Request.Context(), c.Query("actor_id"), c.Query("target_id"), page, limit)
+	if err != nil {
+		ac.respondServiceError(c, "ListAuditLog", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Audit log fetched", out)
+}
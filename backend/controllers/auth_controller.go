@@ -1,10 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"launay-dot-one/models"
 	authsvc "launay-dot-one/services/auth"
+	usersvc "launay-dot-one/services/users"
 	"launay-dot-one/utils"
 
 	"github.com/gin-gonic/gin"
@@ -28,6 +30,7 @@ func (ac *AuthController) RegisterRoutes(r *gin.Engine) {
 	{
 		auth.POST("/register", ac.Register)
 		auth.POST("/login", ac.Login)
+		auth.POST("/password", ac.ChangePassword)
 	}
 }
 
@@ -41,7 +44,14 @@ func (ac *AuthController) Register(c *gin.Context) {
 
 	if err := ac.authService.RegisterUser(c.Request.Context(), &user); err != nil {
 		ac.logger.Error("Registration failed: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Registration failed", err.Error())
+		switch {
+		case errors.Is(err, usersvc.ErrInvalidUsername):
+			utils.RespondError(c, http.StatusBadRequest, "Invalid username", err.Error())
+		case errors.Is(err, authsvc.ErrUserExists), errors.Is(err, usersvc.ErrUsernameTaken):
+			utils.RespondError(c, http.StatusConflict, "Registration failed", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Registration failed", err.Error())
+		}
 		return
 	}
 
@@ -62,9 +72,49 @@ func (ac *AuthController) Login(c *gin.Context) {
 	token, err := ac.authService.LoginUser(c.Request.Context(), creds.Email, creds.Password)
 	if err != nil {
 		ac.logger.Warn("Login failed: ", err)
-		utils.RespondError(c, http.StatusUnauthorized, "Invalid credentials", err.Error())
+		switch {
+		case errors.Is(err, authsvc.ErrAccountRestricted):
+			utils.RespondError(c, http.StatusForbidden, "Account restricted", err.Error())
+		case errors.Is(err, authsvc.ErrPasswordResetRequired):
+			utils.RespondError(c, http.StatusForbidden, "Password reset required", err.Error())
+		default:
+			utils.RespondError(c, http.StatusUnauthorized, "Invalid credentials", err.Error())
+		}
 		return
 	}
 
 	utils.RespondSuccess(c, http.StatusOK, "Login successful", gin.H{"token": token})
 }
+
+// ChangePassword handles POST /auth/password
+//
+//	body: { "email": "...", "current_password": "...", "new_password": "..." }
+//
+// It works without a session so users forced to reset can still get back in.
+func (ac *AuthController) ChangePassword(c *gin.Context) {
+	var body struct {
+		Email           string `json:"email" binding:"required"`
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid request payload", err.Error())
+		return
+	}
+
+	err := ac.authService.ChangePassword(c.Request.Context(), body.Email, body.CurrentPassword, body.NewPassword)
+	if err != nil {
+		ac.logger.Warn("Password change failed: ", err)
+		switch {
+		case errors.Is(err, authsvc.ErrInvalidCredentials):
+			utils.RespondError(c, http.StatusUnauthorized, "Invalid credentials", err.Error())
+		case errors.Is(err, authsvc.ErrAccountRestricted):
+			utils.RespondError(c, http.StatusForbidden, "Account restricted", err.Error())
+		default:
+			utils.RespondError(c, http.StatusBadRequest, "Password change failed", err.Error())
+		}
+		return
+	}
+
+	utils.RespondSuccess(c, http.StatusOK, "Password changed", nil)
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"launay-dot-one/middlewares"
+	botsvc "launay-dot-one/services/bots"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+)
+
+type BotsController struct {
+	svc    botsvc.Service
+	logger *logrus.Logger
+}
+
+func NewBotsController(svc botsvc.Service, logger *logrus.Logger) *BotsController {
+	return &BotsController{svc: svc, logger: logger}
+}
+
+func (bc *BotsController) RegisterRoutes(r *gin.Engine) {
+	grp := r.Group("/bots", middlewares.AuthMiddleware())
+	{
+		grp.POST("", bc.Create)
+		grp.GET("", bc.ListMine)
+		grp.GET("/:bot_id", bc.Get)
+		grp.PATCH("/:bot_id", bc.Update)
+		grp.DELETE("/:bot_id", bc.Delete)
+
+		grp.GET("/:bot_id/tokens", bc.ListTokens)
+		grp.POST("/:bot_id/tokens", bc.CreateToken)
+		grp.DELETE("/:bot_id/tokens/:token_id", bc.RevokeToken)
+
+		grp.POST("/:bot_id/authorize", bc.Authorize)
+	}
+}
+
+func (bc *BotsController) respondError(c *gin.Context, op string, err error) {
+	bc.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	case errors.Is(err, botsvc.ErrNotOwner),
+		errors.Is(err, botsvc.ErrBotOwner),
+		errors.Is(err, botsvc.ErrCannotGrant),
+		errors.Is(err, botsvc.ErrBannedFromGuild),
+		errors.Is(err, permissions.ErrMissingPermission),
+		errors.Is(err, permissions.ErrNotMember):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, botsvc.ErrUsernameTaken),
+		errors.Is(err, botsvc.ErrAlreadyInGuild):
+		utils.RespondError(c, http.StatusConflict, "Conflict", err.Error())
+	case errors.Is(err, botsvc.ErrInvalidUsername),
+		errors.Is(err, botsvc.ErrInvalidDesc),
+		errors.Is(err, botsvc.ErrInvalidTokenName),
+		errors.Is(err, botsvc.ErrInvalidURL),
+		errors.Is(err, botsvc.ErrUnknownPermission),
+		errors.Is(err, botsvc.ErrTooManyBots),
+		errors.Is(err, botsvc.ErrTooManyTokens):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid request", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, op+" failed", err.Error())
+	}
+}
+
+// Create handles POST /bots
+//
+//	body: { "username": "deploy-bot", "description": "...", "public": false, "default_permissions": 6 }
+//
+// The response carries the bot's first token; it is not shown again.
+func (bc *BotsController) Create(c *gin.Context) {
+	var body struct {
+		Username           string `json:"username" binding:"required"`
+		Description        string `json:"description"`
+		Public             bool   `json:"public"`
+		DefaultPermissions uint64 `json:"default_permissions"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	bot, token, err := bc.svc.Create(c.Request.Context(), c.GetString("user_id"), botsvc.Input{
+		Username:           body.Username,
+		Description:        body.Description,
+		Public:             body.Public,
+		DefaultPermissions: body.DefaultPermissions,
+	})
+	if err != nil {
+		bc.respondError(c, "CreateBot", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Bot created", gin.H{"bot": bot, "token": token})
+}
+
+// ListMine handles GET /bots, the caller's own bots.
+func (bc *BotsController) ListMine(c *gin.Context) {
+	out, err := bc.svc.ListMine(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		bc.respondError(c, "ListBots", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Bots fetched", out)
+}
+
+// Get handles GET /bots/:bot_id, e.g. for the authorization screen.
+func (bc *BotsController) Get(c *gin.Context) {
+	bot, err := bc.svc.Get(c.Request.Context(), c.Param("bot_id"), c.GetString("user_id"))
+	if err != nil {
+		bc.respondError(c, "GetBot", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Bot fetched", bot)
+}
+
+// Update handles PATCH /bots/:bot_id
+//
+//	body: { "username": "...", "description": "...", "public": true, "default_permissions": 6,
+//	        "interactions_url": "https://..." }  // all optional
+func (bc *BotsController) Update(c *gin.Context) {
+	var body struct {
+		Username           *string `json:"username"`
+		Description        *string `json:"description"`
+		Public             *bool   `json:"public"`
+		DefaultPermissions *uint64 `json:"default_permissions"`
+		InteractionsURL    *string `json:"interactions_url"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	bot, err := bc.svc.Update(c.Request.Context(), c.Param("bot_id"), c.GetString("user_id"), botsvc.Patch{
+		Username:           body.Username,
+		Description:        body.Description,
+		Public:             body.Public,
+		DefaultPermissions: body.DefaultPermissions,
+		InteractionsURL:    body.InteractionsURL,
+	})
+	if err != nil {
+		bc.respondError(c, "UpdateBot", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Bot updated", bot)
+}
+
+// Delete handles DELETE /bots/:bot_id
+func (bc *BotsController) Delete(c *gin.Context) {
+	if err := bc.svc.Delete(c.Request.Context(), c.Param("bot_id"), c.GetString("user_id")); err != nil {
+		bc.respondError(c, "DeleteBot", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Bot deleted", nil)
+}
+
+// ListTokens handles GET /bots/:bot_id/tokens
+func (bc *BotsController) ListTokens(c *gin.Context) {
+	out, err := bc.svc.ListTokens(c.Request.Context(), c.Param("bot_id"), c.GetString("user_id"))
+	if err != nil {
+		bc.respondError(c, "ListBotTokens", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Tokens fetched", out)
+}
+
+// CreateToken handles POST /bots/:bot_id/tokens
+//
+//	body: { "name": "ci" }
+//
+// The response carries the token; it is not shown again.
+func (bc *BotsController) CreateToken(c *gin.Context) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	t, token, err := bc.svc.CreateToken(c.Request.Context(), c.Param("bot_id"), c.GetString("user_id"), body.Name)
+	if err != nil {
+		bc.respondError(c, "CreateBotToken", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Token created", gin.H{"token": t, "secret": token})
+}
+
+// RevokeToken handles DELETE /bots/:bot_id/tokens/:token_id
+func (bc *BotsController) RevokeToken(c *gin.Context) {
+	if err := bc.svc.RevokeToken(
+		c.Request.Context(), c.Param("bot_id"), c.Param("token_id"), c.GetString("user_id"),
+	); err != nil {
+		bc.respondError(c, "RevokeBotToken", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Token revoked", nil)
+}
+
+// Authorize handles POST /bots/:bot_id/authorize
+//
+//	body: { "guild_id": "...", "permissions": 6 }  // permissions defaults to the bot's default
+func (bc *BotsController) Authorize(c *gin.Context) {
+	var body struct {
+		GuildID     string  `json:"guild_id" binding:"required"`
+		Permissions *uint64 `json:"permissions"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	role, err := bc.svc.Authorize(auditContext(c), c.Param("bot_id"), body.GuildID, body.Permissions, c.GetString("user_id"))
+	if err != nil {
+		bc.respondError(c, "AuthorizeBot", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Bot added to guild", gin.H{"guild_id": body.GuildID, "role": role})
+}
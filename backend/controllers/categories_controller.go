@@ -1,14 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 
 	"launay-dot-one/middlewares"
 	"launay-dot-one/models/guilds"
 	catsvc "launay-dot-one/services/categories"
+	"launay-dot-one/services/permissions"
 	"launay-dot-one/utils"
 )
 
@@ -28,6 +31,7 @@ func (cc *CategoriesController) RegisterRoutes(r *gin.Engine) {
 	{
 		grp.GET("", cc.List)
 		grp.POST("", cc.Create)
+		grp.PATCH("", cc.Reorder)
 		grp.GET(categoryIDParam, cc.Get)
 		grp.PUT(categoryIDParam, cc.Update)
 		grp.DELETE(categoryIDParam, cc.Delete)
@@ -61,9 +65,15 @@ func (cc *CategoriesController) Create(c *gin.Context) {
 	}
 
 	// Pass channels slice into service
-	if err := cc.svc.Create(c.Request.Context(), &category, payload.Channels); err != nil {
+	if err := cc.svc.Create(auditContext(c), &category, payload.Channels, c.GetString("user_id")); err != nil {
 		cc.logger.Error("Create category error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to create category", err.Error())
+		if errors.Is(err, permissions.ErrMissingPermission) || errors.Is(err, permissions.ErrNotMember) {
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		} else if isChannelSettingsError(err) {
+			utils.RespondError(c, http.StatusBadRequest, "Invalid channel", err.Error())
+		} else {
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to create category", err.Error())
+		}
 		return
 	}
 	utils.RespondSuccess(c, http.StatusCreated, "Category created", category)
@@ -101,9 +111,16 @@ func (cc *CategoriesController) Update(c *gin.Context) {
 	}
 	cat.ID = id
 	cat.GuildID = guildID
-	if err := cc.svc.Update(c.Request.Context(), &cat); err != nil {
+	if err := cc.svc.Update(auditContext(c), &cat, c.GetString("user_id")); err != nil {
 		cc.logger.Error("Update category error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to update category", err.Error())
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Category not found", err.Error())
+		case errors.Is(err, permissions.ErrMissingPermission), errors.Is(err, permissions.ErrNotMember):
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to update category", err.Error())
+		}
 		return
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Category updated", cat)
@@ -111,10 +128,48 @@ func (cc *CategoriesController) Update(c *gin.Context) {
 
 func (cc *CategoriesController) Delete(c *gin.Context) {
 	id := c.Param("category_id")
-	if err := cc.svc.Delete(c.Request.Context(), id); err != nil {
+	if err := cc.svc.Delete(auditContext(c), id, c.GetString("user_id")); err != nil {
 		cc.logger.Error("Delete category error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to delete category", err.Error())
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Category not found", err.Error())
+		case errors.Is(err, permissions.ErrMissingPermission), errors.Is(err, permissions.ErrNotMember):
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to delete category", err.Error())
+		}
 		return
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Category deleted", nil)
 }
+
+// Reorder handles PATCH /guilds/:guild_id/categories
+//
+//	body: [{ "id": "...", "position": 0 }]
+func (cc *CategoriesController) Reorder(c *gin.Context) {
+	var payload []struct {
+		ID       string `json:"id" binding:"required"`
+		Position int    `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	moves := make([]catsvc.Move, len(payload))
+	for i, p := range payload {
+		moves[i] = catsvc.Move{ID: p.ID, Position: p.Position}
+	}
+	if err := cc.svc.Reorder(auditContext(c), c.Param("guild_id"), moves, c.GetString("user_id")); err != nil {
+		cc.logger.Error("Reorder categories error: ", err)
+		switch {
+		case errors.Is(err, permissions.ErrMissingPermission), errors.Is(err, permissions.ErrNotMember):
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		case errors.Is(err, catsvc.ErrUnknownCategory), errors.Is(err, catsvc.ErrDuplicateMove):
+			utils.RespondError(c, http.StatusBadRequest, "Invalid reorder", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to reorder categories", err.Error())
+		}
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Categories reordered", nil)
+}
@@ -1,14 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 
 	"launay-dot-one/middlewares"
 	"launay-dot-one/models/guilds"
 	chsvc "launay-dot-one/services/channels"
+	"launay-dot-one/services/permissions"
 	"launay-dot-one/utils"
 )
 
@@ -29,6 +32,7 @@ func (cc *ChannelsController) RegisterRoutes(r *gin.Engine) {
 	{
 		g.GET("", cc.ListByGuild)
 		g.POST("", cc.Create)
+		g.PATCH("", cc.Reorder)
 	}
 	// Under a category
 	c := r.Group("/categories/:category_id/channels", middlewares.AuthMiddleware())
@@ -41,6 +45,11 @@ func (cc *ChannelsController) RegisterRoutes(r *gin.Engine) {
 		single.GET(channelIDParam, cc.Get)
 		single.PUT(channelIDParam, cc.Update)
 		single.DELETE(channelIDParam, cc.Delete)
+		single.POST(channelIDParam+"/sync-permissions", cc.SyncPermissions)
+
+		single.GET(channelIDParam+"/followers", cc.ListFollowers)
+		single.POST(channelIDParam+"/followers", cc.Follow)
+		single.DELETE(channelIDParam+"/followers/:follower_id", cc.Unfollow)
 	}
 }
 
@@ -55,9 +64,15 @@ func (cc *ChannelsController) Create(c *gin.Context) {
 	ch.GuildID = guildID
 
 	// Pass nil for categoryID → top-level channel
-	if err := cc.svc.Create(c.Request.Context(), &ch, nil); err != nil {
+	if err := cc.svc.Create(auditContext(c), &ch, nil, c.GetString("user_id")); err != nil {
 		cc.logger.Error("Create channel error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to create channel", err.Error())
+		if errors.Is(err, permissions.ErrMissingPermission) || errors.Is(err, permissions.ErrNotMember) {
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		} else if isChannelSettingsError(err) || errors.Is(err, chsvc.ErrUnknownCategory) {
+			utils.RespondError(c, http.StatusBadRequest, "Invalid channel", err.Error())
+		} else {
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to create channel", err.Error())
+		}
 		return
 	}
 	utils.RespondSuccess(c, http.StatusCreated, "Channel created", ch)
@@ -104,9 +119,15 @@ func (cc *ChannelsController) Update(c *gin.Context) {
 		return
 	}
 	ch.ID = id
-	if err := cc.svc.Update(c.Request.Context(), &ch); err != nil {
+	if err := cc.svc.Update(auditContext(c), &ch, c.GetString("user_id")); err != nil {
 		cc.logger.Error("Update channel error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to update channel", err.Error())
+		if errors.Is(err, permissions.ErrMissingPermission) || errors.Is(err, permissions.ErrNotMember) {
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		} else if isChannelSettingsError(err) || errors.Is(err, chsvc.ErrUnknownCategory) {
+			utils.RespondError(c, http.StatusBadRequest, "Invalid channel", err.Error())
+		} else {
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to update channel", err.Error())
+		}
 		return
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Channel updated", ch)
@@ -114,10 +135,133 @@ func (cc *ChannelsController) Update(c *gin.Context) {
 
 func (cc *ChannelsController) Delete(c *gin.Context) {
 	id := c.Param("channel_id")
-	if err := cc.svc.Delete(c.Request.Context(), id); err != nil {
+	if err := cc.svc.Delete(auditContext(c), id, c.GetString("user_id")); err != nil {
 		cc.logger.Error("Delete channel error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to delete channel", err.Error())
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Channel not found", err.Error())
+		case errors.Is(err, permissions.ErrMissingPermission), errors.Is(err, permissions.ErrNotMember):
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to delete channel", err.Error())
+		}
 		return
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Channel deleted", nil)
 }
+
+// Reorder handles PATCH /guilds/:guild_id/channels
+//
+//	body: [{ "id": "...", "position": 0, "category_id": "..." }]  // null category_id = top level
+func (cc *ChannelsController) Reorder(c *gin.Context) {
+	var payload []struct {
+		ID         string  `json:"id" binding:"required"`
+		Position   int     `json:"position"`
+		CategoryID *string `json:"category_id"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	moves := make([]chsvc.Move, len(payload))
+	for i, p := range payload {
+		moves[i] = chsvc.Move{ID: p.ID, Position: p.Position, CategoryID: p.CategoryID}
+	}
+	if err := cc.svc.Reorder(auditContext(c), c.Param("guild_id"), moves, c.GetString("user_id")); err != nil {
+		cc.logger.Error("Reorder channels error: ", err)
+		switch {
+		case errors.Is(err, permissions.ErrMissingPermission), errors.Is(err, permissions.ErrNotMember):
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		case errors.Is(err, chsvc.ErrUnknownChannel),
+			errors.Is(err, chsvc.ErrUnknownCategory),
+			errors.Is(err, chsvc.ErrDuplicateMove):
+			utils.RespondError(c, http.StatusBadRequest, "Invalid reorder", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to reorder channels", err.Error())
+		}
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Channels reordered", nil)
+}
+
+// SyncPermissions handles POST /channels/:channel_id/sync-permissions
+func (cc *ChannelsController) SyncPermissions(c *gin.Context) {
+	ch, err := cc.svc.SyncPermissions(auditContext(c), c.Param("channel_id"), c.GetString("user_id"))
+	if err != nil {
+		cc.logger.Error("Sync channel permissions error: ", err)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Channel not found", err.Error())
+		case errors.Is(err, permissions.ErrMissingPermission), errors.Is(err, permissions.ErrNotMember):
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		case errors.Is(err, chsvc.ErrNoCategory):
+			utils.RespondError(c, http.StatusBadRequest, "Channel has no category", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to sync permissions", err.Error())
+		}
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Channel permissions synced", ch)
+}
+
+// isChannelSettingsError reports whether err rejects a channel's type or
+// type-specific settings.
+func isChannelSettingsError(err error) bool {
+	return errors.Is(err, chsvc.ErrInvalidType) ||
+		errors.Is(err, chsvc.ErrInvalidSettings) ||
+		errors.Is(err, chsvc.ErrTypeChange)
+}
+
+func (cc *ChannelsController) respondFollowError(c *gin.Context, op string, err error) {
+	cc.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	case errors.Is(err, permissions.ErrMissingPermission), errors.Is(err, permissions.ErrNotMember):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, chsvc.ErrNotAnnouncement), errors.Is(err, chsvc.ErrInvalidTarget):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid follow", err.Error())
+	case errors.Is(err, chsvc.ErrAlreadyFollowing):
+		utils.RespondError(c, http.StatusConflict, "Already following", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, op+" failed", err.Error())
+	}
+}
+
+// Follow handles POST /channels/:channel_id/followers
+//
+//	body: { "target_channel_id": "..." }
+func (cc *ChannelsController) Follow(c *gin.Context) {
+	var body struct {
+		TargetChannelID string `json:"target_channel_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	f, err := cc.svc.Follow(auditContext(c), c.Param("channel_id"), body.TargetChannelID, c.GetString("user_id"))
+	if err != nil {
+		cc.respondFollowError(c, "Follow", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Channel followed", f)
+}
+
+// ListFollowers handles GET /channels/:channel_id/followers
+func (cc *ChannelsController) ListFollowers(c *gin.Context) {
+	out, err := cc.svc.ListFollowers(c.Request.Context(), c.Param("channel_id"), c.GetString("user_id"))
+	if err != nil {
+		cc.respondFollowError(c, "ListFollowers", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Followers fetched", out)
+}
+
+// Unfollow handles DELETE /channels/:channel_id/followers/:follower_id
+func (cc *ChannelsController) Unfollow(c *gin.Context) {
+	if err := cc.svc.Unfollow(auditContext(c), c.Param("follower_id"), c.GetString("user_id")); err != nil {
+		cc.respondFollowError(c, "Unfollow", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Channel unfollowed", nil)
+}
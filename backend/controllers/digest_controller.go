@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"launay-dot-one/middlewares"
+	"launay-dot-one/models"
+	"launay-dot-one/services/digest"
+	"launay-dot-one/utils"
+)
+
+type DigestController struct {
+	svc    digest.Service
+	logger *logrus.Logger
+}
+
+func NewDigestController(svc digest.Service, logger *logrus.Logger) *DigestController {
+	return &DigestController{svc: svc, logger: logger}
+}
+
+func (dc *DigestController) RegisterRoutes(r *gin.Engine) {
+	auth := middlewares.AuthMiddleware()
+
+	r.GET("/notifications/digest", auth, dc.GetSettings)
+	r.PUT("/notifications/digest", auth, dc.UpdateSettings)
+
+	// no session: the signed token in the link authorises these
+	r.GET("/digest/unsubscribe/:token", dc.ConfirmUnsubscribe)
+	r.POST("/digest/unsubscribe/:token", dc.Unsubscribe)
+}
+
+func (dc *DigestController) respondError(c *gin.Context, op string, err error) {
+	dc.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, digest.ErrInvalidFrequency):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid settings", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, op+" failed", err.Error())
+	}
+}
+
+// GetSettings handles GET /notifications/digest
+func (dc *DigestController) GetSettings(c *gin.Context) {
+	out, err := dc.svc.Settings(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		dc.respondError(c, "GetDigestSettings", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Digest settings fetched", out)
+}
+
+// UpdateSettings handles PUT /notifications/digest
+//
+//	body: { "frequency": "daily" | "weekly" | "never" }
+func (dc *DigestController) UpdateSettings(c *gin.Context) {
+	var body struct {
+		Frequency models.DigestFrequency `json:"frequency" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	out, err := dc.svc.UpdateSettings(c.Request.Context(), c.GetString("user_id"), body.Frequency)
+	if err != nil {
+		dc.respondError(c, "UpdateDigestSettings", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Digest settings updated", out)
+}
+
+// unsubscribePage is what the unsubscribe link shows in a browser. The
+// link itself only asks: mail scanners follow links, and shouldn't opt
+// anyone out by doing so.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html><body style="font-family:Helvetica,Arial,sans-serif;max-width:480px;margin:48px auto;text-align:center">
+{{if .Done}}<p>You won't get email digests any more. You can turn them back on in your notification settings.</p>
+{{else if .Invalid}}<p>This unsubscribe link is invalid.</p>
+{{else}}<p>Stop getting email digests of the messages you missed?</p>
+<form method="post"><button type="submit">Unsubscribe</button></form>
+{{end}}</body></html>`))
+
+func renderUnsubscribe(c *gin.Context, status int, done, invalid bool) {
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	_ = unsubscribePage.Execute(c.Writer, gin.H{"Done": done, "Invalid": invalid})
+}
+
+// ConfirmUnsubscribe handles GET /digest/unsubscribe/:token, a page
+// confirming the unsubscribe.
+func (dc *DigestController) ConfirmUnsubscribe(c *gin.Context) {
+	renderUnsubscribe(c, http.StatusOK, false, false)
+}
+
+// Unsubscribe handles POST /digest/unsubscribe/:token, from the page or as
+// a mail client's one-click unsubscribe (RFC 8058).
+func (dc *DigestController) Unsubscribe(c *gin.Context) {
+	err := dc.svc.Unsubscribe(c.Request.Context(), c.Param("token"))
+	switch {
+	case errors.Is(err, digest.ErrInvalidToken):
+		renderUnsubscribe(c, http.StatusNotFound, false, true)
+	case err != nil:
+		dc.logger.Error("DigestUnsubscribe error: ", err)
+		renderUnsubscribe(c, http.StatusInternalServerError, false, false)
+	default:
+		renderUnsubscribe(c, http.StatusOK, true, false)
+	}
+}
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"launay-dot-one/middlewares"
+	"launay-dot-one/models"
+	"launay-dot-one/services/events"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+)
+
+type EventSubscriptionsController struct {
+	svc    events.Service
+	logger *logrus.Logger
+}
+
+func NewEventSubscriptionsController(svc events.Service, logger *logrus.Logger) *EventSubscriptionsController {
+	return &EventSubscriptionsController{svc: svc, logger: logger}
+}
+
+func (ec *EventSubscriptionsController) RegisterRoutes(r *gin.Engine) {
+	auth := middlewares.AuthMiddleware()
+	r.GET("/guilds/:guild_id/event-subscriptions", auth, ec.List)
+	r.POST("/guilds/:guild_id/event-subscriptions", auth, ec.Create)
+
+	admin := r.Group("/admin", auth, middlewares.RequireRole(models.RoleAdmin))
+	{
+		admin.GET("/event-subscriptions", ec.List)
+		admin.POST("/event-subscriptions", ec.Create)
+	}
+
+	subs := r.Group("/event-subscriptions", auth)
+	{
+		subs.PATCH("/:subscription_id", ec.Update)
+		subs.DELETE("/:subscription_id", ec.Delete)
+		subs.PUT("/:subscription_id/secret", ec.RotateSecret)
+		subs.GET("/:subscription_id/deliveries", ec.ListDeliveries)
+		subs.GET("/:subscription_id/deliveries/:delivery_id", ec.GetDelivery)
+		subs.POST("/:subscription_id/deliveries/:delivery_id/redeliver", ec.Redeliver)
+	}
+}
+
+func eventActor(c *gin.Context) events.Actor {
+	return events.Actor{UserID: c.GetString("user_id"), Role: c.GetString("user_role")}
+}
+
+func (ec *EventSubscriptionsController) respondError(c *gin.Context, op string, err error) {
+	ec.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	case errors.Is(err, events.ErrAdminOnly),
+		errors.Is(err, permissions.ErrMissingPermission),
+		errors.Is(err, permissions.ErrNotMember):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, events.ErrInvalidURL),
+		errors.Is(err, events.ErrInvalidEvents),
+		errors.Is(err, events.ErrTooManySubscriptions):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid request", err.Error())
+	case errors.Is(err, events.ErrSubscriptionDisabled):
+		utils.RespondError(c, http.StatusConflict, "Conflict", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, op+" failed", err.Error())
+	}
+}
+
+// Create handles POST /guilds/:guild_id/event-subscriptions and, for
+// platform-wide subscriptions, POST /admin/event-subscriptions
+//
+//	body: { "url": "https://...", "events": ["message.created", "member.joined"] }
+//
+// The response carries the signing secret; it is not shown again.
+func (ec *EventSubscriptionsController) Create(c *gin.Context) {
+	var body struct {
+		URL    string   `json:"url" binding:"required"`
+		Events []string `json:"events" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	sub := models.EventSubscription{URL: body.URL, Events: body.Events}
+	if guildID := c.Param("guild_id"); guildID != "" {
+		sub.GuildID = &guildID
+	}
+	secret, err := ec.svc.Create(auditContext(c), &sub, eventActor(c))
+	if err != nil {
+		ec.respondError(c, "CreateEventSubscription", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Subscription created", gin.H{"subscription": sub, "secret": secret})
+}
+
+// List handles GET /guilds/:guild_id/event-subscriptions and
+// GET /admin/event-subscriptions
+func (ec *EventSubscriptionsController) List(c *gin.Context) {
+	out, err := ec.svc.List(c.Request.Context(), c.Param("guild_id"), eventActor(c))
+	if err != nil {
+		ec.respondError(c, "ListEventSubscriptions", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Subscriptions fetched", out)
+}
+
+// Update handles PATCH /event-subscriptions/:subscription_id
+//
+//	body: { "url": "...", "events": [...], "enabled": true }  // all optional
+func (ec *EventSubscriptionsController) Update(c *gin.Context) {
+	var body struct {
+		URL     *string   `json:"url"`
+		Events  *[]string `json:"events"`
+		Enabled *bool     `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	sub, err := ec.svc.Update(auditContext(c), c.Param("subscription_id"), events.Patch{
+		URL:     body.URL,
+		Events:  body.Events,
+		Enabled: body.Enabled,
+	}, eventActor(c))
+	if err != nil {
+		ec.respondError(c, "UpdateEventSubscription", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Subscription updated", sub)
+}
+
+// Delete handles DELETE /event-subscriptions/:subscription_id
+func (ec *EventSubscriptionsController) Delete(c *gin.Context) {
+	if err := ec.svc.Delete(auditContext(c), c.Param("subscription_id"), eventActor(c)); err != nil {
+		ec.respondError(c, "DeleteEventSubscription", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Subscription deleted", nil)
+}
+
+// RotateSecret handles PUT /event-subscriptions/:subscription_id/secret.
+// Deliveries are signed with the new secret from then on.
+func (ec *EventSubscriptionsController) RotateSecret(c *gin.Context) {
+	secret, err := ec.svc.RotateSecret(auditContext(c), c.Param("subscription_id"), eventActor(c))
+	if err != nil {
+		ec.respondError(c, "RotateEventSecret", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Secret rotated", gin.H{"secret": secret})
+}
+
+// ListDeliveries handles GET /event-subscriptions/:subscription_id/deliveries
+//
+//	query: status (pending|succeeded|failed), page, limit
+func (ec *EventSubscriptionsController) ListDeliveries(c *gin.Context) {
+	status := models.DeliveryStatus(c.Query("status"))
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed:
+	default:
+		utils.RespondError(c, http.StatusBadRequest, "Invalid status", "status must be pending, succeeded or failed")
+		return
+	}
+	page, limit := utils.Pagination(c, 25, 100)
+	out, err := ec.svc.ListDeliveries(c.Request.Context(), c.Param("subscription_id"), status, eventActor(c), page, limit)
+	if err != nil {
+		ec.respondError(c, "ListEventDeliveries", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Deliveries fetched", out)
+}
+
+// GetDelivery handles GET /event-subscriptions/:subscription_id/deliveries/:delivery_id
+func (ec *EventSubscriptionsController) GetDelivery(c *gin.Context) {
+	d, err := ec.svc.GetDelivery(c.Request.Context(), c.Param("subscription_id"), c.Param("delivery_id"), eventActor(c))
+	if err != nil {
+		ec.respondError(c, "GetEventDelivery", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Delivery fetched", d)
+}
+
+// Redeliver handles POST /event-subscriptions/:subscription_id/deliveries/:delivery_id/redeliver
+func (ec *EventSubscriptionsController) Redeliver(c *gin.Context) {
+	d, err := ec.svc.Redeliver(c.Request.Context(), c.Param("subscription_id"), c.Param("delivery_id"), eventActor(c))
+	if err != nil {
+		ec.respondError(c, "RedeliverEvent", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusAccepted, "Delivery queued", d)
+}
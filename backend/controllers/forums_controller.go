@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"launay-dot-one/middlewares"
+	"launay-dot-one/models/guilds"
+	forumsvc "launay-dot-one/services/forums"
+	"launay-dot-one/services/messaging"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+)
+
+type ForumsController struct {
+	svc    forumsvc.Service
+	logger *logrus.Logger
+}
+
+func NewForumsController(svc forumsvc.Service, logger *logrus.Logger) *ForumsController {
+	return &ForumsController{svc: svc, logger: logger}
+}
+
+func (fc *ForumsController) RegisterRoutes(r *gin.Engine) {
+	g := r.Group("/channels/:channel_id", middlewares.AuthMiddleware())
+	{
+		g.GET("/tags", fc.ListTags)
+		g.POST("/tags", fc.CreateTag)
+		g.PUT("/tags/:tag_id", fc.UpdateTag)
+		g.DELETE("/tags/:tag_id", fc.DeleteTag)
+
+		g.GET("/posts", fc.ListPosts)
+		g.POST("/posts", fc.CreatePost)
+	}
+}
+
+func (fc *ForumsController) respondError(c *gin.Context, op string, err error) {
+	fc.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	case errors.Is(err, permissions.ErrMissingPermission),
+		errors.Is(err, permissions.ErrNotMember),
+		errors.Is(err, messaging.ErrNotGuildMember),
+		errors.Is(err, messaging.ErrTimedOut),
+		errors.Is(err, forumsvc.ErrModeratedTag):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, forumsvc.ErrNotForum),
+		errors.Is(err, forumsvc.ErrInvalidTitle),
+		errors.Is(err, forumsvc.ErrInvalidTag),
+		errors.Is(err, forumsvc.ErrTooManyTags),
+		errors.Is(err, forumsvc.ErrUnknownTag),
+		errors.Is(err, forumsvc.ErrEmptyContent):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid request", err.Error())
+	case errors.Is(err, messaging.ErrSlowmode):
+		utils.RespondError(c, http.StatusTooManyRequests, "Slowmode", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, op+" failed", err.Error())
+	}
+}
+
+// ListTags handles GET /channels/:channel_id/tags
+func (fc *ForumsController) ListTags(c *gin.Context) {
+	out, err := fc.svc.ListTags(c.Request.Context(), c.Param("channel_id"))
+	if err != nil {
+		fc.respondError(c, "ListTags", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Tags fetched", out)
+}
+
+// CreateTag handles POST /channels/:channel_id/tags
+//
+//	body: { "name": "question", "emoji": "❓", "moderated": false }
+func (fc *ForumsController) CreateTag(c *gin.Context) {
+	var tag guilds.ForumTag
+	if err := c.ShouldBindJSON(&tag); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	tag.ChannelID = c.Param("channel_id")
+	if err := fc.svc.CreateTag(auditContext(c), &tag, c.GetString("user_id")); err != nil {
+		fc.respondError(c, "CreateTag", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Tag created", tag)
+}
+
+// UpdateTag handles PUT /channels/:channel_id/tags/:tag_id
+func (fc *ForumsController) UpdateTag(c *gin.Context) {
+	var tag guilds.ForumTag
+	if err := c.ShouldBindJSON(&tag); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	tag.ID = c.Param("tag_id")
+	tag.ChannelID = c.Param("channel_id")
+	if err := fc.svc.UpdateTag(auditContext(c), &tag, c.GetString("user_id")); err != nil {
+		fc.respondError(c, "UpdateTag", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Tag updated", tag)
+}
+
+// DeleteTag handles DELETE /channels/:channel_id/tags/:tag_id
+func (fc *ForumsController) DeleteTag(c *gin.Context) {
+	if err := fc.svc.DeleteTag(auditContext(c), c.Param("tag_id"), c.GetString("user_id")); err != nil {
+		fc.respondError(c, "DeleteTag", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Tag deleted", nil)
+}
+
+// ListPosts handles GET /channels/:channel_id/posts
+//
+//	query: tag_id, page, limit
+func (fc *ForumsController) ListPosts(c *gin.Context) {
+	page, limit := utils.Pagination(c, 25, 100)
+	out, err := fc.svc.ListPosts(c.Request.Context(), c.Param("channel_id"), c.Query("tag_id"), page, limit)
+	if err != nil {
+		fc.respondError(c, "ListPosts", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Posts fetched", out)
+}
+
+// CreatePost handles POST /channels/:channel_id/posts
+//
+//	body: { "title": "...", "content": "...", "tag_ids": ["..."] }
+func (fc *ForumsController) CreatePost(c *gin.Context) {
+	var body struct {
+		Title   string   `json:"title" binding:"required"`
+		Content string   `json:"content" binding:"required"`
+		TagIDs  []string `json:"tag_ids"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	post := guilds.ForumPost{
+		ChannelID: c.Param("channel_id"),
+		AuthorID:  c.GetString("user_id"),
+		Title:     body.Title,
+		TagIDs:    body.TagIDs,
+	}
+	msg, err := fc.svc.CreatePost(c.Request.Context(), &post, body.Content)
+	if err != nil {
+		fc.respondError(c, "CreatePost", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Post created", gin.H{"post": post, "message": msg})
+}
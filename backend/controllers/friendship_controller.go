@@ -1,10 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 
 	"launay-dot-one/middlewares"
 	frsvc "launay-dot-one/services/friendships"
@@ -25,26 +27,67 @@ func (fc *FriendshipController) RegisterRoutes(r *gin.Engine) {
 	{
 		grp.POST("/requests", fc.SendRequest)
 		grp.POST("/requests/:request_id/respond", fc.RespondRequest)
+		grp.DELETE("/requests/:request_id", fc.CancelRequest)
 		grp.GET("/requests", fc.ListRequests)
 		grp.GET("", fc.ListFriends)
+		grp.DELETE("/:user_id", fc.Unfriend)
+
+		grp.GET("/blocks", fc.ListBlocked)
+		grp.PUT("/blocks/:user_id", fc.Block)
+		grp.DELETE("/blocks/:user_id", fc.Unblock)
+
+		grp.GET("/privacy", fc.GetPrivacy)
+		grp.PATCH("/privacy", fc.UpdatePrivacy)
+	}
+
+	r.GET("/users/:user_id/mutual-friends", middlewares.AuthMiddleware(), fc.MutualFriends)
+}
+
+func (fc *FriendshipController) respondError(c *gin.Context, op string, err error) {
+	fc.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	case errors.Is(err, frsvc.ErrNotReceiver),
+		errors.Is(err, frsvc.ErrNotRequester),
+		errors.Is(err, frsvc.ErrRequestsClosed):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, frsvc.ErrRequestExists),
+		errors.Is(err, frsvc.ErrNotPending):
+		utils.RespondError(c, http.StatusConflict, "Conflict", err.Error())
+	case errors.Is(err, frsvc.ErrNotFriends),
+		errors.Is(err, frsvc.ErrNotBlocked):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	case errors.Is(err, frsvc.ErrSelfRequest),
+		errors.Is(err, frsvc.ErrSelfBlock),
+		errors.Is(err, frsvc.ErrInvalidPrivacy):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid request", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, op+" failed", err.Error())
 	}
 }
 
 // SendRequest handles POST /friends/requests
 //
-//	body: { "to_id": "<target user ID>" }
+//	body: { "to_id": "<target user ID>" }  or  { "username": "jane" }
 func (fc *FriendshipController) SendRequest(c *gin.Context) {
 	var body struct {
-		ToID string `json:"to_id" binding:"required"`
+		ToID     string `json:"to_id" binding:"required_without=Username"`
+		Username string `json:"username" binding:"required_without=ToID"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
 		return
 	}
 	fromID := c.GetString("user_id")
-	if err := fc.svc.SendRequest(c.Request.Context(), fromID, body.ToID); err != nil {
-		fc.logger.Error("SendRequest error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to send friend request", err.Error())
+	var err error
+	if body.ToID != "" {
+		err = fc.svc.SendRequest(c.Request.Context(), fromID, body.ToID)
+	} else {
+		err = fc.svc.SendRequestByUsername(c.Request.Context(), fromID, body.Username)
+	}
+	if err != nil {
+		fc.respondError(c, "SendRequest", err)
 		return
 	}
 	utils.RespondSuccess(c, http.StatusCreated, "Friend request sent", nil)
@@ -62,9 +105,9 @@ func (fc *FriendshipController) RespondRequest(c *gin.Context) {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
 		return
 	}
-	if err := fc.svc.RespondRequest(c.Request.Context(), requestID, body.Accept); err != nil {
-		fc.logger.Error("RespondRequest error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to respond to request", err.Error())
+	err := fc.svc.RespondRequest(c.Request.Context(), requestID, c.GetString("user_id"), body.Accept)
+	if err != nil {
+		fc.respondError(c, "RespondRequest", err)
 		return
 	}
 	msg := "Friend request rejected"
@@ -74,6 +117,15 @@ func (fc *FriendshipController) RespondRequest(c *gin.Context) {
 	utils.RespondSuccess(c, http.StatusOK, msg, nil)
 }
 
+// CancelRequest handles DELETE /friends/requests/:request_id
+func (fc *FriendshipController) CancelRequest(c *gin.Context) {
+	if err := fc.svc.CancelRequest(c.Request.Context(), c.Param("request_id"), c.GetString("user_id")); err != nil {
+		fc.respondError(c, "CancelRequest", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Friend request cancelled", nil)
+}
+
 // ListRequests handles GET /friends/requests
 func (fc *FriendshipController) ListRequests(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -97,3 +149,78 @@ func (fc *FriendshipController) ListFriends(c *gin.Context) {
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Friends fetched", friends)
 }
+
+// MutualFriends handles GET /users/:user_id/mutual-friends
+func (fc *FriendshipController) MutualFriends(c *gin.Context) {
+	list, err := fc.svc.MutualFriends(c.Request.Context(), c.GetString("user_id"), c.Param("user_id"))
+	if err != nil {
+		fc.respondError(c, "MutualFriends", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Mutual friends fetched", list)
+}
+
+// Unfriend handles DELETE /friends/:user_id
+func (fc *FriendshipController) Unfriend(c *gin.Context) {
+	if err := fc.svc.Unfriend(c.Request.Context(), c.GetString("user_id"), c.Param("user_id")); err != nil {
+		fc.respondError(c, "Unfriend", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Friend removed", nil)
+}
+
+// ListBlocked handles GET /friends/blocks
+func (fc *FriendshipController) ListBlocked(c *gin.Context) {
+	list, err := fc.svc.ListBlocked(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		fc.respondError(c, "ListBlocked", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Blocked users fetched", list)
+}
+
+// Block handles PUT /friends/blocks/:user_id
+func (fc *FriendshipController) Block(c *gin.Context) {
+	if err := fc.svc.Block(c.Request.Context(), c.GetString("user_id"), c.Param("user_id")); err != nil {
+		fc.respondError(c, "Block", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "User blocked", nil)
+}
+
+// Unblock handles DELETE /friends/blocks/:user_id
+func (fc *FriendshipController) Unblock(c *gin.Context) {
+	if err := fc.svc.Unblock(c.Request.Context(), c.GetString("user_id"), c.Param("user_id")); err != nil {
+		fc.respondError(c, "Unblock", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "User unblocked", nil)
+}
+
+// GetPrivacy handles GET /friends/privacy
+func (fc *FriendshipController) GetPrivacy(c *gin.Context) {
+	ps, err := fc.svc.GetPrivacy(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		fc.respondError(c, "GetPrivacy", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Privacy settings fetched", ps)
+}
+
+// UpdatePrivacy handles PATCH /friends/privacy
+//
+//	body: { "friend_requests": "everyone" | "friends_of_friends" | "nobody",
+//	        "direct_messages": "everyone" | "friends" | "nobody" }
+func (fc *FriendshipController) UpdatePrivacy(c *gin.Context) {
+	var body frsvc.PrivacyPatch
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	ps, err := fc.svc.UpdatePrivacy(c.Request.Context(), c.GetString("user_id"), body)
+	if err != nil {
+		fc.respondError(c, "UpdatePrivacy", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Privacy settings updated", ps)
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -9,6 +10,7 @@ import (
 	"launay-dot-one/middlewares"
 	"launay-dot-one/models/guilds"
 	grsvc "launay-dot-one/services/guildroles"
+	"launay-dot-one/services/permissions"
 	"launay-dot-one/utils"
 )
 
@@ -34,6 +36,7 @@ func (rc *GuildRolesController) RegisterRoutes(r *gin.Engine) {
 	{
 		grp.GET("", rc.List)
 		grp.POST("", rc.Create)
+		grp.PATCH("", rc.Reorder)
 		grp.GET("/:role_id", rc.Get)
 		grp.PUT("/:role_id", rc.Update)
 		grp.DELETE("/:role_id", rc.Delete)
@@ -75,8 +78,12 @@ func (rc *GuildRolesController) Create(c *gin.Context) {
 		Position:    in.Position,
 	}
 
-	if err := rc.svc.Create(c.Request.Context(), &role); err != nil {
+	if err := rc.svc.Create(auditContext(c), &role, c.GetString("user_id")); err != nil {
 		rc.logger.Error("Create role error: ", err)
+		if isRoleForbidden(err) {
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+			return
+		}
 		utils.RespondError(c, http.StatusInternalServerError, "Failed to create role", err.Error())
 		return
 	}
@@ -108,8 +115,16 @@ func (rc *GuildRolesController) Update(c *gin.Context) {
 		Position:    in.Position,
 	}
 
-	if err := rc.svc.Update(c.Request.Context(), &role); err != nil {
+	if err := rc.svc.Update(auditContext(c), &role, c.GetString("user_id")); err != nil {
 		rc.logger.Error("Update role error: ", err)
+		if isRoleForbidden(err) {
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+			return
+		}
+		if errors.Is(err, grsvc.ErrEveryoneRole) || errors.Is(err, grsvc.ErrManagedRole) {
+			utils.RespondError(c, http.StatusBadRequest, "Invalid role update", err.Error())
+			return
+		}
 		utils.RespondError(c, http.StatusInternalServerError, "Failed to update role", err.Error())
 		return
 	}
@@ -129,10 +144,61 @@ func (rc *GuildRolesController) Get(c *gin.Context) {
 
 func (rc *GuildRolesController) Delete(c *gin.Context) {
 	roleID := c.Param("role_id")
-	if err := rc.svc.Delete(c.Request.Context(), roleID); err != nil {
+	if err := rc.svc.Delete(auditContext(c), roleID, c.GetString("user_id")); err != nil {
 		rc.logger.Error("Delete role error: ", err)
+		if isRoleForbidden(err) {
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+			return
+		}
+		if errors.Is(err, grsvc.ErrEveryoneRole) || errors.Is(err, grsvc.ErrManagedRole) {
+			utils.RespondError(c, http.StatusBadRequest, "Invalid role deletion", err.Error())
+			return
+		}
 		utils.RespondError(c, http.StatusInternalServerError, "Failed to delete role", err.Error())
 		return
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Role deleted", nil)
 }
+
+// Reorder handles PATCH /guilds/:guild_id/roles
+//
+//	body: [{ "id": "...", "position": 1 }]
+func (rc *GuildRolesController) Reorder(c *gin.Context) {
+	var payload []struct {
+		ID       string `json:"id" binding:"required"`
+		Position int    `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	moves := make([]grsvc.Move, len(payload))
+	for i, p := range payload {
+		moves[i] = grsvc.Move{ID: p.ID, Position: p.Position}
+	}
+	if err := rc.svc.Reorder(auditContext(c), c.Param("guild_id"), moves, c.GetString("user_id")); err != nil {
+		rc.logger.Error("Reorder roles error: ", err)
+		switch {
+		case errors.Is(err, permissions.ErrMissingPermission),
+			errors.Is(err, permissions.ErrNotMember),
+			errors.Is(err, permissions.ErrHierarchy):
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		case errors.Is(err, grsvc.ErrUnknownRole),
+			errors.Is(err, grsvc.ErrDuplicateMove),
+			errors.Is(err, grsvc.ErrEveryoneRole):
+			utils.RespondError(c, http.StatusBadRequest, "Invalid reorder", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to reorder roles", err.Error())
+		}
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Roles reordered", nil)
+}
+
+// isRoleForbidden reports whether err denies the actor the role change.
+func isRoleForbidden(err error) bool {
+	return errors.Is(err, permissions.ErrMissingPermission) ||
+		errors.Is(err, permissions.ErrNotMember) ||
+		errors.Is(err, permissions.ErrHierarchy) ||
+		errors.Is(err, permissions.ErrCannotGrant)
+}
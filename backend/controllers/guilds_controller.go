@@ -1,13 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 
 	"launay-dot-one/middlewares"
 	mg "launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
 	guildsvc "launay-dot-one/services/guilds"
 	"launay-dot-one/utils"
 )
@@ -29,24 +33,51 @@ func (gc *GuildController) RegisterRoutes(r *gin.Engine) {
 		grp.GET("/:guild_id", gc.GetGuild)
 		grp.PUT("/:guild_id", gc.UpdateGuild)
 		grp.DELETE("/:guild_id", gc.DeleteGuild)
+		grp.POST("/:guild_id/transfer-ownership", gc.TransferOwnership)
 
 		grp.POST("/:guild_id/members", gc.AddMember)
 		grp.PUT("/:guild_id/members/:user_id", gc.UpdateMemberRoles)
 		grp.DELETE("/:guild_id/members/:user_id", gc.RemoveMember)
 		grp.GET("/:guild_id/members", gc.ListMembers)
+		grp.PATCH("/:guild_id/members/@me", gc.UpdateOwnMember)
+		grp.PUT("/:guild_id/members/:user_id/roles/:role_id", gc.AddMemberRole)
+		grp.DELETE("/:guild_id/members/:user_id/roles/:role_id", gc.RemoveMemberRole)
+		grp.GET("/:guild_id/roles/:role_id/members", gc.ListMembersByRole)
+
+		grp.GET("/:guild_id/audit-logs", gc.ListAuditLog)
+		grp.POST("/:guild_id/templates", gc.ExportTemplate)
+	}
+
+	r.GET("/users/:user_id/mutual-guilds", middlewares.AuthMiddleware(), gc.MutualGuilds)
+
+	tpl := r.Group("/guild-templates", middlewares.AuthMiddleware())
+	{
+		tpl.GET("", gc.ListTemplates)
+		tpl.GET("/:template_id", gc.GetTemplate)
 	}
 }
 
+// CreateGuild handles POST /guilds
+//
+//	body: { "name": "...", "description": "...", "template": "community" }  // template optional
 func (gc *GuildController) CreateGuild(c *gin.Context) {
-	var guild mg.Guild
-	if err := c.ShouldBindJSON(&guild); err != nil {
+	var payload struct {
+		mg.Guild
+		Template string `json:"template"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
 		return
 	}
+	guild := payload.Guild
 	ownerID := c.GetString("user_id")
-	if err := gc.svc.CreateGuild(c.Request.Context(), &guild, ownerID); err != nil {
+	if err := gc.svc.CreateGuild(auditContext(c), &guild, payload.Template, ownerID); err != nil {
 		gc.logger.Error("CreateGuild error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to create guild", err.Error())
+		if err == guildsvc.ErrUnknownTemplate {
+			utils.RespondError(c, http.StatusBadRequest, "Unknown template", err.Error())
+		} else {
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to create guild", err.Error())
+		}
 		return
 	}
 	utils.RespondSuccess(c, http.StatusCreated, "Guild created", guild)
@@ -81,7 +112,7 @@ func (gc *GuildController) UpdateGuild(c *gin.Context) {
 		return
 	}
 	requester := c.GetString("user_id")
-	if err := gc.svc.UpdateGuild(c.Request.Context(), id, &upd, requester); err != nil {
+	if err := gc.svc.UpdateGuild(auditContext(c), id, &upd, requester); err != nil {
 		gc.logger.Error("UpdateGuild error: ", err)
 		if err == guildsvc.ErrUnauthorized {
 			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
@@ -93,14 +124,27 @@ func (gc *GuildController) UpdateGuild(c *gin.Context) {
 	utils.RespondSuccess(c, http.StatusOK, "Guild updated", nil)
 }
 
+// DeleteGuild handles DELETE /guilds/:guild_id
+//
+//	body: { "password": "..." }
 func (gc *GuildController) DeleteGuild(c *gin.Context) {
+	var payload struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
 	id := c.Param("guild_id")
 	requester := c.GetString("user_id")
-	if err := gc.svc.DeleteGuild(c.Request.Context(), id, requester); err != nil {
+	if err := gc.svc.DeleteGuild(auditContext(c), id, payload.Password, requester); err != nil {
 		gc.logger.Error("DeleteGuild error: ", err)
-		if err == guildsvc.ErrUnauthorized {
+		switch err {
+		case guildsvc.ErrUnauthorized:
 			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
-		} else {
+		case guildsvc.ErrInvalidPassword:
+			utils.RespondError(c, http.StatusUnauthorized, "Invalid password", err.Error())
+		default:
 			utils.RespondError(c, http.StatusInternalServerError, "Failed to delete guild", err.Error())
 		}
 		return
@@ -108,6 +152,35 @@ func (gc *GuildController) DeleteGuild(c *gin.Context) {
 	utils.RespondSuccess(c, http.StatusOK, "Guild deleted", nil)
 }
 
+// TransferOwnership handles POST /guilds/:guild_id/transfer-ownership
+//
+//	body: { "user_id": "..." }
+func (gc *GuildController) TransferOwnership(c *gin.Context) {
+	var payload struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	err := gc.svc.TransferOwnership(auditContext(c), c.Param("guild_id"), payload.UserID, c.GetString("user_id"))
+	if err != nil {
+		gc.logger.Error("TransferOwnership error: ", err)
+		switch {
+		case errors.Is(err, guildsvc.ErrUnauthorized):
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		case errors.Is(err, guildsvc.ErrNotMember):
+			utils.RespondError(c, http.StatusBadRequest, "Invalid new owner", err.Error())
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Guild not found", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to transfer ownership", err.Error())
+		}
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Ownership transferred", nil)
+}
+
 func (gc *GuildController) AddMember(c *gin.Context) {
 	guildID := c.Param("guild_id")
 	var payload struct {
@@ -119,11 +192,16 @@ func (gc *GuildController) AddMember(c *gin.Context) {
 		return
 	}
 	requester := c.GetString("user_id")
-	if err := gc.svc.AddMember(c.Request.Context(), guildID, payload.UserID, payload.RoleIDs, requester); err != nil {
+	if err := gc.svc.AddMember(auditContext(c), guildID, payload.UserID, payload.RoleIDs, requester); err != nil {
 		gc.logger.Error("AddMember error: ", err)
-		if err == guildsvc.ErrUnauthorized {
+		switch err {
+		case guildsvc.ErrUnauthorized, guildsvc.ErrRoleHierarchy, repositories.ErrBannedFromGuild:
 			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
-		} else {
+		case guildsvc.ErrInvalidRole, guildsvc.ErrManagedRole:
+			utils.RespondError(c, http.StatusBadRequest, "Invalid role", err.Error())
+		case guildsvc.ErrBotMember:
+			utils.RespondError(c, http.StatusBadRequest, "Invalid member", err.Error())
+		default:
 			utils.RespondError(c, http.StatusInternalServerError, "Failed to add member", err.Error())
 		}
 		return
@@ -142,13 +220,8 @@ func (gc *GuildController) UpdateMemberRoles(c *gin.Context) {
 		return
 	}
 	requester := c.GetString("user_id")
-	if err := gc.svc.UpdateMemberRoles(c.Request.Context(), guildID, userID, payload.RoleIDs, requester); err != nil {
-		gc.logger.Error("UpdateMemberRoles error: ", err)
-		if err == guildsvc.ErrUnauthorized {
-			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
-		} else {
-			utils.RespondError(c, http.StatusInternalServerError, "Failed to update member roles", err.Error())
-		}
+	if err := gc.svc.UpdateMemberRoles(auditContext(c), guildID, userID, payload.RoleIDs, requester); err != nil {
+		gc.respondRoleError(c, "UpdateMemberRoles", "Failed to update member roles", err)
 		return
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Member roles updated", nil)
@@ -158,7 +231,7 @@ func (gc *GuildController) RemoveMember(c *gin.Context) {
 	guildID := c.Param("guild_id")
 	userID := c.Param("user_id")
 	requester := c.GetString("user_id")
-	if err := gc.svc.RemoveMember(c.Request.Context(), guildID, userID, requester); err != nil {
+	if err := gc.svc.RemoveMember(auditContext(c), guildID, userID, requester); err != nil {
 		gc.logger.Error("RemoveMember error: ", err)
 		if err == guildsvc.ErrUnauthorized {
 			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
@@ -180,3 +253,187 @@ func (gc *GuildController) ListMembers(c *gin.Context) {
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Members fetched", list)
 }
+
+// MutualGuilds handles GET /users/:user_id/mutual-guilds
+func (gc *GuildController) MutualGuilds(c *gin.Context) {
+	list, err := gc.svc.MutualGuilds(c.Request.Context(), c.GetString("user_id"), c.Param("user_id"))
+	if err != nil {
+		gc.logger.Error("MutualGuilds error: ", err)
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to list mutual guilds", err.Error())
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Mutual guilds fetched", list)
+}
+
+// UpdateOwnMember handles PATCH /guilds/:guild_id/members/@me, the
+// caller's own settings in the guild
+//
+//	body: { "hide_activity": true }
+func (gc *GuildController) UpdateOwnMember(c *gin.Context) {
+	var payload struct {
+		HideActivity *bool `json:"hide_activity" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	err := gc.svc.SetHideActivity(c.Request.Context(), c.Param("guild_id"), c.GetString("user_id"), *payload.HideActivity)
+	if err != nil {
+		gc.logger.Error("UpdateOwnMember error: ", err)
+		if errors.Is(err, guildsvc.ErrNotMember) {
+			utils.RespondError(c, http.StatusNotFound, "Not a member", err.Error())
+		} else {
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to update member", err.Error())
+		}
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Member updated", nil)
+}
+
+// respondRoleError maps role-assignment failures to HTTP statuses.
+func (gc *GuildController) respondRoleError(c *gin.Context, op, msg string, err error) {
+	gc.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, guildsvc.ErrUnauthorized), errors.Is(err, guildsvc.ErrRoleHierarchy):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, guildsvc.ErrInvalidRole), errors.Is(err, guildsvc.ErrManagedRole):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid role", err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, msg, err.Error())
+	}
+}
+
+// AddMemberRole handles PUT /guilds/:guild_id/members/:user_id/roles/:role_id
+func (gc *GuildController) AddMemberRole(c *gin.Context) {
+	if err := gc.svc.AddMemberRole(auditContext(c),
+		c.Param("guild_id"), c.Param("user_id"), c.Param("role_id"), c.GetString("user_id"),
+	); err != nil {
+		gc.respondRoleError(c, "AddMemberRole", "Failed to add role", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Role added", nil)
+}
+
+// RemoveMemberRole handles DELETE /guilds/:guild_id/members/:user_id/roles/:role_id
+func (gc *GuildController) RemoveMemberRole(c *gin.Context) {
+	if err := gc.svc.RemoveMemberRole(auditContext(c),
+		c.Param("guild_id"), c.Param("user_id"), c.Param("role_id"), c.GetString("user_id"),
+	); err != nil {
+		gc.respondRoleError(c, "RemoveMemberRole", "Failed to remove role", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Role removed", nil)
+}
+
+// ListMembersByRole handles GET /guilds/:guild_id/roles/:role_id/members
+func (gc *GuildController) ListMembersByRole(c *gin.Context) {
+	list, err := gc.svc.ListMembersByRole(c.Request.Context(), c.Param("guild_id"), c.Param("role_id"))
+	if err != nil {
+		gc.respondRoleError(c, "ListMembersByRole", "Failed to list members", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Members fetched", list)
+}
+
+// ListAuditLog handles GET /guilds/:guild_id/audit-logs
+//
+//	query: action, actor_id, since, until (RFC 3339), page, limit
+func (gc *GuildController) ListAuditLog(c *gin.Context) {
+	since, err := parseTimeQuery(c, "since")
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid since", err.Error())
+		return
+	}
+	until, err := parseTimeQuery(c, "until")
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid until", err.Error())
+		return
+	}
+	f := repositories.AuditLogFilter{
+		Action:  mg.AuditAction(c.Query("action")),
+		ActorID: c.Query("actor_id"),
+		Since:   since,
+		Until:   until,
+	}
+	page, limit := utils.Pagination(c, 50, 100)
+
+	out, err := gc.svc.ListAuditLog(c.Request.Context(), c.Param("guild_id"), c.GetString("user_id"), f, page, limit)
+	if err != nil {
+		gc.logger.Error("ListAuditLog error: ", err)
+		if err == guildsvc.ErrUnauthorized {
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		} else {
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch audit log", err.Error())
+		}
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Audit log fetched", out)
+}
+
+// parseTimeQuery reads an optional RFC 3339 timestamp from the query string.
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListTemplates handles GET /guild-templates
+func (gc *GuildController) ListTemplates(c *gin.Context) {
+	out, err := gc.svc.ListTemplates(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		gc.logger.Error("ListTemplates error: ", err)
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to list templates", err.Error())
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Templates fetched", out)
+}
+
+// GetTemplate handles GET /guild-templates/:template_id
+func (gc *GuildController) GetTemplate(c *gin.Context) {
+	out, err := gc.svc.GetTemplate(c.Request.Context(), c.Param("template_id"))
+	if err != nil {
+		gc.logger.Error("GetTemplate error: ", err)
+		if err == guildsvc.ErrUnknownTemplate {
+			utils.RespondError(c, http.StatusNotFound, "Template not found", err.Error())
+		} else {
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch template", err.Error())
+		}
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Template fetched", out)
+}
+
+// ExportTemplate handles POST /guilds/:guild_id/templates
+//
+//	body: { "name": "...", "description": "..." }
+func (gc *GuildController) ExportTemplate(c *gin.Context) {
+	var payload struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	out, err := gc.svc.ExportTemplate(c.Request.Context(),
+		c.Param("guild_id"), payload.Name, payload.Description, c.GetString("user_id"),
+	)
+	if err != nil {
+		gc.logger.Error("ExportTemplate error: ", err)
+		if err == guildsvc.ErrUnauthorized {
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		} else {
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to export template", err.Error())
+		}
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Template exported", out)
+}
@@ -1,15 +1,29 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/gorilla/websocket"
+	"launay-dot-one/middlewares"
+	"launay-dot-one/services/auditlog"
 	"launay-dot-one/utils"
 )
 
+// AuditReasonHeader carries an optional, free-form reason for a guild
+// mutation into the audit log.
+const AuditReasonHeader = "X-Audit-Log-Reason"
+
+// auditContext returns the request context carrying the audit reason header.
+func auditContext(c *gin.Context) context.Context {
+	return auditlog.WithReason(c.Request.Context(), c.GetHeader(AuditReasonHeader))
+}
+
 // parseJWT validates a Bearer token and returns its claims.
 func ParseJWT(tokenStr string, secret []byte) (jwt.MapClaims, error) {
 	tok, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
@@ -28,6 +42,42 @@ func ParseJWT(tokenStr string, secret []byte) (jwt.MapClaims, error) {
 	return claims, nil
 }
 
+// socketUser authenticates a websocket handshake and returns the user ID.
+// It accepts a user JWT as "Authorization: Bearer", a bot token as
+// "Authorization: Bot", and with allowProtocol a JWT passed as
+// Sec-WebSocket-Protocol "jwt,<token>" by browsers that can't set headers.
+func socketUser(r *http.Request, secret []byte, allowProtocol bool) (string, error) {
+	auth := r.Header.Get("Authorization")
+	if botID, ok, err := middlewares.BotFromHeader(r.Context(), auth); ok {
+		return botID, err
+	}
+	var tokenStr string
+	if strings.HasPrefix(auth, "Bearer ") {
+		tokenStr = strings.TrimPrefix(auth, "Bearer ")
+	} else if proto := r.Header.Get("Sec-WebSocket-Protocol"); allowProtocol && strings.HasPrefix(proto, "jwt,") {
+		tokenStr = strings.TrimPrefix(proto, "jwt,")
+	}
+	if tokenStr == "" {
+		return "", errors.New("missing Bearer token")
+	}
+	claims, err := ParseJWT(tokenStr, secret)
+	if err != nil {
+		return "", err
+	}
+	userID, _ := claims["user_id"].(string)
+	if userID == "" {
+		return "", errors.New("missing user_id claim")
+	}
+	revoked, err := middlewares.TokenRevoked(r.Context(), userID, claims)
+	if err != nil {
+		return "", err
+	}
+	if revoked {
+		return "", errors.New("session has been revoked")
+	}
+	return userID, nil
+}
+
 // buildUpgrader returns a websocket.Upgrader with origins from WS_ALLOWED_ORIGINS.
 func BuildUpgrader() websocket.Upgrader {
 	raw := utils.GetEnv("WS_ALLOWED_ORIGINS", "https://app.launay.one")
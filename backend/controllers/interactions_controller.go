@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"launay-dot-one/middlewares"
+	"launay-dot-one/models"
+	"launay-dot-one/services/interactions"
+	"launay-dot-one/services/messaging"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+)
+
+type InteractionsController struct {
+	svc    interactions.Service
+	logger *logrus.Logger
+}
+
+func NewInteractionsController(svc interactions.Service, logger *logrus.Logger) *InteractionsController {
+	return &InteractionsController{svc: svc, logger: logger}
+}
+
+func (ic *InteractionsController) RegisterRoutes(r *gin.Engine) {
+	auth := middlewares.AuthMiddleware()
+
+	bots := r.Group("/bots/:bot_id", auth)
+	{
+		bots.GET("/commands", ic.ListCommands)
+		bots.POST("/commands", ic.CreateCommand)
+		bots.GET("/guilds/:guild_id/commands", ic.ListCommands)
+		bots.POST("/guilds/:guild_id/commands", ic.CreateCommand)
+		bots.PUT("/commands/:command_id", ic.UpdateCommand)
+		bots.DELETE("/commands/:command_id", ic.DeleteCommand)
+	}
+
+	r.GET("/guilds/:guild_id/commands", auth, ic.GuildCommands)
+
+	r.POST("/interactions", auth, ic.Invoke)
+	// no session: the interaction token in the path authorises these
+	r.POST("/interactions/:interaction_id/:token/callback", ic.Respond)
+	r.POST("/interactions/:interaction_id/:token/followup", ic.Followup)
+}
+
+func (ic *InteractionsController) respondError(c *gin.Context, op string, err error) {
+	ic.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound),
+		errors.Is(err, interactions.ErrUnknownCommand),
+		errors.Is(err, interactions.ErrInteractionExpired):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	case errors.Is(err, interactions.ErrInvalidToken):
+		utils.RespondError(c, http.StatusUnauthorized, "Unauthorized", err.Error())
+	case errors.Is(err, interactions.ErrNotBotOrOwner),
+		errors.Is(err, interactions.ErrTimedOut),
+		errors.Is(err, messaging.ErrNotGuildMember),
+		errors.Is(err, permissions.ErrMissingPermission),
+		errors.Is(err, permissions.ErrNotMember):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, interactions.ErrCommandNameTaken),
+		errors.Is(err, interactions.ErrAlreadyResponded):
+		utils.RespondError(c, http.StatusConflict, "Conflict", err.Error())
+	case errors.Is(err, interactions.ErrDeadlinePassed):
+		utils.RespondError(c, http.StatusGone, "Too late", err.Error())
+	case errors.Is(err, interactions.ErrBotUnavailable):
+		utils.RespondError(c, http.StatusBadGateway, "Bot unavailable", err.Error())
+	case errors.Is(err, interactions.ErrInvalidCommand),
+		errors.Is(err, interactions.ErrInvalidOptions),
+		errors.Is(err, interactions.ErrInvalidChannel),
+		errors.Is(err, interactions.ErrInvalidResponse),
+		errors.Is(err, interactions.ErrNotAnswered),
+		errors.Is(err, interactions.ErrBotNotInGuild),
+		errors.Is(err, interactions.ErrTooManyCommands),
+		errors.Is(err, messaging.ErrForumPostOnly):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid request", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, op+" failed", err.Error())
+	}
+}
+
+// commandBody is what a bot sends to register or replace a command.
+type commandBody struct {
+	Name        string                 `json:"name" binding:"required"`
+	Description string                 `json:"description" binding:"required"`
+	Options     []models.CommandOption `json:"options"`
+}
+
+func (b commandBody) command() interactions.Command {
+	return interactions.Command{Name: b.Name, Description: b.Description, Options: b.Options}
+}
+
+// ListCommands handles GET /bots/:bot_id/commands (global commands) and
+// GET /bots/:bot_id/guilds/:guild_id/commands.
+func (ic *InteractionsController) ListCommands(c *gin.Context) {
+	out, err := ic.svc.ListCommands(c.Request.Context(), c.Param("bot_id"), c.Param("guild_id"), c.GetString("user_id"))
+	if err != nil {
+		ic.respondError(c, "ListCommands", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Commands fetched", out)
+}
+
+// CreateCommand handles POST /bots/:bot_id/commands and
+// POST /bots/:bot_id/guilds/:guild_id/commands
+//
+//	body: { "name": "remind", "description": "...", "options": [
+//	        { "type": "string", "name": "what", "description": "...", "required": true },
+//	        { "type": "integer", "name": "minutes", "description": "...", "min_value": 1,
+//	          "choices": [{ "name": "an hour", "value": 60 }] } ] }
+func (ic *InteractionsController) CreateCommand(c *gin.Context) {
+	var body commandBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	cmd, err := ic.svc.CreateCommand(
+		c.Request.Context(), c.Param("bot_id"), c.Param("guild_id"), body.command(), c.GetString("user_id"),
+	)
+	if err != nil {
+		ic.respondError(c, "CreateCommand", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Command created", cmd)
+}
+
+// UpdateCommand handles PUT /bots/:bot_id/commands/:command_id with the
+// same body as CreateCommand.
+func (ic *InteractionsController) UpdateCommand(c *gin.Context) {
+	var body commandBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	cmd, err := ic.svc.UpdateCommand(
+		c.Request.Context(), c.Param("bot_id"), c.Param("command_id"), body.command(), c.GetString("user_id"),
+	)
+	if err != nil {
+		ic.respondError(c, "UpdateCommand", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Command updated", cmd)
+}
+
+// DeleteCommand handles DELETE /bots/:bot_id/commands/:command_id
+func (ic *InteractionsController) DeleteCommand(c *gin.Context) {
+	if err := ic.svc.DeleteCommand(
+		c.Request.Context(), c.Param("bot_id"), c.Param("command_id"), c.GetString("user_id"),
+	); err != nil {
+		ic.respondError(c, "DeleteCommand", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Command deleted", nil)
+}
+
+// GuildCommands handles GET /guilds/:guild_id/commands, what members can
+// invoke there.
+func (ic *InteractionsController) GuildCommands(c *gin.Context) {
+	out, err := ic.svc.GuildCommands(c.Request.Context(), c.Param("guild_id"), c.GetString("user_id"))
+	if err != nil {
+		ic.respondError(c, "GuildCommands", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Commands fetched", out)
+}
+
+// Invoke handles POST /interactions
+//
+//	body: { "command_id": "...", "channel_id": "...", "options": [{ "name": "what", "value": "tea" }] }
+//
+// Bots on the gateway answer asynchronously: the invoker gets an
+// INTERACTION_UPDATE event once they do.
+func (ic *InteractionsController) Invoke(c *gin.Context) {
+	var body interactions.Invocation
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	it, err := ic.svc.Invoke(c.Request.Context(), c.GetString("user_id"), body)
+	if err != nil {
+		ic.respondError(c, "Invoke", err)
+		return
+	}
+	status := http.StatusOK
+	if it.Status == models.InteractionPending {
+		status = http.StatusAccepted
+	}
+	utils.RespondSuccess(c, status, "Interaction sent", it)
+}
+
+// Respond handles POST /interactions/:interaction_id/:token/callback
+//
+//	body: { "type": "message", "data": { "content": "..." } }  or  { "type": "deferred" }
+func (ic *InteractionsController) Respond(c *gin.Context) {
+	var body interactions.Response
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	msg, err := ic.svc.Respond(c.Request.Context(), c.Param("interaction_id"), c.Param("token"), body)
+	if err != nil {
+		ic.respondError(c, "RespondInteraction", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Interaction answered", msg)
+}
+
+// Followup handles POST /interactions/:interaction_id/:token/followup
+//
+//	body: { "content": "...", "attachments": [...] }
+func (ic *InteractionsController) Followup(c *gin.Context) {
+	var body interactions.Message
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	msg, err := ic.svc.Followup(c.Request.Context(), c.Param("interaction_id"), c.Param("token"), body)
+	if err != nil {
+		ic.respondError(c, "InteractionFollowup", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Follow-up sent", msg)
+}
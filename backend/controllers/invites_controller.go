@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"launay-dot-one/middlewares"
+	"launay-dot-one/repositories"
+	invsvc "launay-dot-one/services/invites"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+)
+
+type InvitesController struct {
+	svc    invsvc.Service
+	logger *logrus.Logger
+}
+
+func NewInvitesController(svc invsvc.Service, logger *logrus.Logger) *InvitesController {
+	return &InvitesController{svc: svc, logger: logger}
+}
+
+func (ic *InvitesController) RegisterRoutes(r *gin.Engine) {
+	g := r.Group("/guilds/:guild_id/invites", middlewares.AuthMiddleware())
+	{
+		g.POST("", ic.Create)
+		g.GET("", ic.ListByGuild)
+	}
+
+	// public preview, so invite links can be rendered before sign-in
+	r.GET("/invites/:code", ic.Preview)
+
+	inv := r.Group("/invites", middlewares.AuthMiddleware())
+	{
+		inv.POST("/:code/accept", ic.Accept)
+		inv.DELETE("/:code", ic.Revoke)
+	}
+}
+
+func (ic *InvitesController) respondError(c *gin.Context, op string, err error) {
+	ic.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, permissions.ErrMissingPermission),
+		errors.Is(err, permissions.ErrNotMember),
+		errors.Is(err, repositories.ErrBannedFromGuild):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, invsvc.ErrNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Invite not found", err.Error())
+	case errors.Is(err, repositories.ErrInviteUnusable):
+		utils.RespondError(c, http.StatusGone, "Invite no longer valid", err.Error())
+	case errors.Is(err, repositories.ErrAlreadyMember):
+		utils.RespondError(c, http.StatusConflict, "Already a member", err.Error())
+	case errors.Is(err, invsvc.ErrInvalidOptions):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, "Invite operation failed", err.Error())
+	}
+}
+
+// Create handles POST /guilds/:guild_id/invites
+//
+//	body: { "max_uses": 10, "max_age_seconds": 86400, "temporary": false, "target_user_id": "..." }
+//
+// target_user_id, optional, is sent the invite as a notification.
+func (ic *InvitesController) Create(c *gin.Context) {
+	var body struct {
+		MaxUses       int    `json:"max_uses"`
+		MaxAgeSeconds int    `json:"max_age_seconds"`
+		Temporary     bool   `json:"temporary"`
+		TargetUserID  string `json:"target_user_id"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	inv, err := ic.svc.Create(c.Request.Context(), c.Param("guild_id"), c.GetString("user_id"), invsvc.CreateOptions{
+		MaxUses:      body.MaxUses,
+		MaxAge:       time.Duration(body.MaxAgeSeconds) * time.Second,
+		Temporary:    body.Temporary,
+		TargetUserID: body.TargetUserID,
+	})
+	if err != nil {
+		ic.respondError(c, "CreateInvite", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Invite created", inv)
+}
+
+// ListByGuild handles GET /guilds/:guild_id/invites
+func (ic *InvitesController) ListByGuild(c *gin.Context) {
+	out, err := ic.svc.ListByGuild(c.Request.Context(), c.Param("guild_id"), c.GetString("user_id"))
+	if err != nil {
+		ic.respondError(c, "ListInvites", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Invites fetched", out)
+}
+
+// Preview handles GET /invites/:code
+func (ic *InvitesController) Preview(c *gin.Context) {
+	out, err := ic.svc.Preview(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		ic.respondError(c, "PreviewInvite", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Invite fetched", out)
+}
+
+// Accept handles POST /invites/:code/accept
+func (ic *InvitesController) Accept(c *gin.Context) {
+	if c.GetBool("is_bot") {
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", "bots join guilds through bot authorization")
+		return
+	}
+	inv, err := ic.svc.Accept(c.Request.Context(), c.Param("code"), c.GetString("user_id"))
+	if err != nil {
+		ic.respondError(c, "AcceptInvite", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Joined guild", gin.H{"guild_id": inv.GuildID})
+}
+
+// Revoke handles DELETE /invites/:code
+func (ic *InvitesController) Revoke(c *gin.Context) {
+	if err := ic.svc.Revoke(c.Request.Context(), c.Param("code"), c.GetString("user_id")); err != nil {
+		ic.respondError(c, "RevokeInvite", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Invite revoked", nil)
+}
@@ -1,26 +1,33 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strings"
 
 	connectionmanager "launay-dot-one/manager"
 	"launay-dot-one/middlewares"
 	"launay-dot-one/models"
+	"launay-dot-one/services/friendships"
 	"launay-dot-one/services/groups"
 	"launay-dot-one/services/messaging"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/services/voice"
 	"launay-dot-one/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type MessagingController struct {
 	msgSvc   messaging.Service
 	grpSvc   groups.Service
+	voiceSvc voice.Service
 	logger   *logrus.Logger
 	secret   []byte
 	upgrader websocket.Upgrader
@@ -29,12 +36,14 @@ type MessagingController struct {
 func NewMessagingController(
 	ms messaging.Service,
 	gs groups.Service,
+	vs voice.Service,
 	l *logrus.Logger,
 ) *MessagingController {
 	sec := utils.MustEnv("JWT_SECRET")
 	return &MessagingController{
 		msgSvc:   ms,
 		grpSvc:   gs,
+		voiceSvc: vs,
 		logger:   l,
 		secret:   []byte(sec),
 		upgrader: BuildUpgrader(),
@@ -47,6 +56,7 @@ func (mc *MessagingController) RegisterRoutes(r *gin.Engine) {
 		msg.POST("/reaction", middlewares.AuthMiddleware(), mc.HandleAddReaction)
 		msg.GET("/ws", mc.HandleWebSocket) // internal auth
 		msg.GET("/conversations", middlewares.AuthMiddleware(), mc.GetAllUserConversations)
+		msg.POST("/:message_id/crosspost", middlewares.AuthMiddleware(), mc.Crosspost)
 	}
 }
 
@@ -92,21 +102,13 @@ func (mc *MessagingController) GetChatHistory(c *gin.Context) {
 // ----------------------------------------------------------------------------
 
 func (mc *MessagingController) HandleWebSocket(c *gin.Context) {
-	// 1) Auth
-	auth := c.GetHeader("Authorization")
-	parts := strings.Split(auth, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		utils.RespondError(c, http.StatusUnauthorized,
-			"Unauthorized", "Missing Bearer token")
-		return
-	}
-	claims, err := ParseJWT(parts[1], mc.secret)
+	// 1) Auth (user JWT or bot token)
+	senderID, err := socketUser(c.Request, mc.secret, false)
 	if err != nil {
 		utils.RespondError(c, http.StatusUnauthorized,
 			"Unauthorized", err.Error())
 		return
 	}
-	senderID := claims["user_id"].(string)
 
 	// 2) Upgrade
 	conn, err := mc.upgrader.Upgrade(c.Writer, c.Request, nil)
@@ -115,7 +117,15 @@ func (mc *MessagingController) HandleWebSocket(c *gin.Context) {
 		return
 	}
 	connectionmanager.ConnManager.Add(senderID, conn)
-	defer connectionmanager.ConnManager.Remove(senderID)
+	defer connectionmanager.ConnManager.Remove(senderID, conn)
+	// voice rides on the socket it was joined from, so losing that one
+	// ends the call; closing another of the user's sockets doesn't
+	socketID := uuid.NewString()
+	defer func() {
+		if err := mc.voiceSvc.LeaveSocket(context.Background(), senderID, socketID); err != nil {
+			mc.logger.Error("voice leave on disconnect: ", err)
+		}
+	}()
 
 	// 3) Read & broadcast loop
 	ctx := c.Request.Context()
@@ -126,12 +136,23 @@ func (mc *MessagingController) HandleWebSocket(c *gin.Context) {
 			break
 		}
 
+		// voice signaling frames carry an op; chat messages don't
+		var frame struct {
+			Op   string          `json:"op"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &frame); err == nil && frame.Op != "" {
+			connectionmanager.ConnManager.Send(senderID, mc.handleVoiceOp(ctx, senderID, socketID, frame.Op, frame.Data))
+			continue
+		}
+
 		// unmarshal only the old payload fields
 		var p struct {
 			TargetID    string          `json:"target_id"`
 			TargetType  string          `json:"target_type"`
 			Content     string          `json:"content"`
 			Attachments json.RawMessage `json:"attachments,omitempty"`
+			ReplyToID   *string         `json:"reply_to_id,omitempty"`
 		}
 		if err := json.Unmarshal(data, &p); err != nil {
 			mc.logger.Warn("Invalid WS JSON: ", err)
@@ -144,10 +165,11 @@ func (mc *MessagingController) HandleWebSocket(c *gin.Context) {
 			AuthorID:    senderID,
 			Content:     p.Content,
 			Attachments: datatypes.JSON(p.Attachments),
+			ReplyToID:   p.ReplyToID,
 		}
 		if err := mc.msgSvc.SendMessage(ctx, &msg); err != nil {
-			_ = conn.WriteJSON(utils.APIResponse{
-				Code:    http.StatusInternalServerError,
+			connectionmanager.ConnManager.Send(senderID, utils.APIResponse{
+				Code:    messagingErrorCode(err),
 				Message: "Failed to send message",
 				Error:   err.Error(),
 			})
@@ -157,13 +179,11 @@ func (mc *MessagingController) HandleWebSocket(c *gin.Context) {
 		// dispatch
 		switch p.TargetType {
 		case "user":
-			if rc, ok := connectionmanager.ConnManager.Get(p.TargetID); ok {
-				_ = rc.WriteJSON(utils.APIResponse{
-					Code:    http.StatusOK,
-					Message: "New message",
-					Data:    msg,
-				})
-			}
+			connectionmanager.ConnManager.Send(p.TargetID, utils.APIResponse{
+				Code:    http.StatusOK,
+				Message: "New message",
+				Data:    msg,
+			})
 
 		case "group":
 			members, err := mc.grpSvc.ListMembers(ctx, p.TargetID)
@@ -174,18 +194,16 @@ func (mc *MessagingController) HandleWebSocket(c *gin.Context) {
 				if m.UserID == senderID {
 					continue
 				}
-				if rc, ok := connectionmanager.ConnManager.Get(m.UserID); ok {
-					_ = rc.WriteJSON(utils.APIResponse{
-						Code:    http.StatusOK,
-						Message: "New message",
-						Data:    msg,
-					})
-				}
+				connectionmanager.ConnManager.Send(m.UserID, utils.APIResponse{
+					Code:    http.StatusOK,
+					Message: "New message",
+					Data:    msg,
+				})
 			}
 		}
 
 		// ack back
-		_ = conn.WriteJSON(utils.APIResponse{
+		connectionmanager.ConnManager.Send(senderID, utils.APIResponse{
 			Code:    http.StatusOK,
 			Message: "Message sent",
 			Data:    msg,
@@ -193,6 +211,21 @@ func (mc *MessagingController) HandleWebSocket(c *gin.Context) {
 	}
 }
 
+// messagingErrorCode maps a send-path rejection to its HTTP status.
+func messagingErrorCode(err error) int {
+	switch {
+	case errors.Is(err, messaging.ErrNotGuildMember), errors.Is(err, messaging.ErrTimedOut),
+		errors.Is(err, friendships.ErrDMsClosed):
+		return http.StatusForbidden
+	case errors.Is(err, messaging.ErrSlowmode):
+		return http.StatusTooManyRequests
+	case errors.Is(err, messaging.ErrForumPostOnly), errors.Is(err, messaging.ErrInvalidReply):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // HandleAddReaction unchanged
 func (mc *MessagingController) HandleAddReaction(c *gin.Context) {
 	var p struct {
@@ -214,8 +247,7 @@ func (mc *MessagingController) HandleAddReaction(c *gin.Context) {
 		c.Request.Context(), p.MessageID, p.Reaction, userID,
 	); err != nil {
 		mc.logger.Error("AddReaction error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError,
-			"Failed to add reaction", err.Error())
+		utils.RespondError(c, messagingErrorCode(err), "Failed to add reaction", err.Error())
 		return
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Reaction added", nil)
@@ -238,3 +270,26 @@ func (mc *MessagingController) GetAllUserConversations(c *gin.Context) {
 	utils.RespondSuccess(c, http.StatusOK,
 		"Conversations retrieved", gin.H{"conversations": convos})
 }
+
+// Crosspost handles POST /messages/:message_id/crosspost, publishing an
+// announcement message to every following channel.
+func (mc *MessagingController) Crosspost(c *gin.Context) {
+	copies, err := mc.msgSvc.Crosspost(c.Request.Context(), c.Param("message_id"), c.GetString("user_id"))
+	if err != nil {
+		mc.logger.Error("Crosspost error: ", err)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Message not found", err.Error())
+		case errors.Is(err, permissions.ErrMissingPermission), errors.Is(err, permissions.ErrNotMember):
+			utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		case errors.Is(err, messaging.ErrNotAnnouncement):
+			utils.RespondError(c, http.StatusBadRequest, "Not an announcement", err.Error())
+		case errors.Is(err, messaging.ErrAlreadyCrossposted):
+			utils.RespondError(c, http.StatusConflict, "Already published", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to cross-post message", err.Error())
+		}
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Message cross-posted", gin.H{"messages": copies})
+}
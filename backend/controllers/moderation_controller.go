@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"launay-dot-one/middlewares"
+	modsvc "launay-dot-one/services/moderation"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+)
+
+type ModerationController struct {
+	svc    modsvc.Service
+	logger *logrus.Logger
+}
+
+func NewModerationController(svc modsvc.Service, logger *logrus.Logger) *ModerationController {
+	return &ModerationController{svc: svc, logger: logger}
+}
+
+func (mc *ModerationController) RegisterRoutes(r *gin.Engine) {
+	g := r.Group("/guilds/:guild_id", middlewares.AuthMiddleware())
+	{
+		g.GET("/bans", mc.ListBans)
+		g.PUT("/bans/:user_id", mc.Ban)
+		g.DELETE("/bans/:user_id", mc.Unban)
+
+		g.POST("/members/:user_id/kick", mc.Kick)
+		g.PUT("/members/:user_id/timeout", mc.Timeout)
+		g.DELETE("/members/:user_id/timeout", mc.RemoveTimeout)
+	}
+}
+
+func (mc *ModerationController) respondError(c *gin.Context, op string, err error) {
+	mc.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, permissions.ErrMissingPermission),
+		errors.Is(err, permissions.ErrNotMember),
+		errors.Is(err, permissions.ErrHierarchy):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, modsvc.ErrNotMember), errors.Is(err, modsvc.ErrNotBanned):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	case errors.Is(err, modsvc.ErrInvalidDuration), errors.Is(err, modsvc.ErrInvalidExpiry):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, "Moderation action failed", err.Error())
+	}
+}
+
+// ListBans handles GET /guilds/:guild_id/bans
+func (mc *ModerationController) ListBans(c *gin.Context) {
+	out, err := mc.svc.ListBans(c.Request.Context(), c.Param("guild_id"), c.GetString("user_id"))
+	if err != nil {
+		mc.respondError(c, "ListBans", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Bans fetched", out)
+}
+
+// Ban handles PUT /guilds/:guild_id/bans/:user_id
+//
+//	body: { "reason": "spam", "expires_at": "2025-01-01T00:00:00Z" }  // expires_at optional
+func (mc *ModerationController) Ban(c *gin.Context) {
+	var body struct {
+		Reason    string     `json:"reason"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	ban, err := mc.svc.Ban(auditContext(c),
+		c.Param("guild_id"), c.GetString("user_id"), c.Param("user_id"),
+		body.Reason, body.ExpiresAt,
+	)
+	if err != nil {
+		mc.respondError(c, "Ban", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "User banned", ban)
+}
+
+// Unban handles DELETE /guilds/:guild_id/bans/:user_id?reason=...
+func (mc *ModerationController) Unban(c *gin.Context) {
+	if err := mc.svc.Unban(auditContext(c),
+		c.Param("guild_id"), c.GetString("user_id"), c.Param("user_id"), c.Query("reason"),
+	); err != nil {
+		mc.respondError(c, "Unban", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "User unbanned", nil)
+}
+
+// Kick handles POST /guilds/:guild_id/members/:user_id/kick
+//
+//	body: { "reason": "..." }
+func (mc *ModerationController) Kick(c *gin.Context) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&body) // reason is optional
+	if err := mc.svc.Kick(auditContext(c),
+		c.Param("guild_id"), c.GetString("user_id"), c.Param("user_id"), body.Reason,
+	); err != nil {
+		mc.respondError(c, "Kick", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Member kicked", nil)
+}
+
+// Timeout handles PUT /guilds/:guild_id/members/:user_id/timeout
+//
+//	body: { "until": "2025-01-01T00:00:00Z", "reason": "..." }
+func (mc *ModerationController) Timeout(c *gin.Context) {
+	var body struct {
+		Until  time.Time `json:"until" binding:"required"`
+		Reason string    `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	if err := mc.svc.Timeout(auditContext(c),
+		c.Param("guild_id"), c.GetString("user_id"), c.Param("user_id"),
+		body.Until, body.Reason,
+	); err != nil {
+		mc.respondError(c, "Timeout", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Member timed out", nil)
+}
+
+// RemoveTimeout handles DELETE /guilds/:guild_id/members/:user_id/timeout?reason=...
+func (mc *ModerationController) RemoveTimeout(c *gin.Context) {
+	if err := mc.svc.RemoveTimeout(auditContext(c),
+		c.Param("guild_id"), c.GetString("user_id"), c.Param("user_id"), c.Query("reason"),
+	); err != nil {
+		mc.respondError(c, "RemoveTimeout", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Timeout removed", nil)
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"launay-dot-one/middlewares"
+	"launay-dot-one/services/notifications"
+	"launay-dot-one/utils"
+)
+
+type NotificationsController struct {
+	svc    notifications.Service
+	logger *logrus.Logger
+}
+
+func NewNotificationsController(svc notifications.Service, logger *logrus.Logger) *NotificationsController {
+	return &NotificationsController{svc: svc, logger: logger}
+}
+
+func (nc *NotificationsController) RegisterRoutes(r *gin.Engine) {
+	auth := middlewares.AuthMiddleware()
+
+	grp := r.Group("/notifications", auth)
+	{
+		grp.GET("", nc.List)
+		grp.POST("/read-all", nc.MarkAllRead)
+		grp.POST("/:notification_id/read", nc.MarkRead)
+	}
+
+	r.GET("/guilds/:guild_id/notification-settings", auth, nc.GetSettings)
+	r.PUT("/guilds/:guild_id/notification-settings", auth, nc.UpdateGuildSettings)
+	r.PUT("/channels/:channel_id/notification-settings", auth, nc.UpdateChannelSettings)
+}
+
+func (nc *NotificationsController) respondError(c *gin.Context, op string, err error) {
+	nc.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	case errors.Is(err, notifications.ErrNotMember):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, notifications.ErrInvalidSettings):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid settings", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, op+" failed", err.Error())
+	}
+}
+
+// List handles GET /notifications?unread=true&page=&limit=
+func (nc *NotificationsController) List(c *gin.Context) {
+	page, limit := utils.Pagination(c, 50, 100)
+	out, err := nc.svc.List(c.Request.Context(), c.GetString("user_id"), c.Query("unread") == "true", page, limit)
+	if err != nil {
+		nc.respondError(c, "ListNotifications", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Notifications fetched", out)
+}
+
+// MarkRead handles POST /notifications/:notification_id/read
+func (nc *NotificationsController) MarkRead(c *gin.Context) {
+	if err := nc.svc.MarkRead(c.Request.Context(), c.GetString("user_id"), c.Param("notification_id")); err != nil {
+		nc.respondError(c, "MarkNotificationRead", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Notification marked read", nil)
+}
+
+// MarkAllRead handles POST /notifications/read-all
+func (nc *NotificationsController) MarkAllRead(c *gin.Context) {
+	n, err := nc.svc.MarkAllRead(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		nc.respondError(c, "MarkAllNotificationsRead", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Notifications marked read", gin.H{"marked": n})
+}
+
+// GetSettings handles GET /guilds/:guild_id/notification-settings, the
+// caller's settings for the guild and its channels.
+func (nc *NotificationsController) GetSettings(c *gin.Context) {
+	out, err := nc.svc.Settings(c.Request.Context(), c.GetString("user_id"), c.Param("guild_id"))
+	if err != nil {
+		nc.respondError(c, "GetNotificationSettings", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Notification settings fetched", out)
+}
+
+// UpdateGuildSettings handles PUT /guilds/:guild_id/notification-settings
+//
+//	body: { "level": "all" | "mentions" | "nothing" | "", "muted_until": "2025-01-01T00:00:00Z" | null }
+func (nc *NotificationsController) UpdateGuildSettings(c *gin.Context) {
+	var body notifications.Settings
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	out, err := nc.svc.UpdateGuildSettings(c.Request.Context(), c.GetString("user_id"), c.Param("guild_id"), body)
+	if err != nil {
+		nc.respondError(c, "UpdateGuildNotificationSettings", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Notification settings updated", out)
+}
+
+// UpdateChannelSettings handles PUT /channels/:channel_id/notification-settings
+// with the same body as UpdateGuildSettings; an empty level follows the
+// guild.
+func (nc *NotificationsController) UpdateChannelSettings(c *gin.Context) {
+	var body notifications.Settings
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	out, err := nc.svc.UpdateChannelSettings(c.Request.Context(), c.GetString("user_id"), c.Param("channel_id"), body)
+	if err != nil {
+		nc.respondError(c, "UpdateChannelNotificationSettings", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Notification settings updated", out)
+}
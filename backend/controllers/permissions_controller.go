@@ -1,10 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 
 	"launay-dot-one/middlewares"
 	"launay-dot-one/models/guilds"
@@ -61,9 +63,9 @@ func (pc *PermissionsController) Create(c *gin.Context) {
 		return
 	}
 	o.GuildID = guildID
-	if err := pc.svc.Create(c.Request.Context(), &o); err != nil {
+	if err := pc.svc.Create(auditContext(c), &o, c.GetString("user_id")); err != nil {
 		pc.logger.Error("Create permission error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to create permission", err.Error())
+		respondOverwriteError(c, "Failed to create permission", err)
 		return
 	}
 	utils.RespondSuccess(c, http.StatusCreated, "Permission created", o)
@@ -79,20 +81,36 @@ func (pc *PermissionsController) Update(c *gin.Context) {
 	}
 	o.GuildID = guildID
 	o.ID = permID
-	if err := pc.svc.Update(c.Request.Context(), &o); err != nil {
+	if err := pc.svc.Update(auditContext(c), &o, c.GetString("user_id")); err != nil {
 		pc.logger.Error("Update permission error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to update permission", err.Error())
+		respondOverwriteError(c, "Failed to update permission", err)
 		return
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Permission updated", o)
 }
 
 func (pc *PermissionsController) Delete(c *gin.Context) {
+	guildID := c.Param("guild_id")
 	permID := c.Param("perm_id")
-	if err := pc.svc.Delete(c.Request.Context(), permID); err != nil {
+	if err := pc.svc.Delete(auditContext(c), guildID, permID, c.GetString("user_id")); err != nil {
 		pc.logger.Error("Delete permission error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to delete permission", err.Error())
+		respondOverwriteError(c, "Failed to delete permission", err)
 		return
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Permission deleted", nil)
 }
+
+func respondOverwriteError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, permsvc.ErrUnknownOverwrite):
+		utils.RespondError(c, http.StatusNotFound, "Permission not found", err.Error())
+	case errors.Is(err, permsvc.ErrMissingPermission),
+		errors.Is(err, permsvc.ErrNotMember),
+		errors.Is(err, permsvc.ErrCannotGrant):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, permsvc.ErrInvalidOverwrite):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid permission", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, msg, err.Error())
+	}
+}
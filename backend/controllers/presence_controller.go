@@ -3,20 +3,23 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strings"
 
+	connectionmanager "launay-dot-one/manager"
+	"launay-dot-one/models"
 	"launay-dot-one/realtime"
+	invsvc "launay-dot-one/services/invites"
 	"launay-dot-one/utils"
 
-	"github.com/go-redis/redis/v8"
+	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
 
 type PresenceController struct {
 	presenceService realtime.PresenceService
-	redisClient     *redis.Client
+	inviteService   invsvc.Service
 	logger          *logrus.Logger
 	secret          []byte
 	upgrader        websocket.Upgrader
@@ -24,41 +27,28 @@ type PresenceController struct {
 
 func NewPresenceController(
 	ps realtime.PresenceService,
-	rc *redis.Client,
+	is invsvc.Service,
 	l *logrus.Logger,
 ) *PresenceController {
 	// buildUpgrader reads WS_ALLOWED_ORIGINS from env
 	return &PresenceController{
 		presenceService: ps,
-		redisClient:     rc,
+		inviteService:   is,
 		logger:          l,
 		secret:          []byte(utils.MustEnv("JWT_SECRET")),
 		upgrader:        BuildUpgrader(),
 	}
 }
 
-// HandleWebSocket authenticates, upgrades to WS, and tracks presence.
+// HandleWebSocket authenticates, upgrades to WS, and tracks presence for
+// the lifetime of the socket. Each socket is one presence session.
 func (pc *PresenceController) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	// 1. Extract token (Bearer or Sec-WebSocket-Protocol "jwt,<token>")
-	auth := r.Header.Get("Authorization")
-	var tokenStr string
-	if strings.HasPrefix(auth, "Bearer ") {
-		tokenStr = strings.TrimPrefix(auth, "Bearer ")
-	} else if proto := r.Header.Get("Sec-WebSocket-Protocol"); strings.HasPrefix(proto, "jwt,") {
-		tokenStr = strings.TrimPrefix(proto, "jwt,")
-	}
-	if tokenStr == "" {
-		utils.RespondErrorRaw(w, http.StatusUnauthorized, "Unauthorized", "Missing Bearer token")
-		return
-	}
-
-	// 2. Parse claims
-	claims, err := ParseJWT(tokenStr, pc.secret)
+	// 1–2. Authenticate (Bearer, Sec-WebSocket-Protocol "jwt,<token>" or bot token)
+	userID, err := socketUser(r, pc.secret, true)
 	if err != nil {
 		utils.RespondErrorRaw(w, http.StatusUnauthorized, "Unauthorized", err.Error())
 		return
 	}
-	userID := claims["user_id"].(string)
 
 	// 3. Upgrade
 	conn, err := pc.upgrader.Upgrade(w, r, nil)
@@ -67,86 +57,167 @@ func (pc *PresenceController) HandleWebSocket(w http.ResponseWriter, r *http.Req
 		return
 	}
 	defer conn.Close()
+	defer connectionmanager.ConnManager.Track(userID, conn)()
 
-	// 4. Mark online and listen
+	// 4. Open a session and listen
 	ctx := r.Context()
-	pc.setInitialStatus(ctx, userID, conn)
-	pc.listenForMessages(ctx, userID, conn)
-	pc.setDisconnectedStatus(ctx, userID)
+	sessionID, err := pc.presenceService.Connect(ctx, userID)
+	if err != nil {
+		pc.logger.Error("presence connect: ", err)
+		return
+	}
+	pc.sendHello(ctx, userID, sessionID, conn)
+	pc.listenForMessages(ctx, userID, sessionID, conn)
+	// the request context may be gone once the socket is
+	pc.closeSession(context.Background(), userID, sessionID)
 }
 
-// setInitialStatus marks user online and notifies.
-func (pc *PresenceController) setInitialStatus(ctx context.Context, userID string, c *websocket.Conn) {
-	if err := pc.presenceService.SetStatus(ctx, userID, "online"); err != nil {
-		pc.logger.Error("set online: ", err)
+// sendHello tells the client its session, its status, how often to
+// heartbeat and the current presence of the people it can see.
+func (pc *PresenceController) sendHello(ctx context.Context, userID, sessionID string, c *websocket.Conn) {
+	own, err := pc.presenceService.Get(ctx, userID)
+	if err != nil {
+		pc.logger.Error("own presence: ", err)
+		own = &models.Presence{UserID: userID, Status: models.StatusOnline}
+	}
+	visible, err := pc.presenceService.Visible(ctx, userID)
+	if err != nil {
+		pc.logger.Error("visible presences: ", err)
 	}
-	_ = c.WriteJSON(map[string]string{"status": "online", "message": "You are now online"})
+	_ = c.WriteJSON(map[string]interface{}{
+		"op":                 "hello",
+		"status":             own.Status,
+		"message":            "You are now connected",
+		"session_id":         sessionID,
+		"heartbeat_interval": realtime.HeartbeatInterval.Milliseconds(),
+		"presences":          visible,
+	})
 }
 
-// listenForMessages handles incoming status updates.
-func (pc *PresenceController) listenForMessages(ctx context.Context, userID string, c *websocket.Conn) {
+// listenForMessages handles heartbeats and status updates.
+func (pc *PresenceController) listenForMessages(ctx context.Context, userID, sessionID string, c *websocket.Conn) {
 	for {
 		_, payload, err := c.ReadMessage()
 		if err != nil {
 			pc.logger.Warn("WS read: ", err)
 			break
 		}
-		pc.handleMessage(ctx, userID, c, payload)
+		pc.handleMessage(ctx, userID, sessionID, c, payload)
 	}
 }
 
-// handleMessage parses a status update and applies it.
+// handleMessage parses one frame and applies it:
+//
+//	{ "op": "heartbeat" }  // keep-alive only
+//	{ "op": "activity" }   // the user did something; also a keep-alive
+//	{ "op": "custom_status", "data": { "text": "...", "emoji": "...", "expires_at": "..." } }  // data null clears it
+//	{ "op": "set_activity", "data": { "type": "playing", "name": "...", "details": "...",
+//	                                  "started_at": "...", "url": "..." } }  // data null clears it
+//	{ "status": "online" | "idle" | "dnd" | "invisible" }
 func (pc *PresenceController) handleMessage(
 	ctx context.Context,
-	userID string,
+	userID, sessionID string,
 	c *websocket.Conn,
 	payload []byte,
 ) {
 	var req struct {
-		Status string `json:"status"`
+		Op     string          `json:"op"`
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
 	}
 	if err := json.Unmarshal(payload, &req); err != nil {
 		_ = c.WriteJSON(map[string]string{"error": "invalid json"})
 		return
 	}
 
-	switch req.Status {
-	case "online", "away", "dnd":
-		if err := pc.presenceService.SetStatus(ctx, userID, req.Status); err != nil {
-			pc.logger.Error("update status: ", err)
+	switch req.Op {
+	case "heartbeat":
+		if err := pc.presenceService.Heartbeat(ctx, userID, sessionID); err != nil {
+			pc.logger.Error("heartbeat: ", err)
+		}
+		_ = c.WriteJSON(map[string]string{"op": "heartbeat_ack"})
+		return
+	case "activity":
+		if err := pc.presenceService.Activity(ctx, userID, sessionID); err != nil {
+			pc.logger.Error("activity: ", err)
+		}
+		_ = c.WriteJSON(map[string]string{"op": "heartbeat_ack"})
+		return
+	case "custom_status":
+		var cs *models.CustomStatus
+		if err := json.Unmarshal(req.Data, &cs); len(req.Data) > 0 && err != nil {
+			_ = c.WriteJSON(map[string]string{"error": "invalid custom status"})
+			return
+		}
+		if err := pc.presenceService.SetCustomStatus(ctx, userID, cs); err != nil {
+			if !errors.Is(err, realtime.ErrInvalidCustomStatus) {
+				pc.logger.Error("custom status: ", err)
+			}
+			_ = c.WriteJSON(map[string]string{"error": err.Error()})
+			return
+		}
+		_ = c.WriteJSON(map[string]string{"op": "custom_status", "message": "Custom status updated"})
+		return
+	case "set_activity":
+		var a *models.Activity
+		if err := json.Unmarshal(req.Data, &a); len(req.Data) > 0 && err != nil {
+			_ = c.WriteJSON(map[string]string{"error": "invalid activity"})
+			return
+		}
+		if err := pc.presenceService.SetActivity(ctx, userID, a); err != nil {
+			if !errors.Is(err, realtime.ErrInvalidActivity) {
+				pc.logger.Error("set activity: ", err)
+			}
+			_ = c.WriteJSON(map[string]string{"error": err.Error()})
+			return
 		}
-		_ = c.WriteJSON(map[string]string{"status": req.Status, "message": "Status updated"})
+		_ = c.WriteJSON(map[string]string{"op": "set_activity", "message": "Activity updated"})
+		return
+	case "":
 	default:
-		_ = c.WriteJSON(map[string]string{"error": "Invalid status"})
+		_ = c.WriteJSON(map[string]string{"error": "Unknown op"})
+		return
 	}
-}
 
-// setDisconnectedStatus marks user offline on disconnect.
-func (pc *PresenceController) setDisconnectedStatus(ctx context.Context, userID string) {
-	if err := pc.presenceService.SetStatus(ctx, userID, "disconnected"); err != nil {
-		pc.logger.Error("set disconnected: ", err)
+	if err := pc.presenceService.SetStatus(ctx, userID, models.Status(req.Status)); err != nil {
+		if !errors.Is(err, realtime.ErrInvalidStatus) {
+			pc.logger.Error("update status: ", err)
+		}
+		_ = c.WriteJSON(map[string]string{"error": err.Error()})
+		return
 	}
+	_ = c.WriteJSON(map[string]string{"status": req.Status, "message": "Status updated"})
 }
 
-// GetAllPresence returns all presence keys & statuses.
-func (pc *PresenceController) GetAllPresence(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	keys, err := pc.redisClient.Keys(ctx, "presence:*").Result()
+// closeSession ends the socket's session. When it was the user's last
+// one, memberships gained through temporary invites are dropped.
+func (pc *PresenceController) closeSession(ctx context.Context, userID, sessionID string) {
+	offline, err := pc.presenceService.Disconnect(ctx, userID, sessionID)
 	if err != nil {
-		utils.RespondErrorRaw(w, http.StatusInternalServerError,
-			"Failed to fetch presence keys", err.Error())
+		pc.logger.Error("presence disconnect: ", err)
 		return
 	}
-
-	out := make(map[string]string, len(keys))
-	for _, k := range keys {
-		status, err := pc.redisClient.Get(ctx, k).Result()
-		if err != nil {
-			pc.logger.Warnf("redis get %s: %v", k, err)
-			continue
-		}
-		out[strings.TrimPrefix(k, "presence:")] = status
+	if !offline {
+		return
+	}
+	if err := pc.inviteService.DropTemporaryMemberships(ctx, userID); err != nil {
+		pc.logger.Error("drop temporary memberships: ", err)
 	}
+}
 
-	utils.RespondSuccessRaw(w, http.StatusOK, "Presence map fetched", out)
+// GetUserPresence handles GET /users/:user_id/presence. Friends and guild
+// co-members see the user's status (offline when invisible); others are
+// refused.
+func (pc *PresenceController) GetUserPresence(c *gin.Context) {
+	p, err := pc.presenceService.GetFor(c.Request.Context(), c.GetString("user_id"), c.Param("user_id"))
+	if errors.Is(err, realtime.ErrPresenceHidden) {
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+		return
+	}
+	if err != nil {
+		pc.logger.Error("GetUserPresence error: ", err)
+		utils.RespondError(c, http.StatusInternalServerError, "GetUserPresence failed", err.Error())
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Presence fetched", p)
 }
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"launay-dot-one/middlewares"
+	"launay-dot-one/services/push"
+	"launay-dot-one/utils"
+)
+
+type PushController struct {
+	svc    push.Service
+	logger *logrus.Logger
+}
+
+func NewPushController(svc push.Service, logger *logrus.Logger) *PushController {
+	return &PushController{svc: svc, logger: logger}
+}
+
+func (pc *PushController) RegisterRoutes(r *gin.Engine) {
+	// browsers need the key before anyone signs in to subscribe
+	r.GET("/push/vapid-public-key", pc.PublicKey)
+
+	grp := r.Group("/push/subscriptions", middlewares.AuthMiddleware())
+	{
+		grp.GET("", pc.List)
+		grp.POST("", pc.Subscribe)
+		grp.DELETE("/:subscription_id", pc.Unsubscribe)
+	}
+}
+
+func (pc *PushController) respondError(c *gin.Context, op string, err error) {
+	pc.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	case errors.Is(err, push.ErrInvalidSubscription):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid subscription", err.Error())
+	case errors.Is(err, push.ErrTooManySubscriptions):
+		utils.RespondError(c, http.StatusConflict, "Too many devices", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, op+" failed", err.Error())
+	}
+}
+
+// PublicKey handles GET /push/vapid-public-key, the applicationServerKey
+// to subscribe with.
+func (pc *PushController) PublicKey(c *gin.Context) {
+	utils.RespondSuccess(c, http.StatusOK, "VAPID key fetched", gin.H{"public_key": pc.svc.PublicKey()})
+}
+
+// List handles GET /push/subscriptions, the caller's devices.
+func (pc *PushController) List(c *gin.Context) {
+	list, err := pc.svc.ListSubscriptions(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		pc.respondError(c, "ListPushSubscriptions", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Push subscriptions fetched", list)
+}
+
+// Subscribe handles POST /push/subscriptions with the browser's
+// PushSubscription as JSON
+//
+//	body: { "endpoint": "https://...", "keys": { "p256dh": "...", "auth": "..." }, "device_name": "Laptop" }
+func (pc *PushController) Subscribe(c *gin.Context) {
+	var body push.Subscription
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	sub, err := pc.svc.Subscribe(c.Request.Context(), c.GetString("user_id"), body)
+	if err != nil {
+		pc.respondError(c, "Subscribe", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Push subscription saved", sub)
+}
+
+// Unsubscribe handles DELETE /push/subscriptions/:subscription_id
+func (pc *PushController) Unsubscribe(c *gin.Context) {
+	if err := pc.svc.Unsubscribe(c.Request.Context(), c.GetString("user_id"), c.Param("subscription_id")); err != nil {
+		pc.respondError(c, "Unsubscribe", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Push subscription deleted", nil)
+}
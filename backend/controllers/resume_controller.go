@@ -1,10 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 
 	"launay-dot-one/middlewares"
 	m "launay-dot-one/models"
@@ -23,7 +25,8 @@ func NewResumeController(svc resumesvc.Service, logger *logrus.Logger) *ResumeCo
 
 // RegisterRoutes wires up:
 //   - GET/POST/PUT/DELETE   /resume     (auth required)
-//   - GET                     /resumes/:user_id  (public)
+//   - PATCH                 /resume/sharing, POST /resume/sharing/rotate-link (auth required)
+//   - GET                   /resumes/:user_id, /r/:slug  (as the resume's visibility allows)
 func (rc *ResumeController) RegisterRoutes(r *gin.Engine) {
 	grp := r.Group("/resume", middlewares.AuthMiddleware())
 	{
@@ -31,9 +34,29 @@ func (rc *ResumeController) RegisterRoutes(r *gin.Engine) {
 		grp.POST("", rc.CreateResume)
 		grp.PUT("", rc.UpdateResume)
 		grp.DELETE("", rc.DeleteResume)
+		grp.PATCH("/sharing", rc.UpdateSharing)
+		grp.POST("/sharing/rotate-link", rc.RotateShareLink)
+	}
+	// lookups, signed in or not
+	optional := middlewares.OptionalAuthMiddleware()
+	r.GET("/resumes/:user_id", optional, rc.GetResumeByUser)
+	r.GET("/r/:slug", optional, rc.GetResumeBySlug)
+}
+
+func (rc *ResumeController) respondError(c *gin.Context, op string, err error) {
+	rc.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Resume not found", err.Error())
+	case errors.Is(err, resumesvc.ErrSlugTaken):
+		utils.RespondError(c, http.StatusConflict, "Slug taken", err.Error())
+	case errors.Is(err, resumesvc.ErrInvalidVisibility),
+		errors.Is(err, resumesvc.ErrInvalidSlug),
+		errors.Is(err, resumesvc.ErrInvalidSection):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid sharing settings", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, op+" failed", err.Error())
 	}
-	// public lookup
-	r.GET("/resumes/:user_id", rc.GetResumeByUser)
 }
 
 // GetMyResume returns the authenticated user's resume.
@@ -92,13 +115,54 @@ func (rc *ResumeController) DeleteResume(c *gin.Context) {
 	utils.RespondSuccess(c, http.StatusOK, "Resume deleted", nil)
 }
 
-// GetResumeByUser fetches a public view of another user's resume.
+// UpdateSharing handles PATCH /resume/sharing
+//
+//	body: { "visibility": "public" | "unlisted" | "friends" | "private",
+//	        "slug": "jane-doe", "hidden_sections": ["certifications"] }
+//
+// Unlisted resumes are read through /r/<share_slug>, the secret slug in
+// the returned resume.
+func (rc *ResumeController) UpdateSharing(c *gin.Context) {
+	var body resumesvc.Sharing
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	res, err := rc.svc.UpdateSharing(c.Request.Context(), c.GetString("user_id"), body)
+	if err != nil {
+		rc.respondError(c, "UpdateResumeSharing", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Resume sharing updated", res)
+}
+
+// RotateShareLink handles POST /resume/sharing/rotate-link
+func (rc *ResumeController) RotateShareLink(c *gin.Context) {
+	res, err := rc.svc.RotateShareLink(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		rc.respondError(c, "RotateResumeShareLink", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Resume share link rotated", res)
+}
+
+// GetResumeByUser fetches the public view of a user's resume, if its
+// visibility lets the caller read it.
 func (rc *ResumeController) GetResumeByUser(c *gin.Context) {
-	uid := c.Param("user_id")
-	res, err := rc.svc.GetByUser(c.Request.Context(), uid)
+	res, err := rc.svc.View(c.Request.Context(), c.GetString("user_id"), c.Param("user_id"))
 	if err != nil {
-		rc.logger.Error("GetResumeByUser error: ", err)
-		utils.RespondError(c, http.StatusNotFound, "Resume not found", err.Error())
+		rc.respondError(c, "GetResumeByUser", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Resume fetched", res)
+}
+
+// GetResumeBySlug handles GET /r/:slug, by vanity slug or the secret slug
+// of an unlisted link.
+func (rc *ResumeController) GetResumeBySlug(c *gin.Context) {
+	res, err := rc.svc.ViewBySlug(c.Request.Context(), c.GetString("user_id"), c.Param("slug"))
+	if err != nil {
+		rc.respondError(c, "GetResumeBySlug", err)
 		return
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Resume fetched", res)
@@ -1,28 +1,33 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
 	"launay-dot-one/middlewares"
+	accountsvc "launay-dot-one/services/accounts"
 	usersvc "launay-dot-one/services/users"
 	"launay-dot-one/utils"
 )
 
 type UserController struct {
-	userSvc usersvc.Service
-	logger  *logrus.Logger
+	userSvc    usersvc.Service
+	accountSvc accountsvc.Service
+	logger     *logrus.Logger
 }
 
 func NewUserController(
 	logger *logrus.Logger,
 	userSvc usersvc.Service,
+	accountSvc accountsvc.Service,
 ) *UserController {
 	return &UserController{
-		userSvc: userSvc,
-		logger:  logger,
+		userSvc:    userSvc,
+		accountSvc: accountSvc,
+		logger:     logger,
 	}
 }
 
@@ -30,7 +35,7 @@ func (uc *UserController) RegisterRoutes(r *gin.Engine) {
 	// /users endpoints
 	users := r.Group("/users", middlewares.AuthMiddleware())
 	{
-		users.GET("/all", uc.GetAllUsers)
+		users.GET("/search", uc.SearchUsers)
 		users.POST("/avatar", uc.ChangeAvatar)
 		users.GET("/:user_id", uc.GetUserByID)
 	}
@@ -40,18 +45,26 @@ func (uc *UserController) RegisterRoutes(r *gin.Engine) {
 	{
 		user.PUT("/me", uc.UpdateProfile)
 		user.GET("/me", uc.GetCurrent)
+		user.DELETE("/me", uc.DeleteAccount)
+		user.POST("/me/export", uc.ExportData)
 	}
 }
 
-// GetAllUsers returns all users in public form.
-func (uc *UserController) GetAllUsers(c *gin.Context) {
-	list, err := uc.userSvc.List(c.Request.Context())
+// SearchUsers handles GET /users/search?q=&page=&limit=, matching
+// usernames by a prefix of at least two characters.
+func (uc *UserController) SearchUsers(c *gin.Context) {
+	page, limit := utils.Pagination(c, 25, 100)
+	out, err := uc.userSvc.Search(c.Request.Context(), c.Query("q"), page, limit)
 	if err != nil {
-		uc.logger.Error("GetAllUsers error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch users", err.Error())
+		uc.logger.Error("SearchUsers error: ", err)
+		if errors.Is(err, usersvc.ErrQueryTooShort) {
+			utils.RespondError(c, http.StatusBadRequest, "Invalid query", err.Error())
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to search users", err.Error())
 		return
 	}
-	utils.RespondSuccess(c, http.StatusOK, "Users fetched", list)
+	utils.RespondSuccess(c, http.StatusOK, "Users fetched", out)
 }
 
 // ChangeAvatar handles avatar file upload.
@@ -123,8 +136,57 @@ func (uc *UserController) UpdateProfile(c *gin.Context) {
 
 	if err := uc.userSvc.UpdateProfile(c.Request.Context(), userID, updates); err != nil {
 		uc.logger.Error("UpdateProfile error: ", err)
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to update profile", err.Error())
+		switch {
+		case errors.Is(err, usersvc.ErrInvalidUsername):
+			utils.RespondError(c, http.StatusBadRequest, "Invalid username", err.Error())
+		case errors.Is(err, usersvc.ErrUsernameTaken):
+			utils.RespondError(c, http.StatusConflict, "Username taken", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to update profile", err.Error())
+		}
 		return
 	}
 	utils.RespondSuccess(c, http.StatusOK, "Profile updated", nil)
 }
+
+// DeleteAccount schedules the authenticated user's account for deletion.
+//
+//	body: { "password": "..." }
+func (uc *UserController) DeleteAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var input struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+
+	out, err := uc.accountSvc.RequestDeletion(c.Request.Context(), userID, input.Password)
+	if err != nil {
+		uc.logger.Error("DeleteAccount error: ", err)
+		switch {
+		case errors.Is(err, accountsvc.ErrInvalidPassword):
+			utils.RespondError(c, http.StatusUnauthorized, "Invalid password", err.Error())
+		case errors.Is(err, accountsvc.ErrOwnsGuilds), errors.Is(err, accountsvc.ErrGhostAccount):
+			utils.RespondError(c, http.StatusConflict, "Account cannot be deleted yet", err.Error())
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to delete account", err.Error())
+		}
+		return
+	}
+	utils.RespondSuccess(c, http.StatusAccepted, "Account scheduled for deletion", out)
+}
+
+// ExportData builds a ZIP of the authenticated user's data and returns a link.
+func (uc *UserController) ExportData(c *gin.Context) {
+	userID := c.GetString("user_id")
+	out, err := uc.accountSvc.Export(c.Request.Context(), userID)
+	if err != nil {
+		uc.logger.Error("ExportData error: ", err)
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to export data", err.Error())
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Export ready", out)
+}
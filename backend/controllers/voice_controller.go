@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"launay-dot-one/middlewares"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/services/voice"
+	"launay-dot-one/utils"
+)
+
+// Voice socket ops, sent on /messages/ws as { "op": "...", "data": {...} }.
+const (
+	OpVoiceConnect    = "voice_connect"    // data: { "channel_id", "self_mute", "self_deaf" }
+	OpVoiceDisconnect = "voice_disconnect" // data: none
+	OpVoiceState      = "voice_state"      // data: { "self_mute", "self_deaf" }
+	OpVoiceSignal     = "voice_signal"     // data: voice.Signal
+)
+
+type VoiceController struct {
+	svc    voice.Service
+	logger *logrus.Logger
+}
+
+func NewVoiceController(svc voice.Service, logger *logrus.Logger) *VoiceController {
+	return &VoiceController{svc: svc, logger: logger}
+}
+
+func (vc *VoiceController) RegisterRoutes(r *gin.Engine) {
+	r.GET("/channels/:channel_id/voice-states", middlewares.AuthMiddleware(), vc.ListChannel)
+	r.PATCH("/guilds/:guild_id/voice-states/:user_id", middlewares.AuthMiddleware(), vc.UpdateMember)
+}
+
+// voiceErrorCode maps a voice error to its HTTP status.
+func voiceErrorCode(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, permissions.ErrMissingPermission),
+		errors.Is(err, permissions.ErrNotMember),
+		errors.Is(err, voice.ErrTimedOut):
+		return http.StatusForbidden
+	case errors.Is(err, voice.ErrNotConnected), errors.Is(err, voice.ErrPeerNotInCall):
+		return http.StatusConflict
+	case errors.Is(err, voice.ErrNotVoiceChannel), errors.Is(err, voice.ErrInvalidSignal):
+		return http.StatusBadRequest
+	case errors.Is(err, voice.ErrChannelFull):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ListChannel handles GET /channels/:channel_id/voice-states
+func (vc *VoiceController) ListChannel(c *gin.Context) {
+	out, err := vc.svc.ListChannel(c.Request.Context(), c.Param("channel_id"))
+	if err != nil {
+		vc.logger.Error("ListChannel voice error: ", err)
+		utils.RespondError(c, voiceErrorCode(err), "Failed to fetch voice states", err.Error())
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Voice states fetched", out)
+}
+
+// UpdateMember handles PATCH /guilds/:guild_id/voice-states/:user_id
+//
+//	body: { "mute": true, "deaf": false }  // either optional
+func (vc *VoiceController) UpdateMember(c *gin.Context) {
+	var body struct {
+		Mute *bool `json:"mute"`
+		Deaf *bool `json:"deaf"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	st, err := vc.svc.UpdateMember(auditContext(c),
+		c.Param("guild_id"), c.GetString("user_id"), c.Param("user_id"),
+		body.Mute, body.Deaf,
+	)
+	if err != nil {
+		vc.logger.Error("UpdateMember voice error: ", err)
+		utils.RespondError(c, voiceErrorCode(err), "Failed to update voice state", err.Error())
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Voice state updated", st)
+}
+
+// handleVoiceOp runs one voice op received on the messaging socket
+// socketID and returns the acknowledgement for the sender.
+func (mc *MessagingController) handleVoiceOp(
+	ctx context.Context,
+	userID, socketID, op string,
+	data json.RawMessage,
+) utils.APIResponse {
+	var (
+		out interface{}
+		err error
+	)
+	switch op {
+	case OpVoiceConnect:
+		var p struct {
+			ChannelID string `json:"channel_id"`
+			SelfMute  bool   `json:"self_mute"`
+			SelfDeaf  bool   `json:"self_deaf"`
+		}
+		if err = json.Unmarshal(data, &p); err == nil {
+			out, err = mc.voiceSvc.Join(ctx, p.ChannelID, userID, socketID, p.SelfMute, p.SelfDeaf)
+		}
+	case OpVoiceDisconnect:
+		err = mc.voiceSvc.Leave(ctx, userID)
+	case OpVoiceState:
+		var p struct {
+			SelfMute *bool `json:"self_mute"`
+			SelfDeaf *bool `json:"self_deaf"`
+		}
+		if err = json.Unmarshal(data, &p); err == nil {
+			out, err = mc.voiceSvc.UpdateSelf(ctx, userID, p.SelfMute, p.SelfDeaf)
+		}
+	case OpVoiceSignal:
+		var sig voice.Signal
+		if err = json.Unmarshal(data, &sig); err == nil {
+			err = mc.voiceSvc.Relay(ctx, userID, sig)
+		}
+	default:
+		return utils.APIResponse{Code: http.StatusBadRequest, Message: "Unknown op", Error: op}
+	}
+
+	var syntax *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntax), errors.As(err, &typeErr):
+		return utils.APIResponse{Code: http.StatusBadRequest, Message: "Invalid " + op, Error: err.Error()}
+	case err != nil:
+		mc.logger.Warn(op+" error: ", err)
+		return utils.APIResponse{Code: voiceErrorCode(err), Message: "Failed " + op, Error: err.Error()}
+	}
+	return utils.APIResponse{Code: http.StatusOK, Message: op + " ok", Data: out}
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"launay-dot-one/middlewares"
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/services/messaging"
+	"launay-dot-one/services/permissions"
+	whsvc "launay-dot-one/services/webhooks"
+	"launay-dot-one/utils"
+)
+
+type WebhooksController struct {
+	svc    whsvc.Service
+	logger *logrus.Logger
+}
+
+func NewWebhooksController(svc whsvc.Service, logger *logrus.Logger) *WebhooksController {
+	return &WebhooksController{svc: svc, logger: logger}
+}
+
+func (wc *WebhooksController) RegisterRoutes(r *gin.Engine) {
+	auth := middlewares.AuthMiddleware()
+	r.GET("/channels/:channel_id/webhooks", auth, wc.ListByChannel)
+	r.POST("/channels/:channel_id/webhooks", auth, wc.Create)
+	r.GET("/guilds/:guild_id/webhooks", auth, wc.ListByGuild)
+
+	hooks := r.Group("/webhooks")
+	{
+		hooks.PATCH("/:webhook_id", auth, wc.Update)
+		hooks.DELETE("/:webhook_id", auth, wc.Delete)
+		hooks.PUT("/:webhook_id/token", auth, wc.RotateToken)
+
+		// no session: the token in the path authorises the post
+		hooks.POST("/:webhook_id/:token", wc.Execute)
+	}
+}
+
+func (wc *WebhooksController) respondError(c *gin.Context, op string, err error) {
+	wc.logger.Error(op+" error: ", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Not found", err.Error())
+	case errors.Is(err, whsvc.ErrInvalidToken):
+		utils.RespondError(c, http.StatusUnauthorized, "Unauthorized", err.Error())
+	case errors.Is(err, permissions.ErrMissingPermission), errors.Is(err, permissions.ErrNotMember):
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, whsvc.ErrInvalidName),
+		errors.Is(err, whsvc.ErrInvalidAvatar),
+		errors.Is(err, whsvc.ErrInvalidChannel),
+		errors.Is(err, whsvc.ErrEmptyMessage),
+		errors.Is(err, messaging.ErrForumPostOnly):
+		utils.RespondError(c, http.StatusBadRequest, "Invalid request", err.Error())
+	case errors.Is(err, whsvc.ErrRateLimited):
+		utils.RespondError(c, http.StatusTooManyRequests, "Rate limited", err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, op+" failed", err.Error())
+	}
+}
+
+// Create handles POST /channels/:channel_id/webhooks
+//
+//	body: { "name": "CI", "avatar": "https://..." }
+//
+// The response carries the token; it is not shown again.
+func (wc *WebhooksController) Create(c *gin.Context) {
+	var body struct {
+		Name   string `json:"name" binding:"required"`
+		Avatar string `json:"avatar"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	w := guilds.Webhook{ChannelID: c.Param("channel_id"), Name: body.Name, Avatar: body.Avatar}
+	token, err := wc.svc.Create(auditContext(c), &w, c.GetString("user_id"))
+	if err != nil {
+		wc.respondError(c, "CreateWebhook", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusCreated, "Webhook created", gin.H{"webhook": w, "token": token})
+}
+
+// ListByChannel handles GET /channels/:channel_id/webhooks
+func (wc *WebhooksController) ListByChannel(c *gin.Context) {
+	out, err := wc.svc.ListByChannel(c.Request.Context(), c.Param("channel_id"), c.GetString("user_id"))
+	if err != nil {
+		wc.respondError(c, "ListWebhooks", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Webhooks fetched", out)
+}
+
+// ListByGuild handles GET /guilds/:guild_id/webhooks
+func (wc *WebhooksController) ListByGuild(c *gin.Context) {
+	out, err := wc.svc.ListByGuild(c.Request.Context(), c.Param("guild_id"), c.GetString("user_id"))
+	if err != nil {
+		wc.respondError(c, "ListWebhooks", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Webhooks fetched", out)
+}
+
+// Update handles PATCH /webhooks/:webhook_id
+//
+//	body: { "name": "...", "avatar": "...", "channel_id": "..." }  // all optional
+func (wc *WebhooksController) Update(c *gin.Context) {
+	var body struct {
+		Name      *string `json:"name"`
+		Avatar    *string `json:"avatar"`
+		ChannelID *string `json:"channel_id"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	w, err := wc.svc.Update(auditContext(c), c.Param("webhook_id"), whsvc.Patch{
+		Name:      body.Name,
+		Avatar:    body.Avatar,
+		ChannelID: body.ChannelID,
+	}, c.GetString("user_id"))
+	if err != nil {
+		wc.respondError(c, "UpdateWebhook", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Webhook updated", w)
+}
+
+// Delete handles DELETE /webhooks/:webhook_id
+func (wc *WebhooksController) Delete(c *gin.Context) {
+	if err := wc.svc.Delete(auditContext(c), c.Param("webhook_id"), c.GetString("user_id")); err != nil {
+		wc.respondError(c, "DeleteWebhook", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Webhook deleted", nil)
+}
+
+// RotateToken handles PUT /webhooks/:webhook_id/token, invalidating the
+// old token.
+func (wc *WebhooksController) RotateToken(c *gin.Context) {
+	token, err := wc.svc.RotateToken(auditContext(c), c.Param("webhook_id"), c.GetString("user_id"))
+	if err != nil {
+		wc.respondError(c, "RotateWebhookToken", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Webhook token rotated", gin.H{"token": token})
+}
+
+// Execute handles POST /webhooks/:webhook_id/:token
+//
+//	body: { "content": "build #42 passed", "attachments": [...] }
+func (wc *WebhooksController) Execute(c *gin.Context) {
+	var body whsvc.Message
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+	msg, err := wc.svc.Execute(c.Request.Context(), c.Param("webhook_id"), c.Param("token"), body)
+	if err != nil {
+		wc.respondError(c, "ExecuteWebhook", err)
+		return
+	}
+	utils.RespondSuccess(c, http.StatusOK, "Message sent", msg)
+}
@@ -7,10 +7,12 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"time"
 
 	"launay-dot-one/controllers"
 	"launay-dot-one/listeners"
+	"launay-dot-one/mail"
 	"launay-dot-one/models"
 	"launay-dot-one/models/friendships"
 	"launay-dot-one/models/groups"
@@ -19,17 +21,33 @@ import (
 	"launay-dot-one/realtime"
 	"launay-dot-one/repositories"
 
+	"launay-dot-one/middlewares"
+	accountsvc "launay-dot-one/services/accounts"
+	adminsvc "launay-dot-one/services/admin"
+	"launay-dot-one/services/auditlog"
 	authsvc "launay-dot-one/services/auth"
+	botsvc "launay-dot-one/services/bots"
 	"launay-dot-one/services/categories"
 	"launay-dot-one/services/channels"
+	"launay-dot-one/services/digest"
+	"launay-dot-one/services/events"
+	"launay-dot-one/services/forums"
 	frdsvc "launay-dot-one/services/friendships"
 	groupsvc "launay-dot-one/services/groups" // legacy groups
 	"launay-dot-one/services/guildroles"
 	guildsvc "launay-dot-one/services/guilds" // new guilds
+	"launay-dot-one/services/interactions"
+	invsvc "launay-dot-one/services/invites"
 	msgsrv "launay-dot-one/services/messaging"
+	modsvc "launay-dot-one/services/moderation"
+	"launay-dot-one/services/notifications"
 	"launay-dot-one/services/permissions"
+	"launay-dot-one/services/push"
 	resumeSvc "launay-dot-one/services/resumes"
+	"launay-dot-one/services/sessions"
 	usersvc "launay-dot-one/services/users"
+	"launay-dot-one/services/voice"
+	"launay-dot-one/services/webhooks"
 
 	"launay-dot-one/storage"
 	"launay-dot-one/utils"
@@ -68,6 +86,7 @@ func InitServer() (*http.Server, error) {
 		utils.MustEnv("MINIO_ROOT_USER"),
 		utils.MustEnv("MINIO_ROOT_PASSWORD"),
 		utils.GetEnv("STORAGE_BUCKET", "avatars"),
+		exportBucket(),
 		os.Getenv("MINIO_UPLOAD_USER"),
 		os.Getenv("MINIO_UPLOAD_PASSWORD"),
 	); err != nil {
@@ -78,6 +97,10 @@ func InitServer() (*http.Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	exportStore, err := initExportStore(minioClient)
+	if err != nil {
+		return nil, err
+	}
 
 	// ─── Database & Migrations
 	db, err := initDatabaseWithDefaults()
@@ -98,27 +121,137 @@ func InitServer() (*http.Server, error) {
 	categoryRepo := repositories.NewCategoryRepository(db)
 	channelRepo := repositories.NewChannelRepository(db)
 	guildRoleRepo := repositories.NewGuildRoleRepository(db)
+	restrictionRepo := repositories.NewAccountRestrictionRepository(db)
+	adminAuditRepo := repositories.NewAdminAuditLogRepository(db)
+	inviteRepo := repositories.NewGuildInviteRepository(db)
+	banRepo := repositories.NewGuildBanRepository(db)
+	guildTemplateRepo := repositories.NewGuildTemplateRepository(db)
+	guildAuditRepo := repositories.NewGuildAuditLogRepository(db)
+	channelFollowerRepo := repositories.NewChannelFollowerRepository(db)
+	forumRepo := repositories.NewForumRepository(db)
+	webhookRepo := repositories.NewWebhookRepository(db)
+	eventSubRepo := repositories.NewEventSubscriptionRepository(db)
+	botRepo := repositories.NewBotRepository(db)
+	commandRepo := repositories.NewApplicationCommandRepository(db)
+	notificationRepo := repositories.NewNotificationRepository(db)
+	pushRepo := repositories.NewPushSubscriptionRepository(db)
+	digestRepo := repositories.NewDigestRepository(db)
+
+	if err := adminAuditRepo.EnsureImmutable(context.Background()); err != nil {
+		return nil, fmt.Errorf("admin audit log trigger: %w", err)
+	}
+	if err := guildRoleRepo.EnsureEveryoneRoles(context.Background(), guilds.PermDefaultEveryone); err != nil {
+		return nil, fmt.Errorf("@everyone roles: %w", err)
+	}
+	if err := guildMemberRepo.MigrateRoleAssignments(context.Background()); err != nil {
+		return nil, fmt.Errorf("guild member roles migration: %w", err)
+	}
+	if err := channelRepo.NormalizeTypes(context.Background()); err != nil {
+		return nil, fmt.Errorf("channel types: %w", err)
+	}
+	if err := userRepo.EnsureGhost(context.Background()); err != nil {
+		return nil, fmt.Errorf("ghost user: %w", err)
+	}
+	if err := userRepo.EnsureUsernameIndex(context.Background()); err != nil {
+		return nil, fmt.Errorf("username index: %w", err)
+	}
+	if err := eventSubRepo.DropResponseBodies(context.Background()); err != nil {
+		return nil, fmt.Errorf("event delivery response bodies: %w", err)
+	}
+	vapidKey, err := push.LoadVAPIDKey(context.Background(), pushRepo, os.Getenv("VAPID_PRIVATE_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("VAPID key: %w", err)
+	}
 
 	// ─── Services
-	authService := authsvc.NewService(userRepo, jwtSecret, 72*time.Hour)
+	tokenTTL := 72 * time.Hour
+	sessionService := sessions.NewService(rdb, tokenTTL)
+	middlewares.UseSessionGuard(sessionService)
+	authService := authsvc.NewService(userRepo, restrictionRepo, sessionService, jwtSecret, tokenTTL)
 	userService := usersvc.NewService(storageService, userRepo)
 	groupService := groupsvc.NewService(groupRepo)
-	friendService := frdsvc.NewService(friendRepo, db)
-	messagingService := msgsrv.NewService(rdb, messagingRepo)
-	resumeService := resumeSvc.NewService(resumeRepo)
-	guildService := guildsvc.NewService(guildRepo, guildMemberRepo)
-	presenceService := realtime.NewPresenceService(rdb)
-	permService := permissions.NewService(permRepo)
-	categoryService := categories.NewService(categoryRepo, channelRepo)
-	channelService := channels.NewService(channelRepo)
-	guildRoleService := guildroles.NewService(guildRoleRepo)
+	gateway := realtime.NewGateway(guildMemberRepo)
+	presenceService := realtime.NewPresenceService(
+		rdb, userRepo, friendRepo, guildMemberRepo, gateway,
+		utils.GetEnvDuration("PRESENCE_IDLE_AFTER", 10*time.Minute),
+	)
+	pushService := push.NewService(
+		pushRepo, userRepo, rdb, gateway, presenceService, vapidKey,
+		utils.GetEnv("VAPID_SUBJECT", "mailto:admin@localhost"),
+		utils.GetEnvDuration("PUSH_COLLAPSE_WINDOW", 30*time.Second),
+		utils.GetEnvBool("PUSH_ALLOW_LOCAL_ENDPOINTS", false),
+	)
+	notificationService := notifications.NewService(
+		notificationRepo, guildMemberRepo, channelRepo, friendRepo, gateway, pushService,
+		utils.GetEnvDuration("NOTIFICATION_RETENTION", 90*24*time.Hour),
+	)
+	friendService := frdsvc.NewService(friendRepo, userRepo, notificationService)
+	resumeService := resumeSvc.NewService(resumeRepo, userRepo, friendRepo)
+	auditService := auditlog.NewService(
+		guildAuditRepo,
+		utils.GetEnvDuration("GUILD_AUDIT_LOG_RETENTION", 90*24*time.Hour),
+	)
+	permService := permissions.NewService(permRepo, guildRepo, guildMemberRepo, guildRoleRepo, channelRepo, categoryRepo, auditService)
+	eventService := events.NewService(
+		eventSubRepo, adminAuditRepo, permService, auditService,
+		utils.GetEnvDuration("EVENT_WEBHOOK_DISABLE_AFTER", 24*time.Hour),
+		utils.GetEnvDuration("EVENT_DELIVERY_RETENTION", 30*24*time.Hour),
+	)
+	messagingService := msgsrv.NewService(
+		rdb, messagingRepo, channelRepo, guildMemberRepo,
+		forumRepo, channelFollowerRepo, permService, eventService, friendService, notificationService, pushService,
+	)
+	guildService := guildsvc.NewService(
+		guildRepo, guildMemberRepo, guildRoleRepo,
+		categoryRepo, channelRepo, permRepo, guildTemplateRepo, userRepo, banRepo,
+		permService, auditService, eventService,
+	)
+	inviteService := invsvc.NewService(
+		inviteRepo, guildRepo, guildMemberRepo, permService, eventService, notificationService,
+	)
+	botService := botsvc.NewService(
+		botRepo, userRepo, restrictionRepo, guildMemberRepo, banRepo,
+		permService, auditService, eventService, rdb,
+	)
+	middlewares.UseBotAuthenticator(botService)
+	voiceService := voice.NewService(rdb, channelRepo, guildMemberRepo, permService, auditService, gateway)
+	moderationService := modsvc.NewService(
+		banRepo, guildMemberRepo, auditService, permService, gateway, voiceService, notificationService,
+	)
+	categoryService := categories.NewService(categoryRepo, channelRepo, permService, auditService)
+	channelService := channels.NewService(channelRepo, categoryRepo, channelFollowerRepo, permRepo, permService, auditService)
+	forumService := forums.NewService(forumRepo, channelRepo, messagingService, permService, auditService)
+	webhookService := webhooks.NewService(webhookRepo, channelRepo, messagingService, permService, auditService, rdb)
+	guildRoleService := guildroles.NewService(guildRoleRepo, permService, auditService)
+	interactionService := interactions.NewService(
+		commandRepo, botRepo, channelRepo, guildMemberRepo, guildRoleRepo,
+		permService, messagingService, gateway, rdb,
+	)
+	accountService := accountsvc.NewService(
+		userRepo, guildRepo, guildMemberRepo, friendRepo, resumeRepo, messagingRepo,
+		storageService, exportStore, sessionService,
+		utils.GetEnvDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour),
+		utils.GetEnvDuration("DATA_EXPORT_TTL", 7*24*time.Hour),
+	)
+	digestService := digest.NewService(
+		digestRepo, userRepo, messagingRepo, notificationRepo, channelRepo, gateway, initMailer(logger),
+		jwtSecret,
+		utils.GetEnv("APP_URL", "http://localhost:1420"),
+		utils.GetEnv("PUBLIC_API_URL", "http://localhost:8080"),
+		utils.GetEnvDuration("DIGEST_IDLE_AFTER", 24*time.Hour),
+	)
+	adminService := adminsvc.NewService(
+		userRepo, guildRepo, messagingRepo,
+		restrictionRepo, adminAuditRepo,
+		sessionService, rdb,
+	)
 
 	// ─── Controllers
 	authController := controllers.NewAuthController(authService, logger)
-	userController := controllers.NewUserController(logger, userService)
+	userController := controllers.NewUserController(logger, userService, accountService)
 	groupController := controllers.NewGroupController(groupService, logger)
-	messagingController := controllers.NewMessagingController(messagingService, groupService, logger)
-	presenceController := controllers.NewPresenceController(presenceService, rdb, logger)
+	messagingController := controllers.NewMessagingController(messagingService, groupService, voiceService, logger)
+	presenceController := controllers.NewPresenceController(presenceService, inviteService, logger)
 	resumeController := controllers.NewResumeController(resumeService, logger)
 	friendshipController := controllers.NewFriendshipController(friendService, logger)
 	guildController := controllers.NewGuildController(guildService, logger)
@@ -126,6 +259,18 @@ func InitServer() (*http.Server, error) {
 	categoryController := controllers.NewCategoriesController(categoryService, logger)
 	channelController := controllers.NewChannelsController(channelService, logger)
 	guildRolesController := controllers.NewGuildRolesController(guildRoleService, logger)
+	adminController := controllers.NewAdminController(adminService, logger)
+	invitesController := controllers.NewInvitesController(inviteService, logger)
+	moderationController := controllers.NewModerationController(moderationService, logger)
+	forumsController := controllers.NewForumsController(forumService, logger)
+	voiceController := controllers.NewVoiceController(voiceService, logger)
+	webhooksController := controllers.NewWebhooksController(webhookService, logger)
+	eventSubscriptionsController := controllers.NewEventSubscriptionsController(eventService, logger)
+	botsController := controllers.NewBotsController(botService, logger)
+	interactionsController := controllers.NewInteractionsController(interactionService, logger)
+	notificationsController := controllers.NewNotificationsController(notificationService, logger)
+	pushController := controllers.NewPushController(pushService, logger)
+	digestController := controllers.NewDigestController(digestService, logger)
 
 	// ─── Redis‐TTL cleanup & expired listener
 	go func() {
@@ -135,6 +280,70 @@ func InitServer() (*http.Server, error) {
 			if err := messagingService.TransferExpiredMessages(context.Background()); err != nil {
 				logger.Error("TransferExpiredMessages error:", err)
 			}
+			if err := moderationService.LiftExpired(context.Background()); err != nil {
+				logger.Error("LiftExpired error:", err)
+			}
+		}
+	}()
+	// ─── Presence sessions that stopped heartbeating
+	go func() {
+		ticker := time.NewTicker(realtime.HeartbeatInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			offline, err := presenceService.SweepExpired(context.Background())
+			if err != nil {
+				logger.Error("SweepExpired presence error:", err)
+			}
+			for _, id := range offline {
+				if err := inviteService.DropTemporaryMemberships(context.Background(), id); err != nil {
+					logger.Error("DropTemporaryMemberships error:", err)
+				}
+			}
+		}
+	}()
+	// ─── Outgoing event deliveries (durable queue in event_deliveries) & collapsed pushes
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := eventService.ProcessDue(context.Background()); err != nil {
+				logger.Error("ProcessDue event deliveries error:", err)
+			}
+			if _, err := pushService.FlushBursts(context.Background()); err != nil {
+				logger.Error("FlushBursts push error:", err)
+			}
+		}
+	}()
+	// ─── Email digests (one replica at a time, under an advisory lock)
+	go func() {
+		ticker := time.NewTicker(15 * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := digestService.RunDue(context.Background()); err != nil {
+				logger.Error("RunDue digests error:", err)
+			}
+		}
+	}()
+	// ─── Hourly sweeps (account deletion grace period, expired exports & invites, audit & notification retention)
+	go func() {
+		ticker := time.NewTicker(1 * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := accountService.PurgeDue(context.Background()); err != nil {
+				logger.Error("PurgeDue error:", err)
+			}
+			if err := inviteService.DeleteExpired(context.Background()); err != nil {
+				logger.Error("DeleteExpired invites error:", err)
+			}
+			if _, err := auditService.PruneExpired(context.Background()); err != nil {
+				logger.Error("PruneExpired audit log error:", err)
+			}
+			if _, err := eventService.PruneDeliveries(context.Background()); err != nil {
+				logger.Error("PruneDeliveries error:", err)
+			}
+			if _, err := notificationService.PruneRead(context.Background()); err != nil {
+				logger.Error("PruneRead notifications error:", err)
+			}
 		}
 	}()
 	if err := listeners.RedisExpiredListener(context.Background(), rdb, messagingService); err != nil {
@@ -155,11 +364,23 @@ func InitServer() (*http.Server, error) {
 		categoryController,
 		channelController,
 		guildRolesController,
+		adminController,
+		invitesController,
+		moderationController,
+		forumsController,
+		voiceController,
+		webhooksController,
+		eventSubscriptionsController,
+		botsController,
+		interactionsController,
+		notificationsController,
+		pushController,
+		digestController,
 	)
 	cors := handlers.CORS(
 		handlers.AllowedOrigins([]string{utils.GetEnv("CORS_ORIGIN", "http://localhost:1420")}),
-		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"Authorization", "Content-Type"}),
+		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		handlers.AllowedHeaders([]string{"Authorization", "Content-Type", controllers.AuditReasonHeader}),
 	)(router)
 
 	srv := &http.Server{
@@ -172,6 +393,25 @@ func InitServer() (*http.Server, error) {
 	return srv, nil
 }
 
+// initMailer relays mail through SMTP_HOST, or only logs recipients and
+// subjects when unset.
+func initMailer(logger *logrus.Logger) mail.Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		logger.Warn("SMTP_HOST not set: emails are dropped, only their recipients are logged")
+		return mail.NewLogMailer(logger)
+	}
+	port, err := strconv.Atoi(utils.GetEnv("SMTP_PORT", "587"))
+	if err != nil {
+		logger.Fatal("Invalid SMTP_PORT: ", err)
+	}
+	return mail.NewSMTPMailer(
+		host, port,
+		os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"),
+		utils.GetEnv("MAIL_FROM", "Launay <noreply@localhost>"),
+	)
+}
+
 func initRedis(logger *logrus.Logger) (*redis.Client, error) {
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     getEnv("REDIS_ADDRESS", "localhost:6379"),
@@ -197,10 +437,12 @@ func bootstrapMinio(
 	rootUser string,
 	rootPass string,
 	bucket string, // avatars
+	privateBucket string, // data exports, never public
 	uploadUser string, // MINIO_UPLOAD_USER
 	uploadPass string, // MINIO_UPLOAD_PASSWORD
 ) error {
-	const writeAction = "s3:PutObject"
+	// the app uploads avatars/exports and purges them on account deletion
+	writeActions := []string{"s3:PutObject", "s3:DeleteObject"}
 
 	u, err := url.Parse(endpoint)
 	if err != nil {
@@ -216,15 +458,17 @@ func bootstrapMinio(
 		return fmt.Errorf("root S3 client: %w", err)
 	}
 
-	// bucket
-	if err := rootS3.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
-		exists, _ := rootS3.BucketExists(ctx, bucket)
-		if !exists {
-			return fmt.Errorf("make bucket: %w", err)
+	// buckets
+	for _, b := range []string{bucket, privateBucket} {
+		if err := rootS3.MakeBucket(ctx, b, minio.MakeBucketOptions{}); err != nil {
+			exists, _ := rootS3.BucketExists(ctx, b)
+			if !exists {
+				return fmt.Errorf("make bucket %s: %w", b, err)
+			}
 		}
 	}
 
-	// public‑download policy
+	// public‑download policy, for the avatar bucket only
 	policy := fmt.Sprintf(`{
 	  "Version":"2012-10-17",
 	  "Statement":[{
@@ -249,8 +493,20 @@ func bootstrapMinio(
 		"Version": "2012-10-17",
 		"Statement": []map[string]any{{
 			"Effect":   "Allow",
-			"Action":   []string{writeAction},
+			"Action":   writeActions,
 			"Resource": []string{fmt.Sprintf("arn:aws:s3:::%s/*", bucket)},
+		}, {
+			// presigned export downloads are made as this user
+			"Effect":   "Allow",
+			"Action":   append([]string{"s3:GetObject"}, writeActions...),
+			"Resource": []string{fmt.Sprintf("arn:aws:s3:::%s/*", privateBucket)},
+		}, {
+			"Effect": "Allow",
+			"Action": []string{"s3:ListBucket"},
+			"Resource": []string{
+				fmt.Sprintf("arn:aws:s3:::%s", bucket),
+				fmt.Sprintf("arn:aws:s3:::%s", privateBucket),
+			},
 		}},
 	}
 	buf, _ := json.Marshal(writePolicy)
@@ -259,6 +515,17 @@ func bootstrapMinio(
 	return admin.SetPolicy(ctx, writePolID, uploadUser, false) // idempotent
 }
 
+// minioCredentials picks the upload-only user if provided, else root.
+func minioCredentials() (accessKey, secretKey string) {
+	accessKey = os.Getenv("MINIO_UPLOAD_USER")
+	secretKey = os.Getenv("MINIO_UPLOAD_PASSWORD")
+	if accessKey == "" || secretKey == "" {
+		accessKey = os.Getenv("MINIO_ROOT_USER")
+		secretKey = os.Getenv("MINIO_ROOT_PASSWORD")
+	}
+	return accessKey, secretKey
+}
+
 func initMinioClient() (*minio.Client, error) {
 	rawURL := utils.MustEnv("STORAGE_ENDPOINT") // e.g. http://minio:9000
 	u, err := url.Parse(rawURL)
@@ -266,13 +533,7 @@ func initMinioClient() (*minio.Client, error) {
 		return nil, fmt.Errorf("invalid STORAGE_ENDPOINT %q: %w", rawURL, err)
 	}
 
-	// choose upload-only user if provided, else fallback to root
-	accessKey := os.Getenv("MINIO_UPLOAD_USER")
-	secretKey := os.Getenv("MINIO_UPLOAD_PASSWORD")
-	if accessKey == "" || secretKey == "" {
-		accessKey = os.Getenv("MINIO_ROOT_USER")
-		secretKey = os.Getenv("MINIO_ROOT_PASSWORD")
-	}
+	accessKey, secretKey := minioCredentials()
 
 	retries := 5
 	var client *minio.Client
@@ -302,6 +563,31 @@ func initStorageService(client *minio.Client) (*storage.StorageService, error) {
 	return svc, nil
 }
 
+func exportBucket() string {
+	return utils.GetEnv("STORAGE_EXPORT_BUCKET", utils.GetEnv("STORAGE_BUCKET", "avatars")+"-exports")
+}
+
+// initExportStore returns the private store for data exports. Download
+// URLs are presigned for the public storage host (STORAGE_PUBLIC_URL), so
+// the signer talks to that host; with a fixed region it never has to.
+func initExportStore(client *minio.Client) (*storage.StorageService, error) {
+	rawURL := utils.GetEnv("STORAGE_PUBLIC_URL", "http://localhost:8080/storage")
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STORAGE_PUBLIC_URL %q: %w", rawURL, err)
+	}
+	accessKey, secretKey := minioCredentials()
+	presigner, err := minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: u.Scheme == "https",
+		Region: utils.GetEnv("STORAGE_REGION", "us-east-1"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("presign client: %w", err)
+	}
+	return storage.NewPrivateStorageService(client, presigner, exportBucket()), nil
+}
+
 func initDatabaseWithDefaults() (*gorm.DB, error) {
 	host := utils.MustEnv("DB_HOST")
 	user := utils.MustEnv("DB_USER")
@@ -314,7 +600,7 @@ func initDatabaseWithDefaults() (*gorm.DB, error) {
 		"host=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
 		host, user, pass, dbName, sslmode, tz,
 	)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -334,14 +620,35 @@ func initDatabaseWithDefaults() (*gorm.DB, error) {
 
 		// friendship system
 		&friendships.FriendRequest{},
+		&friendships.PrivacySettings{},
 
 		// Discord‐style guilds
 		&guilds.Guild{},
 		&guilds.GuildRole{},
 		&guilds.GuildMember{},
+		&guilds.GuildMemberRole{},
 		&guilds.Category{},
 		&guilds.Channel{},
 		&guilds.PermissionOverwrite{},
+		&guilds.GuildInvite{},
+		&guilds.GuildBan{},
+		&guilds.GuildTemplate{},
+		&guilds.AuditLogEntry{},
+		&guilds.ChannelFollower{},
+		&guilds.ForumTag{},
+		&guilds.ForumPost{},
+		&guilds.Webhook{},
+		&models.EventSubscription{},
+		&models.EventDelivery{},
+		&models.EventDeliveryAttempt{},
+		&models.Bot{},
+		&models.BotToken{},
+		&models.ApplicationCommand{},
+		&models.Notification{},
+		&models.NotificationSetting{},
+		&models.PushSubscription{},
+		&models.VAPIDKey{},
+		&models.DigestSettings{},
 
 		// resumes
 		&models.Resume{},
@@ -351,6 +658,10 @@ func initDatabaseWithDefaults() (*gorm.DB, error) {
 		&resume.Certification{},
 		&resume.Skill{},
 		&resume.Interest{},
+
+		// platform administration
+		&models.AccountRestriction{},
+		&models.AdminAuditLog{},
 	); err != nil {
 		return nil, fmt.Errorf("auto-migration failed: %w", err)
 	}
@@ -0,0 +1,110 @@
+// Package mail sends email through a pluggable Mailer.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Message is an email with a plain text body and an HTML alternative.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Headers are extra headers, e.g. List-Unsubscribe.
+	Headers map[string]string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Build renders msg as a multipart/alternative MIME message from from.
+func Build(from string, msg Message) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, part := range []struct{ typ, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.typ},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&out, "%s: %s\r\n", k, v) }
+	header("From", from)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+uuid.NewString()+"@"+domainOf(from)+">")
+	header("MIME-Version", "1.0")
+	keys := make([]string, 0, len(msg.Headers))
+	for k := range msg.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		header(k, msg.Headers[k])
+	}
+	header("Content-Type", "multipart/alternative; boundary="+w.Boundary())
+	out.WriteString("\r\n")
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+// domainOf is the domain of an address like "Name <user@host>".
+func domainOf(addr string) string {
+	for i := len(addr) - 1; i >= 0; i-- {
+		if addr[i] == '@' {
+			d := addr[i+1:]
+			if n := len(d); n > 0 && d[n-1] == '>' {
+				d = d[:n-1]
+			}
+			return d
+		}
+	}
+	return "localhost"
+}
+
+type logMailer struct{ logger *logrus.Logger }
+
+// NewLogMailer returns a Mailer that only logs who it would send to, for
+// development without an SMTP server.
+func NewLogMailer(logger *logrus.Logger) Mailer {
+	return &logMailer{logger: logger}
+}
+
+// Send logs the recipient and subject only: bodies carry message excerpts
+// and unsubscribe links, which don't belong in logs.
+func (l *logMailer) Send(_ context.Context, msg Message) error {
+	l.logger.Infof("mail to %s: %s", msg.To, msg.Subject)
+	return nil
+}
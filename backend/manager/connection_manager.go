@@ -10,11 +10,16 @@ import (
 type Manager struct {
 	mu          sync.RWMutex
 	connections map[string]*websocket.Conn
+	writeLocks  map[*websocket.Conn]*sync.Mutex
+	// every open socket per user, including ones that only Track
+	sockets map[string]map[*websocket.Conn]bool
 }
 
 // ConnManager is the global instance for managing connections.
 var ConnManager = &Manager{
 	connections: make(map[string]*websocket.Conn),
+	writeLocks:  make(map[*websocket.Conn]*sync.Mutex),
+	sockets:     make(map[string]map[*websocket.Conn]bool),
 }
 
 // Add registers a new connection for a given userID.
@@ -22,13 +27,18 @@ func (m *Manager) Add(userID string, conn *websocket.Conn) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.connections[userID] = conn
+	m.writeLocks[conn] = &sync.Mutex{}
 }
 
-// Remove deletes the connection associated with a userID.
-func (m *Manager) Remove(userID string) {
+// Remove deletes the user's connection, unless a newer one of theirs
+// has replaced conn since.
+func (m *Manager) Remove(userID string, conn *websocket.Conn) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	delete(m.connections, userID)
+	delete(m.writeLocks, conn)
+	if m.connections[userID] == conn {
+		delete(m.connections, userID)
+	}
 }
 
 // Get retrieves the connection for a given userID.
@@ -38,3 +48,55 @@ func (m *Manager) Get(userID string) (*websocket.Conn, bool) {
 	conn, ok := m.connections[userID]
 	return conn, ok
 }
+
+// Send writes v as JSON to the user's connection, serialising concurrent
+// writers (gorilla connections support only one at a time). It reports
+// whether the user was connected and the write succeeded.
+func (m *Manager) Send(userID string, v interface{}) bool {
+	m.mu.RLock()
+	conn, ok := m.connections[userID]
+	lock := m.writeLocks[conn]
+	m.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	return conn.WriteJSON(v) == nil
+}
+
+// Track records another socket of the user, such as a presence socket, so
+// CloseUser can end it. The returned func forgets it again.
+func (m *Manager) Track(userID string, conn *websocket.Conn) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sockets[userID] == nil {
+		m.sockets[userID] = make(map[*websocket.Conn]bool)
+	}
+	m.sockets[userID][conn] = true
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.sockets[userID], conn)
+		if len(m.sockets[userID]) == 0 {
+			delete(m.sockets, userID)
+		}
+	}
+}
+
+// CloseUser closes every socket the user has open, e.g. once their
+// sessions are revoked. The handlers notice on their next read and clean up.
+func (m *Manager) CloseUser(userID string) {
+	m.mu.RLock()
+	conns := make([]*websocket.Conn, 0, len(m.sockets[userID])+1)
+	if conn, ok := m.connections[userID]; ok {
+		conns = append(conns, conn)
+	}
+	for conn := range m.sockets[userID] {
+		conns = append(conns, conn)
+	}
+	m.mu.RUnlock()
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+}
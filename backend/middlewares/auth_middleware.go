@@ -2,12 +2,15 @@ package middlewares
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 
+	"launay-dot-one/models"
 	"launay-dot-one/utils"
 )
 
@@ -17,6 +20,10 @@ func AuthMiddleware() gin.HandlerFunc {
 
 	return func(c *gin.Context) {
 		auth := c.GetHeader("Authorization")
+		if botID, ok, err := BotFromHeader(c.Request.Context(), auth); ok {
+			authenticateBot(c, botID, err)
+			return
+		}
 		if !strings.HasPrefix(auth, "Bearer ") {
 			utils.RespondError(c, http.StatusUnauthorized, "Unauthorized", "Missing or invalid Authorization header")
 			c.Abort()
@@ -50,7 +57,64 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		revoked, err := TokenRevoked(c.Request.Context(), userID, claims)
+		if err != nil {
+			utils.RespondError(c, http.StatusInternalServerError, "Internal server error", err.Error())
+			c.Abort()
+			return
+		}
+		if revoked {
+			utils.RespondError(c, http.StatusUnauthorized, "Unauthorized", "Session has been revoked")
+			c.Abort()
+			return
+		}
+
+		role, _ := claims["role"].(string)
+		if role == "" {
+			role = models.RoleUser
+		}
+
 		c.Set("user_id", userID)
+		c.Set("user_role", role)
 		c.Next()
 	}
 }
+
+// OptionalAuthMiddleware authenticates requests that carry credentials
+// like AuthMiddleware, and lets anonymous ones through without a user_id.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	authenticate := AuthMiddleware()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+// authenticateBot finishes a request carrying a bot token: bots act as
+// plain users, marked with "is_bot", and are metered in their own bucket.
+func authenticateBot(c *gin.Context, botID string, err error) {
+	if err != nil {
+		utils.RespondError(c, http.StatusUnauthorized, "Unauthorized", err.Error())
+		c.Abort()
+		return
+	}
+	allowed, retryAfter, err := botAuth.ThrottleBot(c.Request.Context(), botID)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Internal server error", err.Error())
+		c.Abort()
+		return
+	}
+	if !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		utils.RespondError(c, http.StatusTooManyRequests, "Rate limited", "Bot rate limit exceeded")
+		c.Abort()
+		return
+	}
+	c.Set("user_id", botID)
+	c.Set("user_role", models.RoleUser)
+	c.Set("is_bot", true)
+	c.Next()
+}
@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// BotAuthenticator resolves bot tokens and meters bot traffic. Bots get
+// their own rate-limit buckets, separate from human users.
+type BotAuthenticator interface {
+	// AuthenticateBot returns the bot's user ID for a valid, unrevoked token.
+	AuthenticateBot(ctx context.Context, token string) (string, error)
+
+	// ThrottleBot counts one request against the bot's bucket. When the
+	// bucket is spent it returns false and how long until it refills.
+	ThrottleBot(ctx context.Context, botID string) (bool, time.Duration, error)
+}
+
+var botAuth BotAuthenticator
+
+// UseBotAuthenticator installs the authenticator consulted for
+// "Authorization: Bot <token>". Without one, bot tokens are rejected.
+func UseBotAuthenticator(a BotAuthenticator) {
+	botAuth = a
+}
+
+const botScheme = "Bot "
+
+var errBotAuthUnavailable = errors.New("bot authentication is not available")
+
+// BotFromHeader authenticates an Authorization header carrying a bot
+// token. ok is false when the header holds no bot token at all, so the
+// caller can fall back to other schemes.
+func BotFromHeader(ctx context.Context, header string) (botID string, ok bool, err error) {
+	if !strings.HasPrefix(header, botScheme) {
+		return "", false, nil
+	}
+	if botAuth == nil {
+		return "", true, errBotAuthUnavailable
+	}
+	botID, err = botAuth.AuthenticateBot(ctx, strings.TrimPrefix(header, botScheme))
+	return botID, true, err
+}
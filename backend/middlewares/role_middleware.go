@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"launay-dot-one/utils"
+)
+
+// RequireRole only lets through users whose platform role is one of roles.
+// It must run after AuthMiddleware, which puts the role in the context.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("user_role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		utils.RespondError(c, http.StatusForbidden, "Forbidden", "Insufficient role")
+		c.Abort()
+	}
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// SessionGuard decides whether a signed, unexpired token has been revoked
+// server-side (sessions revoked by an admin, password changes, bans…).
+type SessionGuard interface {
+	IsRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error)
+}
+
+var sessionGuard SessionGuard
+
+// UseSessionGuard installs the guard consulted by AuthMiddleware and
+// TokenRevoked.
+// Without one, every valid JWT is accepted.
+func UseSessionGuard(g SessionGuard) {
+	sessionGuard = g
+}
+
+// TokenRevoked reports whether the user's token, described by its claims,
+// has been revoked. Always false without a guard.
+func TokenRevoked(ctx context.Context, userID string, claims jwt.MapClaims) (bool, error) {
+	if sessionGuard == nil {
+		return false, nil
+	}
+	iat, _ := claims["iat"].(float64)
+	return sessionGuard.IsRevoked(ctx, userID, time.Unix(int64(iat), 0))
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// RestrictionKind distinguishes temporary suspensions from bans.
+type RestrictionKind string
+
+const (
+	RestrictionSuspension RestrictionKind = "suspension"
+	RestrictionBan        RestrictionKind = "ban"
+)
+
+// AccountRestriction blocks a user from signing in until it expires or is lifted.
+type AccountRestriction struct {
+	ID          string          `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	UserID      string          `json:"user_id" gorm:"type:uuid;not null;index"`
+	Kind        RestrictionKind `json:"kind" gorm:"type:text;not null"`
+	Reason      string          `json:"reason" gorm:"type:text"`
+	ModeratorID string          `json:"moderator_id" gorm:"type:uuid;not null"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty"` // nil = permanent
+	LiftedAt    *time.Time      `json:"lifted_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Active reports whether the restriction still applies at the given time.
+func (r *AccountRestriction) Active(now time.Time) bool {
+	if r.LiftedAt != nil {
+		return false
+	}
+	return r.ExpiresAt == nil || r.ExpiresAt.After(now)
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AdminAction names a platform-admin operation recorded in the audit log.
+type AdminAction string
+
+const (
+	AdminActionSuspendUser        AdminAction = "user.suspend"
+	AdminActionBanUser            AdminAction = "user.ban"
+	AdminActionLiftRestrictions   AdminAction = "user.lift_restrictions"
+	AdminActionForcePasswordReset AdminAction = "user.force_password_reset"
+	AdminActionRevokeSessions     AdminAction = "user.revoke_sessions"
+	AdminActionUpdateRole         AdminAction = "user.update_role"
+	AdminActionDeleteGuild        AdminAction = "guild.delete"
+
+	AdminActionCreateEventSubscription AdminAction = "event_subscription.create"
+	AdminActionUpdateEventSubscription AdminAction = "event_subscription.update"
+	AdminActionDeleteEventSubscription AdminAction = "event_subscription.delete"
+	AdminActionRotateEventSecret       AdminAction = "event_subscription.secret_rotate"
+)
+
+// AdminAuditLog is an append-only record of an admin action.
+// Rows are never updated or deleted; a database trigger enforces this.
+type AdminAuditLog struct {
+	ID         string         `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	ActorID    string         `json:"actor_id" gorm:"type:uuid;not null;index"`
+	Action     AdminAction    `json:"action" gorm:"type:text;not null;index"`
+	TargetType string         `json:"target_type" gorm:"type:text"`
+	TargetID   string         `json:"target_id" gorm:"index"`
+	Reason     string         `json:"reason,omitempty" gorm:"type:text"`
+	Metadata   datatypes.JSON `json:"metadata,omitempty" gorm:"type:jsonb"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	MaxCommandName        = 32
+	MaxCommandDescription = 100
+	MaxCommandOptions     = 25
+	MaxOptionChoices      = 25
+	MaxCommandsPerScope   = 100 // per bot, globally or in one guild
+	MaxStringOptionValue  = 6000
+)
+
+// CommandOptionType is the kind of value a command option takes.
+type CommandOptionType string
+
+const (
+	OptionString  CommandOptionType = "string"
+	OptionInteger CommandOptionType = "integer"
+	OptionUser    CommandOptionType = "user"    // a member of the guild
+	OptionChannel CommandOptionType = "channel" // a channel of the guild
+	OptionRole    CommandOptionType = "role"    // a role of the guild
+)
+
+// CommandOptionChoice is one allowed value of a string or integer option.
+type CommandOptionChoice struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// CommandOption describes one typed argument of a command.
+type CommandOption struct {
+	Type        CommandOptionType     `json:"type"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Required    bool                  `json:"required"`
+	Choices     []CommandOptionChoice `json:"choices,omitempty"`
+	MinValue    *int64                `json:"min_value,omitempty"` // integer options only
+	MaxValue    *int64                `json:"max_value,omitempty"`
+}
+
+// ApplicationCommand is a slash command a bot registered, either globally
+// (usable in every guild the bot is in) or for a single guild.
+type ApplicationCommand struct {
+	ID          string                             `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	BotID       string                             `json:"bot_id" gorm:"type:uuid;not null;index:idx_command_scope"`
+	GuildID     *string                            `json:"guild_id,omitempty" gorm:"index:idx_command_scope"` // nil = global
+	Name        string                             `json:"name" gorm:"not null"`
+	Description string                             `json:"description" gorm:"not null"`
+	Options     datatypes.JSONSlice[CommandOption] `json:"options" gorm:"type:jsonb;not null"`
+	CreatedAt   time.Time                          `json:"created_at"`
+	UpdatedAt   time.Time                          `json:"updated_at"`
+}
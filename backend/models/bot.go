@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+const (
+	MinBotUsername    = 2
+	MaxBotUsername    = 32
+	MaxBotDescription = 400
+	MaxBotsPerOwner   = 10
+	MaxTokensPerBot   = 5
+	MaxBotTokenName   = 80
+
+	MaxInteractionsURL = 2048
+)
+
+// Bot describes a bot account: the User row flagged Bot plus who owns it
+// and how guilds may add it.
+type Bot struct {
+	UserID      string `json:"user_id" gorm:"primaryKey;type:uuid"`
+	OwnerID     string `json:"owner_id" gorm:"type:uuid;not null;index"`
+	Description string `json:"description" gorm:"type:text"`
+	// Public bots can be authorized into guilds by anyone with
+	// manage-guild there; private ones only by their owner.
+	Public bool `json:"public" gorm:"not null;default:false"`
+	// DefaultPermissions is what the authorization flow offers by default.
+	DefaultPermissions uint64    `json:"default_permissions" gorm:"type:bigint;not null;default:0"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+
+	// InteractionsURL, when set, receives interactions as signed HTTP
+	// POSTs instead of INTERACTION_CREATE events on the bot's socket.
+	InteractionsURL string `json:"interactions_url,omitempty" gorm:"type:text"`
+	// PublicKey verifies the Ed25519 signature on those POSTs; the server
+	// keeps the private half.
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"-"`
+}
+
+// BotToken is a long-lived credential for a bot, sent as
+// "Authorization: Bot <token>". Only a hash is stored; the token itself
+// is shown once, when it is created.
+type BotToken struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	BotID      string     `json:"bot_id" gorm:"type:uuid;not null;index"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-" gorm:"not null;uniqueIndex"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
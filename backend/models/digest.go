@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// DigestFrequency is how often a user away from the app gets an email
+// digest of what they missed.
+type DigestFrequency string
+
+const (
+	DigestDaily  DigestFrequency = "daily"
+	DigestWeekly DigestFrequency = "weekly"
+	DigestNever  DigestFrequency = "never" // opted out
+)
+
+// DefaultDigestFrequency applies to users who never changed it.
+const DefaultDigestFrequency = DigestDaily
+
+// MaxDigestItems caps the direct messages, and the mentions, listed in
+// one digest; the rest are counted.
+const MaxDigestItems = 10
+
+// DigestSettings are a user's email digest preferences. LastSentAt bounds
+// what the next digest covers; CheckedAt is when the user was last
+// considered, digest or not.
+type DigestSettings struct {
+	UserID     string          `json:"user_id" gorm:"type:uuid;primaryKey"`
+	Frequency  DigestFrequency `json:"frequency" gorm:"type:text;not null;default:'daily'"`
+	LastSentAt *time.Time      `json:"last_sent_at,omitempty"`
+	CheckedAt  *time.Time      `json:"-" gorm:"index"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// Interval is the time between two digests at this frequency.
+func (f DigestFrequency) Interval() time.Duration {
+	if f == DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// DefaultDigestSettings are the settings of a user who has none stored.
+func DefaultDigestSettings(userID string) *DigestSettings {
+	return &DigestSettings{UserID: userID, Frequency: DefaultDigestFrequency}
+}
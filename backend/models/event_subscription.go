@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Events an outgoing webhook can subscribe to.
+const (
+	EventMessageCreated = "message.created"
+	EventMemberJoined   = "member.joined"
+	EventGuildUpdated   = "guild.updated"
+)
+
+// SubscribableEvents lists every event name a subscription may ask for.
+var SubscribableEvents = []string{EventMessageCreated, EventMemberJoined, EventGuildUpdated}
+
+const MaxEventSubscriptionURL = 2048
+
+// EventSubscription sends signed copies of platform events to an outside
+// endpoint. Guild subscriptions see their guild's events; platform
+// subscriptions (GuildID nil) see every guild's.
+type EventSubscription struct {
+	ID                  string                      `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	GuildID             *string                     `json:"guild_id,omitempty" gorm:"index"` // nil = platform-wide
+	URL                 string                      `json:"url" gorm:"type:text;not null"`
+	Secret              string                      `json:"-" gorm:"not null"` // HMAC key, shown once
+	Events              datatypes.JSONSlice[string] `json:"events" gorm:"type:jsonb;not null"`
+	Enabled             bool                        `json:"enabled" gorm:"not null;default:true"`
+	DisabledReason      string                      `json:"disabled_reason,omitempty"`
+	ConsecutiveFailures int                         `json:"consecutive_failures" gorm:"not null;default:0"`
+	FailingSince        *time.Time                  `json:"failing_since,omitempty"`
+	CreatorID           string                      `json:"creator_id" gorm:"not null"`
+	CreatedAt           time.Time                   `json:"created_at"`
+	UpdatedAt           time.Time                   `json:"updated_at"`
+}
+
+// DeliveryStatus tracks an event delivery through the retry queue.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed" // retries exhausted
+)
+
+// EventDelivery is one event queued for one subscription. Pending rows
+// are the durable retry queue; the rest are its delivery log.
+type EventDelivery struct {
+	ID             string         `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	SubscriptionID string         `json:"subscription_id" gorm:"type:uuid;not null;index"`
+	EventID        string         `json:"event_id" gorm:"type:uuid;not null;index"` // same across redeliveries
+	Event          string         `json:"event" gorm:"type:text;not null"`
+	Payload        datatypes.JSON `json:"payload" gorm:"type:jsonb;not null"`
+	Status         DeliveryStatus `json:"status" gorm:"type:text;not null;index:idx_delivery_queue,priority:1"`
+	Attempts       int            `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at" gorm:"index:idx_delivery_queue,priority:2"`
+	LastStatusCode int            `json:"last_status_code,omitempty"`
+	LastError      string         `json:"last_error,omitempty" gorm:"type:text"`
+	RedeliveryOf   *string        `json:"redelivery_of,omitempty" gorm:"type:uuid"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"index"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+
+	AttemptLog []EventDeliveryAttempt `json:"attempt_log,omitempty" gorm:"foreignKey:DeliveryID"`
+}
+
+// EventDeliveryAttempt records one HTTP attempt at a delivery.
+type EventDeliveryAttempt struct {
+	ID           string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	DeliveryID   string    `json:"delivery_id" gorm:"type:uuid;not null;index"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	Error        string    `json:"error,omitempty" gorm:"type:text"`
+	DurationMs   int64     `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
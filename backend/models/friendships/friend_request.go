@@ -11,6 +11,9 @@ const (
 	FriendBlocked  FriendStatus = "blocked"
 )
 
+// FriendRequest is the relationship between two users. A block is a row
+// of its own, from BlockerID (the requester) to the blocked user, and
+// replaces whatever else the pair had.
 type FriendRequest struct {
 	ID          string    `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
 	RequesterID string    `json:"requester_id"`
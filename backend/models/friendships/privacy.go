@@ -0,0 +1,39 @@
+package friendships
+
+import "time"
+
+// FriendRequestPolicy says who may send a user friend requests.
+type FriendRequestPolicy string
+
+const (
+	RequestsFromEveryone         FriendRequestPolicy = "everyone"
+	RequestsFromFriendsOfFriends FriendRequestPolicy = "friends_of_friends"
+	RequestsFromNobody           FriendRequestPolicy = "nobody"
+)
+
+// DMPolicy says who may send a user direct messages.
+type DMPolicy string
+
+const (
+	DMsFromEveryone DMPolicy = "everyone"
+	DMsFromFriends  DMPolicy = "friends"
+	DMsFromNobody   DMPolicy = "nobody"
+)
+
+// PrivacySettings are a user's relationship preferences. Users without a
+// row get DefaultPrivacy.
+type PrivacySettings struct {
+	UserID         string              `json:"user_id" gorm:"type:uuid;primaryKey"`
+	FriendRequests FriendRequestPolicy `json:"friend_requests" gorm:"type:text;not null;default:'everyone'"`
+	DirectMessages DMPolicy            `json:"direct_messages" gorm:"type:text;not null;default:'everyone'"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// DefaultPrivacy returns the settings of a user who never changed them.
+func DefaultPrivacy(userID string) *PrivacySettings {
+	return &PrivacySettings{
+		UserID:         userID,
+		FriendRequests: RequestsFromEveryone,
+		DirectMessages: DMsFromEveryone,
+	}
+}
@@ -0,0 +1,91 @@
+package guilds
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AuditAction names a mutating operation recorded in a guild's audit log.
+type AuditAction string
+
+const (
+	AuditGuildCreate AuditAction = "guild.create"
+	AuditGuildUpdate AuditAction = "guild.update"
+	AuditGuildDelete AuditAction = "guild.delete"
+
+	AuditGuildOwnerTransfer AuditAction = "guild.owner_transfer"
+
+	AuditMemberAdd           AuditAction = "member.add"
+	AuditMemberLeave         AuditAction = "member.leave"
+	AuditMemberRoleUpdate    AuditAction = "member.role_update"
+	AuditMemberBan           AuditAction = "member.ban"
+	AuditMemberUnban         AuditAction = "member.unban"
+	AuditMemberKick          AuditAction = "member.kick"
+	AuditMemberTimeout       AuditAction = "member.timeout"
+	AuditMemberTimeoutRemove AuditAction = "member.timeout_remove"
+	AuditMemberVoiceUpdate   AuditAction = "member.voice_update"
+	AuditBotAdd              AuditAction = "member.bot_add"
+
+	AuditCategoryCreate AuditAction = "category.create"
+	AuditCategoryUpdate AuditAction = "category.update"
+	AuditCategoryDelete AuditAction = "category.delete"
+
+	AuditChannelCreate AuditAction = "channel.create"
+	AuditChannelUpdate AuditAction = "channel.update"
+	AuditChannelDelete AuditAction = "channel.delete"
+	AuditChannelSync   AuditAction = "channel.permissions_sync"
+
+	AuditChannelFollow   AuditAction = "channel.follow"
+	AuditChannelUnfollow AuditAction = "channel.unfollow"
+
+	AuditForumTagCreate AuditAction = "forum_tag.create"
+	AuditForumTagUpdate AuditAction = "forum_tag.update"
+	AuditForumTagDelete AuditAction = "forum_tag.delete"
+
+	AuditWebhookCreate      AuditAction = "webhook.create"
+	AuditWebhookUpdate      AuditAction = "webhook.update"
+	AuditWebhookDelete      AuditAction = "webhook.delete"
+	AuditWebhookTokenRotate AuditAction = "webhook.token_rotate"
+
+	AuditEventSubscriptionCreate AuditAction = "event_subscription.create"
+	AuditEventSubscriptionUpdate AuditAction = "event_subscription.update"
+	AuditEventSubscriptionDelete AuditAction = "event_subscription.delete"
+	AuditEventSecretRotate       AuditAction = "event_subscription.secret_rotate"
+
+	AuditRoleCreate AuditAction = "role.create"
+	AuditRoleUpdate AuditAction = "role.update"
+	AuditRoleDelete AuditAction = "role.delete"
+
+	AuditOverwriteCreate AuditAction = "overwrite.create"
+	AuditOverwriteUpdate AuditAction = "overwrite.update"
+	AuditOverwriteDelete AuditAction = "overwrite.delete"
+)
+
+// Audit target types.
+const (
+	AuditTargetGuild     = "guild"
+	AuditTargetMember    = "member"
+	AuditTargetCategory  = "category"
+	AuditTargetChannel   = "channel"
+	AuditTargetRole      = "role"
+	AuditTargetOverwrite = "overwrite"
+	AuditTargetFollower  = "channel_follower"
+	AuditTargetForumTag  = "forum_tag"
+	AuditTargetWebhook   = "webhook"
+
+	AuditTargetEventSubscription = "event_subscription"
+)
+
+// AuditLogEntry records who changed what inside a Guild.
+type AuditLogEntry struct {
+	ID         string         `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	GuildID    string         `json:"guild_id" gorm:"not null;index:idx_audit_guild_created,priority:1"`
+	ActorID    string         `json:"actor_id" gorm:"index"` // empty for automated actions
+	Action     AuditAction    `json:"action" gorm:"type:text;not null;index"`
+	TargetType string         `json:"target_type" gorm:"type:text"`
+	TargetID   string         `json:"target_id"`
+	Changes    datatypes.JSON `json:"changes,omitempty" gorm:"type:jsonb"` // {"field": {"before": …, "after": …}}
+	Reason     string         `json:"reason,omitempty" gorm:"type:text"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"index:idx_audit_guild_created,priority:2"`
+}
@@ -5,11 +5,47 @@ import "time"
 type ChannelType string
 
 const (
-	ChannelText  ChannelType = "text"
-	ChannelVoice ChannelType = "voice"
+	ChannelText         ChannelType = "text"
+	ChannelVoice        ChannelType = "voice"
+	ChannelAnnouncement ChannelType = "announcement"
+	ChannelForum        ChannelType = "forum"
+	ChannelStage        ChannelType = "stage"
 )
 
-// Channel lives under an optional Category and inherits its permissions.
+// Limits on the type-specific channel settings.
+const (
+	MaxTopicLength     = 1024
+	MaxSlowmodeSeconds = 6 * 60 * 60
+	MinBitrate         = 8000
+	MaxBitrate         = 384000
+	DefaultBitrate     = 64000
+	MaxVoiceUserLimit  = 99
+	MaxStageUserLimit  = 10000
+)
+
+// Valid reports whether t is a known channel type.
+func (t ChannelType) Valid() bool {
+	switch t {
+	case ChannelText, ChannelVoice, ChannelAnnouncement, ChannelForum, ChannelStage:
+		return true
+	}
+	return false
+}
+
+// TextBased reports whether the channel carries messages, and with them a
+// topic, an NSFW flag and slowmode.
+func (t ChannelType) TextBased() bool {
+	return t == ChannelText || t == ChannelAnnouncement || t == ChannelForum
+}
+
+// AudioBased reports whether the channel carries voice, and with it a
+// bitrate and user limit.
+func (t ChannelType) AudioBased() bool {
+	return t == ChannelVoice || t == ChannelStage
+}
+
+// Channel lives under an optional Category. While PermissionsSynced is set
+// its overwrites are kept as a copy of the category's.
 type Channel struct {
 	ID         string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
 	GuildID    string    `json:"guild_id" gorm:"not null;index"`
@@ -19,4 +55,27 @@ type Channel struct {
 	Position   int       `json:"position"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+
+	PermissionsSynced bool `json:"permissions_synced" gorm:"not null;default:false"`
+
+	// text, announcement and forum channels; a stage's topic names the
+	// talk currently on stage
+	Topic           string `json:"topic,omitempty" gorm:"type:text"`
+	NSFW            bool   `json:"nsfw" gorm:"not null;default:false"`
+	SlowmodeSeconds int    `json:"slowmode_seconds" gorm:"not null;default:0"` // per user, 0 = off
+
+	// voice and stage channels
+	Bitrate   int `json:"bitrate,omitempty"`
+	UserLimit int `json:"user_limit,omitempty"` // 0 = unlimited
+}
+
+// ChannelFollower cross-posts an announcement channel's published messages
+// into a text channel of another guild.
+type ChannelFollower struct {
+	ID              string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	ChannelID       string    `json:"channel_id" gorm:"not null;uniqueIndex:idx_follower_pair"` // announcement source
+	GuildID         string    `json:"guild_id" gorm:"not null;index"`                           // follower's guild
+	TargetChannelID string    `json:"target_channel_id" gorm:"not null;uniqueIndex:idx_follower_pair;index"`
+	CreatedBy       string    `json:"created_by" gorm:"not null"`
+	CreatedAt       time.Time `json:"created_at"`
 }
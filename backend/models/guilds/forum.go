@@ -0,0 +1,39 @@
+package guilds
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	MaxForumTags    = 20
+	MaxPostTags     = 5
+	MaxPostTitle    = 100
+	MaxForumTagName = 20
+)
+
+// ForumTag is one of the labels a forum channel offers its posts. Moderated
+// tags can only be applied by members who can manage the channel.
+type ForumTag struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	ChannelID string    `json:"channel_id" gorm:"not null;index"`
+	Name      string    `json:"name" gorm:"not null"`
+	Emoji     string    `json:"emoji,omitempty"`
+	Moderated bool      `json:"moderated" gorm:"not null;default:false"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ForumPost is a titled thread inside a forum channel. Its messages use the
+// post ID as their channel ID.
+type ForumPost struct {
+	ID        string                      `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	GuildID   string                      `json:"guild_id" gorm:"not null;index"`
+	ChannelID string                      `json:"channel_id" gorm:"not null;index"` // the forum
+	AuthorID  string                      `json:"author_id" gorm:"not null"`
+	Title     string                      `json:"title" gorm:"not null"`
+	TagIDs    datatypes.JSONSlice[string] `json:"tag_ids" gorm:"type:jsonb"`
+	CreatedAt time.Time                   `json:"created_at"`
+	UpdatedAt time.Time                   `json:"updated_at"`
+}
@@ -0,0 +1,13 @@
+package guilds
+
+import "time"
+
+// GuildBan keeps a user out of a Guild until it expires or is lifted.
+type GuildBan struct {
+	GuildID     string     `json:"guild_id" gorm:"primaryKey"`
+	UserID      string     `json:"user_id" gorm:"primaryKey;index"`
+	Reason      string     `json:"reason" gorm:"type:text"`
+	ModeratorID string     `json:"moderator_id" gorm:"not null"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" gorm:"index"` // nil = permanent
+	CreatedAt   time.Time  `json:"created_at"`
+}
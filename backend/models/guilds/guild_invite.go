@@ -0,0 +1,23 @@
+package guilds
+
+import "time"
+
+// GuildInvite is a shareable code that lets users join a Guild on their own.
+type GuildInvite struct {
+	Code      string     `json:"code" gorm:"primaryKey;type:text"`
+	GuildID   string     `json:"guild_id" gorm:"not null;index"`
+	CreatorID string     `json:"creator_id" gorm:"not null;index"`
+	MaxUses   int        `json:"max_uses"` // 0 = unlimited
+	Uses      int        `json:"uses" gorm:"not null;default:0"`
+	Temporary bool       `json:"temporary"`            // members leave again when they disconnect
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil = never
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Usable reports whether the invite can still be accepted at the given time.
+func (i *GuildInvite) Usable(now time.Time) bool {
+	if i.ExpiresAt != nil && !i.ExpiresAt.After(now) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
@@ -1,17 +1,28 @@
 package guilds
 
-import (
-	"time"
-
-	"gorm.io/datatypes"
-)
+import "time"
 
 // GuildMember links a User to a Guild with one or more Roles.
 type GuildMember struct {
 	GuildID string `json:"guild_id" gorm:"primaryKey;index"`
 	UserID  string `json:"user_id" gorm:"primaryKey;index"`
-	// You can allow multiple roles per member; this could instead be a join table.
-	RoleIDs   datatypes.JSON `json:"role_ids" gorm:"type:jsonb"`
-	JoinedAt  time.Time      `json:"joined_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
+	// RoleIDs is loaded from guild_member_roles by the repository.
+	RoleIDs   []string  `json:"role_ids" gorm:"-"`
+	JoinedAt  time.Time `json:"joined_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Temporary members joined through a temporary invite and are removed
+	// when their presence connection closes.
+	Temporary bool `json:"temporary" gorm:"not null;default:false"`
+
+	// TimeoutUntil makes the member read-only until the given time.
+	TimeoutUntil *time.Time `json:"timeout_until,omitempty" gorm:"index"`
+
+	// HideActivity keeps the member's activity from the guild's members.
+	HideActivity bool `json:"hide_activity" gorm:"not null;default:false"`
+}
+
+// TimedOut reports whether the member is read-only at the given time.
+func (m *GuildMember) TimedOut(now time.Time) bool {
+	return m.TimeoutUntil != nil && m.TimeoutUntil.After(now)
 }
@@ -0,0 +1,12 @@
+package guilds
+
+import "time"
+
+// GuildMemberRole assigns one Role to one GuildMember. Rows are removed
+// with either side; the role must belong to the member's guild.
+type GuildMemberRole struct {
+	GuildID   string    `json:"guild_id" gorm:"primaryKey"`
+	UserID    string    `json:"user_id" gorm:"primaryKey"`
+	RoleID    string    `json:"role_id" gorm:"primaryKey;type:uuid;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -2,6 +2,10 @@ package guilds
 
 import "time"
 
+// EveryoneRoleName names the implicit role every member holds. Its ID
+// equals the guild ID and it sits at position 0.
+const EveryoneRoleName = "@everyone"
+
 // GuildRole defines a named set of permissions within a Guild.
 type GuildRole struct {
 	ID      string `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
@@ -14,4 +18,15 @@ type GuildRole struct {
 	Position    int       `json:"position"` // order in role list
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Managed roles belong to a bot: they are granted when the bot is
+	// authorized into the guild and removed with it. Nobody else can hold
+	// them, and they can't be renamed or deleted by hand.
+	Managed bool    `json:"managed" gorm:"not null;default:false"`
+	BotID   *string `json:"bot_id,omitempty" gorm:"index"`
+}
+
+// IsEveryone reports whether this is the guild's implicit @everyone role.
+func (r *GuildRole) IsEveryone() bool {
+	return r.ID == r.GuildID
 }
@@ -0,0 +1,63 @@
+package guilds
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// TemplateEveryone is the role key that refers to a guild's @everyone role
+// inside a TemplateStructure.
+const TemplateEveryone = "@everyone"
+
+// TemplateStructure is the reusable skeleton of a guild: roles, categories,
+// channels and role overwrites. Roles are referenced by Key so the structure
+// carries no database IDs.
+type TemplateStructure struct {
+	EveryonePermissions uint64             `json:"everyone_permissions"`
+	Roles               []TemplateRole     `json:"roles"`
+	Categories          []TemplateCategory `json:"categories"`
+	Channels            []TemplateChannel  `json:"channels"` // top-level, outside any category
+}
+
+type TemplateRole struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Permissions uint64 `json:"permissions"`
+	Color       int    `json:"color"`
+	Hoist       bool   `json:"hoist"`
+	Position    int    `json:"position"`
+}
+
+type TemplateCategory struct {
+	Name       string              `json:"name"`
+	Position   int                 `json:"position"`
+	Overwrites []TemplateOverwrite `json:"overwrites,omitempty"`
+	Channels   []TemplateChannel   `json:"channels"`
+}
+
+type TemplateChannel struct {
+	Name       string              `json:"name"`
+	Type       string              `json:"type"`
+	Position   int                 `json:"position"`
+	Overwrites []TemplateOverwrite `json:"overwrites,omitempty"`
+}
+
+// TemplateOverwrite applies allow/deny bits for a role key.
+type TemplateOverwrite struct {
+	Role  string `json:"role"`
+	Allow int64  `json:"allow"`
+	Deny  int64  `json:"deny"`
+}
+
+// GuildTemplate is a structure exported from an existing guild and stored
+// so new guilds can be created from it.
+type GuildTemplate struct {
+	ID            string         `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	Name          string         `json:"name" gorm:"not null"`
+	Description   string         `json:"description"`
+	SourceGuildID string         `json:"source_guild_id" gorm:"index"`
+	CreatorID     string         `json:"creator_id" gorm:"not null;index"`
+	Structure     datatypes.JSON `json:"structure" gorm:"type:jsonb;not null"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package guilds
+
+// Permission bits stored in GuildRole.Permissions and in the Allow/Deny
+// fields of PermissionOverwrite.
+const (
+	PermAdministrator uint64 = 1 << iota
+	PermViewChannel
+	PermSendMessages
+	PermManageMessages
+	PermManageChannels
+	PermManageRoles
+	PermManageGuild
+	PermCreateInvite
+	PermKickMembers
+	PermBanMembers
+	PermModerateMembers
+	PermViewAuditLog
+	PermAddReactions
+	PermMentionEveryone
+	PermManageWebhooks
+	PermConnect
+	PermSpeak
+	PermMuteMembers
+	PermDeafenMembers
+	PermUseApplicationCommands
+
+	permEnd // keep last
+)
+
+// PermAllDefined has every bit defined above set.
+const PermAllDefined = permEnd - 1
+
+// PermAll grants every permission; owners and administrators resolve to it.
+const PermAll uint64 = 1<<64 - 1
+
+// PermDefaultEveryone is the baseline granted by a new guild's @everyone role.
+const PermDefaultEveryone = PermViewChannel | PermSendMessages | PermAddReactions |
+	PermCreateInvite | PermConnect | PermSpeak | PermUseApplicationCommands
@@ -0,0 +1,23 @@
+package guilds
+
+import "time"
+
+const (
+	MaxWebhookName   = 80
+	MaxWebhookAvatar = 2048
+)
+
+// Webhook lets an outside service post into a channel without a user
+// session. Only a hash of its token is stored; the token itself is shown
+// once, when it is created or rotated.
+type Webhook struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	GuildID   string    `json:"guild_id" gorm:"not null;index"`
+	ChannelID string    `json:"channel_id" gorm:"not null;index"`
+	Name      string    `json:"name" gorm:"not null"`
+	Avatar    string    `json:"avatar"` // public URL
+	TokenHash string    `json:"-" gorm:"not null"`
+	CreatorID string    `json:"creator_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// InteractionType tells a bot what kind of interaction it received.
+type InteractionType string
+
+const InteractionCommand InteractionType = "command"
+
+// InteractionStatus tracks whether the bot has answered an interaction.
+type InteractionStatus string
+
+const (
+	InteractionPending   InteractionStatus = "pending"
+	InteractionDeferred  InteractionStatus = "deferred"
+	InteractionResponded InteractionStatus = "responded"
+)
+
+// InteractionResponseType is how a bot answers an interaction: with a
+// message right away, or by deferring and following up later.
+type InteractionResponseType string
+
+const (
+	ResponseMessage  InteractionResponseType = "message"
+	ResponseDeferred InteractionResponseType = "deferred"
+)
+
+// InteractionOption is one validated option value of an invocation.
+// Values are strings, except integer options which carry an int64.
+type InteractionOption struct {
+	Name  string            `json:"name"`
+	Type  CommandOptionType `json:"type"`
+	Value interface{}       `json:"value"`
+}
+
+// Interaction is one invocation of a command, sent to its bot. It only
+// lives in Redis, for as long as the bot may follow up on it. Token lets
+// the bot answer without a session.
+type Interaction struct {
+	ID          string              `json:"id"`
+	Type        InteractionType     `json:"type"`
+	Token       string              `json:"token"`
+	BotID       string              `json:"bot_id"`
+	CommandID   string              `json:"command_id"`
+	CommandName string              `json:"command_name"`
+	GuildID     string              `json:"guild_id"`
+	ChannelID   string              `json:"channel_id"`
+	UserID      string              `json:"user_id"`
+	Options     []InteractionOption `json:"options"`
+	Status      InteractionStatus   `json:"status"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
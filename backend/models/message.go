@@ -1,6 +1,8 @@
 package models
 
 import (
+	"regexp"
+	"strings"
 	"time"
 
 	"gorm.io/datatypes"
@@ -15,4 +17,27 @@ type Message struct {
 	Reactions   datatypes.JSON `json:"reactions,omitempty" gorm:"type:jsonb"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
+
+	CrosspostedFrom *string `json:"crossposted_from,omitempty" gorm:"index"` // source announcement message
+	WebhookID       *string `json:"webhook_id,omitempty" gorm:"index"`       // set when a webhook posted it; AuthorID is then the webhook ID
+
+	InteractionID *string `json:"interaction_id,omitempty" gorm:"index"` // set on a bot's answer to a command
+
+	ReplyToID *string `json:"reply_to_id,omitempty" gorm:"index"` // the message this one answers, in the same channel
+}
+
+// mentionPattern matches a user mention, written <@user-id> in content.
+var mentionPattern = regexp.MustCompile(`<@([0-9a-fA-F-]{36})>`)
+
+// Mentions returns the IDs of the users the message mentions, once each.
+func (m *Message) Mentions() []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(m.Content, -1) {
+		if id := strings.ToLower(match[1]); !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
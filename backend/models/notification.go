@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// MaxNotificationExcerpt caps the message text copied into a notification.
+const MaxNotificationExcerpt = 140
+
+// NotificationType says what a notification is about.
+type NotificationType string
+
+const (
+	NotificationFriendRequest  NotificationType = "friend_request"
+	NotificationFriendAccepted NotificationType = "friend_accepted"
+	NotificationMention        NotificationType = "mention"
+	NotificationReply          NotificationType = "reply"
+	NotificationMessage        NotificationType = "message" // channels set to all messages
+	NotificationGuildInvite    NotificationType = "guild_invite"
+	NotificationModeration     NotificationType = "moderation"
+)
+
+// Notification is an entry in a user's inbox. ActorID is who caused it;
+// the other references are set when they apply, and Data carries what
+// the type needs to render (an invite code, a moderation action…).
+type Notification struct {
+	ID        string           `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	UserID    string           `json:"user_id" gorm:"type:uuid;not null;index:idx_notification_inbox,priority:1"`
+	Type      NotificationType `json:"type" gorm:"type:text;not null"`
+	ActorID   string           `json:"actor_id,omitempty"`
+	GuildID   *string          `json:"guild_id,omitempty" gorm:"index"`
+	ChannelID *string          `json:"channel_id,omitempty"`
+	MessageID *string          `json:"message_id,omitempty"`
+	Data      datatypes.JSON   `json:"data,omitempty" gorm:"type:jsonb"`
+	ReadAt    *time.Time       `json:"read_at,omitempty"`
+	CreatedAt time.Time        `json:"created_at" gorm:"index:idx_notification_inbox,priority:2,sort:desc"`
+}
+
+// NotificationLevel is which messages of a guild or channel notify a user.
+type NotificationLevel string
+
+const (
+	NotifyInherit  NotificationLevel = ""         // channels: follow the guild
+	NotifyAll      NotificationLevel = "all"      // every message
+	NotifyMentions NotificationLevel = "mentions" // mentions and replies only
+	NotifyNothing  NotificationLevel = "nothing"
+)
+
+// DefaultNotificationLevel applies to guilds a user never configured.
+const DefaultNotificationLevel = NotifyMentions
+
+// NotificationSetting is a user's notification preference for a guild
+// (ScopeID = GuildID) or for one of its channels. Until MutedUntil, the
+// scope notifies nothing at all.
+type NotificationSetting struct {
+	UserID     string            `json:"user_id" gorm:"type:uuid;primaryKey"`
+	ScopeID    string            `json:"scope_id" gorm:"type:uuid;primaryKey"`
+	GuildID    string            `json:"guild_id" gorm:"type:uuid;not null;index"`
+	Level      NotificationLevel `json:"level" gorm:"type:text;not null;default:''"`
+	MutedUntil *time.Time        `json:"muted_until,omitempty"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// Muted reports whether the setting silences its scope at t.
+func (s *NotificationSetting) Muted(t time.Time) bool {
+	return s.MutedUntil != nil && s.MutedUntil.After(t)
+}
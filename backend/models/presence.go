@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+const (
+	MaxCustomStatusText  = 128
+	MaxCustomStatusEmoji = 64
+	MaxCustomStatusTTL   = 30 * 24 * time.Hour
+
+	MaxActivityName    = 128
+	MaxActivityDetails = 128
+	MaxActivityURL     = 512
+)
+
+// ActivityType says what kind of thing a user is doing.
+type ActivityType string
+
+const (
+	ActivityPlaying   ActivityType = "playing"
+	ActivityListening ActivityType = "listening"
+	ActivityWatching  ActivityType = "watching"
+	ActivityWorking   ActivityType = "working" // e.g. on a repository
+)
+
+// Activity is what a user is currently doing, shown with their presence.
+// It lasts until cleared or until the user goes offline.
+type Activity struct {
+	Type      ActivityType `json:"type"`
+	Name      string       `json:"name"`
+	Details   string       `json:"details,omitempty"`
+	StartedAt *time.Time   `json:"started_at,omitempty"`
+	URL       string       `json:"url,omitempty"`
+}
+
+// CustomStatus is the short text a user shows next to their status. It
+// clears itself at ExpiresAt when set.
+type CustomStatus struct {
+	Text      string     `json:"text,omitempty"`
+	Emoji     string     `json:"emoji,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Presence is what others see of a user's connection state.
+type Presence struct {
+	UserID       string        `json:"user_id"`
+	Status       Status        `json:"status"`
+	CustomStatus *CustomStatus `json:"custom_status,omitempty"`
+	Activity     *Activity     `json:"activity,omitempty"`
+}
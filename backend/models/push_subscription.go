@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+const (
+	MaxPushEndpoint   = 2048
+	MaxPushDeviceName = 64
+	// MaxPushSubscriptions caps the devices one user can register.
+	MaxPushSubscriptions = 20
+)
+
+// PushSubscription is a browser or device a user registered for Web Push
+// (RFC 8030). P256dh and Auth are the keys the browser handed out with
+// the subscription; pushes to Endpoint are encrypted to them (RFC 8291).
+type PushSubscription struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	UserID     string     `json:"user_id" gorm:"type:uuid;not null;index"`
+	Endpoint   string     `json:"endpoint" gorm:"type:text;not null;uniqueIndex"`
+	P256dh     string     `json:"-" gorm:"not null"` // base64url, uncompressed P-256 point
+	Auth       string     `json:"-" gorm:"not null"` // base64url, 16 bytes
+	DeviceName string     `json:"device_name,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastPushAt *time.Time `json:"last_push_at,omitempty"`
+}
+
+// VAPIDKey is the P-256 key pair the server signs pushes with (RFC 8292).
+// There is a single row, so every replica signs with the same key the
+// browsers subscribed with.
+type VAPIDKey struct {
+	ID         int    `gorm:"primaryKey"`
+	PublicKey  string `gorm:"not null"` // base64url, uncompressed point
+	PrivateKey string `gorm:"not null"` // base64url, 32-byte scalar
+	CreatedAt  time.Time
+}
@@ -4,6 +4,36 @@ package models
 import (
 	models "launay-dot-one/models/resume"
 	"time"
+
+	"gorm.io/datatypes"
+)
+
+// ResumeVisibility says who can read a résumé besides its owner.
+type ResumeVisibility string
+
+const (
+	ResumePublic   ResumeVisibility = "public"   // anyone
+	ResumeUnlisted ResumeVisibility = "unlisted" // anyone with the secret share link
+	ResumeFriends  ResumeVisibility = "friends"
+	ResumePrivate  ResumeVisibility = "private"
+)
+
+// ResumeSection names a section of a résumé its owner can hide.
+type ResumeSection string
+
+const (
+	SectionEducation      ResumeSection = "education"
+	SectionExperience     ResumeSection = "experience"
+	SectionProjects       ResumeSection = "projects"
+	SectionCertifications ResumeSection = "certifications"
+	SectionSkills         ResumeSection = "skills"
+	SectionInterests      ResumeSection = "interests"
+)
+
+// Vanity slugs, as in /r/jane-doe, are lowercase words joined by dashes.
+const (
+	MinResumeSlug = 3
+	MaxResumeSlug = 40
 )
 
 type Resume struct {
@@ -13,6 +43,13 @@ type Resume struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
+	// Sharing, changed only through the sharing settings. ShareSlug is the
+	// secret in the unlisted link; Slug the optional vanity one.
+	Visibility     ResumeVisibility                   `json:"visibility" gorm:"type:text;not null;default:'private'"`
+	Slug           *string                            `json:"slug,omitempty" gorm:"uniqueIndex"`
+	ShareSlug      *string                            `json:"share_slug,omitempty" gorm:"uniqueIndex"`
+	HiddenSections datatypes.JSONSlice[ResumeSection] `json:"hidden_sections" gorm:"type:jsonb"`
+
 	Educations     []models.Education     `gorm:"foreignKey:ResumeID"`
 	Experiences    []models.Experience    `gorm:"foreignKey:ResumeID"`
 	Projects       []models.Project       `gorm:"foreignKey:ResumeID"`
@@ -20,3 +57,63 @@ type Resume struct {
 	Skills         []models.Skill         `gorm:"foreignKey:ResumeID"`
 	Interests      []models.Interest      `gorm:"foreignKey:ResumeID"`
 }
+
+// Hides reports whether the owner hid a section from viewers.
+func (r *Resume) Hides(section ResumeSection) bool {
+	for _, s := range r.HiddenSections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// PublicResume is what viewers see of a résumé: its content without
+// internal IDs or timestamps, less the sections its owner hid.
+type PublicResume struct {
+	Username       string                       `json:"username"`
+	Title          string                       `json:"title"`
+	Educations     []models.PublicEducation     `json:"educations,omitempty"`
+	Experiences    []models.PublicExperience    `json:"experiences,omitempty"`
+	Projects       []models.PublicProject       `json:"projects,omitempty"`
+	Certifications []models.PublicCertification `json:"certifications,omitempty"`
+	Skills         []models.PublicSkill         `json:"skills,omitempty"`
+	Interests      []models.PublicInterest      `json:"interests,omitempty"`
+}
+
+// ToPublic converts the résumé into what viewers see; username is its
+// owner's.
+func (r *Resume) ToPublic(username string) PublicResume {
+	out := PublicResume{Username: username, Title: r.Title}
+	if !r.Hides(SectionEducation) {
+		for _, e := range r.Educations {
+			out.Educations = append(out.Educations, e.Public())
+		}
+	}
+	if !r.Hides(SectionExperience) {
+		for _, e := range r.Experiences {
+			out.Experiences = append(out.Experiences, e.Public())
+		}
+	}
+	if !r.Hides(SectionProjects) {
+		for _, p := range r.Projects {
+			out.Projects = append(out.Projects, p.Public())
+		}
+	}
+	if !r.Hides(SectionCertifications) {
+		for _, c := range r.Certifications {
+			out.Certifications = append(out.Certifications, c.Public())
+		}
+	}
+	if !r.Hides(SectionSkills) {
+		for _, s := range r.Skills {
+			out.Skills = append(out.Skills, s.Public())
+		}
+	}
+	if !r.Hides(SectionInterests) {
+		for _, i := range r.Interests {
+			out.Interests = append(out.Interests, i.Public())
+		}
+	}
+	return out
+}
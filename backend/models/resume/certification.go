@@ -11,3 +11,14 @@ type Certification struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// PublicCertification is a Certification as résumé viewers see it.
+type PublicCertification struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+func (c Certification) Public() PublicCertification {
+	return PublicCertification{Name: c.Name, Provider: c.Provider, URL: c.URL}
+}
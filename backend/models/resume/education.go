@@ -12,3 +12,15 @@ type Education struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 }
+
+// PublicEducation is an Education as résumé viewers see it.
+type PublicEducation struct {
+	Institution string     `json:"institution"`
+	Degree      string     `json:"degree,omitempty"`
+	StartDate   *time.Time `json:"start_date,omitempty"`
+	EndDate     *time.Time `json:"end_date,omitempty"`
+}
+
+func (e Education) Public() PublicEducation {
+	return PublicEducation{Institution: e.Institution, Degree: e.Degree, StartDate: e.StartDate, EndDate: e.EndDate}
+}
@@ -14,3 +14,24 @@ type Experience struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 }
+
+// PublicExperience is an Experience as résumé viewers see it.
+type PublicExperience struct {
+	Company     string     `json:"company"`
+	JobTitle    string     `json:"job_title"`
+	Location    string     `json:"location,omitempty"`
+	Description string     `json:"description,omitempty"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date,omitempty"`
+}
+
+func (e Experience) Public() PublicExperience {
+	return PublicExperience{
+		Company:     e.Company,
+		JobTitle:    e.JobTitle,
+		Location:    e.Location,
+		Description: e.Description,
+		StartDate:   e.StartDate,
+		EndDate:     e.EndDate,
+	}
+}
@@ -9,3 +9,12 @@ type Interest struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// PublicInterest is an Interest as résumé viewers see it.
+type PublicInterest struct {
+	Name string `json:"name"`
+}
+
+func (i Interest) Public() PublicInterest {
+	return PublicInterest{Name: i.Name}
+}
@@ -12,3 +12,15 @@ type Project struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// PublicProject is a Project as résumé viewers see it.
+type PublicProject struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+	RepoURL     string `json:"repo_url,omitempty"`
+}
+
+func (p Project) Public() PublicProject {
+	return PublicProject{Name: p.Name, Description: p.Description, URL: p.URL, RepoURL: p.RepoURL}
+}
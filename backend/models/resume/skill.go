@@ -10,3 +10,13 @@ type Skill struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// PublicSkill is a Skill as résumé viewers see it.
+type PublicSkill struct {
+	Name     string `json:"name"`
+	Category string `json:"category,omitempty"`
+}
+
+func (s Skill) Public() PublicSkill {
+	return PublicSkill{Name: s.Name, Category: s.Category}
+}
@@ -8,12 +8,46 @@ import (
 type Status string
 
 const (
-	StatusOnline  Status = "online"
-	StatusOffline Status = "offline"
-	StatusIdle    Status = "idle"
-	StatusDND     Status = "dnd"
+	StatusOnline    Status = "online"
+	StatusOffline   Status = "offline"
+	StatusIdle      Status = "idle"
+	StatusDND       Status = "dnd"
+	StatusInvisible Status = "invisible" // connected, but shown to others as offline
 )
 
+// Selectable reports whether a user may pick the status themselves.
+// Offline only follows from disconnecting; invisible is how to look it.
+func (s Status) Selectable() bool {
+	switch s {
+	case StatusOnline, StatusIdle, StatusDND, StatusInvisible:
+		return true
+	}
+	return false
+}
+
+// Public is the status others see.
+func (s Status) Public() Status {
+	if s == StatusInvisible {
+		return StatusOffline
+	}
+	return s
+}
+
+// Platform-wide roles stored in User.Role.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Usernames are unique handles, ignoring case.
+const (
+	MinUsername = 2
+	MaxUsername = 32
+)
+
+// GhostUserID owns the content of purged accounts ("Deleted User").
+const GhostUserID = "00000000-0000-0000-0000-000000000000"
+
 // User is your full user record.
 type User struct {
 	ID         string     `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
@@ -27,35 +61,41 @@ type User struct {
 	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
 	CreatedAt  time.Time  `json:"created_at"`
 	UpdatedAt  time.Time  `json:"updated_at"`
-	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"` // deletion requested; purged after the grace period
+
+	// PasswordResetRequired blocks login until the user picks a new password.
+	PasswordResetRequired bool `json:"password_reset_required" gorm:"not null;default:false"`
+
+	// Bot accounts have no password; they authenticate with bot tokens.
+	Bot bool `json:"bot" gorm:"not null;default:false"`
 }
 
-// PublicUser is the safe projection for API responses.
+// PublicUser is the safe projection for API responses. Status and last
+// seen are left out: they are only shown through the presence service,
+// which applies the user's visibility rules.
 type PublicUser struct {
-	ID         string     `json:"id"`
-	Username   string     `json:"username"`
-	Bio        string     `json:"bio"`
-	Email      string     `json:"email"`
-	Role       string     `json:"role"`
-	Avatar     string     `json:"avatar"`
-	Status     Status     `json:"status"`
-	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Bio       string    `json:"bio"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Avatar    string    `json:"avatar"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Bot       bool      `json:"bot"`
 }
 
 // ToPublic converts the full User into its PublicUser view.
 func (u *User) ToPublic() PublicUser {
 	return PublicUser{
-		ID:         u.ID,
-		Username:   u.Username,
-		Bio:        u.Bio,
-		Email:      u.Email,
-		Role:       u.Role,
-		Avatar:     u.Avatar,
-		Status:     u.Status,
-		LastSeenAt: u.LastSeenAt,
-		CreatedAt:  u.CreatedAt,
-		UpdatedAt:  u.UpdatedAt,
+		ID:        u.ID,
+		Username:  u.Username,
+		Bio:       u.Bio,
+		Email:     u.Email,
+		Role:      u.Role,
+		Avatar:    u.Avatar,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+		Bot:       u.Bot,
 	}
 }
@@ -0,0 +1,84 @@
+package realtime
+
+import (
+	"context"
+
+	connectionmanager "launay-dot-one/manager"
+	"launay-dot-one/repositories"
+)
+
+// Gateway event types pushed over the messaging socket.
+const (
+	EventGuildBanAdd       = "GUILD_BAN_ADD"
+	EventGuildBanRemove    = "GUILD_BAN_REMOVE"
+	EventGuildMemberRemove = "GUILD_MEMBER_REMOVE"
+	EventGuildMemberUpdate = "GUILD_MEMBER_UPDATE"
+	EventVoiceStateUpdate  = "VOICE_STATE_UPDATE"
+	EventVoiceSignal       = "VOICE_SIGNAL"
+	EventPresenceUpdate    = "PRESENCE_UPDATE"
+	EventNotification      = "NOTIFICATION_CREATE" // to the notified user
+
+	EventInteractionCreate = "INTERACTION_CREATE" // to the bot
+	EventInteractionUpdate = "INTERACTION_UPDATE" // to the invoking user
+)
+
+// Event is a server-initiated dispatch.
+type Event struct {
+	Type    string      `json:"type"`
+	GuildID string      `json:"guild_id,omitempty"`
+	Data    interface{} `json:"data"`
+}
+
+// Gateway fans events out to connected clients.
+type Gateway interface {
+	// SendToUsers delivers evt to each listed user that is connected.
+	SendToUsers(userIDs []string, evt Event)
+
+	// SendToGuild delivers evt to every connected member of the guild,
+	// plus any extra users (e.g. someone who was just removed).
+	SendToGuild(ctx context.Context, guildID string, evt Event, extra ...string) error
+
+	// Connected reports whether the user has a socket open.
+	Connected(userID string) bool
+}
+
+type gateway struct {
+	memberRepo *repositories.GuildMemberRepository
+}
+
+// NewGateway builds a Gateway on top of the global connection manager.
+func NewGateway(memberRepo *repositories.GuildMemberRepository) Gateway {
+	return &gateway{memberRepo: memberRepo}
+}
+
+func (g *gateway) SendToUsers(userIDs []string, evt Event) {
+	for _, id := range userIDs {
+		connectionmanager.ConnManager.Send(id, evt)
+	}
+}
+
+func (g *gateway) Connected(userID string) bool {
+	_, ok := connectionmanager.ConnManager.Get(userID)
+	return ok
+}
+
+func (g *gateway) SendToGuild(ctx context.Context, guildID string, evt Event, extra ...string) error {
+	members, err := g.memberRepo.ListByGuild(ctx, guildID)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(members)+len(extra))
+	seen := make(map[string]bool, cap(ids))
+	for _, m := range members {
+		ids = append(ids, m.UserID)
+		seen[m.UserID] = true
+	}
+	for _, id := range extra {
+		if !seen[id] {
+			ids = append(ids, id)
+		}
+	}
+	evt.GuildID = guildID
+	g.SendToUsers(ids, evt)
+	return nil
+}
@@ -2,28 +2,638 @@ package realtime
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"launay-dot-one/models"
+	"launay-dot-one/repositories"
+
 	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const (
+	// HeartbeatInterval is how often clients are asked to heartbeat.
+	HeartbeatInterval = 30 * time.Second
+	// SessionTimeout drops a session that missed this long of heartbeats.
+	SessionTimeout = 3 * HeartbeatInterval
+)
+
+var (
+	ErrInvalidStatus       = errors.New("status must be one of online, idle, dnd or invisible")
+	ErrPresenceHidden      = errors.New("only friends and guild co-members can see this user's presence")
+	ErrInvalidCustomStatus = fmt.Errorf(
+		"custom status takes at most %d characters of text and %d of emoji, and must expire within %s",
+		models.MaxCustomStatusText, models.MaxCustomStatusEmoji, models.MaxCustomStatusTTL,
+	)
+	ErrInvalidActivity = fmt.Errorf(
+		"activity needs a type (playing, listening, watching or working) and a name of at most %d characters; "+
+			"details take at most %d and the URL, http(s), at most %d",
+		models.MaxActivityName, models.MaxActivityDetails, models.MaxActivityURL,
+	)
 )
 
-// PresenceService defines methods for updating user presence.
+// PresenceService tracks a user's presence across all their sessions.
+// The user picks a status (online by default); while it is online, the
+// user shows as idle once none of their sessions reported activity for
+// the idle threshold, and online again as soon as one does. Changes are
+// broadcast to the user's friends and guild co-members, who see an
+// invisible user as offline.
 type PresenceService interface {
-	SetStatus(ctx context.Context, userID string, status string) error
-	// Optionally: GetStatus(ctx context.Context, userID string) (string, error)
+	// Connect opens a session and returns its ID. A new session counts as
+	// activity.
+	Connect(ctx context.Context, userID string) (string, error)
+
+	// Heartbeat keeps a session alive without counting as activity.
+	Heartbeat(ctx context.Context, userID, sessionID string) error
+
+	// Activity keeps a session alive and marks it active.
+	Activity(ctx context.Context, userID, sessionID string) error
+
+	// SetStatus sets the status the user picked, for all their sessions.
+	SetStatus(ctx context.Context, userID string, status models.Status) error
+
+	// Disconnect closes a session and reports whether the user went
+	// offline with it.
+	Disconnect(ctx context.Context, userID, sessionID string) (bool, error)
+
+	// SetCustomStatus sets the user's custom status; nil clears it.
+	SetCustomStatus(ctx context.Context, userID string, cs *models.CustomStatus) error
+
+	// SetActivity sets what the user is doing; nil clears it. Members of
+	// guilds where the user hides their activity don't see it, unless they
+	// are friends or share another guild where it isn't hidden.
+	SetActivity(ctx context.Context, userID string, a *models.Activity) error
+
+	// Get returns a user's own presence, invisible included.
+	Get(ctx context.Context, userID string) (*models.Presence, error)
+
+	// DoNotDisturb reports whether the user picked do not disturb. The
+	// choice outlives their sessions, so it holds while they're offline.
+	DoNotDisturb(ctx context.Context, userID string) (bool, error)
+
+	// GetFor returns the presence viewerID sees of userID. Only the user,
+	// their friends and guild co-members may look.
+	GetFor(ctx context.Context, viewerID, userID string) (*models.Presence, error)
+
+	// Visible returns the presences of the user's friends and guild
+	// co-members that don't appear offline.
+	Visible(ctx context.Context, userID string) ([]models.Presence, error)
+
+	// SweepExpired drops sessions that stopped heartbeating, idles users
+	// who stopped being active and returns the users who went offline.
+	SweepExpired(ctx context.Context) ([]string, error)
 }
 
 type presenceService struct {
 	redisClient *redis.Client
+	userRepo    *repositories.UserRepository
+	friendRepo  *repositories.FriendRequestRepository
+	memberRepo  *repositories.GuildMemberRepository
+	gateway     Gateway
+	idleAfter   time.Duration
+}
+
+// NewPresenceService creates a new PresenceService. Users show as idle
+// after idleAfter without activity.
+func NewPresenceService(
+	redisClient *redis.Client,
+	userRepo *repositories.UserRepository,
+	friendRepo *repositories.FriendRequestRepository,
+	memberRepo *repositories.GuildMemberRepository,
+	gateway Gateway,
+	idleAfter time.Duration,
+) PresenceService {
+	return &presenceService{
+		redisClient: redisClient,
+		userRepo:    userRepo,
+		friendRepo:  friendRepo,
+		memberRepo:  memberRepo,
+		gateway:     gateway,
+		idleAfter:   idleAfter,
+	}
 }
 
-// NewPresenceService creates a new PresenceService.
-func NewPresenceService(redisClient *redis.Client) PresenceService {
-	return &presenceService{redisClient: redisClient}
+// Redis layout: the effective status of a connected user, the status they
+// picked, a hash of their sessions, their custom status (expiring with
+// it), their activity (dropped when they go offline), and one sorted set
+// of connected users scored by when their status next needs recomputing,
+// which the sweeper walks.
+const presenceDueKey = "presence:due"
+
+func presenceKey(userID string) string { return "presence:" + userID }
+func chosenKey(userID string) string   { return "presence:chosen:" + userID }
+func sessionsKey(userID string) string { return "presence:sessions:" + userID }
+func customKey(userID string) string   { return "presence:custom:" + userID }
+func activityKey(userID string) string { return "presence:activity:" + userID }
+
+type session struct {
+	ActiveAt  int64 `json:"active_at"`  // unix seconds of the last activity
+	ExpiresAt int64 `json:"expires_at"` // unix seconds
+}
+
+// parseStatus reads a stored status; anything unknown (including values
+// from older releases) is offline.
+func parseStatus(s string) models.Status {
+	if models.Status(s).Selectable() {
+		return models.Status(s)
+	}
+	return models.StatusOffline
+}
+
+// touch refreshes a session's expiry and, when active is set, its
+// activity time.
+func (ps *presenceService) touch(ctx context.Context, userID, sessionID string, active bool) error {
+	now := time.Now()
+	s := session{ExpiresAt: now.Add(SessionTimeout).Unix()}
+	if active {
+		s.ActiveAt = now.Unix()
+	} else {
+		raw, err := ps.redisClient.HGet(ctx, sessionsKey(userID), sessionID).Bytes()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		var old session
+		if err == nil && json.Unmarshal(raw, &old) == nil {
+			s.ActiveAt = old.ActiveAt
+		}
+	}
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := ps.redisClient.HSet(ctx, sessionsKey(userID), sessionID, raw).Err(); err != nil {
+		return err
+	}
+	_, err = ps.recompute(ctx, userID)
+	return err
+}
+
+func (ps *presenceService) Connect(ctx context.Context, userID string) (string, error) {
+	id := uuid.NewString()
+	return id, ps.touch(ctx, userID, id, true)
+}
+
+func (ps *presenceService) Heartbeat(ctx context.Context, userID, sessionID string) error {
+	return ps.touch(ctx, userID, sessionID, false)
+}
+
+func (ps *presenceService) Activity(ctx context.Context, userID, sessionID string) error {
+	return ps.touch(ctx, userID, sessionID, true)
+}
+
+func (ps *presenceService) SetStatus(ctx context.Context, userID string, status models.Status) error {
+	if !status.Selectable() {
+		return ErrInvalidStatus
+	}
+	if err := ps.redisClient.Set(ctx, chosenKey(userID), string(status), 0).Err(); err != nil {
+		return err
+	}
+	_, err := ps.recompute(ctx, userID)
+	return err
 }
 
-// SetStatus stores the user's presence in Redis with a TTL.
-func (ps *presenceService) SetStatus(ctx context.Context, userID string, status string) error {
-	// You can use a TTL to auto-expire stale status entries.
-	return ps.redisClient.Set(ctx, "presence:"+userID, status, 5*time.Minute).Err()
-}
\ No newline at end of file
+func (ps *presenceService) Disconnect(ctx context.Context, userID, sessionID string) (bool, error) {
+	if err := ps.redisClient.HDel(ctx, sessionsKey(userID), sessionID).Err(); err != nil {
+		return false, err
+	}
+	status, err := ps.recompute(ctx, userID)
+	return status == models.StatusOffline, err
+}
+
+// recompute drops the user's expired sessions, stores the status that
+// follows from the ones left and broadcasts it when it changed.
+func (ps *presenceService) recompute(ctx context.Context, userID string) (models.Status, error) {
+	vals, err := ps.redisClient.MGet(ctx, presenceKey(userID), chosenKey(userID)).Result()
+	if err != nil {
+		return "", err
+	}
+	prev, chosen := models.StatusOffline, models.StatusOnline
+	if s, ok := vals[0].(string); ok {
+		prev = parseStatus(s)
+	}
+	if s, ok := vals[1].(string); ok && parseStatus(s) != models.StatusOffline {
+		chosen = parseStatus(s)
+	}
+	all, err := ps.redisClient.HGetAll(ctx, sessionsKey(userID)).Result()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().Unix()
+	idleAfter := int64(ps.idleAfter / time.Second)
+	var (
+		live, active bool
+		due          int64 // when a session expires or goes idle next
+		expired      []string
+	)
+	soonest := func(t int64) {
+		if due == 0 || t < due {
+			due = t
+		}
+	}
+	for id, raw := range all {
+		var s session
+		if json.Unmarshal([]byte(raw), &s) != nil || s.ExpiresAt < now {
+			expired = append(expired, id)
+			continue
+		}
+		live = true
+		soonest(s.ExpiresAt)
+		if s.ActiveAt+idleAfter > now {
+			active = true
+			soonest(s.ActiveAt + idleAfter)
+		}
+	}
+	next := models.StatusOffline
+	if live {
+		next = chosen
+		if chosen == models.StatusOnline && !active {
+			next = models.StatusIdle
+		}
+	}
+
+	pipe := ps.redisClient.TxPipeline()
+	if len(expired) > 0 {
+		pipe.HDel(ctx, sessionsKey(userID), expired...)
+	}
+	if next == models.StatusOffline {
+		pipe.Del(ctx, presenceKey(userID), activityKey(userID))
+		pipe.ZRem(ctx, presenceDueKey, userID)
+	} else {
+		pipe.Set(ctx, presenceKey(userID), string(next), 0)
+		pipe.ZAdd(ctx, presenceDueKey, &redis.Z{Score: float64(due), Member: userID})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	if next != prev {
+		if err := ps.changed(ctx, userID, prev, next); err != nil {
+			return "", err
+		}
+	}
+	return next, nil
+}
+
+// changed records a new status on the user row and broadcasts it. Others
+// only hear about it when the status they see changed.
+func (ps *presenceService) changed(ctx context.Context, userID string, prev, next models.Status) error {
+	public := prev.Public() != next.Public()
+	if public {
+		updates := map[string]interface{}{"status": next.Public()}
+		if next.Public() == models.StatusOffline {
+			updates["last_seen_at"] = time.Now()
+		}
+		if err := ps.userRepo.UpdateFields(ctx, userID, updates); err != nil {
+			return err
+		}
+	}
+	return ps.broadcast(ctx, userID, public)
+}
+
+// broadcast sends the user's presence to their own sockets and, when
+// others is set, what others see of it to everyone who may see it.
+func (ps *presenceService) broadcast(ctx context.Context, userID string, others bool) error {
+	own, err := ps.many(ctx, []string{userID}, true, false)
+	if err != nil {
+		return err
+	}
+	ps.gateway.SendToUsers([]string{userID}, Event{Type: EventPresenceUpdate, Data: own[0]})
+	if !others {
+		return nil
+	}
+	all, withActivity, err := ps.audience(ctx, userID)
+	if err != nil {
+		return err
+	}
+	seen, err := ps.many(ctx, []string{userID}, true, true)
+	if err != nil {
+		return err
+	}
+	p := seen[0]
+	var rest []string
+	if p.Activity != nil {
+		rest = make([]string, 0, len(all))
+		for _, id := range all {
+			if !withActivity[id] {
+				rest = append(rest, id)
+			}
+		}
+		ids := make([]string, 0, len(withActivity))
+		for id := range withActivity {
+			ids = append(ids, id)
+		}
+		ps.gateway.SendToUsers(ids, Event{Type: EventPresenceUpdate, Data: p})
+		p.Activity = nil
+	} else {
+		rest = all
+	}
+	ps.gateway.SendToUsers(rest, Event{Type: EventPresenceUpdate, Data: p})
+	return nil
+}
+
+// audience lists who may see the user's presence: their friends and guild
+// co-members. withActivity marks those who may see their activity too.
+func (ps *presenceService) audience(ctx context.Context, userID string) ([]string, map[string]bool, error) {
+	friends, err := ps.friendRepo.ListFriendIDs(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	co, err := ps.memberRepo.ListCoMemberIDs(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	shown, err := ps.memberRepo.ListActivityAudience(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return union(friends, co), idSet(friends, shown), nil
+}
+
+// viewable is audience seen from the viewer's side: whose presence the
+// viewer may see, and whose activity.
+func (ps *presenceService) viewable(ctx context.Context, viewerID string) ([]string, map[string]bool, error) {
+	friends, err := ps.friendRepo.ListFriendIDs(ctx, viewerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	co, err := ps.memberRepo.ListCoMemberIDs(ctx, viewerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	shown, err := ps.memberRepo.ListCoMembersShowingActivity(ctx, viewerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return union(friends, co), idSet(friends, shown), nil
+}
+
+// union returns the IDs of both lists, without duplicates.
+func union(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	out := make([]string, 0, len(a)+len(b))
+	for _, l := range [][]string{a, b} {
+		for _, id := range l {
+			if !seen[id] {
+				seen[id] = true
+				out = append(out, id)
+			}
+		}
+	}
+	return out
+}
+
+func idSet(lists ...[]string) map[string]bool {
+	out := make(map[string]bool)
+	for _, l := range lists {
+		for _, id := range l {
+			out[id] = true
+		}
+	}
+	return out
+}
+
+func validCustomStatus(cs *models.CustomStatus) error {
+	if len([]rune(cs.Text)) > models.MaxCustomStatusText || len([]rune(cs.Emoji)) > models.MaxCustomStatusEmoji {
+		return ErrInvalidCustomStatus
+	}
+	if cs.ExpiresAt != nil {
+		if ttl := time.Until(*cs.ExpiresAt); ttl <= 0 || ttl > models.MaxCustomStatusTTL {
+			return ErrInvalidCustomStatus
+		}
+	}
+	return nil
+}
+
+func (ps *presenceService) SetCustomStatus(ctx context.Context, userID string, cs *models.CustomStatus) error {
+	if cs != nil {
+		cs.Text = strings.TrimSpace(cs.Text)
+		cs.Emoji = strings.TrimSpace(cs.Emoji)
+		if cs.Text == "" && cs.Emoji == "" {
+			cs = nil
+		}
+	}
+	if cs == nil {
+		if err := ps.redisClient.Del(ctx, customKey(userID)).Err(); err != nil {
+			return err
+		}
+	} else {
+		if err := validCustomStatus(cs); err != nil {
+			return err
+		}
+		raw, err := json.Marshal(cs)
+		if err != nil {
+			return err
+		}
+		var ttl time.Duration // 0 keeps it until cleared
+		if cs.ExpiresAt != nil {
+			ttl = time.Until(*cs.ExpiresAt)
+		}
+		if err := ps.redisClient.Set(ctx, customKey(userID), raw, ttl).Err(); err != nil {
+			return err
+		}
+	}
+	p, err := ps.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return ps.broadcast(ctx, userID, p.Status.Public() != models.StatusOffline)
+}
+
+func validActivity(a *models.Activity) error {
+	switch a.Type {
+	case models.ActivityPlaying, models.ActivityListening, models.ActivityWatching, models.ActivityWorking:
+	default:
+		return ErrInvalidActivity
+	}
+	if n := len([]rune(a.Name)); n == 0 || n > models.MaxActivityName {
+		return ErrInvalidActivity
+	}
+	if len([]rune(a.Details)) > models.MaxActivityDetails || len(a.URL) > models.MaxActivityURL {
+		return ErrInvalidActivity
+	}
+	if a.URL != "" {
+		u, err := url.Parse(a.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidActivity
+		}
+	}
+	return nil
+}
+
+func (ps *presenceService) SetActivity(ctx context.Context, userID string, a *models.Activity) error {
+	if a == nil {
+		if err := ps.redisClient.Del(ctx, activityKey(userID)).Err(); err != nil {
+			return err
+		}
+	} else {
+		a.Name = strings.TrimSpace(a.Name)
+		a.Details = strings.TrimSpace(a.Details)
+		if err := validActivity(a); err != nil {
+			return err
+		}
+		now := time.Now()
+		if a.StartedAt == nil || a.StartedAt.After(now) {
+			a.StartedAt = &now
+		}
+		raw, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		if err := ps.redisClient.Set(ctx, activityKey(userID), raw, 0).Err(); err != nil {
+			return err
+		}
+	}
+	p, err := ps.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return ps.broadcast(ctx, userID, p.Status.Public() != models.StatusOffline)
+}
+
+func (ps *presenceService) Get(ctx context.Context, userID string) (*models.Presence, error) {
+	out, err := ps.many(ctx, []string{userID}, true, false)
+	if err != nil {
+		return nil, err
+	}
+	return &out[0], nil
+}
+
+func (ps *presenceService) DoNotDisturb(ctx context.Context, userID string) (bool, error) {
+	chosen, err := ps.redisClient.Get(ctx, chosenKey(userID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	return models.Status(chosen) == models.StatusDND, err
+}
+
+// canSee reports whether viewerID may see userID's presence, and their
+// activity.
+func (ps *presenceService) canSee(ctx context.Context, viewerID, userID string) (presence, activity bool, err error) {
+	friends, err := ps.friendRepo.AreFriends(ctx, viewerID, userID)
+	if err != nil || friends {
+		return friends, friends, err
+	}
+	if activity, err = ps.memberRepo.ShareGuildShowingActivity(ctx, viewerID, userID); err != nil || activity {
+		return activity, activity, err
+	}
+	presence, err = ps.memberRepo.ShareGuild(ctx, viewerID, userID)
+	return presence, false, err
+}
+
+func (ps *presenceService) GetFor(ctx context.Context, viewerID, userID string) (*models.Presence, error) {
+	if viewerID == userID {
+		return ps.Get(ctx, userID)
+	}
+	presence, activity, err := ps.canSee(ctx, viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !presence {
+		return nil, ErrPresenceHidden
+	}
+	out, err := ps.many(ctx, []string{userID}, true, true)
+	if err != nil {
+		return nil, err
+	}
+	if !activity {
+		out[0].Activity = nil
+	}
+	return &out[0], nil
+}
+
+// many loads the presence of each user, as others see it when public is
+// set. Users who appear offline get no custom status or activity, and are
+// skipped unless withOffline is set.
+func (ps *presenceService) many(
+	ctx context.Context,
+	userIDs []string,
+	withOffline, public bool,
+) ([]models.Presence, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	const perUser = 3
+	keys := make([]string, 0, perUser*len(userIDs))
+	for _, id := range userIDs {
+		keys = append(keys, presenceKey(id), customKey(id), activityKey(id))
+	}
+	vals, err := ps.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Presence, 0, len(userIDs))
+	for i, id := range userIDs {
+		v := vals[perUser*i : perUser*i+perUser]
+		p := models.Presence{UserID: id, Status: models.StatusOffline}
+		if s, ok := v[0].(string); ok {
+			p.Status = parseStatus(s)
+		}
+		if public {
+			p.Status = p.Status.Public()
+		}
+		if p.Status == models.StatusOffline {
+			if withOffline {
+				out = append(out, p)
+			}
+			continue
+		}
+		if raw, ok := v[1].(string); ok {
+			var cs models.CustomStatus
+			if json.Unmarshal([]byte(raw), &cs) == nil {
+				p.CustomStatus = &cs
+			}
+		}
+		if raw, ok := v[2].(string); ok {
+			var a models.Activity
+			if json.Unmarshal([]byte(raw), &a) == nil {
+				p.Activity = &a
+			}
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (ps *presenceService) Visible(ctx context.Context, userID string) ([]models.Presence, error) {
+	ids, withActivity, err := ps.viewable(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out, err := ps.many(ctx, ids, false, true)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		if !withActivity[out[i].UserID] {
+			out[i].Activity = nil
+		}
+	}
+	return out, nil
+}
+
+func (ps *presenceService) SweepExpired(ctx context.Context) ([]string, error) {
+	ids, err := ps.redisClient.ZRangeByScore(ctx, presenceDueKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	var offline []string
+	for _, id := range ids {
+		status, err := ps.recompute(ctx, id)
+		if err != nil {
+			return offline, err
+		}
+		if status == models.StatusOffline {
+			offline = append(offline, id)
+		}
+	}
+	return offline, nil
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"launay-dot-one/models"
+
+	"gorm.io/gorm"
+)
+
+// AccountRestrictionRepository manages suspensions and bans.
+type AccountRestrictionRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountRestrictionRepository(db *gorm.DB) *AccountRestrictionRepository {
+	return &AccountRestrictionRepository{db}
+}
+
+func (r *AccountRestrictionRepository) Create(ctx context.Context, rs *models.AccountRestriction) error {
+	return r.db.WithContext(ctx).Create(rs).Error
+}
+
+// ListByUser returns every restriction ever applied to a user, newest first.
+func (r *AccountRestrictionRepository) ListByUser(ctx context.Context, userID string) ([]models.AccountRestriction, error) {
+	var out []models.AccountRestriction
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&out).Error
+	return out, err
+}
+
+// GetActive returns the restriction currently blocking a user, if any.
+func (r *AccountRestrictionRepository) GetActive(ctx context.Context, userID string, now time.Time) (*models.AccountRestriction, error) {
+	var rs models.AccountRestriction
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Order("expires_at DESC NULLS FIRST").
+		First(&rs).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// LiftActive marks every active restriction for a user as lifted.
+func (r *AccountRestrictionRepository) LiftActive(ctx context.Context, userID string, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.AccountRestriction{}).
+		Where("user_id = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Update("lifted_at", now)
+	return res.RowsAffected, res.Error
+}
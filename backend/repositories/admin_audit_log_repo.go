@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+
+	"launay-dot-one/models"
+
+	"gorm.io/gorm"
+)
+
+// AdminAuditLogRepository appends to and reads the admin audit log.
+// It deliberately exposes no update or delete operations.
+type AdminAuditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminAuditLogRepository(db *gorm.DB) *AdminAuditLogRepository {
+	return &AdminAuditLogRepository{db}
+}
+
+func (r *AdminAuditLogRepository) Create(ctx context.Context, e *models.AdminAuditLog) error {
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// List returns entries newest first, optionally filtered by actor or target.
+func (r *AdminAuditLogRepository) List(
+	ctx context.Context,
+	actorID, targetID string,
+	offset, limit int,
+) ([]models.AdminAuditLog, int64, error) {
+	q := r.db.WithContext(ctx).Model(&models.AdminAuditLog{})
+	if actorID != "" {
+		q = q.Where("actor_id = ?", actorID)
+	}
+	if targetID != "" {
+		q = q.Where("target_id = ?", targetID)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var out []models.AdminAuditLog
+	err := q.Order("created_at DESC").Offset(offset).Limit(limit).Find(&out).Error
+	return out, total, err
+}
+
+// EnsureImmutable installs a trigger that rejects UPDATE and DELETE on the
+// audit table, so entries stay intact even for direct SQL access.
+func (r *AdminAuditLogRepository) EnsureImmutable(ctx context.Context) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Exec(`
+CREATE OR REPLACE FUNCTION admin_audit_logs_immutable() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'admin_audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;`).Error; err != nil {
+		return err
+	}
+	if err := db.Exec(`DROP TRIGGER IF EXISTS admin_audit_logs_immutable ON admin_audit_logs;`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`
+CREATE TRIGGER admin_audit_logs_immutable
+BEFORE UPDATE OR DELETE ON admin_audit_logs
+FOR EACH ROW EXECUTE FUNCTION admin_audit_logs_immutable();`).Error
+}
@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+
+	"launay-dot-one/models"
+
+	"gorm.io/gorm"
+)
+
+type ApplicationCommandRepository struct {
+	db *gorm.DB
+}
+
+func NewApplicationCommandRepository(db *gorm.DB) *ApplicationCommandRepository {
+	return &ApplicationCommandRepository{db}
+}
+
+func (r *ApplicationCommandRepository) Create(ctx context.Context, c *models.ApplicationCommand) error {
+	return r.db.WithContext(ctx).Create(c).Error
+}
+
+func (r *ApplicationCommandRepository) GetByID(ctx context.Context, id string) (*models.ApplicationCommand, error) {
+	var c models.ApplicationCommand
+	if err := r.db.WithContext(ctx).First(&c, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// commandScope narrows a query to the bot's global commands when guildID
+// is empty, or to its commands in that guild.
+func commandScope(q *gorm.DB, botID, guildID string) *gorm.DB {
+	q = q.Where("bot_id = ?", botID)
+	if guildID == "" {
+		return q.Where("guild_id IS NULL")
+	}
+	return q.Where("guild_id = ?", guildID)
+}
+
+// List returns a bot's commands in one scope, see commandScope.
+func (r *ApplicationCommandRepository) List(ctx context.Context, botID, guildID string) ([]models.ApplicationCommand, error) {
+	var out []models.ApplicationCommand
+	err := commandScope(r.db.WithContext(ctx), botID, guildID).Order("name ASC").Find(&out).Error
+	return out, err
+}
+
+func (r *ApplicationCommandRepository) Count(ctx context.Context, botID, guildID string) (int64, error) {
+	var n int64
+	err := commandScope(r.db.WithContext(ctx).Model(&models.ApplicationCommand{}), botID, guildID).Count(&n).Error
+	return n, err
+}
+
+// NameTaken reports whether another command of the bot in the same scope
+// already uses name. exceptID skips the command being renamed.
+func (r *ApplicationCommandRepository) NameTaken(ctx context.Context, botID, guildID, name, exceptID string) (bool, error) {
+	q := commandScope(r.db.WithContext(ctx).Model(&models.ApplicationCommand{}), botID, guildID).Where("name = ?", name)
+	if exceptID != "" {
+		q = q.Where("id <> ?", exceptID)
+	}
+	var n int64
+	err := q.Count(&n).Error
+	return n > 0, err
+}
+
+// ListForGuild returns every command usable in a guild: the guild's own
+// commands and the global ones, of the bots that are currently members.
+func (r *ApplicationCommandRepository) ListForGuild(ctx context.Context, guildID string) ([]models.ApplicationCommand, error) {
+	var out []models.ApplicationCommand
+	err := r.db.WithContext(ctx).
+		Where("guild_id = ? OR guild_id IS NULL", guildID).
+		Where("bot_id IN (?)", r.db.Model(&models.User{}).
+			Select("users.id").
+			Joins("JOIN guild_members gm ON gm.user_id = users.id::text").
+			Where("users.bot AND gm.guild_id = ?", guildID)).
+		Order("name ASC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *ApplicationCommandRepository) Update(ctx context.Context, c *models.ApplicationCommand) error {
+	return r.db.WithContext(ctx).Save(c).Error
+}
+
+func (r *ApplicationCommandRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&models.ApplicationCommand{}, "id = ?", id).Error
+}
@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"launay-dot-one/models"
+	"launay-dot-one/models/guilds"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BotRepository stores bot accounts, their tokens and the guilds they
+// are authorized into.
+type BotRepository struct {
+	db *gorm.DB
+}
+
+func NewBotRepository(db *gorm.DB) *BotRepository {
+	return &BotRepository{db}
+}
+
+// Create inserts the bot's user row, its bot row and its first token
+// together.
+func (r *BotRepository) Create(ctx context.Context, u *models.User, b *models.Bot, t *models.BotToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(u).Error; err != nil {
+			return err
+		}
+		b.UserID = u.ID
+		if err := tx.Create(b).Error; err != nil {
+			return err
+		}
+		t.BotID = u.ID
+		return tx.Create(t).Error
+	})
+}
+
+func (r *BotRepository) GetByID(ctx context.Context, id string) (*models.Bot, error) {
+	var b models.Bot
+	if err := r.db.WithContext(ctx).First(&b, "user_id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *BotRepository) ListByOwner(ctx context.Context, ownerID string) ([]models.Bot, error) {
+	var out []models.Bot
+	err := r.db.WithContext(ctx).
+		Where("owner_id = ?", ownerID).
+		Order("created_at ASC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *BotRepository) CountByOwner(ctx context.Context, ownerID string) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&models.Bot{}).Where("owner_id = ?", ownerID).Count(&n).Error
+	return n, err
+}
+
+// Update saves the bot row and, when username is not empty, renames its
+// user.
+func (r *BotRepository) Update(ctx context.Context, b *models.Bot, username string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(b).Error; err != nil {
+			return err
+		}
+		if username == "" {
+			return nil
+		}
+		return tx.Model(&models.User{}).Where("id = ?", b.UserID).
+			Updates(map[string]interface{}{"username": username, "updated_at": time.Now()}).Error
+	})
+}
+
+// Delete hard-deletes a bot like an account purge: its messages go to
+// the ghost user, its tokens, managed roles, commands and memberships are
+// removed.
+func (r *BotRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return purgeBot(tx, id)
+	})
+}
+
+func purgeBot(tx *gorm.DB, id string) error {
+	if err := tx.Where("bot_id = ?", id).Delete(&models.BotToken{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("bot_id = ?", id).Delete(&guilds.GuildRole{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("bot_id = ?", id).Delete(&models.ApplicationCommand{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&models.Bot{}, "user_id = ?", id).Error; err != nil {
+		return err
+	}
+	return purgeUser(tx, id)
+}
+
+func (r *BotRepository) CreateToken(ctx context.Context, t *models.BotToken) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+// ListTokens returns the bot's tokens that haven't been revoked.
+func (r *BotRepository) ListTokens(ctx context.Context, botID string) ([]models.BotToken, error) {
+	var out []models.BotToken
+	err := r.db.WithContext(ctx).
+		Where("bot_id = ? AND revoked_at IS NULL", botID).
+		Order("created_at ASC").
+		Find(&out).Error
+	return out, err
+}
+
+// GetActiveToken looks a token up by its hash, ignoring revoked ones.
+func (r *BotRepository) GetActiveToken(ctx context.Context, hash string) (*models.BotToken, error) {
+	var t models.BotToken
+	if err := r.db.WithContext(ctx).
+		First(&t, "token_hash = ? AND revoked_at IS NULL", hash).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RevokeToken marks one of the bot's tokens revoked and reports whether
+// there was such a token.
+func (r *BotRepository) RevokeToken(ctx context.Context, botID, tokenID string, now time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.BotToken{}).
+		Where("id = ? AND bot_id = ? AND revoked_at IS NULL", tokenID, botID).
+		Update("revoked_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *BotRepository) TouchToken(ctx context.Context, id string, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.BotToken{}).
+		Where("id = ?", id).
+		Update("last_used_at", now).Error
+}
+
+// AddToGuild creates the bot's managed role and its membership holding
+// that role in one transaction. Roles at or above the new role's position
+// move up one to make room, keeping positions unique.
+func (r *BotRepository) AddToGuild(ctx context.Context, role *guilds.GuildRole, m *guilds.GuildMember) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// lock the guild's roles against a concurrent reorder
+		var ids []string
+		if err := tx.Model(&guilds.GuildRole{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("guild_id = ?", role.GuildID).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&guilds.GuildRole{}).
+			Where("guild_id = ? AND position >= ?", role.GuildID, role.Position).
+			Updates(map[string]interface{}{
+				"position":   gorm.Expr("position + 1"),
+				"updated_at": role.UpdatedAt,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(role).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(m).Error; err != nil {
+			return err
+		}
+		m.RoleIDs = []string{role.ID}
+		return insertMemberRoles(tx, m.GuildID, m.UserID, m.RoleIDs)
+	})
+}
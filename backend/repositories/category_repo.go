@@ -2,10 +2,12 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"launay-dot-one/models/guilds"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CategoryRepository struct {
@@ -44,3 +46,34 @@ func (r *CategoryRepository) Update(ctx context.Context, c *guilds.Category) err
 func (r *CategoryRepository) Delete(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Delete(&guilds.Category{}, "id = ?", id).Error
 }
+
+// Reorder locks the guild's categories and passes them to plan, then saves the
+// positions of the rows plan returns, all in one transaction.
+func (r *CategoryRepository) Reorder(
+	ctx context.Context,
+	guildID string,
+	plan func([]guilds.Category) ([]guilds.Category, error),
+) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []guilds.Category
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("guild_id = ?", guildID).
+			Order("position ASC").
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		changed, err := plan(rows)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, row := range changed {
+			if err := tx.Model(&guilds.Category{}).
+				Where("id = ?", row.ID).
+				Updates(map[string]interface{}{"position": row.Position, "updated_at": now}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
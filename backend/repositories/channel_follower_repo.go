@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+
+	"launay-dot-one/models/guilds"
+
+	"gorm.io/gorm"
+)
+
+type ChannelFollowerRepository struct {
+	db *gorm.DB
+}
+
+func NewChannelFollowerRepository(db *gorm.DB) *ChannelFollowerRepository {
+	return &ChannelFollowerRepository{db}
+}
+
+func (r *ChannelFollowerRepository) Create(ctx context.Context, f *guilds.ChannelFollower) error {
+	return r.db.WithContext(ctx).Create(f).Error
+}
+
+func (r *ChannelFollowerRepository) GetByID(ctx context.Context, id string) (*guilds.ChannelFollower, error) {
+	var f guilds.ChannelFollower
+	if err := r.db.WithContext(ctx).First(&f, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *ChannelFollowerRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&guilds.ChannelFollower{}, "id = ?", id).Error
+}
+
+// ListByChannel returns the followers of an announcement channel.
+func (r *ChannelFollowerRepository) ListByChannel(ctx context.Context, channelID string) ([]guilds.ChannelFollower, error) {
+	var out []guilds.ChannelFollower
+	err := r.db.WithContext(ctx).
+		Where("channel_id = ?", channelID).
+		Order("created_at ASC").
+		Find(&out).Error
+	return out, err
+}
+
+// DeleteByChannel drops every follower of a channel.
+func (r *ChannelFollowerRepository) DeleteByChannel(ctx context.Context, channelID string) error {
+	return r.db.WithContext(ctx).Delete(&guilds.ChannelFollower{}, "channel_id = ?", channelID).Error
+}
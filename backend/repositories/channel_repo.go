@@ -2,10 +2,13 @@ package repositories
 
 import (
 	"context"
+	"time"
 
+	"launay-dot-one/models"
 	"launay-dot-one/models/guilds"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ChannelRepository struct {
@@ -50,6 +53,87 @@ func (r *ChannelRepository) Update(ctx context.Context, ch *guilds.Channel) erro
 	return r.db.WithContext(ctx).Save(ch).Error
 }
 
+// Delete removes the channel together with its webhooks, its follows in
+// either direction and, for a forum, its tags and posts.
 func (r *ChannelRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Delete(&guilds.Channel{}, "id = ?", id).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("channel_id = ? OR target_channel_id = ?", id, id).
+			Delete(&guilds.ChannelFollower{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&guilds.Webhook{}, &guilds.ForumTag{}, &guilds.ForumPost{}} {
+			if err := tx.Where("channel_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("scope_id = ?", id).Delete(&models.NotificationSetting{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&guilds.Channel{}, "id = ?", id).Error
+	})
+}
+
+// NormalizeTypes backfills channels created while the type was free text:
+// unknown types become text and voice channels get the default bitrate.
+func (r *ChannelRepository) NormalizeTypes(ctx context.Context) error {
+	audio := []guilds.ChannelType{guilds.ChannelVoice, guilds.ChannelStage}
+	known := append([]guilds.ChannelType{guilds.ChannelText, guilds.ChannelAnnouncement, guilds.ChannelForum}, audio...)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&guilds.Channel{}).
+			Where("type IS NULL OR type NOT IN ?", known).
+			Update("type", guilds.ChannelText).Error; err != nil {
+			return err
+		}
+		return tx.Model(&guilds.Channel{}).
+			Where("type IN ? AND (bitrate IS NULL OR bitrate = 0)", audio).
+			Update("bitrate", guilds.DefaultBitrate).Error
+	})
+}
+
+// SetPermissionsSynced flips a channel's sync flag without touching its
+// overwrites.
+func (r *ChannelRepository) SetPermissionsSynced(ctx context.Context, id string, synced bool) error {
+	return r.db.WithContext(ctx).
+		Model(&guilds.Channel{}).
+		Where("id = ?", id).
+		Update("permissions_synced", synced).Error
+}
+
+// UnsyncCategory clears the sync flag on every channel under a category.
+func (r *ChannelRepository) UnsyncCategory(ctx context.Context, categoryID string) error {
+	return r.db.WithContext(ctx).
+		Model(&guilds.Channel{}).
+		Where("category_id = ?", categoryID).
+		Update("permissions_synced", false).Error
+}
+
+// Reorder locks the guild's channels and passes them to plan, then saves the
+// positions and categories of the rows plan returns, all in one transaction.
+func (r *ChannelRepository) Reorder(
+	ctx context.Context,
+	guildID string,
+	plan func([]guilds.Channel) ([]guilds.Channel, error),
+) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []guilds.Channel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("guild_id = ?", guildID).
+			Order("position ASC").
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		changed, err := plan(rows)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, row := range changed {
+			if err := tx.Model(&guilds.Channel{}).
+				Where("id = ?", row.ID).
+				Updates(map[string]interface{}{"position": row.Position, "category_id": row.CategoryID, "updated_at": now}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
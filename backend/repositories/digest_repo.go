@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"launay-dot-one/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DigestRepository stores users' email digest settings and finds who is
+// due a digest.
+type DigestRepository struct{ db *gorm.DB }
+
+func NewDigestRepository(db *gorm.DB) *DigestRepository {
+	return &DigestRepository{db}
+}
+
+// GetSettings returns a user's digest settings, the defaults if they
+// never changed them.
+func (r *DigestRepository) GetSettings(ctx context.Context, userID string) (*models.DigestSettings, error) {
+	var ds models.DigestSettings
+	err := r.db.WithContext(ctx).First(&ds, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DefaultDigestSettings(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ds, nil
+}
+
+// SaveFrequency sets how often the user gets digests.
+func (r *DigestRepository) SaveFrequency(ctx context.Context, userID string, f models.DigestFrequency) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"frequency", "updated_at"}),
+		}).
+		Create(&models.DigestSettings{UserID: userID, Frequency: f, UpdatedAt: time.Now()}).Error
+}
+
+// MarkChecked records that the user was considered at, and sent a digest
+// when sent is set.
+func (r *DigestRepository) MarkChecked(ctx context.Context, userID string, at time.Time, sent bool) error {
+	ds := &models.DigestSettings{UserID: userID, Frequency: models.DefaultDigestFrequency, CheckedAt: &at, UpdatedAt: at}
+	columns := []string{"checked_at"}
+	if sent {
+		ds.LastSentAt = &at
+		columns = append(columns, "last_sent_at")
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns(columns),
+		}).
+		Create(ds).Error
+}
+
+// ListDue returns users away since before idleSince who didn't opt out and
+// weren't considered within their digest interval of now.
+func (r *DigestRepository) ListDue(ctx context.Context, idleSince, now time.Time, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT u.* FROM users u
+		LEFT JOIN digest_settings d ON d.user_id = u.id
+		WHERE NOT u.bot AND u.deleted_at IS NULL AND u.id <> @ghost
+		  AND u.status = @offline
+		  AND COALESCE(u.last_seen_at, u.created_at) <= @idle
+		  AND COALESCE(d.frequency, @default) <> @never
+		  AND (d.checked_at IS NULL OR d.checked_at <= CASE d.frequency WHEN @weekly THEN @week ELSE @day END)
+		ORDER BY u.id
+		LIMIT @limit`,
+		sql.Named("ghost", models.GhostUserID),
+		sql.Named("offline", models.StatusOffline),
+		sql.Named("idle", idleSince),
+		sql.Named("default", models.DefaultDigestFrequency),
+		sql.Named("never", models.DigestNever),
+		sql.Named("weekly", models.DigestWeekly),
+		sql.Named("week", now.Add(-models.DigestWeekly.Interval())),
+		sql.Named("day", now.Add(-models.DigestDaily.Interval())),
+		sql.Named("limit", limit),
+	).Scan(&users).Error
+	return users, err
+}
+
+// WithAdvisoryLock runs fn while holding a Postgres advisory lock on key,
+// so only one replica runs it at a time. When another session holds the
+// lock it returns false without running fn.
+func (r *DigestRepository) WithAdvisoryLock(ctx context.Context, key int64, fn func() error) (bool, error) {
+	ran := false
+	// session locks belong to a connection: take and release it on the same one
+	err := r.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", key)
+		ran = true
+		return fn()
+	})
+	return ran, err
+}
@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"launay-dot-one/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EventSubscriptionRepository stores outgoing event subscriptions and
+// their deliveries. Pending deliveries double as the retry queue.
+type EventSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewEventSubscriptionRepository(db *gorm.DB) *EventSubscriptionRepository {
+	return &EventSubscriptionRepository{db}
+}
+
+func (r *EventSubscriptionRepository) Create(ctx context.Context, s *models.EventSubscription) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+func (r *EventSubscriptionRepository) GetByID(ctx context.Context, id string) (*models.EventSubscription, error) {
+	var s models.EventSubscription
+	if err := r.db.WithContext(ctx).First(&s, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// List returns a guild's subscriptions, or the platform-wide ones when
+// guildID is empty.
+func (r *EventSubscriptionRepository) List(ctx context.Context, guildID string) ([]models.EventSubscription, error) {
+	q := r.db.WithContext(ctx)
+	if guildID == "" {
+		q = q.Where("guild_id IS NULL")
+	} else {
+		q = q.Where("guild_id = ?", guildID)
+	}
+	var out []models.EventSubscription
+	err := q.Order("created_at ASC").Find(&out).Error
+	return out, err
+}
+
+// ListSubscribers returns the enabled subscriptions that want event from
+// guildID: the guild's own plus every platform-wide one.
+func (r *EventSubscriptionRepository) ListSubscribers(
+	ctx context.Context,
+	guildID, event string,
+) ([]models.EventSubscription, error) {
+	raw, _ := json.Marshal([]string{event})
+	var out []models.EventSubscription
+	err := r.db.WithContext(ctx).
+		Where("enabled AND (guild_id = ? OR guild_id IS NULL)", guildID).
+		Where("events @> ?::jsonb", string(raw)).
+		Find(&out).Error
+	return out, err
+}
+
+func (r *EventSubscriptionRepository) Update(ctx context.Context, s *models.EventSubscription) error {
+	return r.db.WithContext(ctx).Save(s).Error
+}
+
+// Delete removes a subscription together with its delivery log.
+func (r *EventSubscriptionRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			`DELETE FROM event_delivery_attempts WHERE delivery_id IN (SELECT id FROM event_deliveries WHERE subscription_id = ?)`,
+			id,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.EventDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.EventSubscription{}, "id = ?", id).Error
+	})
+}
+
+// RecordFailure bumps the subscription's failure streak, starting the
+// clock on the first failure, and returns the updated row.
+func (r *EventSubscriptionRepository) RecordFailure(
+	ctx context.Context,
+	id string,
+	now time.Time,
+) (*models.EventSubscription, error) {
+	var s models.EventSubscription
+	err := r.db.WithContext(ctx).Model(&s).
+		Clauses(clause.Returning{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+			"failing_since":        gorm.Expr("COALESCE(failing_since, ?)", now),
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// RecordSuccess ends the subscription's failure streak.
+func (r *EventSubscriptionRepository) RecordSuccess(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&models.EventSubscription{}).
+		Where("id = ? AND consecutive_failures > 0", id).
+		Updates(map[string]interface{}{"consecutive_failures": 0, "failing_since": nil}).Error
+}
+
+// Disable switches a subscription off; its pending deliveries stay queued
+// until it is enabled again.
+func (r *EventSubscriptionRepository) Disable(ctx context.Context, id, reason string) error {
+	return r.db.WithContext(ctx).Model(&models.EventSubscription{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"enabled": false, "disabled_reason": reason}).Error
+}
+
+func (r *EventSubscriptionRepository) CreateDeliveries(ctx context.Context, ds []models.EventDelivery) error {
+	if len(ds) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&ds).Error
+}
+
+// ClaimDue locks up to limit pending deliveries of enabled subscriptions
+// that are due, and pushes their next attempt out by lease so that no
+// other worker picks them up while they are being sent.
+func (r *EventSubscriptionRepository) ClaimDue(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]models.EventDelivery, error) {
+	var out []models.EventDelivery
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+			Where("subscription_id IN (SELECT id FROM event_subscriptions WHERE enabled)").
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&out).Error; err != nil {
+			return err
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		ids := make([]string, len(out))
+		for i := range out {
+			ids[i] = out[i].ID
+		}
+		return tx.Model(&models.EventDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return out, err
+}
+
+// SaveAttempt stores the outcome of an attempt together with the
+// delivery's new state.
+func (r *EventSubscriptionRepository) SaveAttempt(
+	ctx context.Context,
+	d *models.EventDelivery,
+	a *models.EventDeliveryAttempt,
+) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(a).Error; err != nil {
+			return err
+		}
+		return tx.Omit("AttemptLog").Save(d).Error
+	})
+}
+
+// GetDelivery loads a delivery with its attempts, oldest first.
+func (r *EventSubscriptionRepository) GetDelivery(ctx context.Context, id string) (*models.EventDelivery, error) {
+	var d models.EventDelivery
+	err := r.db.WithContext(ctx).
+		Preload("AttemptLog", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		First(&d, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListDeliveries returns a subscription's deliveries newest first,
+// optionally filtered by status.
+func (r *EventSubscriptionRepository) ListDeliveries(
+	ctx context.Context,
+	subscriptionID string,
+	status models.DeliveryStatus,
+	offset, limit int,
+) ([]models.EventDelivery, int64, error) {
+	q := r.db.WithContext(ctx).Model(&models.EventDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var out []models.EventDelivery
+	err := q.Order("created_at DESC").Offset(offset).Limit(limit).Find(&out).Error
+	return out, total, err
+}
+
+// PruneDeliveries drops finished deliveries created before cutoff.
+func (r *EventSubscriptionRepository) PruneDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		const finished = `SELECT id FROM event_deliveries WHERE status <> ? AND created_at < ?`
+		if err := tx.Exec(
+			`DELETE FROM event_delivery_attempts WHERE delivery_id IN (`+finished+`)`,
+			models.DeliveryPending, cutoff,
+		).Error; err != nil {
+			return err
+		}
+		res := tx.Where("status <> ? AND created_at < ?", models.DeliveryPending, cutoff).
+			Delete(&models.EventDelivery{})
+		n = res.RowsAffected
+		return res.Error
+	})
+	return n, err
+}
+
+// DropResponseBodies removes the response_body column earlier versions
+// filled with what endpoints answered.
+func (r *EventSubscriptionRepository) DropResponseBodies(ctx context.Context) error {
+	m := r.db.WithContext(ctx).Migrator()
+	if !m.HasColumn(&models.EventDeliveryAttempt{}, "response_body") {
+		return nil
+	}
+	return m.DropColumn(&models.EventDeliveryAttempt{}, "response_body")
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+
+	"launay-dot-one/models/guilds"
+
+	"gorm.io/gorm"
+)
+
+type ForumRepository struct {
+	db *gorm.DB
+}
+
+func NewForumRepository(db *gorm.DB) *ForumRepository {
+	return &ForumRepository{db}
+}
+
+func (r *ForumRepository) CreateTag(ctx context.Context, t *guilds.ForumTag) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+func (r *ForumRepository) GetTag(ctx context.Context, id string) (*guilds.ForumTag, error) {
+	var t guilds.ForumTag
+	if err := r.db.WithContext(ctx).First(&t, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *ForumRepository) ListTags(ctx context.Context, channelID string) ([]guilds.ForumTag, error) {
+	var out []guilds.ForumTag
+	err := r.db.WithContext(ctx).
+		Where("channel_id = ?", channelID).
+		Order("created_at ASC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *ForumRepository) CountTags(ctx context.Context, channelID string) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&guilds.ForumTag{}).Where("channel_id = ?", channelID).Count(&n).Error
+	return n, err
+}
+
+func (r *ForumRepository) UpdateTag(ctx context.Context, t *guilds.ForumTag) error {
+	return r.db.WithContext(ctx).Save(t).Error
+}
+
+// DeleteTag removes the tag and strips it from every post that carried it.
+func (r *ForumRepository) DeleteTag(ctx context.Context, t *guilds.ForumTag) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&guilds.ForumPost{}).
+			Where("channel_id = ? AND tag_ids @> ?::jsonb", t.ChannelID, tagFilter(t.ID)).
+			Update("tag_ids", gorm.Expr("tag_ids - ?", t.ID)).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&guilds.ForumTag{}, "id = ?", t.ID).Error
+	})
+}
+
+func (r *ForumRepository) CreatePost(ctx context.Context, p *guilds.ForumPost) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+func (r *ForumRepository) GetPost(ctx context.Context, id string) (*guilds.ForumPost, error) {
+	var p guilds.ForumPost
+	if err := r.db.WithContext(ctx).First(&p, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ForumRepository) DeletePost(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&guilds.ForumPost{}, "id = ?", id).Error
+}
+
+// ListPosts pages through a forum's posts, newest first, optionally only
+// those carrying tagID.
+func (r *ForumRepository) ListPosts(
+	ctx context.Context,
+	channelID, tagID string,
+	offset, limit int,
+) ([]guilds.ForumPost, int64, error) {
+	q := r.db.WithContext(ctx).Model(&guilds.ForumPost{}).Where("channel_id = ?", channelID)
+	if tagID != "" {
+		q = q.Where("tag_ids @> ?::jsonb", tagFilter(tagID))
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var out []guilds.ForumPost
+	err := q.Order("created_at DESC").Offset(offset).Limit(limit).Find(&out).Error
+	return out, total, err
+}
+
+// tagFilter builds the jsonb containment operand matching posts with tagID.
+func tagFilter(tagID string) string {
+	raw, _ := json.Marshal([]string{tagID})
+	return string(raw)
+}
@@ -2,8 +2,14 @@ package repositories
 
 import (
 	"context"
-	"gorm.io/gorm"
+	"errors"
+	"time"
+
 	"launay-dot-one/models/friendships"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type FriendRequestRepository struct{ db *gorm.DB }
@@ -49,3 +55,167 @@ func (r *FriendRequestRepository) ListFriends(ctx context.Context, userID string
 		Find(&friends).Error
 	return friends, err
 }
+
+// ListFriendIDs returns the IDs of the user's accepted friends.
+func (r *FriendRequestRepository) ListFriendIDs(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).
+		Model(&friendships.FriendRequest{}).
+		Where("status = ? AND (requester_id = ? OR receiver_id = ?)",
+			friendships.FriendAccepted, userID, userID).
+		Select("CASE WHEN requester_id = ? THEN receiver_id ELSE requester_id END", userID).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// AreFriends reports whether two users have an accepted friendship.
+func (r *FriendRequestRepository) AreFriends(ctx context.Context, userA, userB string) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&friendships.FriendRequest{}).
+		Where("status = ? AND ((requester_id = ? AND receiver_id = ?) OR (requester_id = ? AND receiver_id = ?))",
+			friendships.FriendAccepted, userA, userB, userB, userA).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// Between returns every row linking two users, in either direction.
+func (r *FriendRequestRepository) Between(ctx context.Context, userA, userB string) ([]friendships.FriendRequest, error) {
+	var list []friendships.FriendRequest
+	err := r.db.WithContext(ctx).
+		Where("(requester_id = ? AND receiver_id = ?) OR (requester_id = ? AND receiver_id = ?)",
+			userA, userB, userB, userA).
+		Find(&list).Error
+	return list, err
+}
+
+func (r *FriendRequestRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&friendships.FriendRequest{}, "id = ?", id).Error
+}
+
+// DeleteFriendship ends an accepted friendship and reports whether there
+// was one.
+func (r *FriendRequestRepository) DeleteFriendship(ctx context.Context, userA, userB string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("status = ? AND ((requester_id = ? AND receiver_id = ?) OR (requester_id = ? AND receiver_id = ?))",
+			friendships.FriendAccepted, userA, userB, userB, userA).
+		Delete(&friendships.FriendRequest{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Block drops any friendship or pending request between the pair and
+// records blockerID's block, once.
+func (r *FriendRequestRepository) Block(ctx context.Context, blockerID, blockedID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("status <> ? AND ((requester_id = ? AND receiver_id = ?) OR (requester_id = ? AND receiver_id = ?))",
+				friendships.FriendBlocked, blockerID, blockedID, blockedID, blockerID).
+			Delete(&friendships.FriendRequest{}).Error; err != nil {
+			return err
+		}
+		var n int64
+		if err := tx.Model(&friendships.FriendRequest{}).
+			Where("status = ? AND blocker_id = ? AND receiver_id = ?", friendships.FriendBlocked, blockerID, blockedID).
+			Count(&n).Error; err != nil || n > 0 {
+			return err
+		}
+		return tx.Create(&friendships.FriendRequest{
+			ID:          uuid.NewString(),
+			RequesterID: blockerID,
+			ReceiverID:  blockedID,
+			Status:      string(friendships.FriendBlocked),
+			BlockerID:   blockerID,
+		}).Error
+	})
+}
+
+// Unblock lifts blockerID's block and reports whether there was one.
+func (r *FriendRequestRepository) Unblock(ctx context.Context, blockerID, blockedID string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("status = ? AND blocker_id = ? AND receiver_id = ?", friendships.FriendBlocked, blockerID, blockedID).
+		Delete(&friendships.FriendRequest{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// ListBlocked returns the blocks a user placed, newest first.
+func (r *FriendRequestRepository) ListBlocked(ctx context.Context, blockerID string) ([]friendships.FriendRequest, error) {
+	var list []friendships.FriendRequest
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND blocker_id = ?", friendships.FriendBlocked, blockerID).
+		Order("created_at DESC").
+		Find(&list).Error
+	return list, err
+}
+
+// IsBlocked reports whether either user blocked the other.
+func (r *FriendRequestRepository) IsBlocked(ctx context.Context, userA, userB string) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&friendships.FriendRequest{}).
+		Where("status = ? AND ((requester_id = ? AND receiver_id = ?) OR (requester_id = ? AND receiver_id = ?))",
+			friendships.FriendBlocked, userA, userB, userB, userA).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// ListBlockerIDs returns, among candidateIDs, the users who blocked userID
+// or were blocked by them.
+func (r *FriendRequestRepository) ListBlockerIDs(ctx context.Context, userID string, candidateIDs []string) ([]string, error) {
+	if len(candidateIDs) == 0 {
+		return nil, nil
+	}
+	var ids []string
+	err := r.db.WithContext(ctx).
+		Model(&friendships.FriendRequest{}).
+		Where("status = ? AND ((requester_id = ? AND receiver_id IN ?) OR (receiver_id = ? AND requester_id IN ?))",
+			friendships.FriendBlocked, userID, candidateIDs, userID, candidateIDs).
+		Select("CASE WHEN requester_id = ? THEN receiver_id ELSE requester_id END", userID).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// friendIDsSQL selects the IDs of a user's friends; it takes the user ID
+// three times.
+const friendIDsSQL = `SELECT CASE WHEN requester_id = ? THEN receiver_id ELSE requester_id END AS id
+	FROM friend_requests WHERE status = 'accepted' AND (requester_id = ? OR receiver_id = ?)`
+
+// HaveMutualFriend reports whether two users have a friend in common.
+func (r *FriendRequestRepository) HaveMutualFriend(ctx context.Context, userA, userB string) (bool, error) {
+	var ok bool
+	err := r.db.WithContext(ctx).
+		Raw("SELECT EXISTS (SELECT 1 FROM ("+friendIDsSQL+") a JOIN ("+friendIDsSQL+") b ON a.id = b.id)",
+			userA, userA, userA, userB, userB, userB).
+		Scan(&ok).Error
+	return ok, err
+}
+
+// ListMutualFriendIDs returns the friends two users have in common.
+func (r *FriendRequestRepository) ListMutualFriendIDs(ctx context.Context, userA, userB string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).
+		Raw(friendIDsSQL+" INTERSECT "+friendIDsSQL, userA, userA, userA, userB, userB, userB).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// GetPrivacy returns a user's privacy settings, the defaults if they never
+// changed them.
+func (r *FriendRequestRepository) GetPrivacy(ctx context.Context, userID string) (*friendships.PrivacySettings, error) {
+	var ps friendships.PrivacySettings
+	err := r.db.WithContext(ctx).First(&ps, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return friendships.DefaultPrivacy(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
+
+// SavePrivacy upserts a user's privacy settings.
+func (r *FriendRequestRepository) SavePrivacy(ctx context.Context, ps *friendships.PrivacySettings) error {
+	ps.UpdatedAt = time.Now()
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(ps).Error
+}
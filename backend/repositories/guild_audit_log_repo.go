@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"launay-dot-one/models/guilds"
+
+	"gorm.io/gorm"
+)
+
+type GuildAuditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewGuildAuditLogRepository(db *gorm.DB) *GuildAuditLogRepository {
+	return &GuildAuditLogRepository{db}
+}
+
+func (r *GuildAuditLogRepository) Create(ctx context.Context, e *guilds.AuditLogEntry) error {
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// AuditLogFilter narrows a guild audit log query; zero values match everything.
+type AuditLogFilter struct {
+	Action  guilds.AuditAction
+	ActorID string
+	Since   *time.Time
+	Until   *time.Time
+}
+
+// List returns a guild's entries newest first.
+func (r *GuildAuditLogRepository) List(
+	ctx context.Context,
+	guildID string,
+	f AuditLogFilter,
+	offset, limit int,
+) ([]guilds.AuditLogEntry, int64, error) {
+	q := r.db.WithContext(ctx).Model(&guilds.AuditLogEntry{}).Where("guild_id = ?", guildID)
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if f.ActorID != "" {
+		q = q.Where("actor_id = ?", f.ActorID)
+	}
+	if f.Since != nil {
+		q = q.Where("created_at >= ?", *f.Since)
+	}
+	if f.Until != nil {
+		q = q.Where("created_at < ?", *f.Until)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var out []guilds.AuditLogEntry
+	err := q.Order("created_at DESC").Offset(offset).Limit(limit).Find(&out).Error
+	return out, total, err
+}
+
+// DeleteOlderThan prunes entries past the retention window.
+func (r *GuildAuditLogRepository) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("created_at < ?", cutoff).Delete(&guilds.AuditLogEntry{})
+	return res.RowsAffected, res.Error
+}
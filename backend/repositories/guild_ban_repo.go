@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"launay-dot-one/models/guilds"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GuildBanRepository struct {
+	db *gorm.DB
+}
+
+func NewGuildBanRepository(db *gorm.DB) *GuildBanRepository {
+	return &GuildBanRepository{db}
+}
+
+// Ban records the ban and removes the membership in one transaction.
+// Banning an already banned user replaces the previous ban.
+func (r *GuildBanRepository) Ban(ctx context.Context, b *guilds.GuildBan) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(b).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&guilds.GuildMember{}, "guild_id = ? AND user_id = ?", b.GuildID, b.UserID).Error; err != nil {
+			return err
+		}
+		return deleteManagedRoles(tx, b.GuildID, b.UserID)
+	})
+}
+
+// GetActive returns the ban keeping a user out of a guild, if any.
+func (r *GuildBanRepository) GetActive(ctx context.Context, guildID, userID string, now time.Time) (*guilds.GuildBan, error) {
+	var b guilds.GuildBan
+	err := r.db.WithContext(ctx).
+		Where("guild_id = ? AND user_id = ?", guildID, userID).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		First(&b).Error
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *GuildBanRepository) Delete(ctx context.Context, guildID, userID string) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Delete(&guilds.GuildBan{}, "guild_id = ? AND user_id = ?", guildID, userID)
+	return res.RowsAffected, res.Error
+}
+
+func (r *GuildBanRepository) ListByGuild(ctx context.Context, guildID string) ([]guilds.GuildBan, error) {
+	var out []guilds.GuildBan
+	err := r.db.WithContext(ctx).
+		Where("guild_id = ?", guildID).
+		Order("created_at DESC").
+		Find(&out).Error
+	return out, err
+}
+
+// DeleteExpired removes lapsed bans and returns them.
+func (r *GuildBanRepository) DeleteExpired(ctx context.Context, now time.Time) ([]guilds.GuildBan, error) {
+	var out []guilds.GuildBan
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("expires_at IS NOT NULL AND expires_at <= ?", now).
+		Delete(&out).Error
+	return out, err
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"launay-dot-one/models/guilds"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInviteUnusable means the invite is unknown, expired or used up.
+	ErrInviteUnusable = errors.New("invite is invalid, expired or exhausted")
+	// ErrAlreadyMember means the user already belongs to the invite's guild.
+	ErrAlreadyMember = errors.New("already a member of this guild")
+	// ErrBannedFromGuild means the user is banned from the invite's guild.
+	ErrBannedFromGuild = errors.New("banned from this guild")
+)
+
+type GuildInviteRepository struct {
+	db *gorm.DB
+}
+
+func NewGuildInviteRepository(db *gorm.DB) *GuildInviteRepository {
+	return &GuildInviteRepository{db}
+}
+
+func (r *GuildInviteRepository) Create(ctx context.Context, inv *guilds.GuildInvite) error {
+	return r.db.WithContext(ctx).Create(inv).Error
+}
+
+func (r *GuildInviteRepository) Get(ctx context.Context, code string) (*guilds.GuildInvite, error) {
+	var inv guilds.GuildInvite
+	if err := r.db.WithContext(ctx).First(&inv, "code = ?", code).Error; err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *GuildInviteRepository) ListByGuild(ctx context.Context, guildID string) ([]guilds.GuildInvite, error) {
+	var out []guilds.GuildInvite
+	err := r.db.WithContext(ctx).
+		Where("guild_id = ?", guildID).
+		Order("created_at DESC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *GuildInviteRepository) Delete(ctx context.Context, code string) error {
+	return r.db.WithContext(ctx).Delete(&guilds.GuildInvite{}, "code = ?", code).Error
+}
+
+// Accept consumes one use of the invite and adds the member in a single
+// transaction. The conditional UPDATE takes a row lock, so concurrent
+// accepts are serialised and can never push uses past max_uses.
+func (r *GuildInviteRepository) Accept(
+	ctx context.Context,
+	code, userID string,
+	now time.Time,
+) (*guilds.GuildInvite, error) {
+	var inv guilds.GuildInvite
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&guilds.GuildInvite{}).
+			Where("code = ?", code).
+			Where("max_uses = 0 OR uses < max_uses").
+			Where("expires_at IS NULL OR expires_at > ?", now).
+			Update("uses", gorm.Expr("uses + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInviteUnusable
+		}
+		if err := tx.First(&inv, "code = ?", code).Error; err != nil {
+			return err
+		}
+
+		var banned int64
+		if err := tx.Model(&guilds.GuildBan{}).
+			Where("guild_id = ? AND user_id = ?", inv.GuildID, userID).
+			Where("expires_at IS NULL OR expires_at > ?", now).
+			Count(&banned).Error; err != nil {
+			return err
+		}
+		if banned > 0 {
+			return ErrBannedFromGuild
+		}
+
+		mem := &guilds.GuildMember{
+			GuildID:   inv.GuildID,
+			UserID:    userID,
+			JoinedAt:  now,
+			UpdatedAt: now,
+			Temporary: inv.Temporary,
+		}
+		res = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(mem)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrAlreadyMember // rolls back the consumed use
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// DeleteExpired removes invites that can no longer be used.
+func (r *GuildInviteRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).
+		Where("(expires_at IS NOT NULL AND expires_at <= ?) OR (max_uses > 0 AND uses >= max_uses)", now).
+		Delete(&guilds.GuildInvite{}).Error
+}
@@ -3,8 +3,10 @@ package repositories
 import (
 	"context"
 	"launay-dot-one/models/guilds"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GuildMemberRepository struct{ db *gorm.DB }
@@ -13,8 +15,14 @@ func NewGuildMemberRepository(db *gorm.DB) *GuildMemberRepository {
 	return &GuildMemberRepository{db}
 }
 
+// Add inserts the member together with its initial role assignments.
 func (r *GuildMemberRepository) Add(ctx context.Context, m *guilds.GuildMember) error {
-	return r.db.WithContext(ctx).Create(m).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(m).Error; err != nil {
+			return err
+		}
+		return insertMemberRoles(tx, m.GuildID, m.UserID, m.RoleIDs)
+	})
 }
 
 func (r *GuildMemberRepository) Update(ctx context.Context, m *guilds.GuildMember) error {
@@ -22,9 +30,12 @@ func (r *GuildMemberRepository) Update(ctx context.Context, m *guilds.GuildMembe
 }
 
 func (r *GuildMemberRepository) Remove(ctx context.Context, guildID, userID string) error {
-	return r.db.WithContext(ctx).
-		Delete(guilds.GuildMember{}, "guild_id = ? AND user_id = ?", guildID, userID).
-		Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(guilds.GuildMember{}, "guild_id = ? AND user_id = ?", guildID, userID).Error; err != nil {
+			return err
+		}
+		return deleteManagedRoles(tx, guildID, userID)
+	})
 }
 
 func (r *GuildMemberRepository) Get(ctx context.Context, guildID, userID string) (*guilds.GuildMember, error) {
@@ -34,13 +45,320 @@ func (r *GuildMemberRepository) Get(ctx context.Context, guildID, userID string)
 		Error; err != nil {
 		return nil, err
 	}
-	return &m, nil
+	ms := []guilds.GuildMember{m}
+	if err := r.loadRoles(ctx, ms, "guild_id = ? AND user_id = ?", guildID, userID); err != nil {
+		return nil, err
+	}
+	return &ms[0], nil
 }
 
 func (r *GuildMemberRepository) ListByGuild(ctx context.Context, guildID string) ([]guilds.GuildMember, error) {
 	var ms []guilds.GuildMember
+	if err := r.db.WithContext(ctx).
+		Where("guild_id = ?", guildID).
+		Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	return ms, r.loadRoles(ctx, ms, "guild_id = ?", guildID)
+}
+
+// ListByUser returns every guild membership held by a user.
+func (r *GuildMemberRepository) ListByUser(ctx context.Context, userID string) ([]guilds.GuildMember, error) {
+	var ms []guilds.GuildMember
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	return ms, r.loadRoles(ctx, ms, "user_id = ?", userID)
+}
+
+// ListByRole returns the guild's members holding the given role.
+func (r *GuildMemberRepository) ListByRole(ctx context.Context, guildID, roleID string) ([]guilds.GuildMember, error) {
+	var ms []guilds.GuildMember
+	if err := r.db.WithContext(ctx).
+		Where("guild_id = ? AND user_id IN (?)", guildID,
+			r.db.Model(&guilds.GuildMemberRole{}).
+				Select("user_id").
+				Where("guild_id = ? AND role_id = ?", guildID, roleID),
+		).
+		Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	return ms, r.loadRoles(ctx, ms, "guild_id = ?", guildID)
+}
+
+// SetRoles replaces every role assignment of a member.
+func (r *GuildMemberRepository) SetRoles(ctx context.Context, guildID, userID string, roleIDs []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&guilds.GuildMemberRole{}, "guild_id = ? AND user_id = ?", guildID, userID).
+			Error; err != nil {
+			return err
+		}
+		if err := insertMemberRoles(tx, guildID, userID, roleIDs); err != nil {
+			return err
+		}
+		return touchMember(tx, guildID, userID)
+	})
+}
+
+// AddRole assigns a single role; assigning a held role is a no-op.
+func (r *GuildMemberRepository) AddRole(ctx context.Context, guildID, userID, roleID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := insertMemberRoles(tx, guildID, userID, []string{roleID}); err != nil {
+			return err
+		}
+		return touchMember(tx, guildID, userID)
+	})
+}
+
+// RemoveRole unassigns a single role, returning gorm.ErrRecordNotFound
+// if the member did not hold it.
+func (r *GuildMemberRepository) RemoveRole(ctx context.Context, guildID, userID, roleID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&guilds.GuildMemberRole{},
+			"guild_id = ? AND user_id = ? AND role_id = ?", guildID, userID, roleID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return touchMember(tx, guildID, userID)
+	})
+}
+
+// loadRoles fills RoleIDs on ms from the assignments matching the query.
+func (r *GuildMemberRepository) loadRoles(ctx context.Context, ms []guilds.GuildMember, query string, args ...interface{}) error {
+	if len(ms) == 0 {
+		return nil
+	}
+	var rows []guilds.GuildMemberRole
+	if err := r.db.WithContext(ctx).
+		Where(query, args...).
+		Order("created_at ASC").
+		Find(&rows).Error; err != nil {
+		return err
+	}
+	byMember := make(map[[2]string][]string, len(ms))
+	for _, row := range rows {
+		k := [2]string{row.GuildID, row.UserID}
+		byMember[k] = append(byMember[k], row.RoleID)
+	}
+	for i := range ms {
+		ids := byMember[[2]string{ms[i].GuildID, ms[i].UserID}]
+		if ids == nil {
+			ids = []string{}
+		}
+		ms[i].RoleIDs = ids
+	}
+	return nil
+}
+
+func insertMemberRoles(tx *gorm.DB, guildID, userID string, roleIDs []string) error {
+	if len(roleIDs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	rows := make([]guilds.GuildMemberRole, len(roleIDs))
+	for i, id := range roleIDs {
+		rows[i] = guilds.GuildMemberRole{GuildID: guildID, UserID: userID, RoleID: id, CreatedAt: now}
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+func touchMember(tx *gorm.DB, guildID, userID string) error {
+	return tx.Model(&guilds.GuildMember{}).
+		Where("guild_id = ? AND user_id = ?", guildID, userID).
+		Update("updated_at", time.Now()).Error
+}
+
+// MigrateRoleAssignments installs the guild_member_roles foreign keys and
+// moves any legacy guild_members.role_ids JSON arrays into the join table.
+// IDs that don't name a role of the same guild are dropped. Safe to run
+// on every start.
+func (r *GuildMemberRepository) MigrateRoleAssignments(ctx context.Context) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stmts := []string{
+			// target for the composite FK that pins a role to its guild
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_guild_roles_guild_id_id ON guild_roles (guild_id, id);`,
+			`DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_guild_member_roles_member') THEN
+		ALTER TABLE guild_member_roles ADD CONSTRAINT fk_guild_member_roles_member
+			FOREIGN KEY (guild_id, user_id) REFERENCES guild_members (guild_id, user_id) ON DELETE CASCADE;
+	END IF;
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_guild_member_roles_role') THEN
+		ALTER TABLE guild_member_roles ADD CONSTRAINT fk_guild_member_roles_role
+			FOREIGN KEY (guild_id, role_id) REFERENCES guild_roles (guild_id, id) ON DELETE CASCADE;
+	END IF;
+END $$;`,
+			`DO $$
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'guild_members' AND column_name = 'role_ids'
+	) THEN
+		INSERT INTO guild_member_roles (guild_id, user_id, role_id, created_at)
+		SELECT m.guild_id, m.user_id, r.id, now()
+		FROM guild_members m
+		CROSS JOIN LATERAL jsonb_array_elements_text(
+			CASE WHEN jsonb_typeof(m.role_ids) = 'array' THEN m.role_ids ELSE '[]'::jsonb END
+		) AS e(role_id)
+		JOIN guild_roles r ON r.guild_id = m.guild_id AND r.id::text = e.role_id
+		ON CONFLICT DO NOTHING;
+		ALTER TABLE guild_members DROP COLUMN role_ids;
+	END IF;
+END $$;`,
+		}
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *GuildMemberRepository) CountByGuild(ctx context.Context, guildID string) (int64, error) {
+	var n int64
 	err := r.db.WithContext(ctx).
+		Model(&guilds.GuildMember{}).
 		Where("guild_id = ?", guildID).
-		Find(&ms).Error
-	return ms, err
+		Count(&n).Error
+	return n, err
+}
+
+// ListCoMemberIDs returns everyone who shares at least one guild with the
+// user, the user excluded.
+func (r *GuildMemberRepository) ListCoMemberIDs(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).
+		Model(&guilds.GuildMember{}).
+		Distinct("user_id").
+		Where("guild_id IN (?) AND user_id <> ?",
+			r.db.Model(&guilds.GuildMember{}).Select("guild_id").Where("user_id = ?", userID),
+			userID).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// FilterMemberIDs returns those of userIDs that are members of the guild.
+func (r *GuildMemberRepository) FilterMemberIDs(ctx context.Context, guildID string, userIDs []string) ([]string, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	var ids []string
+	err := r.db.WithContext(ctx).
+		Model(&guilds.GuildMember{}).
+		Where("guild_id = ? AND user_id IN ?", guildID, userIDs).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// ShareGuild reports whether two users are members of a common guild.
+func (r *GuildMemberRepository) ShareGuild(ctx context.Context, userA, userB string) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&guilds.GuildMember{}).
+		Where("user_id = ? AND guild_id IN (?)", userB,
+			r.db.Model(&guilds.GuildMember{}).Select("guild_id").Where("user_id = ?", userA)).
+		Limit(1).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// ListMutualGuilds returns the guilds both users are members of, by name.
+func (r *GuildMemberRepository) ListMutualGuilds(ctx context.Context, userA, userB string) ([]guilds.Guild, error) {
+	var list []guilds.Guild
+	err := r.db.WithContext(ctx).
+		Joins("JOIN guild_members a ON a.guild_id = guilds.id AND a.user_id = ?", userA).
+		Joins("JOIN guild_members b ON b.guild_id = guilds.id AND b.user_id = ?", userB).
+		Order("guilds.name").
+		Find(&list).Error
+	return list, err
+}
+
+// ListActivityAudience returns everyone who shares a guild with the user
+// in which the user doesn't hide their activity.
+func (r *GuildMemberRepository) ListActivityAudience(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).
+		Model(&guilds.GuildMember{}).
+		Distinct("user_id").
+		Where("guild_id IN (?) AND user_id <> ?",
+			r.db.Model(&guilds.GuildMember{}).Select("guild_id").Where("user_id = ? AND NOT hide_activity", userID),
+			userID).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// ListCoMembersShowingActivity returns the user's co-members who show
+// their activity in at least one guild they share with the user.
+func (r *GuildMemberRepository) ListCoMembersShowingActivity(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).
+		Model(&guilds.GuildMember{}).
+		Distinct("user_id").
+		Where("guild_id IN (?) AND user_id <> ? AND NOT hide_activity",
+			r.db.Model(&guilds.GuildMember{}).Select("guild_id").Where("user_id = ?", userID),
+			userID).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// ShareGuildShowingActivity reports whether userB shows their activity in
+// a guild they share with userA.
+func (r *GuildMemberRepository) ShareGuildShowingActivity(ctx context.Context, userA, userB string) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&guilds.GuildMember{}).
+		Where("user_id = ? AND NOT hide_activity AND guild_id IN (?)", userB,
+			r.db.Model(&guilds.GuildMember{}).Select("guild_id").Where("user_id = ?", userA)).
+		Limit(1).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// SetHideActivity updates whether a member hides their activity in the
+// guild and reports whether there was such a member.
+func (r *GuildMemberRepository) SetHideActivity(ctx context.Context, guildID, userID string, hide bool) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&guilds.GuildMember{}).
+		Where("guild_id = ? AND user_id = ?", guildID, userID).
+		Updates(map[string]interface{}{"hide_activity": hide, "updated_at": time.Now()})
+	return res.RowsAffected > 0, res.Error
+}
+
+// RemoveTemporary drops every temporary membership held by a user.
+func (r *GuildMemberRepository) RemoveTemporary(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).
+		Delete(guilds.GuildMember{}, "user_id = ? AND temporary", userID).
+		Error
+}
+
+// SetTimeout sets (or clears, with nil) a member's timeout.
+func (r *GuildMemberRepository) SetTimeout(ctx context.Context, guildID, userID string, until *time.Time) error {
+	res := r.db.WithContext(ctx).
+		Model(&guilds.GuildMember{}).
+		Where("guild_id = ? AND user_id = ?", guildID, userID).
+		Updates(map[string]interface{}{"timeout_until": until, "updated_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ClearExpiredTimeouts lifts lapsed timeouts and returns the affected members.
+func (r *GuildMemberRepository) ClearExpiredTimeouts(ctx context.Context, now time.Time) ([]guilds.GuildMember, error) {
+	var out []guilds.GuildMember
+	err := r.db.WithContext(ctx).
+		Model(&out).
+		Clauses(clause.Returning{}).
+		Where("timeout_until IS NOT NULL AND timeout_until <= ?", now).
+		Updates(map[string]interface{}{"timeout_until": nil, "updated_at": now}).Error
+	return out, err
 }
@@ -19,13 +19,97 @@ func (r *GuildRepository) Create(ctx context.Context, g *guilds.Guild) error {
 	return r.db.WithContext(ctx).Create(g).Error
 }
 
+// GuildSeed is everything a new guild starts with; IDs must be set by
+// the caller so rows can reference each other.
+type GuildSeed struct {
+	Guild      *guilds.Guild
+	Owner      *guilds.GuildMember
+	Roles      []guilds.GuildRole
+	Categories []guilds.Category
+	Channels   []guilds.Channel
+	Overwrites []guilds.PermissionOverwrite
+}
+
+// CreateSeeded inserts a guild and its initial structure in one transaction.
+func (r *GuildRepository) CreateSeeded(ctx context.Context, seed *GuildSeed) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(seed.Guild).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(seed.Owner).Error; err != nil {
+			return err
+		}
+		if len(seed.Roles) > 0 {
+			if err := tx.Create(&seed.Roles).Error; err != nil {
+				return err
+			}
+		}
+		if len(seed.Categories) > 0 {
+			if err := tx.Create(&seed.Categories).Error; err != nil {
+				return err
+			}
+		}
+		if len(seed.Channels) > 0 {
+			if err := tx.Create(&seed.Channels).Error; err != nil {
+				return err
+			}
+		}
+		if len(seed.Overwrites) > 0 {
+			if err := tx.Create(&seed.Overwrites).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (r *GuildRepository) Update(ctx context.Context, g *guilds.Guild) error {
 	return r.db.WithContext(ctx).Save(g).Error
 }
 
+// Delete removes a guild and everything that belongs to it in one
+// transaction: messages in its channels, channels, categories, overwrites,
+// member role assignments, members, roles, invites and bans. The audit
+// log is kept and ages out through retention.
 func (r *GuildRepository) Delete(ctx context.Context, guildID string) error {
-	return r.db.WithContext(ctx).
-		Delete(&guilds.Guild{}, "id = ?", guildID).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range []string{
+			`DELETE FROM messages WHERE channel_id IN (SELECT id::text FROM channels WHERE guild_id = ?)`,
+			`DELETE FROM messages WHERE channel_id IN (SELECT id::text FROM forum_posts WHERE guild_id = ?)`,
+			`DELETE FROM forum_tags WHERE channel_id IN (SELECT id::text FROM channels WHERE guild_id = ?)`,
+			`DELETE FROM channel_followers WHERE channel_id IN (SELECT id::text FROM channels WHERE guild_id = ?)`,
+			`DELETE FROM event_delivery_attempts WHERE delivery_id IN (
+				SELECT d.id FROM event_deliveries d
+				JOIN event_subscriptions s ON s.id = d.subscription_id
+				WHERE s.guild_id = ?)`,
+			`DELETE FROM event_deliveries WHERE subscription_id IN (SELECT id FROM event_subscriptions WHERE guild_id = ?)`,
+			`DELETE FROM event_subscriptions WHERE guild_id = ?`,
+			`DELETE FROM application_commands WHERE guild_id = ?`,
+			`DELETE FROM notification_settings WHERE guild_id = ?`,
+		} {
+			if err := tx.Exec(stmt, guildID).Error; err != nil {
+				return err
+			}
+		}
+		for _, model := range []interface{}{
+			&guilds.Webhook{},
+			&guilds.ForumPost{},
+			&guilds.ChannelFollower{},
+			&guilds.PermissionOverwrite{},
+			&guilds.Channel{},
+			&guilds.Category{},
+			&guilds.GuildMemberRole{},
+			&guilds.GuildMember{},
+			&guilds.GuildRole{},
+			&guilds.GuildInvite{},
+			&guilds.GuildBan{},
+		} {
+			if err := tx.Where("guild_id = ?", guildID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&guilds.Guild{}, "id = ?", guildID).Error
+	})
 }
 
 func (r *GuildRepository) GetByID(ctx context.Context, guildID string) (*guilds.Guild, error) {
@@ -41,3 +125,15 @@ func (r *GuildRepository) List(ctx context.Context) ([]guilds.Guild, error) {
 	var list []guilds.Guild
 	return list, r.db.WithContext(ctx).Find(&list).Error
 }
+
+func (r *GuildRepository) Count(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&guilds.Guild{}).Count(&n).Error
+	return n, err
+}
+
+// ListByOwner returns the guilds owned by a user.
+func (r *GuildRepository) ListByOwner(ctx context.Context, ownerID string) ([]guilds.Guild, error) {
+	var list []guilds.Guild
+	return list, r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Find(&list).Error
+}
@@ -2,10 +2,13 @@ package repositories
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"launay-dot-one/models/guilds"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GuildRoleRepository struct {
@@ -44,3 +47,66 @@ func (r *GuildRoleRepository) Update(ctx context.Context, role *guilds.GuildRole
 func (r *GuildRoleRepository) Delete(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Delete(&guilds.GuildRole{}, "id = ?", id).Error
 }
+
+// deleteManagedRoles drops the roles a bot was granted in a guild; the
+// assignments go with them. Runs wherever a member leaves a guild.
+func deleteManagedRoles(tx *gorm.DB, guildID, userID string) error {
+	return tx.Where("guild_id = ? AND bot_id = ?", guildID, userID).Delete(&guilds.GuildRole{}).Error
+}
+
+// ListByIDs returns the roles of a guild whose IDs are in ids.
+func (r *GuildRoleRepository) ListByIDs(ctx context.Context, guildID string, ids []string) ([]guilds.GuildRole, error) {
+	var roles []guilds.GuildRole
+	if len(ids) == 0 {
+		return roles, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("guild_id = ? AND id IN ?", guildID, ids).
+		Order("position ASC").
+		Find(&roles).Error
+	return roles, err
+}
+
+// EnsureEveryoneRoles backfills the @everyone role (ID = guild ID) for
+// guilds created before it existed.
+func (r *GuildRoleRepository) EnsureEveryoneRoles(ctx context.Context, perms uint64) error {
+	return r.db.WithContext(ctx).Exec(`
+INSERT INTO guild_roles (id, guild_id, name, permissions, position, created_at, updated_at)
+SELECT g.id, g.id::text, @name, @perms, 0, now(), now()
+FROM guilds g
+WHERE NOT EXISTS (SELECT 1 FROM guild_roles r WHERE r.id = g.id)`,
+		sql.Named("name", guilds.EveryoneRoleName),
+		sql.Named("perms", int64(perms)),
+	).Error
+}
+
+// Reorder locks the guild's roles and passes them to plan, then saves the
+// positions of the rows plan returns, all in one transaction.
+func (r *GuildRoleRepository) Reorder(
+	ctx context.Context,
+	guildID string,
+	plan func([]guilds.GuildRole) ([]guilds.GuildRole, error),
+) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []guilds.GuildRole
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("guild_id = ?", guildID).
+			Order("position ASC").
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		changed, err := plan(rows)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, row := range changed {
+			if err := tx.Model(&guilds.GuildRole{}).
+				Where("id = ?", row.ID).
+				Updates(map[string]interface{}{"position": row.Position, "updated_at": now}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+
+	"launay-dot-one/models/guilds"
+
+	"gorm.io/gorm"
+)
+
+type GuildTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewGuildTemplateRepository(db *gorm.DB) *GuildTemplateRepository {
+	return &GuildTemplateRepository{db}
+}
+
+func (r *GuildTemplateRepository) Create(ctx context.Context, t *guilds.GuildTemplate) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+func (r *GuildTemplateRepository) Get(ctx context.Context, id string) (*guilds.GuildTemplate, error) {
+	var t guilds.GuildTemplate
+	if err := r.db.WithContext(ctx).First(&t, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListByCreator returns the templates a user exported, newest first.
+func (r *GuildTemplateRepository) ListByCreator(ctx context.Context, creatorID string) ([]guilds.GuildTemplate, error) {
+	var out []guilds.GuildTemplate
+	err := r.db.WithContext(ctx).
+		Where("creator_id = ?", creatorID).
+		Order("created_at DESC").
+		Find(&out).Error
+	return out, err
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"launay-dot-one/models"
 
@@ -20,6 +21,14 @@ func (r *MessagingRepository) SaveMessage(ctx context.Context, msg models.Messag
 	return r.db.WithContext(ctx).Create(&msg).Error
 }
 
+func (r *MessagingRepository) GetByID(ctx context.Context, id string) (*models.Message, error) {
+	var msg models.Message
+	if err := r.db.WithContext(ctx).First(&msg, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
 // GetMessages retrieves messages for a given target (user, group, or channel)
 // ordered by timestamp.
 func (r *MessagingRepository) GetMessages(ctx context.Context, targetID, targetType string) ([]models.Message, error) {
@@ -53,3 +62,37 @@ func (r *MessagingRepository) GetAllMessagesForUser(ctx context.Context, userID
 
 	return messages, err
 }
+
+// ListDirectSince returns the newest direct messages others sent the user
+// after since, up to limit, and how many there are in all.
+func (r *MessagingRepository) ListDirectSince(
+	ctx context.Context, userID string, since time.Time, limit int,
+) ([]models.Message, int64, error) {
+	q := r.db.WithContext(ctx).
+		Model(&models.Message{}).
+		Where("channel_id = ? AND author_id <> ? AND created_at > ?", userID, userID, since)
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var messages []models.Message
+	err := q.Order("created_at DESC").Limit(limit).Find(&messages).Error
+	return messages, total, err
+}
+
+// CountMessages returns the number of persisted messages.
+func (r *MessagingRepository) CountMessages(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&models.Message{}).Count(&n).Error
+	return n, err
+}
+
+// ListByAuthor returns every persisted message written by a user.
+func (r *MessagingRepository) ListByAuthor(ctx context.Context, authorID string) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.WithContext(ctx).
+		Where("author_id = ?", authorID).
+		Order("created_at ASC").
+		Find(&messages).Error
+	return messages, err
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"launay-dot-one/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationRepository stores users' notifications and their
+// per-guild and per-channel notification settings.
+type NotificationRepository struct{ db *gorm.DB }
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db}
+}
+
+func (r *NotificationRepository) CreateMany(ctx context.Context, ns []models.Notification) error {
+	if len(ns) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&ns).Error
+}
+
+// List pages through a user's notifications, newest first.
+func (r *NotificationRepository) List(
+	ctx context.Context, userID string, unreadOnly bool, offset, limit int,
+) ([]models.Notification, int64, error) {
+	q := r.db.WithContext(ctx).Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		q = q.Where("read_at IS NULL")
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []models.Notification
+	err := q.Order("created_at DESC").Offset(offset).Limit(limit).Find(&list).Error
+	return list, total, err
+}
+
+// ListUnreadSince returns the user's newest unread notifications of the
+// given types created after since, up to limit, and how many there are
+// in all.
+func (r *NotificationRepository) ListUnreadSince(
+	ctx context.Context, userID string, types []models.NotificationType, since time.Time, limit int,
+) ([]models.Notification, int64, error) {
+	q := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL AND type IN ? AND created_at > ?", userID, types, since)
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []models.Notification
+	err := q.Order("created_at DESC").Limit(limit).Find(&list).Error
+	return list, total, err
+}
+
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID string) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&n).Error
+	return n, err
+}
+
+// MarkRead marks one of the user's notifications read and reports whether
+// they have it.
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID, id string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	return res.RowsAffected > 0, res.Error
+}
+
+// MarkAllRead marks every unread notification of the user read and
+// returns how many there were.
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return res.RowsAffected, res.Error
+}
+
+// DeleteReadBefore drops notifications read before cutoff.
+func (r *NotificationRepository) DeleteReadBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Where("read_at < ?", cutoff).
+		Delete(&models.Notification{})
+	return res.RowsAffected, res.Error
+}
+
+// ListSettings returns a user's settings for a guild and its channels.
+func (r *NotificationRepository) ListSettings(ctx context.Context, userID, guildID string) ([]models.NotificationSetting, error) {
+	var list []models.NotificationSetting
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND guild_id = ?", userID, guildID).
+		Find(&list).Error
+	return list, err
+}
+
+// ListSettingsFor returns the settings the users have for any of the
+// scopes.
+func (r *NotificationRepository) ListSettingsFor(
+	ctx context.Context, userIDs, scopeIDs []string,
+) ([]models.NotificationSetting, error) {
+	if len(userIDs) == 0 || len(scopeIDs) == 0 {
+		return nil, nil
+	}
+	var list []models.NotificationSetting
+	err := r.db.WithContext(ctx).
+		Where("user_id IN ? AND scope_id IN ?", userIDs, scopeIDs).
+		Find(&list).Error
+	return list, err
+}
+
+// ListAllMessagesSubscribers returns the users whose level for the
+// channel is all messages, set on the channel or inherited from the
+// guild. Mutes are left to the caller.
+func (r *NotificationRepository) ListAllMessagesSubscribers(ctx context.Context, guildID, channelID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT g.user_id FROM notification_settings g
+		WHERE g.scope_id = @guild AND g.level = @all AND NOT EXISTS (
+			SELECT 1 FROM notification_settings c
+			WHERE c.user_id = g.user_id AND c.scope_id = @channel AND c.level <> '')
+		UNION
+		SELECT user_id FROM notification_settings WHERE scope_id = @channel AND level = @all`,
+		sql.Named("guild", guildID), sql.Named("channel", channelID), sql.Named("all", models.NotifyAll),
+	).Scan(&ids).Error
+	return ids, err
+}
+
+// SaveSetting upserts a user's setting for a scope.
+func (r *NotificationRepository) SaveSetting(ctx context.Context, s *models.NotificationSetting) error {
+	s.UpdatedAt = time.Now()
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(s).Error
+}
@@ -5,6 +5,7 @@ import (
 
 	"launay-dot-one/models/guilds"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -20,6 +21,14 @@ func (r *PermissionOverwriteRepository) Create(ctx context.Context, o *guilds.Pe
 	return r.db.WithContext(ctx).Create(o).Error
 }
 
+func (r *PermissionOverwriteRepository) GetByID(ctx context.Context, id string) (*guilds.PermissionOverwrite, error) {
+	var o guilds.PermissionOverwrite
+	if err := r.db.WithContext(ctx).First(&o, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
 func (r *PermissionOverwriteRepository) Update(ctx context.Context, o *guilds.PermissionOverwrite) error {
 	return r.db.WithContext(ctx).Save(o).Error
 }
@@ -44,3 +53,61 @@ func (r *PermissionOverwriteRepository) List(
 	var out []guilds.PermissionOverwrite
 	return out, q.Find(&out).Error
 }
+
+// SyncChannels replaces the overwrites of the given channels with copies of
+// their category's and marks them synced, in one transaction.
+func (r *PermissionOverwriteRepository) SyncChannels(ctx context.Context, categoryID string, channelIDs []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return syncChannels(tx, categoryID, channelIDs)
+	})
+}
+
+// ResyncCategory re-copies a category's overwrites onto every channel under
+// it that is still synced.
+func (r *PermissionOverwriteRepository) ResyncCategory(ctx context.Context, categoryID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		if err := tx.Model(&guilds.Channel{}).
+			Where("category_id = ? AND permissions_synced", categoryID).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		return syncChannels(tx, categoryID, ids)
+	})
+}
+
+func syncChannels(tx *gorm.DB, categoryID string, channelIDs []string) error {
+	if len(channelIDs) == 0 {
+		return nil
+	}
+	var src []guilds.PermissionOverwrite
+	if err := tx.Where("category_id = ?", categoryID).Find(&src).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("channel_id IN ?", channelIDs).
+		Delete(&guilds.PermissionOverwrite{}).Error; err != nil {
+		return err
+	}
+	copies := make([]guilds.PermissionOverwrite, 0, len(src)*len(channelIDs))
+	for _, chID := range channelIDs {
+		for _, o := range src {
+			copies = append(copies, guilds.PermissionOverwrite{
+				ID:            uuid.NewString(),
+				GuildID:       o.GuildID,
+				ChannelID:     chID,
+				OverwriteType: o.OverwriteType,
+				TargetID:      o.TargetID,
+				Allow:         o.Allow,
+				Deny:          o.Deny,
+			})
+		}
+	}
+	if len(copies) > 0 {
+		if err := tx.Create(&copies).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&guilds.Channel{}).
+		Where("id IN ?", channelIDs).
+		Update("permissions_synced", true).Error
+}
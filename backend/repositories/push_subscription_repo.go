@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"launay-dot-one/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PushSubscriptionRepository stores users' Web Push subscriptions and the
+// server's VAPID key.
+type PushSubscriptionRepository struct{ db *gorm.DB }
+
+func NewPushSubscriptionRepository(db *gorm.DB) *PushSubscriptionRepository {
+	return &PushSubscriptionRepository{db}
+}
+
+// Save registers a subscription. A browser re-subscribing, or the same
+// endpoint signing in as someone else, takes over the existing row.
+func (r *PushSubscriptionRepository) Save(ctx context.Context, s *models.PushSubscription) error {
+	s.CreatedAt = time.Now()
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "endpoint"}},
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "p256dh", "auth", "device_name"}),
+		}).
+		Create(s).Error
+}
+
+func (r *PushSubscriptionRepository) ListByUser(ctx context.Context, userID string) ([]models.PushSubscription, error) {
+	var list []models.PushSubscription
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&list).Error
+	return list, err
+}
+
+// CountByUser counts a user's subscriptions, other than the one at
+// endpoint.
+func (r *PushSubscriptionRepository) CountByUser(ctx context.Context, userID, endpoint string) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&models.PushSubscription{}).
+		Where("user_id = ? AND endpoint <> ?", userID, endpoint).
+		Count(&n).Error
+	return n, err
+}
+
+// Delete removes one of the user's subscriptions and reports whether
+// they had it.
+func (r *PushSubscriptionRepository) Delete(ctx context.Context, userID, id string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&models.PushSubscription{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteByID removes a subscription the push service no longer accepts.
+func (r *PushSubscriptionRepository) DeleteByID(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&models.PushSubscription{}, "id = ?", id).Error
+}
+
+func (r *PushSubscriptionRepository) MarkPushed(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.PushSubscription{}).
+		Where("id = ?", id).
+		Update("last_push_at", at).Error
+}
+
+// EnsureVAPIDKey stores key unless a key already exists, and returns the
+// stored one, so replicas starting together agree on a single key.
+func (r *PushSubscriptionRepository) EnsureVAPIDKey(ctx context.Context, key *models.VAPIDKey) (*models.VAPIDKey, error) {
+	key.ID = 1
+	key.CreatedAt = time.Now()
+	db := r.db.WithContext(ctx)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(key).Error; err != nil {
+		return nil, err
+	}
+	var stored models.VAPIDKey
+	if err := db.First(&stored, "id = ?", 1).Error; err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
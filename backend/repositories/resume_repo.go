@@ -20,6 +20,14 @@ func (r *ResumeRepository) Update(ctx context.Context, res *models.Resume) error
 	return r.db.WithContext(ctx).Save(res).Error
 }
 
+// UpdateSharing saves who can read the résumé and how it's found.
+func (r *ResumeRepository) UpdateSharing(ctx context.Context, res *models.Resume) error {
+	return r.db.WithContext(ctx).
+		Model(res).
+		Select("visibility", "slug", "share_slug", "hidden_sections").
+		Updates(res).Error
+}
+
 func (r *ResumeRepository) Delete(ctx context.Context, resumeID string) error {
 	return r.db.WithContext(ctx).
 		Delete(&models.Resume{}, "id = ?", resumeID).
@@ -27,6 +35,22 @@ func (r *ResumeRepository) Delete(ctx context.Context, resumeID string) error {
 }
 
 func (r *ResumeRepository) GetByUser(ctx context.Context, userID string) (*models.Resume, error) {
+	return r.getWhere(ctx, "user_id = ?", userID)
+}
+
+// GetBySlug finds a résumé by its vanity slug.
+func (r *ResumeRepository) GetBySlug(ctx context.Context, slug string) (*models.Resume, error) {
+	return r.getWhere(ctx, "slug = ?", slug)
+}
+
+// GetByShareSlug finds a résumé by the secret slug of its share link.
+func (r *ResumeRepository) GetByShareSlug(ctx context.Context, shareSlug string) (*models.Resume, error) {
+	return r.getWhere(ctx, "share_slug = ?", shareSlug)
+}
+
+// getWhere loads the first résumé matching the condition, with all its
+// sections.
+func (r *ResumeRepository) getWhere(ctx context.Context, query string, args ...interface{}) (*models.Resume, error) {
 	var res models.Resume
 	err := r.db.WithContext(ctx).
 		Preload("Educations").
@@ -35,7 +59,8 @@ func (r *ResumeRepository) GetByUser(ctx context.Context, userID string) (*model
 		Preload("Certifications").
 		Preload("Skills").
 		Preload("Interests").
-		First(&res, "user_id = ?", userID).Error
+		Where(query, args...).
+		First(&res).Error
 	if err != nil {
 		return nil, err
 	}
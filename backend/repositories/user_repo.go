@@ -2,10 +2,18 @@ package repositories
 
 import (
 	"context"
+	"database/sql"
+	"strings"
+	"time"
 
 	"launay-dot-one/models"
+	"launay-dot-one/models/friendships"
+	"launay-dot-one/models/groups"
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/models/resume"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserRepository handles CRUD operations on User models.
@@ -32,6 +40,16 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User,
 	return &user, nil
 }
 
+// ListByIDs returns the users with the given IDs that exist.
+func (r *UserRepository) ListByIDs(ctx context.Context, ids []string) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
 // GetByEmail retrieves a User by their email address.
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
@@ -41,6 +59,81 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 	return &user, nil
 }
 
+// GetByUsername retrieves a live account by username, ignoring case. An
+// exact match wins over one that differs only in case.
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).
+		Where("lower(username) = lower(?) AND deleted_at IS NULL AND id <> ?", username, models.GhostUserID).
+		Order(clause.Expr{SQL: "username = ? DESC", Vars: []interface{}{username}}).
+		First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UsernameTaken reports whether another account uses the username,
+// ignoring case.
+func (r *UserRepository) UsernameTaken(ctx context.Context, username, exceptID string) (bool, error) {
+	var n int64
+	q := r.db.WithContext(ctx).Model(&models.User{}).Where("lower(username) = lower(?)", username)
+	if exceptID != "" {
+		q = q.Where("id <> ?", exceptID)
+	}
+	err := q.Count(&n).Error
+	return n > 0, err
+}
+
+// SearchByUsername returns live accounts whose username starts with the
+// prefix, ignoring case, in username order.
+func (r *UserRepository) SearchByUsername(ctx context.Context, prefix string, offset, limit int) ([]models.User, int64, error) {
+	q := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("lower(username) LIKE ? ESCAPE '\\' AND deleted_at IS NULL AND id <> ?",
+			likeEscaper.Replace(strings.ToLower(prefix))+"%", models.GhostUserID)
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []models.User
+	err := q.Order("lower(username), username").Offset(offset).Limit(limit).Find(&users).Error
+	return users, total, err
+}
+
+// likeEscaper escapes LIKE wildcards so user input matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// EnsureUsernameIndex makes usernames unique ignoring case, with an index
+// on lower(username) that also serves prefix search. Names that clash
+// with an older account's are first suffixed with part of their ID.
+func (r *UserRepository) EnsureUsernameIndex(ctx context.Context) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// the ghost keeps its name; otherwise the oldest account does
+		if err := tx.Exec(`
+			UPDATE users u
+			SET username = left(u.username, ?) || '_' || left(replace(u.id::text, '-', ''), 6)
+			FROM (
+				SELECT id, row_number() OVER (
+					PARTITION BY lower(username)
+					ORDER BY id = ? DESC, created_at, id
+				) AS n
+				FROM users
+			) d
+			WHERE u.id = d.id AND d.n > 1`,
+			models.MaxUsername-7, models.GhostUserID,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DROP INDEX IF EXISTS idx_users_username_lower").Error; err != nil {
+			return err
+		}
+		return tx.Exec(
+			"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower_unique ON users (lower(username) text_pattern_ops)",
+		).Error
+	})
+}
+
 // UpdateAvatar updates only the Avatar field of the specified user.
 func (r *UserRepository) UpdateAvatar(ctx context.Context, userID, avatarURL string) error {
 	return r.db.WithContext(ctx).
@@ -50,15 +143,6 @@ func (r *UserRepository) UpdateAvatar(ctx context.Context, userID, avatarURL str
 		Error
 }
 
-// ListAll returns all User records.
-func (r *UserRepository) ListAll(ctx context.Context) ([]models.User, error) {
-	var users []models.User
-	if err := r.db.WithContext(ctx).Find(&users).Error; err != nil {
-		return nil, err
-	}
-	return users, nil
-}
-
 // Update updates a User's fields based on the provided userID and updates map.
 func (r *UserRepository) UpdateFields(ctx context.Context, userID string, updates map[string]interface{}) error {
 	return r.db.WithContext(ctx).
@@ -67,3 +151,114 @@ func (r *UserRepository) UpdateFields(ctx context.Context, userID string, update
 		Updates(updates).
 		Error
 }
+
+// Search returns users whose username or email contains the query, paginated.
+func (r *UserRepository) Search(ctx context.Context, query string, offset, limit int) ([]models.User, int64, error) {
+	q := r.db.WithContext(ctx).Model(&models.User{})
+	if query != "" {
+		like := "%" + query + "%"
+		q = q.Where("username ILIKE ? OR email ILIKE ?", like, like)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []models.User
+	err := q.Order("created_at DESC").Offset(offset).Limit(limit).Find(&users).Error
+	return users, total, err
+}
+
+// Count returns the total number of users.
+func (r *UserRepository) Count(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&models.User{}).Count(&n).Error
+	return n, err
+}
+
+// EnsureGhost creates the placeholder account that inherits purged content.
+// Its password is not a bcrypt hash, so nobody can sign in as it.
+func (r *UserRepository) EnsureGhost(ctx context.Context) error {
+	ghost := models.User{
+		ID:       models.GhostUserID,
+		Username: "Deleted User",
+		Email:    "deleted-user@invalid",
+		Password: "!",
+		Role:     models.RoleUser,
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&ghost).Error
+}
+
+// ListDeletionDue returns users who requested deletion before cutoff.
+func (r *UserRepository) ListDeletionDue(ctx context.Context, cutoff time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Find(&users).Error
+	return users, err
+}
+
+// Purge hard-deletes a user in one transaction. Messages they wrote (or
+// received as DMs) are re-attributed to the ghost account; memberships,
+// friendships, restrictions and the résumé are removed, and so are the
+// bots they own.
+func (r *UserRepository) Purge(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var botIDs []string
+		if err := tx.Model(&models.Bot{}).Where("owner_id = ?", userID).Pluck("user_id", &botIDs).Error; err != nil {
+			return err
+		}
+		for _, id := range botIDs {
+			if err := purgeBot(tx, id); err != nil {
+				return err
+			}
+		}
+		return purgeUser(tx, userID)
+	})
+}
+
+func purgeUser(tx *gorm.DB, userID string) error {
+	if err := tx.Model(&models.Message{}).
+		Where("author_id = ?", userID).
+		Update("author_id", models.GhostUserID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Message{}).
+		Where("channel_id = ?", userID).
+		Update("channel_id", models.GhostUserID).Error; err != nil {
+		return err
+	}
+
+	resumeIDs := tx.Model(&models.Resume{}).Select("id").Where("user_id = ?", userID)
+	for _, sub := range []interface{}{
+		&resume.Education{}, &resume.Experience{}, &resume.Project{},
+		&resume.Certification{}, &resume.Skill{}, &resume.Interest{},
+	} {
+		if err := tx.Where("resume_id IN (?)", resumeIDs).Delete(sub).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, d := range []struct {
+		model interface{}
+		where string
+	}{
+		{&models.Resume{}, "user_id = @id"},
+		{&guilds.GuildMember{}, "user_id = @id"},
+		{&groups.GroupMembership{}, "user_id = @id"},
+		{&friendships.FriendRequest{}, "requester_id = @id OR receiver_id = @id"},
+		{&friendships.PrivacySettings{}, "user_id = @id"},
+		{&models.AccountRestriction{}, "user_id = @id"},
+		{&models.Notification{}, "user_id = @id"},
+		{&models.NotificationSetting{}, "user_id = @id"},
+		{&models.PushSubscription{}, "user_id = @id"},
+		{&models.DigestSettings{}, "user_id = @id"},
+	} {
+		if err := tx.Where(d.where, sql.Named("id", userID)).Delete(d.model).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Delete(&models.User{}, "id = ?", userID).Error
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+
+	"launay-dot-one/models/guilds"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, w *guilds.Webhook) error {
+	return r.db.WithContext(ctx).Create(w).Error
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id string) (*guilds.Webhook, error) {
+	var w guilds.Webhook
+	if err := r.db.WithContext(ctx).First(&w, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (r *WebhookRepository) ListByChannel(ctx context.Context, channelID string) ([]guilds.Webhook, error) {
+	var out []guilds.Webhook
+	err := r.db.WithContext(ctx).
+		Where("channel_id = ?", channelID).
+		Order("created_at ASC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *WebhookRepository) ListByGuild(ctx context.Context, guildID string) ([]guilds.Webhook, error) {
+	var out []guilds.Webhook
+	err := r.db.WithContext(ctx).
+		Where("guild_id = ?", guildID).
+		Order("created_at ASC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, w *guilds.Webhook) error {
+	return r.db.WithContext(ctx).Save(w).Error
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&guilds.Webhook{}, "id = ?", id).Error
+}
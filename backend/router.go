@@ -23,6 +23,18 @@ func SetupRouter(
 	categoryController *controllers.CategoriesController,
 	channelController *controllers.ChannelsController,
 	guildRolesController *controllers.GuildRolesController,
+	adminController *controllers.AdminController,
+	invitesController *controllers.InvitesController,
+	moderationController *controllers.ModerationController,
+	forumsController *controllers.ForumsController,
+	voiceController *controllers.VoiceController,
+	webhooksController *controllers.WebhooksController,
+	eventSubscriptionsController *controllers.EventSubscriptionsController,
+	botsController *controllers.BotsController,
+	interactionsController *controllers.InteractionsController,
+	notificationsController *controllers.NotificationsController,
+	pushController *controllers.PushController,
+	digestController *controllers.DigestController,
 ) *gin.Engine {
 	router := gin.New()
 	router.Use(gin.Recovery(), middlewares.Logger())
@@ -47,10 +59,22 @@ func SetupRouter(
 	categoryController.RegisterRoutes(router)
 	channelController.RegisterRoutes(router)
 	guildRolesController.RegisterRoutes(router)
+	adminController.RegisterRoutes(router)
+	invitesController.RegisterRoutes(router)
+	moderationController.RegisterRoutes(router)
+	forumsController.RegisterRoutes(router)
+	voiceController.RegisterRoutes(router)
+	webhooksController.RegisterRoutes(router)
+	eventSubscriptionsController.RegisterRoutes(router)
+	botsController.RegisterRoutes(router)
+	interactionsController.RegisterRoutes(router)
+	notificationsController.RegisterRoutes(router)
+	pushController.RegisterRoutes(router)
+	digestController.RegisterRoutes(router)
 
-	// Presence WS & helper
-	router.GET("/presence", gin.WrapF(presenceController.GetAllPresence))
+	// Presence WS & lookups
 	router.GET("/ws/presence", gin.WrapF(presenceController.HandleWebSocket))
+	router.GET("/users/:user_id/presence", middlewares.AuthMiddleware(), presenceController.GetUserPresence)
 
 	// Catch‐all
 	router.NoRoute(func(c *gin.Context) {
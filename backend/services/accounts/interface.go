@@ -0,0 +1,33 @@
+package accounts
+
+import (
+	"context"
+	"time"
+)
+
+// Service covers the account lifecycle: deletion and personal-data export.
+type Service interface {
+	// RequestDeletion confirms the password, marks the account for deletion
+	// and signs the user out. Logging in again before PurgeAt cancels it.
+	RequestDeletion(ctx context.Context, userID, password string) (*DeletionDTO, error)
+
+	// Export bundles the user's data as a ZIP of JSON files, stores it in the
+	// bucket and returns a download link.
+	Export(ctx context.Context, userID string) (*ExportDTO, error)
+
+	// PurgeDue hard-deletes accounts whose grace period has elapsed and
+	// removes expired exports.
+	PurgeDue(ctx context.Context) error
+}
+
+// DeletionDTO tells the client when the account will be gone for good.
+type DeletionDTO struct {
+	RequestedAt time.Time `json:"requested_at"`
+	PurgeAt     time.Time `json:"purge_at"`
+}
+
+// ExportDTO points at a generated export archive.
+type ExportDTO struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
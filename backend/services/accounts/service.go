@@ -0,0 +1,198 @@
+package accounts
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"launay-dot-one/models"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/sessions"
+	"launay-dot-one/storage"
+)
+
+var (
+	ErrInvalidPassword = errors.New("invalid password")
+	ErrOwnsGuilds      = errors.New("transfer or delete the guilds you own first")
+	ErrGhostAccount    = errors.New("the deleted-user account cannot be modified")
+)
+
+// exportPrefix is where export archives live in their bucket, and where
+// older versions left them in the public one.
+const exportPrefix = "exports/"
+
+type service struct {
+	userRepo      *repositories.UserRepository
+	guildRepo     *repositories.GuildRepository
+	memberRepo    *repositories.GuildMemberRepository
+	friendRepo    *repositories.FriendRequestRepository
+	resumeRepo    *repositories.ResumeRepository
+	messagingRepo *repositories.MessagingRepository
+	storageSvc    *storage.StorageService
+	exportStore   *storage.StorageService
+	sessionSvc    sessions.Service
+	gracePeriod   time.Duration
+	exportTTL     time.Duration
+}
+
+// NewService constructs the account service. Archives go to exportStore,
+// a private bucket. gracePeriod is how long a deletion request can be
+// cancelled; exportTTL how long archives are kept and downloadable.
+func NewService(
+	userRepo *repositories.UserRepository,
+	guildRepo *repositories.GuildRepository,
+	memberRepo *repositories.GuildMemberRepository,
+	friendRepo *repositories.FriendRequestRepository,
+	resumeRepo *repositories.ResumeRepository,
+	messagingRepo *repositories.MessagingRepository,
+	storageSvc *storage.StorageService,
+	exportStore *storage.StorageService,
+	sessionSvc sessions.Service,
+	gracePeriod, exportTTL time.Duration,
+) Service {
+	// an archive is only reachable through its presigned URL
+	exportTTL = min(exportTTL, storage.MaxPresignedTTL)
+	return &service{
+		userRepo:      userRepo,
+		guildRepo:     guildRepo,
+		memberRepo:    memberRepo,
+		friendRepo:    friendRepo,
+		resumeRepo:    resumeRepo,
+		messagingRepo: messagingRepo,
+		storageSvc:    storageSvc,
+		exportStore:   exportStore,
+		sessionSvc:    sessionSvc,
+		gracePeriod:   gracePeriod,
+		exportTTL:     exportTTL,
+	}
+}
+
+func (s *service) RequestDeletion(ctx context.Context, userID, password string) (*DeletionDTO, error) {
+	if userID == models.GhostUserID {
+		return nil, ErrGhostAccount
+	}
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidPassword
+	}
+	owned, err := s.guildRepo.ListByOwner(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(owned) > 0 {
+		return nil, ErrOwnsGuilds
+	}
+
+	now := time.Now()
+	if u.DeletedAt != nil {
+		now = *u.DeletedAt // already scheduled; keep the original clock
+	} else if err := s.userRepo.UpdateFields(ctx, userID, map[string]interface{}{
+		"deleted_at": now,
+	}); err != nil {
+		return nil, err
+	}
+	if err := s.sessionSvc.RevokeAll(ctx, userID); err != nil {
+		return nil, fmt.Errorf("revoke sessions: %w", err)
+	}
+	return &DeletionDTO{RequestedAt: now, PurgeAt: now.Add(s.gracePeriod)}, nil
+}
+
+func (s *service) Export(ctx context.Context, userID string) (*ExportDTO, error) {
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	files := map[string]interface{}{"profile.json": u.ToPublic()}
+
+	res, err := s.resumeRepo.GetByUser(ctx, userID)
+	switch {
+	case err == nil:
+		files["resume.json"] = res
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+	if files["friendships.json"], err = s.friendRepo.ListForUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if files["guild_memberships.json"], err = s.memberRepo.ListByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if files["messages.json"], err = s.messagingRepo.ListByAuthor(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, v := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return nil, fmt.Errorf("encode %s: %w", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	object := fmt.Sprintf("%s%s/%s.zip", exportPrefix, userID, uuid.NewString())
+	if _, err := s.exportStore.PutObject(ctx, object, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "application/zip"); err != nil {
+		return nil, fmt.Errorf("store export: %w", err)
+	}
+	expiresAt := time.Now().Add(s.exportTTL)
+	url, err := s.exportStore.PresignedGetURL(ctx, object, s.exportTTL)
+	if err != nil {
+		return nil, fmt.Errorf("sign export URL: %w", err)
+	}
+	return &ExportDTO{URL: url, ExpiresAt: expiresAt}, nil
+}
+
+func (s *service) PurgeDue(ctx context.Context) error {
+	due, err := s.userRepo.ListDeletionDue(ctx, time.Now().Add(-s.gracePeriod))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, u := range due {
+		if err := s.purge(ctx, u.ID); err != nil {
+			errs = append(errs, fmt.Errorf("purge %s: %w", u.ID, err))
+		}
+	}
+	if err := s.exportStore.DeleteOlderThan(ctx, exportPrefix, time.Now().Add(-s.exportTTL)); err != nil {
+		errs = append(errs, fmt.Errorf("expire exports: %w", err))
+	}
+	// exports used to be written to the public bucket
+	if err := s.storageSvc.DeletePrefix(ctx, exportPrefix); err != nil {
+		errs = append(errs, fmt.Errorf("remove public exports: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *service) purge(ctx context.Context, userID string) error {
+	if err := s.userRepo.Purge(ctx, userID); err != nil {
+		return err
+	}
+	// objects are keyed by user ID: avatars under "<id>/", exports under "exports/<id>/"
+	if err := s.storageSvc.DeletePrefix(ctx, userID+"/"); err != nil {
+		return fmt.Errorf("purge avatars: %w", err)
+	}
+	if err := s.exportStore.DeletePrefix(ctx, exportPrefix+userID+"/"); err != nil {
+		return fmt.Errorf("purge exports: %w", err)
+	}
+	return nil
+}
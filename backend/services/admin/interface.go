@@ -0,0 +1,81 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	m "launay-dot-one/models"
+)
+
+// Service implements platform administration. Every mutating call records
+// an entry in the admin audit log.
+type Service interface {
+	// ListUsers searches users by username or email, paginated.
+	ListUsers(ctx context.Context, query string, page, limit int) (*UserPage, error)
+
+	// GetUser returns a user together with their restriction history.
+	GetUser(ctx context.Context, userID string) (*UserDetail, error)
+
+	// Restrict suspends or bans a user and revokes their sessions.
+	// Suspensions require an expiry; bans without one are permanent.
+	Restrict(
+		ctx context.Context,
+		actorID, userID string,
+		kind m.RestrictionKind,
+		reason string,
+		expiresAt *time.Time,
+	) (*m.AccountRestriction, error)
+
+	// LiftRestrictions ends every active suspension or ban for a user.
+	LiftRestrictions(ctx context.Context, actorID, userID, reason string) error
+
+	// ForcePasswordReset blocks login until the user changes their password.
+	ForcePasswordReset(ctx context.Context, actorID, userID, reason string) error
+
+	// RevokeSessions invalidates every token currently held by the user.
+	RevokeSessions(ctx context.Context, actorID, userID, reason string) error
+
+	// UpdateRole changes a user's platform role.
+	UpdateRole(ctx context.Context, actorID, userID, role, reason string) error
+
+	// DeleteGuild removes a guild regardless of ownership.
+	DeleteGuild(ctx context.Context, actorID, guildID, reason string) error
+
+	// Stats returns platform-wide counters.
+	Stats(ctx context.Context) (*Stats, error)
+
+	// ListAuditLog returns admin audit entries, optionally filtered.
+	ListAuditLog(ctx context.Context, actorID, targetID string, page, limit int) (*AuditPage, error)
+}
+
+// UserPage is one page of user search results.
+type UserPage struct {
+	Users []m.PublicUser `json:"users"`
+	Total int64          `json:"total"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+}
+
+// UserDetail is the admin view of a single account.
+type UserDetail struct {
+	User                  m.PublicUser           `json:"user"`
+	PasswordResetRequired bool                   `json:"password_reset_required"`
+	ActiveRestriction     *m.AccountRestriction  `json:"active_restriction,omitempty"`
+	Restrictions          []m.AccountRestriction `json:"restrictions"`
+}
+
+// Stats holds platform-wide counters.
+type Stats struct {
+	Users        int64 `json:"users"`
+	Guilds       int64 `json:"guilds"`
+	Messages     int64 `json:"messages"`
+	RedisBacklog int64 `json:"redis_backlog"` // messages not yet persisted
+}
+
+// AuditPage is one page of admin audit entries.
+type AuditPage struct {
+	Entries []m.AdminAuditLog `json:"entries"`
+	Total   int64             `json:"total"`
+	Page    int               `json:"page"`
+	Limit   int               `json:"limit"`
+}
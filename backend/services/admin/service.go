@@ -0,0 +1,283 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/datatypes"
+
+	"launay-dot-one/models"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/sessions"
+)
+
+var (
+	ErrSelfAction     = errors.New("admins cannot perform this action on themselves")
+	ErrInvalidRole    = errors.New("invalid role")
+	ErrInvalidKind    = errors.New("restriction kind must be suspension or ban")
+	ErrExpiryRequired = errors.New("suspensions require a future expires_at")
+)
+
+type service struct {
+	userRepo        *repositories.UserRepository
+	guildRepo       *repositories.GuildRepository
+	messagingRepo   *repositories.MessagingRepository
+	restrictionRepo *repositories.AccountRestrictionRepository
+	auditRepo       *repositories.AdminAuditLogRepository
+	sessionSvc      sessions.Service
+	redisClient     *redis.Client
+}
+
+// NewService constructs the admin service.
+func NewService(
+	userRepo *repositories.UserRepository,
+	guildRepo *repositories.GuildRepository,
+	messagingRepo *repositories.MessagingRepository,
+	restrictionRepo *repositories.AccountRestrictionRepository,
+	auditRepo *repositories.AdminAuditLogRepository,
+	sessionSvc sessions.Service,
+	redisClient *redis.Client,
+) Service {
+	return &service{
+		userRepo:        userRepo,
+		guildRepo:       guildRepo,
+		messagingRepo:   messagingRepo,
+		restrictionRepo: restrictionRepo,
+		auditRepo:       auditRepo,
+		sessionSvc:      sessionSvc,
+		redisClient:     redisClient,
+	}
+}
+
+func (s *service) ListUsers(ctx context.Context, query string, page, limit int) (*UserPage, error) {
+	users, total, err := s.userRepo.Search(ctx, query, (page-1)*limit, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.PublicUser, len(users))
+	for i, u := range users {
+		out[i] = u.ToPublic()
+	}
+	return &UserPage{Users: out, Total: total, Page: page, Limit: limit}, nil
+}
+
+func (s *service) GetUser(ctx context.Context, userID string) (*UserDetail, error) {
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	history, err := s.restrictionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	detail := &UserDetail{
+		User:                  u.ToPublic(),
+		PasswordResetRequired: u.PasswordResetRequired,
+		Restrictions:          history,
+	}
+	now := time.Now()
+	for i := range history {
+		if history[i].Active(now) {
+			detail.ActiveRestriction = &history[i]
+			break
+		}
+	}
+	return detail, nil
+}
+
+func (s *service) Restrict(
+	ctx context.Context,
+	actorID, userID string,
+	kind models.RestrictionKind,
+	reason string,
+	expiresAt *time.Time,
+) (*models.AccountRestriction, error) {
+	if actorID == userID {
+		return nil, ErrSelfAction
+	}
+	if kind != models.RestrictionSuspension && kind != models.RestrictionBan {
+		return nil, ErrInvalidKind
+	}
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, ErrExpiryRequired
+	}
+	if kind == models.RestrictionSuspension && expiresAt == nil {
+		return nil, ErrExpiryRequired
+	}
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	rs := &models.AccountRestriction{
+		UserID:      userID,
+		Kind:        kind,
+		Reason:      reason,
+		ModeratorID: actorID,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   now,
+	}
+	if err := s.restrictionRepo.Create(ctx, rs); err != nil {
+		return nil, err
+	}
+	if err := s.sessionSvc.RevokeAll(ctx, userID); err != nil {
+		return nil, fmt.Errorf("revoke sessions: %w", err)
+	}
+
+	action := models.AdminActionSuspendUser
+	if kind == models.RestrictionBan {
+		action = models.AdminActionBanUser
+	}
+	meta := map[string]interface{}{"restriction_id": rs.ID}
+	if expiresAt != nil {
+		meta["expires_at"] = expiresAt
+	}
+	if err := s.audit(ctx, actorID, action, "user", userID, reason, meta); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func (s *service) LiftRestrictions(ctx context.Context, actorID, userID, reason string) error {
+	n, err := s.restrictionRepo.LiftActive(ctx, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	return s.audit(ctx, actorID, models.AdminActionLiftRestrictions, "user", userID, reason,
+		map[string]interface{}{"lifted": n})
+}
+
+func (s *service) ForcePasswordReset(ctx context.Context, actorID, userID, reason string) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+	if err := s.userRepo.UpdateFields(ctx, userID, map[string]interface{}{
+		"password_reset_required": true,
+	}); err != nil {
+		return err
+	}
+	if err := s.sessionSvc.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+	return s.audit(ctx, actorID, models.AdminActionForcePasswordReset, "user", userID, reason, nil)
+}
+
+func (s *service) RevokeSessions(ctx context.Context, actorID, userID, reason string) error {
+	if err := s.sessionSvc.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+	return s.audit(ctx, actorID, models.AdminActionRevokeSessions, "user", userID, reason, nil)
+}
+
+func (s *service) UpdateRole(ctx context.Context, actorID, userID, role, reason string) error {
+	if role != models.RoleUser && role != models.RoleAdmin {
+		return ErrInvalidRole
+	}
+	if actorID == userID {
+		return ErrSelfAction
+	}
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+	if err := s.userRepo.UpdateFields(ctx, userID, map[string]interface{}{"role": role}); err != nil {
+		return err
+	}
+	// the role travels inside the JWT, so force a fresh login
+	if err := s.sessionSvc.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+	return s.audit(ctx, actorID, models.AdminActionUpdateRole, "user", userID, reason,
+		map[string]interface{}{"before": u.Role, "after": role})
+}
+
+func (s *service) DeleteGuild(ctx context.Context, actorID, guildID, reason string) error {
+	g, err := s.guildRepo.GetByID(ctx, guildID)
+	if err != nil {
+		return fmt.Errorf("guild not found: %w", err)
+	}
+	if err := s.guildRepo.Delete(ctx, guildID); err != nil {
+		return err
+	}
+	return s.audit(ctx, actorID, models.AdminActionDeleteGuild, "guild", guildID, reason,
+		map[string]interface{}{"name": g.Name, "owner_id": g.OwnerID})
+}
+
+func (s *service) Stats(ctx context.Context) (*Stats, error) {
+	var (
+		st  Stats
+		err error
+	)
+	if st.Users, err = s.userRepo.Count(ctx); err != nil {
+		return nil, err
+	}
+	if st.Guilds, err = s.guildRepo.Count(ctx); err != nil {
+		return nil, err
+	}
+	if st.Messages, err = s.messagingRepo.CountMessages(ctx); err != nil {
+		return nil, err
+	}
+	if st.RedisBacklog, err = s.countKeys(ctx, "message:*"); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (s *service) ListAuditLog(ctx context.Context, actorID, targetID string, page, limit int) (*AuditPage, error) {
+	entries, total, err := s.auditRepo.List(ctx, actorID, targetID, (page-1)*limit, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditPage{Entries: entries, Total: total, Page: page, Limit: limit}, nil
+}
+
+// countKeys walks the keyspace with SCAN so a large backlog never blocks Redis.
+func (s *service) countKeys(ctx context.Context, pattern string) (int64, error) {
+	var (
+		cursor uint64
+		n      int64
+	)
+	for {
+		keys, next, err := s.redisClient.Scan(ctx, cursor, pattern, 1000).Result()
+		if err != nil {
+			return 0, err
+		}
+		n += int64(len(keys))
+		if next == 0 {
+			return n, nil
+		}
+		cursor = next
+	}
+}
+
+func (s *service) audit(
+	ctx context.Context,
+	actorID string,
+	action models.AdminAction,
+	targetType, targetID, reason string,
+	meta map[string]interface{},
+) error {
+	entry := &models.AdminAuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		CreatedAt:  time.Now(),
+	}
+	if meta != nil {
+		raw, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		entry.Metadata = datatypes.JSON(raw)
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
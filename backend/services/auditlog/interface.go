@@ -0,0 +1,42 @@
+package auditlog
+
+import (
+	"context"
+
+	mg "launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
+)
+
+// Service writes and reads guild audit log entries. It performs no
+// permission checks of its own; callers authorize before listing.
+type Service interface {
+	// Record appends an entry. Changes are computed from Before/After.
+	Record(ctx context.Context, r Record) error
+
+	// List returns one page of a guild's entries, newest first.
+	List(ctx context.Context, guildID string, f repositories.AuditLogFilter, page, limit int) (*Page, error)
+
+	// PruneExpired deletes entries older than the retention window.
+	PruneExpired(ctx context.Context) (int64, error)
+}
+
+// Record describes one mutation. Before is nil for creations and After
+// is nil for deletions; either may be a model or a plain map.
+type Record struct {
+	GuildID    string
+	ActorID    string // empty for automated actions
+	Action     mg.AuditAction
+	TargetType string
+	TargetID   string
+	Before     interface{}
+	After      interface{}
+	Reason     string // falls back to the reason carried by ctx
+}
+
+// Page is one page of audit entries.
+type Page struct {
+	Entries []mg.AuditLogEntry `json:"entries"`
+	Total   int64              `json:"total"`
+	Page    int                `json:"page"`
+	Limit   int                `json:"limit"`
+}
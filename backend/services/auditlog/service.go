@@ -0,0 +1,146 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"gorm.io/datatypes"
+
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
+)
+
+// ignoredFields never show up in a diff: they change on every write or
+// are already captured by the entry itself.
+var ignoredFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+type reasonKey struct{}
+
+// WithReason attaches a free-form audit reason to ctx, so services can
+// record it without threading it through every signature.
+func WithReason(ctx context.Context, reason string) context.Context {
+	if reason == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, reasonKey{}, reason)
+}
+
+// ReasonFrom returns the reason attached by WithReason, if any.
+func ReasonFrom(ctx context.Context) string {
+	r, _ := ctx.Value(reasonKey{}).(string)
+	return r
+}
+
+type service struct {
+	repo      *repositories.GuildAuditLogRepository
+	retention time.Duration
+}
+
+// NewService constructs the audit log service. A retention of zero keeps
+// entries forever.
+func NewService(repo *repositories.GuildAuditLogRepository, retention time.Duration) Service {
+	return &service{repo: repo, retention: retention}
+}
+
+func (s *service) Record(ctx context.Context, r Record) error {
+	changes, err := Diff(r.Before, r.After)
+	if err != nil {
+		return err
+	}
+	reason := r.Reason
+	if reason == "" {
+		reason = ReasonFrom(ctx)
+	}
+	entry := &guilds.AuditLogEntry{
+		GuildID:    r.GuildID,
+		ActorID:    r.ActorID,
+		Action:     r.Action,
+		TargetType: r.TargetType,
+		TargetID:   r.TargetID,
+		Changes:    changes,
+		Reason:     reason,
+		CreatedAt:  time.Now(),
+	}
+	return s.repo.Create(ctx, entry)
+}
+
+func (s *service) List(
+	ctx context.Context,
+	guildID string,
+	f repositories.AuditLogFilter,
+	page, limit int,
+) (*Page, error) {
+	entries, total, err := s.repo.List(ctx, guildID, f, (page-1)*limit, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{Entries: entries, Total: total, Page: page, Limit: limit}, nil
+}
+
+func (s *service) PruneExpired(ctx context.Context) (int64, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	return s.repo.DeleteOlderThan(ctx, time.Now().Add(-s.retention))
+}
+
+// Diff returns {"field": {"before": …, "after": …}} for every JSON field
+// that differs between before and after, or nil when nothing changed.
+func Diff(before, after interface{}) (datatypes.JSON, error) {
+	b, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]map[string]interface{}{}
+	for k, av := range a {
+		if ignoredFields[k] {
+			continue
+		}
+		if bv, ok := b[k]; !ok || !reflect.DeepEqual(bv, av) {
+			changes[k] = map[string]interface{}{"before": b[k], "after": av}
+		}
+	}
+	for k, bv := range b {
+		if ignoredFields[k] {
+			continue
+		}
+		if _, ok := a[k]; !ok {
+			changes[k] = map[string]interface{}{"before": bv, "after": nil}
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(raw), nil
+}
+
+// toMap flattens a model into its JSON representation.
+func toMap(v interface{}) (map[string]interface{}, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return map[string]interface{}{}, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
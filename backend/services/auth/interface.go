@@ -13,4 +13,8 @@ type Service interface {
 
 	// LoginUser validates credentials and returns a JWT.
 	LoginUser(ctx context.Context, email, password string) (string, error)
+
+	// ChangePassword replaces the password after checking the current one,
+	// clears any forced reset and revokes existing sessions.
+	ChangePassword(ctx context.Context, email, currentPassword, newPassword string) error
 }
@@ -3,39 +3,67 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"launay-dot-one/models"
 	"launay-dot-one/repositories"
+	"launay-dot-one/services/sessions"
+	usersvc "launay-dot-one/services/users"
+)
+
+var (
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrAccountRestricted     = errors.New("account restricted")
+	ErrPasswordResetRequired = errors.New("password reset required")
+	ErrUserExists            = errors.New("user already exists")
 )
 
 type Claims struct {
 	UserID string `json:"user_id"`
+	Role   string `json:"role"`
 	jwt.RegisteredClaims
 }
 
 type service struct {
-	userRepo  *repositories.UserRepository
-	jwtSecret string
-	ttl       time.Duration
+	userRepo        *repositories.UserRepository
+	restrictionRepo *repositories.AccountRestrictionRepository
+	sessionSvc      sessions.Service
+	jwtSecret       string
+	ttl             time.Duration
 }
 
 // NewService constructs the auth service.
 func NewService(
 	userRepo *repositories.UserRepository,
+	restrictionRepo *repositories.AccountRestrictionRepository,
+	sessionSvc sessions.Service,
 	jwtSecret string,
 	ttl time.Duration,
 ) Service {
-	return &service{userRepo, jwtSecret, ttl}
+	return &service{userRepo, restrictionRepo, sessionSvc, jwtSecret, ttl}
 }
 
 func (s *service) RegisterUser(ctx context.Context, user *models.User) error {
+	user.Username = strings.TrimSpace(user.Username)
+	if n := len([]rune(user.Username)); n < models.MinUsername || n > models.MaxUsername {
+		return usersvc.ErrInvalidUsername
+	}
 	if _, err := s.userRepo.GetByEmail(ctx, user.Email); err == nil {
-		return errors.New("user already exists")
+		return ErrUserExists
+	}
+	taken, err := s.userRepo.UsernameTaken(ctx, user.Username, "")
+	if err != nil {
+		return err
+	}
+	if taken {
+		return usersvc.ErrUsernameTaken
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
@@ -45,26 +73,46 @@ func (s *service) RegisterUser(ctx context.Context, user *models.User) error {
 
 	user.ID = uuid.NewString()
 	user.Password = string(hash)
-	user.Role = "user"
+	user.Role = models.RoleUser
+	user.PasswordResetRequired = false
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
+		// lost a race for the email or the name
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			if _, err := s.userRepo.GetByEmail(ctx, user.Email); err == nil {
+				return ErrUserExists
+			}
+			return usersvc.ErrUsernameTaken
+		}
 		return err
 	}
 	return nil
 }
 
 func (s *service) LoginUser(ctx context.Context, email, password string) (string, error) {
-	user, err := s.userRepo.GetByEmail(ctx, email)
+	user, err := s.authenticate(ctx, email, password)
 	if err != nil {
-		return "", errors.New("invalid credentials")
+		return "", err
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", errors.New("invalid credentials")
+	if user.PasswordResetRequired {
+		return "", ErrPasswordResetRequired
+	}
+	// signing back in during the grace period cancels a pending deletion
+	if user.DeletedAt != nil {
+		if err := s.userRepo.UpdateFields(ctx, user.ID, map[string]interface{}{
+			"deleted_at": nil,
+		}); err != nil {
+			return "", fmt.Errorf("cancel deletion: %w", err)
+		}
 	}
 
-	now := time.Now()
+	now, err := s.issueTime(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
 	claims := Claims{
 		UserID: user.ID,
+		Role:   user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(s.ttl)),
@@ -73,3 +121,66 @@ func (s *service) LoginUser(ctx context.Context, email, password string) (string
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(s.jwtSecret))
 }
+
+// issueTime returns when to issue the user's next token: now, unless their
+// sessions were revoked within this second, in which case a token with
+// this second's iat would be revoked too and issuing waits for the next.
+func (s *service) issueTime(ctx context.Context, userID string) (time.Time, error) {
+	now := time.Now()
+	revoked, err := s.sessionSvc.IsRevoked(ctx, userID, now)
+	if err != nil || !revoked {
+		return now, err
+	}
+	select {
+	case <-time.After(time.Until(now.Truncate(time.Second).Add(time.Second))):
+		return time.Now(), nil
+	case <-ctx.Done():
+		return time.Time{}, ctx.Err()
+	}
+}
+
+func (s *service) ChangePassword(ctx context.Context, email, currentPassword, newPassword string) error {
+	if newPassword == "" || newPassword == currentPassword {
+		return errors.New("new password must differ from the current one")
+	}
+	user, err := s.authenticate(ctx, email, currentPassword)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdateFields(ctx, user.ID, map[string]interface{}{
+		"password":                string(hash),
+		"password_reset_required": false,
+	}); err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+	return s.sessionSvc.RevokeAll(ctx, user.ID)
+}
+
+// authenticate checks credentials and refuses users under an active
+// suspension or ban.
+func (s *service) authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil || user.Bot {
+		return nil, ErrInvalidCredentials // bots sign in with tokens only
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	r, err := s.restrictionRepo.GetActive(ctx, user.ID, time.Now())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return user, nil
+	case err != nil:
+		return nil, err
+	default:
+		until := "permanently"
+		if r.ExpiresAt != nil {
+			until = "until " + r.ExpiresAt.Format(time.RFC3339)
+		}
+		return nil, fmt.Errorf("%w: %s %s: %s", ErrAccountRestricted, r.Kind, until, r.Reason)
+	}
+}
@@ -0,0 +1,64 @@
+package bots
+
+import (
+	"context"
+	"time"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/models/guilds"
+)
+
+type Service interface {
+	// Create registers a bot account owned by ownerID and returns it with
+	// its first token, which is never shown again. Bots can't own bots.
+	Create(ctx context.Context, ownerID string, in Input) (*View, string, error)
+
+	// ListMine returns the bots ownerID owns.
+	ListMine(ctx context.Context, ownerID string) ([]View, error)
+
+	// Get returns a bot's profile: public bots are visible to everyone,
+	// private ones only to their owner.
+	Get(ctx context.Context, botID, viewerID string) (*View, error)
+
+	// Update, Delete and the token methods are reserved to the owner.
+	// Deleting a bot removes it from every guild.
+	Update(ctx context.Context, botID, ownerID string, patch Patch) (*View, error)
+	Delete(ctx context.Context, botID, ownerID string) error
+	CreateToken(ctx context.Context, botID, ownerID, name string) (*m.BotToken, string, error)
+	ListTokens(ctx context.Context, botID, ownerID string) ([]m.BotToken, error)
+	RevokeToken(ctx context.Context, botID, tokenID, ownerID string) error
+
+	// Authorize adds the bot to a guild with a managed role carrying perms
+	// (the bot's default permissions when nil). The actor needs
+	// manage-guild there and can only grant permissions they hold.
+	Authorize(ctx context.Context, botID, guildID string, perms *uint64, actorID string) (*guilds.GuildRole, error)
+
+	// AuthenticateBot and ThrottleBot back the "Authorization: Bot" scheme
+	// in the auth middleware and websocket handshakes.
+	AuthenticateBot(ctx context.Context, token string) (string, error)
+	ThrottleBot(ctx context.Context, botID string) (bool, time.Duration, error)
+}
+
+// Input describes a new bot.
+type Input struct {
+	Username           string
+	Description        string
+	Public             bool
+	DefaultPermissions uint64
+}
+
+// Patch lists the bot fields an update may change; nil leaves a field as
+// it is.
+type Patch struct {
+	Username           *string
+	Description        *string
+	Public             *bool
+	DefaultPermissions *uint64
+	InteractionsURL    *string // "" switches back to gateway delivery
+}
+
+// View is a bot together with its public user profile.
+type View struct {
+	m.Bot
+	User m.PublicUser `json:"user"`
+}
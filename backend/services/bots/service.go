@@ -0,0 +1,496 @@
+package bots
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+	"launay-dot-one/services/events"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotOwner          = errors.New("only the bot's owner can do that")
+	ErrBotOwner          = errors.New("bots can't own bots")
+	ErrInvalidUsername   = fmt.Errorf("bot username must be %d to %d characters", m.MinBotUsername, m.MaxBotUsername)
+	ErrUsernameTaken     = errors.New("username is already taken")
+	ErrInvalidDesc       = fmt.Errorf("bot description must be at most %d characters", m.MaxBotDescription)
+	ErrInvalidTokenName  = fmt.Errorf("token name must be at most %d characters", m.MaxBotTokenName)
+	ErrTooManyBots       = fmt.Errorf("a user can own at most %d bots", m.MaxBotsPerOwner)
+	ErrTooManyTokens     = fmt.Errorf("a bot can have at most %d active tokens", m.MaxTokensPerBot)
+	ErrInvalidToken      = errors.New("invalid bot token")
+	ErrAlreadyInGuild    = errors.New("bot is already in this guild")
+	ErrBannedFromGuild   = errors.New("bot is banned from this guild")
+	ErrCannotGrant       = errors.New("cannot grant permissions you don't hold")
+	ErrBotRestricted     = errors.New("bot account is restricted")
+	ErrUnknownPermission = errors.New("unknown permission bits")
+	ErrInvalidURL        = fmt.Errorf("interactions URL must be an http(s) URL of at most %d characters", m.MaxInteractionsURL)
+)
+
+const (
+	tokenBytes = 32
+
+	// rateLimit requests per rateWindow, per bot
+	rateLimit  = 50
+	rateWindow = time.Second
+
+	// last-used timestamps are only written this often
+	touchInterval = time.Minute
+)
+
+type service struct {
+	repo            *repositories.BotRepository
+	userRepo        *repositories.UserRepository
+	restrictionRepo *repositories.AccountRestrictionRepository
+	memberRepo      *repositories.GuildMemberRepository
+	banRepo         *repositories.GuildBanRepository
+	permSvc         permissions.Service
+	audit           auditlog.Service
+	events          events.Publisher
+	redisClient     *redis.Client
+}
+
+func NewService(
+	repo *repositories.BotRepository,
+	userRepo *repositories.UserRepository,
+	restrictionRepo *repositories.AccountRestrictionRepository,
+	memberRepo *repositories.GuildMemberRepository,
+	banRepo *repositories.GuildBanRepository,
+	permSvc permissions.Service,
+	audit auditlog.Service,
+	events events.Publisher,
+	redisClient *redis.Client,
+) Service {
+	return &service{
+		repo:            repo,
+		userRepo:        userRepo,
+		restrictionRepo: restrictionRepo,
+		memberRepo:      memberRepo,
+		banRepo:         banRepo,
+		permSvc:         permSvc,
+		audit:           audit,
+		events:          events,
+		redisClient:     redisClient,
+	}
+}
+
+func generateToken() (token, hash string, err error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateKeyPair returns a hex-encoded Ed25519 key pair for signing
+// interaction deliveries.
+func generateKeyPair() (pub, priv string, err error) {
+	pk, sk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(pk), hex.EncodeToString(sk), nil
+}
+
+// validInteractionsURL accepts an empty URL, which switches the bot back
+// to gateway delivery.
+func validInteractionsURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	if len(raw) > m.MaxInteractionsURL {
+		return ErrInvalidURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		utils.IsInternalHost(u.Hostname()) {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+func validUsername(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if n := len([]rune(name)); n < m.MinBotUsername || n > m.MaxBotUsername {
+		return "", ErrInvalidUsername
+	}
+	return name, nil
+}
+
+func validPermissions(perms uint64) error {
+	if perms&^guilds.PermAllDefined != 0 {
+		return ErrUnknownPermission
+	}
+	return nil
+}
+
+// view loads the bot's user row to go with it.
+func (s *service) view(ctx context.Context, b *m.Bot) (*View, error) {
+	u, err := s.userRepo.GetByID(ctx, b.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &View{Bot: *b, User: u.ToPublic()}, nil
+}
+
+// owned loads a bot and checks that ownerID owns it.
+func (s *service) owned(ctx context.Context, botID, ownerID string) (*m.Bot, error) {
+	b, err := s.repo.GetByID(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	if b.OwnerID != ownerID {
+		return nil, ErrNotOwner
+	}
+	return b, nil
+}
+
+func (s *service) Create(ctx context.Context, ownerID string, in Input) (*View, string, error) {
+	owner, err := s.userRepo.GetByID(ctx, ownerID)
+	if err != nil {
+		return nil, "", err
+	}
+	if owner.Bot {
+		return nil, "", ErrBotOwner
+	}
+	name, err := validUsername(in.Username)
+	if err != nil {
+		return nil, "", err
+	}
+	if len([]rune(in.Description)) > m.MaxBotDescription {
+		return nil, "", ErrInvalidDesc
+	}
+	if err := validPermissions(in.DefaultPermissions); err != nil {
+		return nil, "", err
+	}
+	n, err := s.repo.CountByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, "", err
+	}
+	if n >= m.MaxBotsPerOwner {
+		return nil, "", ErrTooManyBots
+	}
+	token, hash, err := generateToken()
+	if err != nil {
+		return nil, "", err
+	}
+	pub, priv, err := generateKeyPair()
+	if err != nil {
+		return nil, "", err
+	}
+
+	id := uuid.NewString()
+	u := &m.User{
+		ID:       id,
+		Username: name,
+		Email:    id + "@bots.invalid", // bots never sign in by email
+		Password: "!",                  // not a bcrypt hash, so no password matches
+		Role:     m.RoleUser,
+		Status:   m.StatusOffline,
+		Bot:      true,
+	}
+	b := &m.Bot{
+		OwnerID:            ownerID,
+		Description:        in.Description,
+		Public:             in.Public,
+		DefaultPermissions: in.DefaultPermissions,
+		PublicKey:          pub,
+		PrivateKey:         priv,
+	}
+	t := &m.BotToken{Name: "default", TokenHash: hash}
+	if err := s.repo.Create(ctx, u, b, t); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, "", ErrUsernameTaken
+		}
+		return nil, "", err
+	}
+	return &View{Bot: *b, User: u.ToPublic()}, token, nil
+}
+
+func (s *service) ListMine(ctx context.Context, ownerID string) ([]View, error) {
+	list, err := s.repo.ListByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]View, 0, len(list))
+	for i := range list {
+		v, err := s.view(ctx, &list[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *v)
+	}
+	return out, nil
+}
+
+func (s *service) Get(ctx context.Context, botID, viewerID string) (*View, error) {
+	b, err := s.repo.GetByID(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	if b.OwnerID != viewerID {
+		if !b.Public {
+			return nil, gorm.ErrRecordNotFound // private bots stay hidden
+		}
+		b.InteractionsURL = ""
+	}
+	return s.view(ctx, b)
+}
+
+func (s *service) Update(ctx context.Context, botID, ownerID string, patch Patch) (*View, error) {
+	b, err := s.owned(ctx, botID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	var username string
+	if patch.Username != nil {
+		if username, err = validUsername(*patch.Username); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Description != nil {
+		if len([]rune(*patch.Description)) > m.MaxBotDescription {
+			return nil, ErrInvalidDesc
+		}
+		b.Description = *patch.Description
+	}
+	if patch.Public != nil {
+		b.Public = *patch.Public
+	}
+	if patch.DefaultPermissions != nil {
+		if err := validPermissions(*patch.DefaultPermissions); err != nil {
+			return nil, err
+		}
+		b.DefaultPermissions = *patch.DefaultPermissions
+	}
+	if patch.InteractionsURL != nil {
+		if err := validInteractionsURL(*patch.InteractionsURL); err != nil {
+			return nil, err
+		}
+		b.InteractionsURL = *patch.InteractionsURL
+	}
+	if b.PublicKey == "" { // bots created before interactions had no key
+		if b.PublicKey, b.PrivateKey, err = generateKeyPair(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.Update(ctx, b, username); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+	return s.view(ctx, b)
+}
+
+func (s *service) Delete(ctx context.Context, botID, ownerID string) error {
+	if _, err := s.owned(ctx, botID, ownerID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, botID)
+}
+
+func (s *service) CreateToken(ctx context.Context, botID, ownerID, name string) (*m.BotToken, string, error) {
+	if _, err := s.owned(ctx, botID, ownerID); err != nil {
+		return nil, "", err
+	}
+	name = strings.TrimSpace(name)
+	if len([]rune(name)) > m.MaxBotTokenName {
+		return nil, "", ErrInvalidTokenName
+	}
+	active, err := s.repo.ListTokens(ctx, botID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(active) >= m.MaxTokensPerBot {
+		return nil, "", ErrTooManyTokens
+	}
+	token, hash, err := generateToken()
+	if err != nil {
+		return nil, "", err
+	}
+	t := &m.BotToken{BotID: botID, Name: name, TokenHash: hash}
+	if err := s.repo.CreateToken(ctx, t); err != nil {
+		return nil, "", err
+	}
+	return t, token, nil
+}
+
+func (s *service) ListTokens(ctx context.Context, botID, ownerID string) ([]m.BotToken, error) {
+	if _, err := s.owned(ctx, botID, ownerID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListTokens(ctx, botID)
+}
+
+func (s *service) RevokeToken(ctx context.Context, botID, tokenID, ownerID string) error {
+	if _, err := s.owned(ctx, botID, ownerID); err != nil {
+		return err
+	}
+	ok, err := s.repo.RevokeToken(ctx, botID, tokenID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *service) Authorize(
+	ctx context.Context,
+	botID, guildID string,
+	perms *uint64,
+	actorID string,
+) (*guilds.GuildRole, error) {
+	b, err := s.repo.GetByID(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	if !b.Public && b.OwnerID != actorID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := s.permSvc.Require(ctx, guildID, actorID, guilds.PermManageGuild); err != nil {
+		return nil, err
+	}
+	grant := b.DefaultPermissions
+	if perms != nil {
+		grant = *perms
+	}
+	if err := validPermissions(grant); err != nil {
+		return nil, err
+	}
+	held, err := s.permSvc.GuildPermissions(ctx, guildID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if grant&^held != 0 {
+		return nil, ErrCannotGrant
+	}
+	if _, err := s.memberRepo.Get(ctx, guildID, botID); err == nil {
+		return nil, ErrAlreadyInGuild
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	now := time.Now()
+	if _, err := s.banRepo.GetActive(ctx, guildID, botID, now); err == nil {
+		return nil, ErrBannedFromGuild
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	u, err := s.userRepo.GetByID(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &guilds.GuildRole{
+		GuildID:     guildID,
+		Name:        u.Username,
+		Permissions: grant,
+		Position:    1, // just above @everyone; admins may move it up
+		Managed:     true,
+		BotID:       &botID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	mem := &guilds.GuildMember{GuildID: guildID, UserID: botID, JoinedAt: now, UpdatedAt: now}
+	if err := s.repo.AddToGuild(ctx, role, mem); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrAlreadyInGuild
+		}
+		return nil, err
+	}
+	if err := s.audit.Record(ctx, auditlog.Record{
+		GuildID:    guildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditRoleCreate,
+		TargetType: guilds.AuditTargetRole,
+		TargetID:   role.ID,
+		After:      role,
+	}); err != nil {
+		return nil, err
+	}
+	if err := s.audit.Record(ctx, auditlog.Record{
+		GuildID:    guildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditBotAdd,
+		TargetType: guilds.AuditTargetMember,
+		TargetID:   botID,
+		After:      map[string]interface{}{"role_ids": mem.RoleIDs, "permissions": grant},
+	}); err != nil {
+		return nil, err
+	}
+	_ = s.events.Publish(ctx, guildID, m.EventMemberJoined, events.MemberJoined{
+		UserID:   botID,
+		JoinedAt: now,
+		AddedBy:  actorID,
+	})
+	return role, nil
+}
+
+func (s *service) AuthenticateBot(ctx context.Context, token string) (string, error) {
+	t, err := s.repo.GetActiveToken(ctx, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrInvalidToken
+	}
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if _, err := s.restrictionRepo.GetActive(ctx, t.BotID, now); err == nil {
+		return "", ErrBotRestricted
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= touchInterval {
+		if err := s.repo.TouchToken(ctx, t.ID, now); err != nil {
+			return "", err
+		}
+	}
+	return t.BotID, nil
+}
+
+// ThrottleBot allows rateLimit requests per fixed rateWindow.
+func (s *service) ThrottleBot(ctx context.Context, botID string) (bool, time.Duration, error) {
+	key := "bot:rate:" + botID
+	n, err := s.redisClient.Incr(ctx, key).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	if n == 1 {
+		if err := s.redisClient.PExpire(ctx, key, rateWindow).Err(); err != nil {
+			return false, 0, err
+		}
+	}
+	if n <= rateLimit {
+		return true, 0, nil
+	}
+	ttl, err := s.redisClient.PTTL(ctx, key).Result()
+	if err != nil || ttl < 0 {
+		ttl = rateWindow
+	}
+	return false, ttl, nil
+}
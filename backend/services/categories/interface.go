@@ -6,10 +6,22 @@ import (
 	"launay-dot-one/models/guilds"
 )
 
+// Service manages a guild's categories. Every change requires
+// manage-channels.
 type Service interface {
-	Create(ctx context.Context, c *guilds.Category, channels []*guilds.Channel) error
+	Create(ctx context.Context, c *guilds.Category, channels []*guilds.Channel, actorID string) error
 	Get(ctx context.Context, id string) (*guilds.Category, error)
 	List(ctx context.Context, guildID string) ([]guilds.Category, error)
-	Update(ctx context.Context, c *guilds.Category) error
-	Delete(ctx context.Context, id string) error
+	Update(ctx context.Context, c *guilds.Category, actorID string) error
+	Delete(ctx context.Context, id, actorID string) error
+
+	// Reorder applies the moves and renumbers every category densely in
+	// one transaction. Requires manage-channels.
+	Reorder(ctx context.Context, guildID string, moves []Move, actorID string) error
+}
+
+// Move places a category at a position.
+type Move struct {
+	ID       string
+	Position int
 }
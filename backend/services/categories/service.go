@@ -2,37 +2,81 @@ package categories
 
 import (
 	"context"
+	"errors"
 
 	"launay-dot-one/models/guilds"
 	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+	channelsvc "launay-dot-one/services/channels"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+)
+
+var (
+	ErrUnknownCategory = errors.New("category does not belong to this guild")
+	ErrDuplicateMove   = errors.New("category listed more than once")
 )
 
 type service struct {
 	repo        *repositories.CategoryRepository
 	channelRepo *repositories.ChannelRepository
+	permSvc     permissions.Service
+	audit       auditlog.Service
 }
 
 func NewService(
 	repo *repositories.CategoryRepository,
 	channelRepo *repositories.ChannelRepository,
+	permSvc permissions.Service,
+	audit auditlog.Service,
 ) Service {
-	return &service{repo: repo, channelRepo: channelRepo}
+	return &service{repo: repo, channelRepo: channelRepo, permSvc: permSvc, audit: audit}
 }
 
 func (s *service) Create(
 	ctx context.Context,
 	c *guilds.Category,
 	channels []*guilds.Channel,
+	actorID string,
 ) error {
+	if err := s.permSvc.Require(ctx, c.GuildID, actorID, guilds.PermManageChannels); err != nil {
+		return err
+	}
+	for _, ch := range channels {
+		if err := channelsvc.ValidateSettings(ch); err != nil {
+			return err
+		}
+	}
 	if err := s.repo.Create(ctx, c); err != nil {
 		return err
 	}
+	if err := s.audit.Record(ctx, auditlog.Record{
+		GuildID:    c.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditCategoryCreate,
+		TargetType: guilds.AuditTargetCategory,
+		TargetID:   c.ID,
+		After:      c,
+	}); err != nil {
+		return err
+	}
 	for _, ch := range channels {
 		ch.GuildID = c.GuildID
 		ch.CategoryID = &c.ID
+		ch.PermissionsSynced = true // a new category has no overwrites to copy
 		if err := s.channelRepo.Create(ctx, ch); err != nil {
 			return err
 		}
+		if err := s.audit.Record(ctx, auditlog.Record{
+			GuildID:    c.GuildID,
+			ActorID:    actorID,
+			Action:     guilds.AuditChannelCreate,
+			TargetType: guilds.AuditTargetChannel,
+			TargetID:   ch.ID,
+			After:      ch,
+		}); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -45,10 +89,111 @@ func (s *service) List(ctx context.Context, guildID string) ([]guilds.Category,
 	return s.repo.ListByGuild(ctx, guildID)
 }
 
-func (s *service) Update(ctx context.Context, c *guilds.Category) error {
-	return s.repo.Update(ctx, c)
+func (s *service) Update(ctx context.Context, c *guilds.Category, actorID string) error {
+	before, err := s.repo.GetByID(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.permSvc.Require(ctx, before.GuildID, actorID, guilds.PermManageChannels); err != nil {
+		return err
+	}
+	// only Reorder moves a category
+	c.GuildID = before.GuildID
+	c.Position = before.Position
+	if err := s.repo.Update(ctx, c); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    before.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditCategoryUpdate,
+		TargetType: guilds.AuditTargetCategory,
+		TargetID:   c.ID,
+		Before:     before,
+		After:      c,
+	})
 }
 
-func (s *service) Delete(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+func (s *service) Delete(ctx context.Context, id, actorID string) error {
+	before, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.permSvc.Require(ctx, before.GuildID, actorID, guilds.PermManageChannels); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := s.channelRepo.UnsyncCategory(ctx, id); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    before.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditCategoryDelete,
+		TargetType: guilds.AuditTargetCategory,
+		TargetID:   id,
+		Before:     before,
+	})
+}
+
+func (s *service) Reorder(ctx context.Context, guildID string, moves []Move, actorID string) error {
+	if err := s.permSvc.Require(ctx, guildID, actorID, guilds.PermManageChannels); err != nil {
+		return err
+	}
+	requested := make(map[string]int, len(moves))
+	for _, m := range moves {
+		if _, dup := requested[m.ID]; dup {
+			return ErrDuplicateMove
+		}
+		requested[m.ID] = m.Position
+	}
+
+	var before, after []guilds.Category
+	err := s.repo.Reorder(ctx, guildID, func(rows []guilds.Category) ([]guilds.Category, error) {
+		items := make([]utils.Positioned, len(rows))
+		index := make(map[string]guilds.Category, len(rows))
+		seen := 0
+		for i, c := range rows {
+			index[c.ID] = c
+			items[i] = utils.Positioned{ID: c.ID, Position: c.Position}
+			if pos, ok := requested[c.ID]; ok {
+				seen++
+				items[i].Position = pos
+				items[i].Moved = true
+			}
+		}
+		if seen != len(requested) {
+			return nil, ErrUnknownCategory
+		}
+		for pos, id := range utils.DenseOrder(items) {
+			c := index[id]
+			if c.Position == pos {
+				continue
+			}
+			before = append(before, c)
+			c.Position = pos
+			after = append(after, c)
+		}
+		return after, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range after {
+		if err := s.audit.Record(ctx, auditlog.Record{
+			GuildID:    guildID,
+			ActorID:    actorID,
+			Action:     guilds.AuditCategoryUpdate,
+			TargetType: guilds.AuditTargetCategory,
+			TargetID:   after[i].ID,
+			Before:     map[string]interface{}{"position": before[i].Position},
+			After:      map[string]interface{}{"position": after[i].Position},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,101 @@
+package categories
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+	"launay-dot-one/services/permissions"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	testGuild = "guild-1"
+	manager   = "manager"
+	member    = "member"
+)
+
+// fakePermissions grants manage-channels to manager only.
+type fakePermissions struct{ permissions.Service }
+
+func (fakePermissions) Require(_ context.Context, guildID, userID string, perm uint64) error {
+	if guildID == testGuild && userID == manager && perm == guilds.PermManageChannels {
+		return nil
+	}
+	return permissions.ErrMissingPermission
+}
+
+type fakeAudit struct {
+	auditlog.Service
+	records []auditlog.Record
+}
+
+func (f *fakeAudit) Record(_ context.Context, r auditlog.Record) error {
+	f.records = append(f.records, r)
+	return nil
+}
+
+func newTestService(t *testing.T) (*service, *fakeAudit, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "categories.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the model's uuid_generate_v4() default is Postgres only
+	if err := db.Exec(`CREATE TABLE categories (
+		id TEXT PRIMARY KEY, guild_id TEXT NOT NULL, name TEXT, position INTEGER,
+		created_at DATETIME, updated_at DATETIME)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	audit := &fakeAudit{}
+	svc := NewService(
+		repositories.NewCategoryRepository(db), repositories.NewChannelRepository(db),
+		fakePermissions{}, audit,
+	).(*service)
+	return svc, audit, db
+}
+
+func TestChangesRequireManageChannels(t *testing.T) {
+	ctx := context.Background()
+	svc, audit, db := newTestService(t)
+	if err := db.Create(&guilds.Category{ID: "cat-1", GuildID: testGuild, Name: "Text"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Create(ctx, &guilds.Category{ID: "cat-2", GuildID: testGuild, Name: "Voice"}, nil, member); !errors.Is(err, permissions.ErrMissingPermission) {
+		t.Errorf("Create by a member: %v, want ErrMissingPermission", err)
+	}
+	if err := svc.Update(ctx, &guilds.Category{ID: "cat-1", Name: "Renamed"}, member); !errors.Is(err, permissions.ErrMissingPermission) {
+		t.Errorf("Update by a member: %v, want ErrMissingPermission", err)
+	}
+	if err := svc.Delete(ctx, "cat-1", member); !errors.Is(err, permissions.ErrMissingPermission) {
+		t.Errorf("Delete by a member: %v, want ErrMissingPermission", err)
+	}
+
+	cats, err := svc.List(ctx, testGuild)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cats) != 1 || cats[0].Name != "Text" {
+		t.Fatalf("categories after denied changes = %+v", cats)
+	}
+	if len(audit.records) != 0 {
+		t.Errorf("denied changes were audited: %+v", audit.records)
+	}
+
+	if err := svc.Update(ctx, &guilds.Category{ID: "cat-1", Name: "Renamed"}, manager); err != nil {
+		t.Fatalf("Update by a manager: %v", err)
+	}
+	if got, err := svc.Get(ctx, "cat-1"); err != nil || got.Name != "Renamed" {
+		t.Errorf("category after the manager's update = %+v, %v", got, err)
+	}
+	if len(audit.records) != 1 || audit.records[0].ActorID != manager {
+		t.Errorf("audit records = %+v", audit.records)
+	}
+}
@@ -7,10 +7,33 @@ import (
 )
 
 type Service interface {
-	Create(ctx context.Context, ch *guilds.Channel, categoryID *string) error
+	Create(ctx context.Context, ch *guilds.Channel, categoryID *string, actorID string) error
 	Get(ctx context.Context, id string) (*guilds.Channel, error)
 	ListByGuild(ctx context.Context, guildID string) ([]guilds.Channel, error)
 	ListByCategory(ctx context.Context, categoryID string) ([]guilds.Channel, error)
-	Update(ctx context.Context, ch *guilds.Channel) error
-	Delete(ctx context.Context, id string) error
+	Update(ctx context.Context, ch *guilds.Channel, actorID string) error
+	Delete(ctx context.Context, id, actorID string) error
+
+	// Reorder applies the moves and renumbers every channel densely, per
+	// category, in one transaction. Requires manage-channels.
+	Reorder(ctx context.Context, guildID string, moves []Move, actorID string) error
+
+	// SyncPermissions replaces the channel's overwrites with copies of its
+	// category's and marks it synced. Requires manage-roles.
+	SyncPermissions(ctx context.Context, channelID, actorID string) (*guilds.Channel, error)
+
+	// Follow makes targetChannelID receive the announcement channel's
+	// cross-posts. Requires manage-webhooks in the target's guild.
+	Follow(ctx context.Context, channelID, targetChannelID, actorID string) (*guilds.ChannelFollower, error)
+	Unfollow(ctx context.Context, followerID, actorID string) error
+	// ListFollowers requires manage-channels in the announcement's guild.
+	ListFollowers(ctx context.Context, channelID, actorID string) ([]guilds.ChannelFollower, error)
+}
+
+// Move places a channel at a position inside a category; a nil or empty
+// CategoryID moves it to the top level.
+type Move struct {
+	ID         string
+	Position   int
+	CategoryID *string
 }
@@ -2,26 +2,132 @@ package channels
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"launay-dot-one/models/guilds"
 	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownChannel  = errors.New("channel does not belong to this guild")
+	ErrUnknownCategory = errors.New("category does not belong to this guild")
+	ErrDuplicateMove   = errors.New("channel listed more than once")
+	ErrNoCategory      = errors.New("channel is not in a category")
+
+	ErrInvalidType      = errors.New("unknown channel type")
+	ErrInvalidSettings  = errors.New("invalid channel settings")
+	ErrTypeChange       = errors.New("only text and announcement channels can convert into each other")
+	ErrNotAnnouncement  = errors.New("channel is not an announcement channel")
+	ErrInvalidTarget    = errors.New("followers must post into a text channel")
+	ErrAlreadyFollowing = errors.New("target channel already follows this channel")
 )
 
 type service struct {
-	repo *repositories.ChannelRepository
+	repo         *repositories.ChannelRepository
+	categoryRepo *repositories.CategoryRepository
+	followerRepo *repositories.ChannelFollowerRepository
+	permRepo     *repositories.PermissionOverwriteRepository
+	permSvc      permissions.Service
+	audit        auditlog.Service
 }
 
-func NewService(repo *repositories.ChannelRepository) Service {
-	return &service{repo}
+func NewService(
+	repo *repositories.ChannelRepository,
+	categoryRepo *repositories.CategoryRepository,
+	followerRepo *repositories.ChannelFollowerRepository,
+	permRepo *repositories.PermissionOverwriteRepository,
+	permSvc permissions.Service,
+	audit auditlog.Service,
+) Service {
+	return &service{repo, categoryRepo, followerRepo, permRepo, permSvc, audit}
+}
+
+// ValidateSettings checks the channel's type and normalises its settings:
+// fields that don't apply to the type are cleared and voice channels get the
+// default bitrate.
+func ValidateSettings(ch *guilds.Channel) error {
+	if ch.Type == "" {
+		ch.Type = string(guilds.ChannelText)
+	}
+	t := guilds.ChannelType(ch.Type)
+	if !t.Valid() {
+		return ErrInvalidType
+	}
+	if len(ch.Topic) > guilds.MaxTopicLength {
+		return fmt.Errorf("%w: topic is longer than %d characters", ErrInvalidSettings, guilds.MaxTopicLength)
+	}
+
+	if t.TextBased() {
+		if ch.SlowmodeSeconds < 0 || ch.SlowmodeSeconds > guilds.MaxSlowmodeSeconds {
+			return fmt.Errorf("%w: slowmode must be between 0 and %d seconds", ErrInvalidSettings, guilds.MaxSlowmodeSeconds)
+		}
+		ch.Bitrate, ch.UserLimit = 0, 0
+		return nil
+	}
+
+	ch.NSFW, ch.SlowmodeSeconds = false, 0
+	if t != guilds.ChannelStage {
+		ch.Topic = ""
+	}
+	if ch.Bitrate == 0 {
+		ch.Bitrate = guilds.DefaultBitrate
+	}
+	if ch.Bitrate < guilds.MinBitrate || ch.Bitrate > guilds.MaxBitrate {
+		return fmt.Errorf("%w: bitrate must be between %d and %d", ErrInvalidSettings, guilds.MinBitrate, guilds.MaxBitrate)
+	}
+	limit := guilds.MaxVoiceUserLimit
+	if t == guilds.ChannelStage {
+		limit = guilds.MaxStageUserLimit
+	}
+	if ch.UserLimit < 0 || ch.UserLimit > limit {
+		return fmt.Errorf("%w: user limit must be between 0 and %d", ErrInvalidSettings, limit)
+	}
+	return nil
 }
 
 func (s *service) Create(
 	ctx context.Context,
 	ch *guilds.Channel,
 	categoryID *string,
+	actorID string,
 ) error {
+	if err := s.permSvc.Require(ctx, ch.GuildID, actorID, guilds.PermManageChannels); err != nil {
+		return err
+	}
+	if err := ValidateSettings(ch); err != nil {
+		return err
+	}
+	if categoryID != nil {
+		if err := s.requireCategory(ctx, ch.GuildID, *categoryID); err != nil {
+			return err
+		}
+	}
 	ch.CategoryID = categoryID
-	return s.repo.Create(ctx, ch)
+	ch.PermissionsSynced = false
+	if err := s.repo.Create(ctx, ch); err != nil {
+		return err
+	}
+	// a channel created inside a category starts out synced to it
+	if categoryID != nil {
+		if err := s.permRepo.SyncChannels(ctx, *categoryID, []string{ch.ID}); err != nil {
+			return err
+		}
+		ch.PermissionsSynced = true
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    ch.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditChannelCreate,
+		TargetType: guilds.AuditTargetChannel,
+		TargetID:   ch.ID,
+		After:      ch,
+	})
 }
 
 func (s *service) Get(ctx context.Context, id string) (*guilds.Channel, error) {
@@ -36,10 +142,329 @@ func (s *service) ListByCategory(ctx context.Context, categoryID string) ([]guil
 	return s.repo.ListByCategory(ctx, categoryID)
 }
 
-func (s *service) Update(ctx context.Context, ch *guilds.Channel) error {
-	return s.repo.Update(ctx, ch)
+func (s *service) Update(ctx context.Context, ch *guilds.Channel, actorID string) error {
+	before, err := s.repo.GetByID(ctx, ch.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.permSvc.Require(ctx, before.GuildID, actorID, guilds.PermManageChannels); err != nil {
+		return err
+	}
+	if ch.CategoryID != nil && *ch.CategoryID == "" {
+		ch.CategoryID = nil
+	}
+	if ch.CategoryID != nil {
+		if err := s.requireCategory(ctx, before.GuildID, *ch.CategoryID); err != nil {
+			return err
+		}
+	}
+	// a channel never moves between guilds, only Reorder moves it within
+	// one, and only overwrite edits or an explicit sync change its sync state
+	ch.GuildID = before.GuildID
+	ch.Position = before.Position
+	ch.PermissionsSynced = before.PermissionsSynced
+	if ch.Type == "" {
+		ch.Type = before.Type
+	}
+	if ch.Type != before.Type && !convertible(before.Type, ch.Type) {
+		return ErrTypeChange
+	}
+	if err := ValidateSettings(ch); err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, ch); err != nil {
+		return err
+	}
+	// followers only make sense while the channel publishes announcements
+	if before.Type == string(guilds.ChannelAnnouncement) && ch.Type != before.Type {
+		if err := s.followerRepo.DeleteByChannel(ctx, ch.ID); err != nil {
+			return err
+		}
+	}
+	if categoryKey(ch.CategoryID) != categoryKey(before.CategoryID) {
+		if err := s.followCategory(ctx, ch); err != nil {
+			return err
+		}
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    before.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditChannelUpdate,
+		TargetType: guilds.AuditTargetChannel,
+		TargetID:   ch.ID,
+		Before:     before,
+		After:      ch,
+	})
+}
+
+// requireCategory checks that the category exists in the guild.
+func (s *service) requireCategory(ctx context.Context, guildID, categoryID string) error {
+	cat, err := s.categoryRepo.GetByID(ctx, categoryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUnknownCategory
+	}
+	if err != nil {
+		return err
+	}
+	if cat.GuildID != guildID {
+		return ErrUnknownCategory
+	}
+	return nil
+}
+
+func (s *service) Delete(ctx context.Context, id, actorID string) error {
+	before, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.permSvc.Require(ctx, before.GuildID, actorID, guilds.PermManageChannels); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    before.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditChannelDelete,
+		TargetType: guilds.AuditTargetChannel,
+		TargetID:   id,
+		Before:     before,
+	})
+}
+
+func (s *service) Reorder(ctx context.Context, guildID string, moves []Move, actorID string) error {
+	if err := s.permSvc.Require(ctx, guildID, actorID, guilds.PermManageChannels); err != nil {
+		return err
+	}
+	cats, err := s.categoryRepo.ListByGuild(ctx, guildID)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(cats))
+	for _, c := range cats {
+		known[c.ID] = true
+	}
+	byID := make(map[string]Move, len(moves))
+	for _, m := range moves {
+		if _, dup := byID[m.ID]; dup {
+			return ErrDuplicateMove
+		}
+		if m.CategoryID != nil && *m.CategoryID == "" {
+			m.CategoryID = nil
+		}
+		if m.CategoryID != nil && !known[*m.CategoryID] {
+			return ErrUnknownCategory
+		}
+		byID[m.ID] = m
+	}
+
+	var before, after []guilds.Channel
+	err = s.repo.Reorder(ctx, guildID, func(rows []guilds.Channel) ([]guilds.Channel, error) {
+		seen := 0
+		containers := map[string][]utils.Positioned{} // "" = top level
+		index := make(map[string]guilds.Channel, len(rows))
+		for _, ch := range rows {
+			index[ch.ID] = ch
+			cat := categoryKey(ch.CategoryID)
+			item := utils.Positioned{ID: ch.ID, Position: ch.Position}
+			if m, ok := byID[ch.ID]; ok {
+				seen++
+				cat = categoryKey(m.CategoryID)
+				item.Position = m.Position
+				item.Moved = true
+			}
+			containers[cat] = append(containers[cat], item)
+		}
+		if seen != len(byID) {
+			return nil, ErrUnknownChannel
+		}
+
+		var changed []guilds.Channel
+		for cat, items := range containers {
+			for pos, id := range utils.DenseOrder(items) {
+				ch := index[id]
+				if ch.Position == pos && categoryKey(ch.CategoryID) == cat {
+					continue
+				}
+				before = append(before, ch)
+				ch.Position = pos
+				ch.CategoryID = nil
+				if cat != "" {
+					catID := cat
+					ch.CategoryID = &catID
+				}
+				changed = append(changed, ch)
+			}
+		}
+		after = changed
+		return changed, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range after {
+		if categoryKey(after[i].CategoryID) != categoryKey(before[i].CategoryID) {
+			if err := s.followCategory(ctx, &after[i]); err != nil {
+				return err
+			}
+		}
+		if err := s.audit.Record(ctx, auditlog.Record{
+			GuildID:    guildID,
+			ActorID:    actorID,
+			Action:     guilds.AuditChannelUpdate,
+			TargetType: guilds.AuditTargetChannel,
+			TargetID:   after[i].ID,
+			Before:     placement(before[i]),
+			After:      placement(after[i]),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *service) SyncPermissions(ctx context.Context, channelID, actorID string) (*guilds.Channel, error) {
+	ch, err := s.repo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.permSvc.Require(ctx, ch.GuildID, actorID, guilds.PermManageRoles); err != nil {
+		return nil, err
+	}
+	if ch.CategoryID == nil {
+		return nil, ErrNoCategory
+	}
+	if err := s.permRepo.SyncChannels(ctx, *ch.CategoryID, []string{ch.ID}); err != nil {
+		return nil, err
+	}
+	wasSynced := ch.PermissionsSynced
+	ch.PermissionsSynced = true
+	if err := s.audit.Record(ctx, auditlog.Record{
+		GuildID:    ch.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditChannelSync,
+		TargetType: guilds.AuditTargetChannel,
+		TargetID:   ch.ID,
+		Before:     map[string]interface{}{"permissions_synced": wasSynced},
+		After:      map[string]interface{}{"permissions_synced": true, "category_id": ch.CategoryID},
+	}); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// followCategory re-syncs a synced channel that changed category: it takes
+// the new category's overwrites, or stops being synced at the top level.
+func (s *service) followCategory(ctx context.Context, ch *guilds.Channel) error {
+	if !ch.PermissionsSynced {
+		return nil
+	}
+	if ch.CategoryID == nil {
+		ch.PermissionsSynced = false
+		return s.repo.SetPermissionsSynced(ctx, ch.ID, false)
+	}
+	return s.permRepo.SyncChannels(ctx, *ch.CategoryID, []string{ch.ID})
+}
+
+func (s *service) Follow(ctx context.Context, channelID, targetChannelID, actorID string) (*guilds.ChannelFollower, error) {
+	src, err := s.repo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if src.Type != string(guilds.ChannelAnnouncement) {
+		return nil, ErrNotAnnouncement
+	}
+	if err := s.permSvc.RequireChannel(ctx, src, actorID, guilds.PermViewChannel); err != nil {
+		return nil, err
+	}
+	dst, err := s.repo.GetByID(ctx, targetChannelID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidTarget
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dst.ID == src.ID || dst.Type != string(guilds.ChannelText) {
+		return nil, ErrInvalidTarget
+	}
+	if err := s.permSvc.RequireChannel(ctx, dst, actorID, guilds.PermManageWebhooks); err != nil {
+		return nil, err
+	}
+
+	f := &guilds.ChannelFollower{
+		ChannelID:       src.ID,
+		GuildID:         dst.GuildID,
+		TargetChannelID: dst.ID,
+		CreatedBy:       actorID,
+	}
+	if err := s.followerRepo.Create(ctx, f); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrAlreadyFollowing
+		}
+		return nil, err
+	}
+	if err := s.audit.Record(ctx, auditlog.Record{
+		GuildID:    dst.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditChannelFollow,
+		TargetType: guilds.AuditTargetFollower,
+		TargetID:   f.ID,
+		After:      f,
+	}); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *service) Unfollow(ctx context.Context, followerID, actorID string) error {
+	f, err := s.followerRepo.GetByID(ctx, followerID)
+	if err != nil {
+		return err
+	}
+	if err := s.permSvc.Require(ctx, f.GuildID, actorID, guilds.PermManageWebhooks); err != nil {
+		return err
+	}
+	if err := s.followerRepo.Delete(ctx, f.ID); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    f.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditChannelUnfollow,
+		TargetType: guilds.AuditTargetFollower,
+		TargetID:   f.ID,
+		Before:     f,
+	})
+}
+
+func (s *service) ListFollowers(ctx context.Context, channelID, actorID string) ([]guilds.ChannelFollower, error) {
+	src, err := s.repo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.permSvc.RequireChannel(ctx, src, actorID, guilds.PermManageChannels); err != nil {
+		return nil, err
+	}
+	return s.followerRepo.ListByChannel(ctx, channelID)
+}
+
+// convertible reports whether a channel may switch between the two types;
+// only the two plain message channels share enough to convert.
+func convertible(from, to string) bool {
+	text, news := string(guilds.ChannelText), string(guilds.ChannelAnnouncement)
+	return (from == text && to == news) || (from == news && to == text)
+}
+
+func categoryKey(id *string) string {
+	if id == nil {
+		return ""
+	}
+	return *id
 }
 
-func (s *service) Delete(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+// placement is the part of a channel a reorder can change.
+func placement(ch guilds.Channel) map[string]interface{} {
+	return map[string]interface{}{"position": ch.Position, "category_id": ch.CategoryID}
 }
@@ -0,0 +1,23 @@
+package digest
+
+import (
+	"context"
+
+	m "launay-dot-one/models"
+)
+
+// Service emails users who have been away for a day a digest of the
+// direct messages and mentions they haven't read, as often as they ask.
+type Service interface {
+	Settings(ctx context.Context, userID string) (*m.DigestSettings, error)
+	UpdateSettings(ctx context.Context, userID string, frequency m.DigestFrequency) (*m.DigestSettings, error)
+
+	// Unsubscribe opts out the user a digest's signed unsubscribe token
+	// was issued to.
+	Unsubscribe(ctx context.Context, token string) error
+
+	// RunDue sends the digests that are due and returns how many it sent.
+	// Replicas can all call it: while one is sending, the others return
+	// straight away.
+	RunDue(ctx context.Context) (int, error)
+}
@@ -0,0 +1,323 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"launay-dot-one/mail"
+	m "launay-dot-one/models"
+	"launay-dot-one/realtime"
+	"launay-dot-one/repositories"
+)
+
+var (
+	ErrInvalidFrequency = errors.New("frequency must be daily, weekly or never")
+	ErrInvalidToken     = errors.New("unsubscribe link is invalid")
+)
+
+const (
+	// lockKey is the advisory lock replicas take to send digests ("digest"
+	// in ASCII).
+	lockKey   int64 = 0x646967657374
+	batchSize       = 100
+)
+
+//go:embed templates
+var templates embed.FS
+
+var (
+	htmlBody = htmltemplate.Must(htmltemplate.ParseFS(templates, "templates/digest.html"))
+	textBody = texttemplate.Must(texttemplate.ParseFS(templates, "templates/digest.txt"))
+)
+
+type service struct {
+	repo             *repositories.DigestRepository
+	userRepo         *repositories.UserRepository
+	messagingRepo    *repositories.MessagingRepository
+	notificationRepo *repositories.NotificationRepository
+	channelRepo      *repositories.ChannelRepository
+	gateway          realtime.Gateway
+	mailer           mail.Mailer
+	secret           []byte
+	appURL           string
+	apiURL           string
+	idleAfter        time.Duration
+}
+
+// NewService wires up email digests for users away longer than
+// idleAfter. Unsubscribe links are signed with secret and point at
+// apiURL; the digest links to the app at appURL.
+func NewService(
+	repo *repositories.DigestRepository,
+	userRepo *repositories.UserRepository,
+	messagingRepo *repositories.MessagingRepository,
+	notificationRepo *repositories.NotificationRepository,
+	channelRepo *repositories.ChannelRepository,
+	gateway realtime.Gateway,
+	mailer mail.Mailer,
+	secret, appURL, apiURL string,
+	idleAfter time.Duration,
+) Service {
+	return &service{
+		repo:             repo,
+		userRepo:         userRepo,
+		messagingRepo:    messagingRepo,
+		notificationRepo: notificationRepo,
+		channelRepo:      channelRepo,
+		gateway:          gateway,
+		mailer:           mailer,
+		secret:           []byte(secret),
+		appURL:           strings.TrimRight(appURL, "/"),
+		apiURL:           strings.TrimRight(apiURL, "/"),
+		idleAfter:        idleAfter,
+	}
+}
+
+func (s *service) Settings(ctx context.Context, userID string) (*m.DigestSettings, error) {
+	return s.repo.GetSettings(ctx, userID)
+}
+
+func (s *service) UpdateSettings(ctx context.Context, userID string, frequency m.DigestFrequency) (*m.DigestSettings, error) {
+	switch frequency {
+	case m.DigestDaily, m.DigestWeekly, m.DigestNever:
+	default:
+		return nil, ErrInvalidFrequency
+	}
+	if err := s.repo.SaveFrequency(ctx, userID, frequency); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSettings(ctx, userID)
+}
+
+// sign is the unsubscribe signature for a user.
+func (s *service) sign(userID string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("digest-unsubscribe:" + userID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// unsubscribeURL is the one-click unsubscribe link for a user: their ID
+// and its signature.
+func (s *service) unsubscribeURL(userID string) string {
+	return s.apiURL + "/digest/unsubscribe/" + userID + "." + s.sign(userID)
+}
+
+func (s *service) Unsubscribe(ctx context.Context, token string) error {
+	userID, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(userID))) {
+		return ErrInvalidToken
+	}
+	return s.repo.SaveFrequency(ctx, userID, m.DigestNever)
+}
+
+func (s *service) RunDue(ctx context.Context) (int, error) {
+	sent := 0
+	_, err := s.repo.WithAdvisoryLock(ctx, lockKey, func() error {
+		now := time.Now()
+		for {
+			users, err := s.repo.ListDue(ctx, now.Add(-s.idleAfter), now, batchSize)
+			if err != nil {
+				return err
+			}
+			for i := range users {
+				// a failed send leaves the user due, so stop rather than
+				// list them again; the next run retries
+				ok, err := s.send(ctx, &users[i], now)
+				if err != nil {
+					return err
+				}
+				if ok {
+					sent++
+				}
+			}
+			if len(users) < batchSize {
+				return nil
+			}
+		}
+	})
+	return sent, err
+}
+
+// item is a message or mention as the templates show it.
+type item struct {
+	From    string
+	Where   string
+	Excerpt string
+	At      time.Time
+}
+
+// digest is the data the templates render.
+type digest struct {
+	Username       string
+	DMs            []item
+	MoreDMs        int64
+	Mentions       []item
+	MoreMentions   int64
+	Frequency      m.DigestFrequency
+	AppURL         string
+	UnsubscribeURL string
+}
+
+// send emails the user what they haven't read since they were last
+// online or last got a digest, if anything, and reports whether it did.
+func (s *service) send(ctx context.Context, u *m.User, now time.Time) (bool, error) {
+	// invisible users look offline but may well be reading
+	if s.gateway.Connected(u.ID) {
+		return false, s.repo.MarkChecked(ctx, u.ID, now, false)
+	}
+	settings, err := s.repo.GetSettings(ctx, u.ID)
+	if err != nil {
+		return false, err
+	}
+	since := u.CreatedAt
+	if u.LastSeenAt != nil {
+		since = *u.LastSeenAt
+	}
+	if settings.LastSentAt != nil && settings.LastSentAt.After(since) {
+		since = *settings.LastSentAt
+	}
+
+	dms, dmTotal, err := s.messagingRepo.ListDirectSince(ctx, u.ID, since, m.MaxDigestItems)
+	if err != nil {
+		return false, err
+	}
+	mentions, mentionTotal, err := s.notificationRepo.ListUnreadSince(
+		ctx, u.ID, []m.NotificationType{m.NotificationMention, m.NotificationReply}, since, m.MaxDigestItems,
+	)
+	if err != nil {
+		return false, err
+	}
+	if dmTotal+mentionTotal == 0 {
+		return false, s.repo.MarkChecked(ctx, u.ID, now, false)
+	}
+
+	d, err := s.build(ctx, u, settings.Frequency, dms, mentions)
+	if err != nil {
+		return false, err
+	}
+	d.MoreDMs = dmTotal - int64(len(dms))
+	d.MoreMentions = mentionTotal - int64(len(mentions))
+
+	var html, text bytes.Buffer
+	if err := htmlBody.Execute(&html, d); err != nil {
+		return false, err
+	}
+	if err := textBody.Execute(&text, d); err != nil {
+		return false, err
+	}
+	if err := s.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: subject(dmTotal, mentionTotal),
+		Text:    text.String(),
+		HTML:    html.String(),
+		// one-click unsubscribe from the mail client (RFC 8058)
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + d.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}); err != nil {
+		return false, err
+	}
+	return true, s.repo.MarkChecked(ctx, u.ID, now, true)
+}
+
+// build resolves the names the digest shows.
+func (s *service) build(
+	ctx context.Context, u *m.User, frequency m.DigestFrequency, dms []m.Message, mentions []m.Notification,
+) (*digest, error) {
+	ids := make([]string, 0, len(dms)+len(mentions))
+	for _, msg := range dms {
+		ids = append(ids, msg.AuthorID)
+	}
+	for _, n := range mentions {
+		ids = append(ids, n.ActorID)
+	}
+	users, err := s.userRepo.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(users))
+	for _, other := range users {
+		names[other.ID] = other.Username
+	}
+	name := func(id string) string {
+		if n, ok := names[id]; ok {
+			return n
+		}
+		return "Someone"
+	}
+
+	d := &digest{
+		Username:       u.Username,
+		Frequency:      frequency,
+		AppURL:         s.appURL,
+		UnsubscribeURL: s.unsubscribeURL(u.ID),
+	}
+	for _, msg := range dms {
+		d.DMs = append(d.DMs, item{From: name(msg.AuthorID), Excerpt: excerpt(msg.Content), At: msg.CreatedAt.UTC()})
+	}
+	channels := make(map[string]string)
+	for _, n := range mentions {
+		where := "a channel"
+		if n.ChannelID != nil {
+			where = s.channelName(ctx, *n.ChannelID, channels)
+		}
+		var data struct {
+			Excerpt string `json:"excerpt"`
+		}
+		_ = json.Unmarshal(n.Data, &data)
+		d.Mentions = append(d.Mentions, item{From: name(n.ActorID), Where: where, Excerpt: data.Excerpt, At: n.CreatedAt.UTC()})
+	}
+	return d, nil
+}
+
+// channelName is how a mention's channel shows, looked up once per digest.
+func (s *service) channelName(ctx context.Context, channelID string, cache map[string]string) string {
+	if name, ok := cache[channelID]; ok {
+		return name
+	}
+	name := "a channel"
+	ch, err := s.channelRepo.GetByID(ctx, channelID)
+	if err == nil {
+		name = "#" + ch.Name
+	}
+	cache[channelID] = name
+	return name
+}
+
+func excerpt(content string) string {
+	if r := []rune(content); len(r) > m.MaxNotificationExcerpt {
+		return string(r[:m.MaxNotificationExcerpt]) + "…"
+	}
+	return content
+}
+
+// subject sums a digest up, e.g. "You have 3 direct messages and 1 mention waiting".
+func subject(dms, mentions int64) string {
+	var parts []string
+	if dms > 0 {
+		parts = append(parts, plural(dms, "direct message"))
+	}
+	if mentions > 0 {
+		parts = append(parts, plural(mentions, "mention"))
+	}
+	return "You have " + strings.Join(parts, " and ") + " waiting"
+}
+
+func plural(n int64, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
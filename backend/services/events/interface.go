@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	m "launay-dot-one/models"
+)
+
+// Publisher queues events for outgoing delivery. Publishing is best
+// effort: callers don't fail the action that raised the event when it
+// returns an error.
+type Publisher interface {
+	// Publish queues one delivery of event per enabled subscriber: the
+	// guild's own subscriptions and every platform-wide one.
+	Publish(ctx context.Context, guildID, event string, data interface{}) error
+}
+
+type Service interface {
+	Publisher
+
+	// Subscriptions with a guild require manage-webhooks in that guild;
+	// platform-wide ones (no guild) require the platform admin role.
+	// Create and RotateSecret return the signing secret, which is never
+	// shown again.
+	Create(ctx context.Context, sub *m.EventSubscription, actor Actor) (string, error)
+	List(ctx context.Context, guildID string, actor Actor) ([]m.EventSubscription, error)
+	Update(ctx context.Context, id string, patch Patch, actor Actor) (*m.EventSubscription, error)
+	Delete(ctx context.Context, id string, actor Actor) error
+	RotateSecret(ctx context.Context, id string, actor Actor) (string, error)
+
+	// ListDeliveries pages through a subscription's delivery log, newest
+	// first; status optionally filters it.
+	ListDeliveries(
+		ctx context.Context,
+		id string,
+		status m.DeliveryStatus,
+		actor Actor,
+		page, limit int,
+	) (*DeliveryPage, error)
+
+	// GetDelivery returns one delivery with every attempt made at it.
+	GetDelivery(ctx context.Context, id, deliveryID string, actor Actor) (*m.EventDelivery, error)
+
+	// Redeliver queues a fresh copy of a past delivery. It keeps the
+	// event ID so receivers can tell it is the same event.
+	Redeliver(ctx context.Context, id, deliveryID string, actor Actor) (*m.EventDelivery, error)
+
+	// ProcessDue sends every delivery whose attempt is due and returns how
+	// many it tried. Failures are rescheduled with exponential backoff.
+	ProcessDue(ctx context.Context) (int, error)
+
+	// PruneDeliveries drops finished deliveries past the retention window.
+	PruneDeliveries(ctx context.Context) (int64, error)
+}
+
+// Actor is the caller managing a subscription.
+type Actor struct {
+	UserID string
+	Role   string // platform role, see models.RoleAdmin
+}
+
+// Patch lists the subscription fields an update may change; nil leaves a
+// field as it is. Re-enabling a subscription clears its failure streak.
+type Patch struct {
+	URL     *string
+	Events  *[]string
+	Enabled *bool
+}
+
+// DeliveryPage is one page of a subscription's delivery log.
+type DeliveryPage struct {
+	Deliveries []m.EventDelivery `json:"deliveries"`
+	Total      int64             `json:"total"`
+	Page       int               `json:"page"`
+	Limit      int               `json:"limit"`
+}
+
+// Envelope is the JSON body POSTed to subscribers.
+type Envelope struct {
+	ID        string      `json:"id"` // event ID, stable across redeliveries
+	Type      string      `json:"type"`
+	GuildID   string      `json:"guild_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// MemberJoined is the data of a member.joined event. It names the invite
+// used, or the member who added the user directly.
+type MemberJoined struct {
+	UserID     string    `json:"user_id"`
+	JoinedAt   time.Time `json:"joined_at"`
+	InviteCode string    `json:"invite_code,omitempty"`
+	AddedBy    string    `json:"added_by,omitempty"`
+	Temporary  bool      `json:"temporary"`
+}
@@ -0,0 +1,594 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAdminOnly            = errors.New("only platform admins can manage platform-wide subscriptions")
+	ErrInvalidURL           = errors.New("subscription URL must be an http(s) URL")
+	ErrInvalidEvents        = errors.New("subscription needs at least one known event")
+	ErrTooManySubscriptions = errors.New("guild has too many event subscriptions")
+	ErrSubscriptionDisabled = errors.New("subscription is disabled")
+)
+
+// Signature headers sent with every delivery. The signature is the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret.
+const (
+	HeaderEvent      = "X-Event-Type"
+	HeaderEventID    = "X-Event-ID"
+	HeaderDeliveryID = "X-Delivery-ID"
+	HeaderTimestamp  = "X-Signature-Timestamp"
+	HeaderSignature  = "X-Signature-256"
+)
+
+const (
+	secretBytes = 32
+
+	maxGuildSubscriptions = 10
+
+	// retry schedule: baseBackoff doubling per attempt, capped at maxBackoff
+	maxAttempts = 10
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+
+	// a subscription is disabled once it has failed at least
+	// disableMinFailures attempts in a row for longer than disableAfter
+	disableMinFailures = 10
+
+	requestTimeout  = 10 * time.Second
+	claimLease      = 5 * time.Minute
+	claimBatch      = 50
+	sendConcurrency = 8
+)
+
+type service struct {
+	repo         *repositories.EventSubscriptionRepository
+	adminAudit   *repositories.AdminAuditLogRepository
+	permSvc      permissions.Service
+	audit        auditlog.Service
+	client       *http.Client
+	disableAfter time.Duration
+	retention    time.Duration
+}
+
+// NewService wires up event subscriptions. Endpoints failing for longer
+// than disableAfter are switched off; finished deliveries are kept for
+// retention (0 keeps them forever).
+func NewService(
+	repo *repositories.EventSubscriptionRepository,
+	adminAudit *repositories.AdminAuditLogRepository,
+	permSvc permissions.Service,
+	audit auditlog.Service,
+	disableAfter, retention time.Duration,
+) Service {
+	return &service{
+		repo:       repo,
+		adminAudit: adminAudit,
+		permSvc:    permSvc,
+		audit:      audit,
+		// a redirect is an answer, not a delivery
+		client:       utils.PublicHTTPClient(requestTimeout, false),
+		disableAfter: disableAfter,
+		retention:    retention,
+	}
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign computes the signature header value for body sent at timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the wait before the attempt following the n-th one.
+func backoff(n int) time.Duration {
+	d := baseBackoff << (n - 1)
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+func validate(sub *m.EventSubscription) error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		len(sub.URL) > m.MaxEventSubscriptionURL || utils.IsInternalHost(u.Hostname()) {
+		return ErrInvalidURL
+	}
+	seen := make(map[string]bool, len(sub.Events))
+	events := make([]string, 0, len(sub.Events))
+	for _, e := range sub.Events {
+		if seen[e] {
+			continue
+		}
+		known := false
+		for _, k := range m.SubscribableEvents {
+			known = known || k == e
+		}
+		if !known {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidEvents, e)
+		}
+		seen[e] = true
+		events = append(events, e)
+	}
+	if len(events) == 0 {
+		return ErrInvalidEvents
+	}
+	sub.Events = events
+	return nil
+}
+
+// authorize checks that actor may manage subscriptions of guildID, or
+// platform-wide ones when guildID is empty.
+func (s *service) authorize(ctx context.Context, guildID string, actor Actor) error {
+	if guildID == "" {
+		if actor.Role != m.RoleAdmin {
+			return ErrAdminOnly
+		}
+		return nil
+	}
+	return s.permSvc.Require(ctx, guildID, actor.UserID, guilds.PermManageWebhooks)
+}
+
+// manageable loads a subscription the actor may manage.
+func (s *service) manageable(ctx context.Context, id string, actor Actor) (*m.EventSubscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, guildOf(sub), actor); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func guildOf(sub *m.EventSubscription) string {
+	if sub.GuildID == nil {
+		return ""
+	}
+	return *sub.GuildID
+}
+
+// record writes guild subscription changes to the guild audit log and
+// platform-wide ones to the admin audit log.
+func (s *service) record(
+	ctx context.Context,
+	actorID string,
+	action guilds.AuditAction,
+	adminAction m.AdminAction,
+	before, after *m.EventSubscription,
+) error {
+	target := after
+	if target == nil {
+		target = before
+	}
+	if target.GuildID != nil {
+		r := auditlog.Record{
+			GuildID:    *target.GuildID,
+			ActorID:    actorID,
+			Action:     action,
+			TargetType: guilds.AuditTargetEventSubscription,
+			TargetID:   target.ID,
+		}
+		if before != nil {
+			r.Before = before
+		}
+		if after != nil {
+			r.After = after
+		}
+		return s.audit.Record(ctx, r)
+	}
+	if actorID == "" {
+		return nil // the admin log only holds actions taken by an admin
+	}
+	meta, err := json.Marshal(map[string]interface{}{"url": target.URL, "events": target.Events, "enabled": target.Enabled})
+	if err != nil {
+		return err
+	}
+	if err := s.adminAudit.Create(ctx, &m.AdminAuditLog{
+		ActorID:    actorID,
+		Action:     adminAction,
+		TargetType: guilds.AuditTargetEventSubscription,
+		TargetID:   target.ID,
+		Metadata:   datatypes.JSON(meta),
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
+
+func (s *service) Create(ctx context.Context, sub *m.EventSubscription, actor Actor) (string, error) {
+	guildID := guildOf(sub)
+	if err := s.authorize(ctx, guildID, actor); err != nil {
+		return "", err
+	}
+	if err := validate(sub); err != nil {
+		return "", err
+	}
+	if guildID != "" {
+		existing, err := s.repo.List(ctx, guildID)
+		if err != nil {
+			return "", err
+		}
+		if len(existing) >= maxGuildSubscriptions {
+			return "", fmt.Errorf("%w: at most %d", ErrTooManySubscriptions, maxGuildSubscriptions)
+		}
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+	sub.ID = ""
+	sub.Secret = secret
+	sub.Enabled = true
+	sub.DisabledReason = ""
+	sub.ConsecutiveFailures = 0
+	sub.FailingSince = nil
+	sub.CreatorID = actor.UserID
+	if err := s.repo.Create(ctx, sub); err != nil {
+		return "", err
+	}
+	if err := s.record(ctx, actor.UserID,
+		guilds.AuditEventSubscriptionCreate, m.AdminActionCreateEventSubscription, nil, sub); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+func (s *service) List(ctx context.Context, guildID string, actor Actor) ([]m.EventSubscription, error) {
+	if err := s.authorize(ctx, guildID, actor); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, guildID)
+}
+
+func (s *service) Update(ctx context.Context, id string, patch Patch, actor Actor) (*m.EventSubscription, error) {
+	sub, err := s.manageable(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	before := *sub
+	if patch.URL != nil {
+		sub.URL = *patch.URL
+	}
+	if patch.Events != nil {
+		sub.Events = *patch.Events
+	}
+	if patch.Enabled != nil {
+		if *patch.Enabled && !sub.Enabled {
+			sub.ConsecutiveFailures = 0
+			sub.FailingSince = nil
+		}
+		sub.Enabled = *patch.Enabled
+		sub.DisabledReason = ""
+	}
+	if err := validate(sub); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, sub); err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, actor.UserID,
+		guilds.AuditEventSubscriptionUpdate, m.AdminActionUpdateEventSubscription, &before, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *service) Delete(ctx context.Context, id string, actor Actor) error {
+	sub, err := s.manageable(ctx, id, actor)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, sub.ID); err != nil {
+		return err
+	}
+	return s.record(ctx, actor.UserID,
+		guilds.AuditEventSubscriptionDelete, m.AdminActionDeleteEventSubscription, sub, nil)
+}
+
+func (s *service) RotateSecret(ctx context.Context, id string, actor Actor) (string, error) {
+	sub, err := s.manageable(ctx, id, actor)
+	if err != nil {
+		return "", err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+	sub.Secret = secret
+	if err := s.repo.Update(ctx, sub); err != nil {
+		return "", err
+	}
+	// the secret is not serialised, so the entry only says that it happened
+	if err := s.record(ctx, actor.UserID,
+		guilds.AuditEventSecretRotate, m.AdminActionRotateEventSecret, nil, sub); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+func (s *service) ListDeliveries(
+	ctx context.Context,
+	id string,
+	status m.DeliveryStatus,
+	actor Actor,
+	page, limit int,
+) (*DeliveryPage, error) {
+	sub, err := s.manageable(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	out, total, err := s.repo.ListDeliveries(ctx, sub.ID, status, (page-1)*limit, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &DeliveryPage{Deliveries: out, Total: total, Page: page, Limit: limit}, nil
+}
+
+// delivery loads one of sub's deliveries.
+func (s *service) delivery(ctx context.Context, sub *m.EventSubscription, deliveryID string) (*m.EventDelivery, error) {
+	d, err := s.repo.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if d.SubscriptionID != sub.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return d, nil
+}
+
+func (s *service) GetDelivery(ctx context.Context, id, deliveryID string, actor Actor) (*m.EventDelivery, error) {
+	sub, err := s.manageable(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	return s.delivery(ctx, sub, deliveryID)
+}
+
+func (s *service) Redeliver(ctx context.Context, id, deliveryID string, actor Actor) (*m.EventDelivery, error) {
+	sub, err := s.manageable(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if !sub.Enabled {
+		return nil, ErrSubscriptionDisabled
+	}
+	orig, err := s.delivery(ctx, sub, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	d := m.EventDelivery{
+		ID:             uuid.NewString(),
+		SubscriptionID: sub.ID,
+		EventID:        orig.EventID,
+		Event:          orig.Event,
+		Payload:        orig.Payload,
+		Status:         m.DeliveryPending,
+		NextAttemptAt:  now,
+		RedeliveryOf:   &orig.ID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.repo.CreateDeliveries(ctx, []m.EventDelivery{d}); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (s *service) Publish(ctx context.Context, guildID, event string, data interface{}) error {
+	subs, err := s.repo.ListSubscribers(ctx, guildID, event)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+	now := time.Now()
+	env := Envelope{ID: uuid.NewString(), Type: event, GuildID: guildID, CreatedAt: now, Data: data}
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	ds := make([]m.EventDelivery, len(subs))
+	for i, sub := range subs {
+		ds[i] = m.EventDelivery{
+			ID:             uuid.NewString(),
+			SubscriptionID: sub.ID,
+			EventID:        env.ID,
+			Event:          event,
+			Payload:        datatypes.JSON(raw),
+			Status:         m.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+	}
+	return s.repo.CreateDeliveries(ctx, ds)
+}
+
+func (s *service) ProcessDue(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		due, err := s.repo.ClaimDue(ctx, time.Now(), claimLease, claimBatch)
+		if err != nil {
+			return total, err
+		}
+		if len(due) == 0 {
+			return total, nil
+		}
+		total += len(due)
+
+		subs := make(map[string]*m.EventSubscription)
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			firstErr error
+		)
+		sem := make(chan struct{}, sendConcurrency)
+		for i := range due {
+			d := &due[i]
+			sub, ok := subs[d.SubscriptionID]
+			if !ok {
+				sub, err = s.repo.GetByID(ctx, d.SubscriptionID)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					sub = nil // deleted since it was claimed
+				} else if err != nil {
+					return total, err
+				}
+				subs[d.SubscriptionID] = sub
+			}
+			if sub == nil {
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(sub *m.EventSubscription, d *m.EventDelivery) {
+				defer func() { <-sem; wg.Done() }()
+				if err := s.attempt(ctx, sub, d); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}(sub, d)
+		}
+		wg.Wait()
+		if firstErr != nil {
+			return total, firstErr
+		}
+		if len(due) < claimBatch {
+			return total, nil
+		}
+	}
+}
+
+// attempt sends d once and records the outcome, rescheduling or
+// finishing the delivery and updating the subscription's failure streak.
+func (s *service) attempt(ctx context.Context, sub *m.EventSubscription, d *m.EventDelivery) error {
+	start := time.Now()
+	code, sendErr := s.send(ctx, sub, d)
+	now := time.Now()
+
+	a := &m.EventDeliveryAttempt{
+		DeliveryID: d.ID,
+		StatusCode: code,
+		DurationMs: now.Sub(start).Milliseconds(),
+		CreatedAt:  now,
+	}
+	d.Attempts++
+	d.LastStatusCode = code
+	d.LastError = ""
+	ok := sendErr == nil && code >= 200 && code < 300
+	switch {
+	case sendErr != nil:
+		a.Error = sendErr.Error()
+	case !ok:
+		a.Error = fmt.Sprintf("endpoint answered %d", code)
+	}
+	if ok {
+		d.Status = m.DeliverySucceeded
+		d.DeliveredAt = &now
+	} else {
+		d.LastError = a.Error
+		if d.Attempts >= maxAttempts {
+			d.Status = m.DeliveryFailed
+		} else {
+			d.NextAttemptAt = now.Add(backoff(d.Attempts))
+		}
+	}
+	d.UpdatedAt = now
+	if err := s.repo.SaveAttempt(ctx, d, a); err != nil {
+		return err
+	}
+
+	if ok {
+		return s.repo.RecordSuccess(ctx, sub.ID)
+	}
+	updated, err := s.repo.RecordFailure(ctx, sub.ID, now)
+	if err != nil {
+		return err
+	}
+	if !updated.Enabled || updated.ConsecutiveFailures < disableMinFailures ||
+		updated.FailingSince == nil || now.Sub(*updated.FailingSince) < s.disableAfter {
+		return nil
+	}
+	reason := fmt.Sprintf("disabled after %d consecutive failed deliveries since %s",
+		updated.ConsecutiveFailures, updated.FailingSince.UTC().Format(time.RFC3339))
+	if err := s.repo.Disable(ctx, sub.ID, reason); err != nil {
+		return err
+	}
+	before := *updated
+	updated.Enabled = false
+	updated.DisabledReason = reason
+	return s.record(ctx, "", guilds.AuditEventSubscriptionUpdate, m.AdminActionUpdateEventSubscription, &before, updated)
+}
+
+// send POSTs the signed payload and returns the status code. The response
+// body is discarded: endpoints are user-supplied, so storing what they
+// answer would turn deliveries into a way to read arbitrary responses.
+func (s *service) send(ctx context.Context, sub *m.EventSubscription, d *m.EventDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "launay-dot-one-webhooks/1.0")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderEventID, d.EventID)
+	req.Header.Set(HeaderDeliveryID, d.ID)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, ts, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func (s *service) PruneDeliveries(ctx context.Context) (int64, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	return s.repo.PruneDeliveries(ctx, time.Now().Add(-s.retention))
+}
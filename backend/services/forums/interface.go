@@ -0,0 +1,34 @@
+package forums
+
+import (
+	"context"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/models/guilds"
+)
+
+type Service interface {
+	ListTags(ctx context.Context, channelID string) ([]guilds.ForumTag, error)
+
+	// CreateTag, UpdateTag and DeleteTag manage a forum's tag set and
+	// require manage-channels.
+	CreateTag(ctx context.Context, tag *guilds.ForumTag, actorID string) error
+	UpdateTag(ctx context.Context, tag *guilds.ForumTag, actorID string) error
+	DeleteTag(ctx context.Context, tagID, actorID string) error
+
+	// CreatePost opens a titled thread in a forum channel with content as its
+	// first message. Moderated tags require manage-channels.
+	CreatePost(ctx context.Context, post *guilds.ForumPost, content string) (*m.Message, error)
+
+	// ListPosts pages through a forum's posts, newest first, optionally
+	// filtered by tag.
+	ListPosts(ctx context.Context, channelID, tagID string, page, limit int) (*PostPage, error)
+}
+
+// PostPage is one page of forum posts.
+type PostPage struct {
+	Posts []guilds.ForumPost `json:"posts"`
+	Total int64              `json:"total"`
+	Page  int                `json:"page"`
+	Limit int                `json:"limit"`
+}
@@ -0,0 +1,259 @@
+package forums
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+	"launay-dot-one/services/messaging"
+	"launay-dot-one/services/permissions"
+
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrNotForum     = errors.New("channel is not a forum")
+	ErrInvalidTitle = errors.New("post title is required")
+	ErrInvalidTag   = errors.New("invalid tag")
+	ErrTooManyTags  = errors.New("too many tags")
+	ErrUnknownTag   = errors.New("tag does not belong to this forum")
+	ErrModeratedTag = errors.New("only moderators can apply this tag")
+	ErrEmptyContent = errors.New("post content is required")
+)
+
+type service struct {
+	repo        *repositories.ForumRepository
+	channelRepo *repositories.ChannelRepository
+	msgSvc      messaging.Service
+	permSvc     permissions.Service
+	audit       auditlog.Service
+}
+
+func NewService(
+	repo *repositories.ForumRepository,
+	channelRepo *repositories.ChannelRepository,
+	msgSvc messaging.Service,
+	permSvc permissions.Service,
+	audit auditlog.Service,
+) Service {
+	return &service{repo, channelRepo, msgSvc, permSvc, audit}
+}
+
+// forum loads a channel and checks that it is a forum.
+func (s *service) forum(ctx context.Context, channelID string) (*guilds.Channel, error) {
+	ch, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if ch.Type != string(guilds.ChannelForum) {
+		return nil, ErrNotForum
+	}
+	return ch, nil
+}
+
+func validateTag(t *guilds.ForumTag) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" || len(t.Name) > guilds.MaxForumTagName {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidTag, guilds.MaxForumTagName)
+	}
+	return nil
+}
+
+func (s *service) ListTags(ctx context.Context, channelID string) ([]guilds.ForumTag, error) {
+	if _, err := s.forum(ctx, channelID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListTags(ctx, channelID)
+}
+
+func (s *service) CreateTag(ctx context.Context, tag *guilds.ForumTag, actorID string) error {
+	ch, err := s.forum(ctx, tag.ChannelID)
+	if err != nil {
+		return err
+	}
+	if err := s.permSvc.RequireChannel(ctx, ch, actorID, guilds.PermManageChannels); err != nil {
+		return err
+	}
+	if err := validateTag(tag); err != nil {
+		return err
+	}
+	n, err := s.repo.CountTags(ctx, ch.ID)
+	if err != nil {
+		return err
+	}
+	if n >= guilds.MaxForumTags {
+		return fmt.Errorf("%w: a forum holds at most %d tags", ErrTooManyTags, guilds.MaxForumTags)
+	}
+	tag.ID = ""
+	if err := s.repo.CreateTag(ctx, tag); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    ch.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditForumTagCreate,
+		TargetType: guilds.AuditTargetForumTag,
+		TargetID:   tag.ID,
+		After:      tag,
+	})
+}
+
+func (s *service) UpdateTag(ctx context.Context, tag *guilds.ForumTag, actorID string) error {
+	before, err := s.repo.GetTag(ctx, tag.ID)
+	if err != nil {
+		return err
+	}
+	if tag.ChannelID != "" && tag.ChannelID != before.ChannelID {
+		return ErrUnknownTag
+	}
+	ch, err := s.forum(ctx, before.ChannelID)
+	if err != nil {
+		return err
+	}
+	if err := s.permSvc.RequireChannel(ctx, ch, actorID, guilds.PermManageChannels); err != nil {
+		return err
+	}
+	if err := validateTag(tag); err != nil {
+		return err
+	}
+	tag.ChannelID = before.ChannelID
+	tag.CreatedAt = before.CreatedAt
+	if err := s.repo.UpdateTag(ctx, tag); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    ch.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditForumTagUpdate,
+		TargetType: guilds.AuditTargetForumTag,
+		TargetID:   tag.ID,
+		Before:     before,
+		After:      tag,
+	})
+}
+
+func (s *service) DeleteTag(ctx context.Context, tagID, actorID string) error {
+	before, err := s.repo.GetTag(ctx, tagID)
+	if err != nil {
+		return err
+	}
+	ch, err := s.channelRepo.GetByID(ctx, before.ChannelID)
+	if err != nil {
+		return err
+	}
+	if err := s.permSvc.RequireChannel(ctx, ch, actorID, guilds.PermManageChannels); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteTag(ctx, before); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    ch.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditForumTagDelete,
+		TargetType: guilds.AuditTargetForumTag,
+		TargetID:   tagID,
+		Before:     before,
+	})
+}
+
+func (s *service) CreatePost(ctx context.Context, post *guilds.ForumPost, content string) (*m.Message, error) {
+	ch, err := s.forum(ctx, post.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	post.Title = strings.TrimSpace(post.Title)
+	if post.Title == "" || len(post.Title) > guilds.MaxPostTitle {
+		return nil, fmt.Errorf("%w: title must be 1 to %d characters", ErrInvalidTitle, guilds.MaxPostTitle)
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrEmptyContent
+	}
+	tagIDs, err := s.checkPostTags(ctx, ch, post.AuthorID, post.TagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	post.ID = ""
+	post.GuildID = ch.GuildID
+	post.TagIDs = tagIDs
+	if err := s.repo.CreatePost(ctx, post); err != nil {
+		return nil, err
+	}
+	// the opening message goes through the regular send path, which checks
+	// membership, timeouts and slowmode
+	msg := &m.Message{ChannelID: post.ID, AuthorID: post.AuthorID, Content: content}
+	if err := s.msgSvc.SendMessage(ctx, msg); err != nil {
+		_ = s.repo.DeletePost(ctx, post.ID)
+		return nil, err
+	}
+	return msg, nil
+}
+
+// checkPostTags de-duplicates the requested tags and checks each belongs to
+// the forum and, if moderated, that the author may apply it.
+func (s *service) checkPostTags(
+	ctx context.Context,
+	ch *guilds.Channel,
+	authorID string,
+	requested []string,
+) (datatypes.JSONSlice[string], error) {
+	out := datatypes.JSONSlice[string]{}
+	if len(requested) == 0 {
+		return out, nil
+	}
+	tags, err := s.repo.ListTags(ctx, ch.ID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]guilds.ForumTag, len(tags))
+	for _, t := range tags {
+		byID[t.ID] = t
+	}
+	seen := map[string]bool{}
+	moderated := false
+	for _, id := range requested {
+		if seen[id] {
+			continue
+		}
+		t, ok := byID[id]
+		if !ok {
+			return nil, ErrUnknownTag
+		}
+		seen[id] = true
+		moderated = moderated || t.Moderated
+		out = append(out, id)
+	}
+	if len(out) > guilds.MaxPostTags {
+		return nil, fmt.Errorf("%w: a post carries at most %d tags", ErrTooManyTags, guilds.MaxPostTags)
+	}
+	if moderated {
+		if err := s.permSvc.RequireChannel(ctx, ch, authorID, guilds.PermManageChannels); err != nil {
+			if errors.Is(err, permissions.ErrMissingPermission) {
+				return nil, ErrModeratedTag
+			}
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (s *service) ListPosts(
+	ctx context.Context,
+	channelID, tagID string,
+	page, limit int,
+) (*PostPage, error) {
+	if _, err := s.forum(ctx, channelID); err != nil {
+		return nil, err
+	}
+	posts, total, err := s.repo.ListPosts(ctx, channelID, tagID, (page-1)*limit, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &PostPage{Posts: posts, Total: total, Page: page, Limit: limit}, nil
+}
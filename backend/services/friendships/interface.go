@@ -2,23 +2,61 @@ package friendships
 
 import (
 	"context"
+	"time"
 
 	mfriend "launay-dot-one/models/friendships"
 )
 
 // Service defines the friendship‐related business logic.
 type Service interface {
-	// SendRequest creates a new friend request from fromID to toID.
+	// SendRequest creates a new friend request from fromID to toID, if
+	// toID's privacy settings allow it and neither blocked the other.
 	SendRequest(ctx context.Context, fromID, toID string) error
 
-	// RespondRequest accepts or rejects an existing request.
-	RespondRequest(ctx context.Context, requestID string, accept bool) error
+	// SendRequestByUsername resolves the username, ignoring case, and sends
+	// it a request like SendRequest.
+	SendRequestByUsername(ctx context.Context, fromID, username string) error
+
+	// RespondRequest accepts or rejects a pending request sent to userID.
+	RespondRequest(ctx context.Context, requestID, userID string, accept bool) error
+
+	// CancelRequest withdraws a pending request userID sent.
+	CancelRequest(ctx context.Context, requestID, userID string) error
 
 	// ListRequests fetches all incoming or outgoing requests for a user.
 	ListRequests(ctx context.Context, userID string) ([]RequestDTO, error)
 
 	// ListFriends returns the IDs of all accepted friends for a user.
 	ListFriends(ctx context.Context, userID string) ([]FriendDTO, error)
+
+	// MutualFriends returns the friends viewerID and userID have in common.
+	MutualFriends(ctx context.Context, viewerID, userID string) ([]FriendDTO, error)
+
+	// Unfriend ends the friendship between userID and friendID.
+	Unfriend(ctx context.Context, userID, friendID string) error
+
+	// Block ends any friendship or pending request with targetID and keeps
+	// them from sending userID friend requests or direct messages.
+	Block(ctx context.Context, userID, targetID string) error
+
+	// Unblock lifts a block userID placed.
+	Unblock(ctx context.Context, userID, targetID string) error
+
+	// ListBlocked returns the users userID blocked.
+	ListBlocked(ctx context.Context, userID string) ([]BlockDTO, error)
+
+	// IsBlocked reports whether either user blocked the other.
+	IsBlocked(ctx context.Context, userA, userB string) (bool, error)
+
+	// CheckDM returns ErrDMsClosed unless fromID may send toID a direct
+	// message. Targets that aren't users (groups) pass.
+	CheckDM(ctx context.Context, fromID, toID string) error
+
+	// GetPrivacy returns the user's privacy settings.
+	GetPrivacy(ctx context.Context, userID string) (*mfriend.PrivacySettings, error)
+
+	// UpdatePrivacy changes the fields set in the patch.
+	UpdatePrivacy(ctx context.Context, userID string, patch PrivacyPatch) (*mfriend.PrivacySettings, error)
 }
 
 // RequestDTO is a safe projection of a FriendRequest model.
@@ -33,3 +71,15 @@ type RequestDTO struct {
 type FriendDTO struct {
 	UserID string
 }
+
+// BlockDTO is a user someone blocked, and since when.
+type BlockDTO struct {
+	UserID    string
+	BlockedAt time.Time
+}
+
+// PrivacyPatch holds the privacy settings to change; nil fields are kept.
+type PrivacyPatch struct {
+	FriendRequests *mfriend.FriendRequestPolicy `json:"friend_requests"`
+	DirectMessages *mfriend.DMPolicy            `json:"direct_messages"`
+}
@@ -2,65 +2,160 @@ package friendships
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 
+	m "launay-dot-one/models"
 	mfriend "launay-dot-one/models/friendships"
 	"launay-dot-one/repositories"
+	"launay-dot-one/services/notifications"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrSelfRequest    = errors.New("cannot send friend request to yourself")
+	ErrRequestExists  = errors.New("friend request already exists")
+	ErrRequestsClosed = errors.New("this user isn't accepting friend requests from you")
+	ErrNotReceiver    = errors.New("only the receiver can respond to a friend request")
+	ErrNotRequester   = errors.New("only the sender can cancel a friend request")
+	ErrNotPending     = errors.New("friend request was already answered")
+	ErrNotFriends     = errors.New("you are not friends with this user")
+	ErrSelfBlock      = errors.New("cannot block yourself")
+	ErrNotBlocked     = errors.New("you haven't blocked this user")
+	ErrDMsClosed      = errors.New("this user isn't accepting direct messages from you")
+	ErrInvalidPrivacy = errors.New(
+		"friend_requests must be everyone, friends_of_friends or nobody; direct_messages everyone, friends or nobody",
+	)
+)
+
 type service struct {
-	repo *repositories.FriendRequestRepository
-	db   *gorm.DB
+	repo     *repositories.FriendRequestRepository
+	userRepo *repositories.UserRepository
+	notifier notifications.Service
 }
 
 // NewService constructs the friendship service.
-func NewService(repo *repositories.FriendRequestRepository, db *gorm.DB) Service {
-	return &service{repo: repo, db: db}
+func NewService(
+	repo *repositories.FriendRequestRepository,
+	userRepo *repositories.UserRepository,
+	notifier notifications.Service,
+) Service {
+	return &service{repo: repo, userRepo: userRepo, notifier: notifier}
 }
 
 func (s *service) SendRequest(ctx context.Context, fromID, toID string) error {
 	if fromID == toID {
-		return errors.New("cannot send friend request to yourself")
+		return ErrSelfRequest
+	}
+	if _, err := s.userRepo.GetByID(ctx, toID); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.Between(ctx, fromID, toID)
+	if err != nil {
+		return err
+	}
+	for _, r := range existing {
+		switch mfriend.FriendStatus(r.Status) {
+		case mfriend.FriendBlocked:
+			return ErrRequestsClosed
+		case mfriend.FriendPending, mfriend.FriendAccepted:
+			return ErrRequestExists
+		}
 	}
 
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 1) check duplicates (unchanged)
-		existing, err := s.repo.ListForUser(ctx, fromID)
+	privacy, err := s.repo.GetPrivacy(ctx, toID)
+	if err != nil {
+		return err
+	}
+	switch privacy.FriendRequests {
+	case mfriend.RequestsFromNobody:
+		return ErrRequestsClosed
+	case mfriend.RequestsFromFriendsOfFriends:
+		ok, err := s.repo.HaveMutualFriend(ctx, fromID, toID)
 		if err != nil {
 			return err
 		}
-		for _, r := range existing {
-			samePair := (r.RequesterID == fromID && r.ReceiverID == toID) ||
-				(r.RequesterID == toID && r.ReceiverID == fromID)
-			if samePair && (r.Status == string(mfriend.FriendPending) || r.Status == string(mfriend.FriendAccepted)) {
-				return errors.New("friend request already exists")
-			}
+		if !ok {
+			return ErrRequestsClosed
 		}
+	}
 
-		// 2) build the new request — ***populate ID here***
-		fr := &mfriend.FriendRequest{
-			ID:          uuid.NewString(),
-			RequesterID: fromID,
-			ReceiverID:  toID,
-			Status:      string(mfriend.FriendPending),
-		}
-		return s.repo.Create(ctx, fr)
-	})
+	fr := &mfriend.FriendRequest{
+		ID:          uuid.NewString(),
+		RequesterID: fromID,
+		ReceiverID:  toID,
+		Status:      string(mfriend.FriendPending),
+	}
+	if err := s.repo.Create(ctx, fr); err != nil {
+		return err
+	}
+	s.notify(ctx, toID, fromID, m.NotificationFriendRequest, fr.ID)
+	return nil
+}
+
+// notify tells userID about a friend request from actorID.
+func (s *service) notify(ctx context.Context, userID, actorID string, typ m.NotificationType, requestID string) {
+	data, _ := json.Marshal(map[string]string{"request_id": requestID})
+	_ = s.notifier.Notify(ctx, m.Notification{UserID: userID, Type: typ, ActorID: actorID, Data: data})
+}
+
+func (s *service) SendRequestByUsername(ctx context.Context, fromID, username string) error {
+	u, err := s.userRepo.GetByUsername(ctx, strings.TrimSpace(username))
+	if err != nil {
+		return err
+	}
+	return s.SendRequest(ctx, fromID, u.ID)
+}
+
+// pending loads a request that is still awaiting an answer.
+func (s *service) pending(ctx context.Context, requestID string) (*mfriend.FriendRequest, error) {
+	fr, err := s.repo.Get(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if fr.Status == string(mfriend.FriendBlocked) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if fr.Status != string(mfriend.FriendPending) {
+		return nil, ErrNotPending
+	}
+	return fr, nil
 }
 
-func (s *service) RespondRequest(ctx context.Context, requestID string, accept bool) error {
-	_, err := s.repo.Get(ctx, requestID)
+func (s *service) RespondRequest(ctx context.Context, requestID, userID string, accept bool) error {
+	fr, err := s.pending(ctx, requestID)
 	if err != nil {
 		return err
 	}
+	if fr.ReceiverID != userID {
+		return ErrNotReceiver
+	}
 	newStatus := mfriend.FriendRejected
 	if accept {
 		newStatus = mfriend.FriendAccepted
 	}
-	return s.repo.UpdateStatus(ctx, requestID, newStatus)
+	if err := s.repo.UpdateStatus(ctx, requestID, newStatus); err != nil {
+		return err
+	}
+	if accept {
+		s.notify(ctx, fr.RequesterID, userID, m.NotificationFriendAccepted, requestID)
+	}
+	return nil
+}
+
+func (s *service) CancelRequest(ctx context.Context, requestID, userID string) error {
+	fr, err := s.pending(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if fr.RequesterID != userID {
+		return ErrNotRequester
+	}
+	return s.repo.Delete(ctx, requestID)
 }
 
 func (s *service) ListRequests(ctx context.Context, userID string) ([]RequestDTO, error) {
@@ -68,14 +163,17 @@ func (s *service) ListRequests(ctx context.Context, userID string) ([]RequestDTO
 	if err != nil {
 		return nil, err
 	}
-	out := make([]RequestDTO, len(list))
-	for i, r := range list {
-		out[i] = RequestDTO{
+	out := make([]RequestDTO, 0, len(list))
+	for _, r := range list {
+		if r.Status == string(mfriend.FriendBlocked) {
+			continue // blocks are listed by ListBlocked, and only to the blocker
+		}
+		out = append(out, RequestDTO{
 			ID:          r.ID,
 			RequesterID: r.RequesterID,
 			ReceiverID:  r.ReceiverID,
 			Status:      mfriend.FriendStatus(r.Status),
-		}
+		})
 	}
 	return out, nil
 }
@@ -95,3 +193,132 @@ func (s *service) ListFriends(ctx context.Context, userID string) ([]FriendDTO,
 	}
 	return out, nil
 }
+
+func (s *service) MutualFriends(ctx context.Context, viewerID, userID string) ([]FriendDTO, error) {
+	ids, err := s.repo.ListMutualFriendIDs(ctx, viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]FriendDTO, len(ids))
+	for i, id := range ids {
+		out[i] = FriendDTO{UserID: id}
+	}
+	return out, nil
+}
+
+func (s *service) Unfriend(ctx context.Context, userID, friendID string) error {
+	ok, err := s.repo.DeleteFriendship(ctx, userID, friendID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFriends
+	}
+	return nil
+}
+
+func (s *service) Block(ctx context.Context, userID, targetID string) error {
+	if userID == targetID {
+		return ErrSelfBlock
+	}
+	if _, err := s.userRepo.GetByID(ctx, targetID); err != nil {
+		return err
+	}
+	return s.repo.Block(ctx, userID, targetID)
+}
+
+func (s *service) Unblock(ctx context.Context, userID, targetID string) error {
+	ok, err := s.repo.Unblock(ctx, userID, targetID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotBlocked
+	}
+	return nil
+}
+
+func (s *service) ListBlocked(ctx context.Context, userID string) ([]BlockDTO, error) {
+	list, err := s.repo.ListBlocked(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]BlockDTO, len(list))
+	for i, r := range list {
+		out[i] = BlockDTO{UserID: r.ReceiverID, BlockedAt: r.CreatedAt}
+	}
+	return out, nil
+}
+
+func (s *service) IsBlocked(ctx context.Context, userA, userB string) (bool, error) {
+	return s.repo.IsBlocked(ctx, userA, userB)
+}
+
+func (s *service) CheckDM(ctx context.Context, fromID, toID string) error {
+	if fromID == toID {
+		return nil
+	}
+	if _, err := s.userRepo.GetByID(ctx, toID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	blocked, err := s.repo.IsBlocked(ctx, fromID, toID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrDMsClosed
+	}
+	privacy, err := s.repo.GetPrivacy(ctx, toID)
+	if err != nil {
+		return err
+	}
+	switch privacy.DirectMessages {
+	case mfriend.DMsFromNobody:
+		return ErrDMsClosed
+	case mfriend.DMsFromFriends:
+		ok, err := s.repo.AreFriends(ctx, fromID, toID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrDMsClosed
+		}
+	}
+	return nil
+}
+
+func (s *service) GetPrivacy(ctx context.Context, userID string) (*mfriend.PrivacySettings, error) {
+	return s.repo.GetPrivacy(ctx, userID)
+}
+
+func (s *service) UpdatePrivacy(
+	ctx context.Context, userID string, patch PrivacyPatch,
+) (*mfriend.PrivacySettings, error) {
+	ps, err := s.repo.GetPrivacy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if p := patch.FriendRequests; p != nil {
+		switch *p {
+		case mfriend.RequestsFromEveryone, mfriend.RequestsFromFriendsOfFriends, mfriend.RequestsFromNobody:
+			ps.FriendRequests = *p
+		default:
+			return nil, ErrInvalidPrivacy
+		}
+	}
+	if p := patch.DirectMessages; p != nil {
+		switch *p {
+		case mfriend.DMsFromEveryone, mfriend.DMsFromFriends, mfriend.DMsFromNobody:
+			ps.DirectMessages = *p
+		default:
+			return nil, ErrInvalidPrivacy
+		}
+	}
+	if err := s.repo.SavePrivacy(ctx, ps); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
@@ -7,9 +7,22 @@ import (
 )
 
 type Service interface {
-	Create(ctx context.Context, role *guilds.GuildRole) error
+	// Create, Update and Delete require manage-roles and a role below the
+	// actor's highest; a role can't gain bits the actor doesn't hold.
+	Create(ctx context.Context, role *guilds.GuildRole, actorID string) error
 	Get(ctx context.Context, id string) (*guilds.GuildRole, error)
 	List(ctx context.Context, guildID string) ([]guilds.GuildRole, error)
-	Update(ctx context.Context, role *guilds.GuildRole) error
-	Delete(ctx context.Context, id string) error
+	Update(ctx context.Context, role *guilds.GuildRole, actorID string) error
+	Delete(ctx context.Context, id, actorID string) error
+
+	// Reorder applies the moves and renumbers roles densely from 1 (0 is
+	// @everyone) in one transaction. Requires manage-roles; non-owners
+	// can only arrange roles below their own highest role.
+	Reorder(ctx context.Context, guildID string, moves []Move, actorID string) error
+}
+
+// Move places a role at a position.
+type Move struct {
+	ID       string
+	Position int
 }
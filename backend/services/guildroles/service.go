@@ -2,21 +2,54 @@ package guildroles
 
 import (
 	"context"
+	"errors"
 
 	"launay-dot-one/models/guilds"
 	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+)
+
+var (
+	// ErrEveryoneRole is returned when deleting or renaming the @everyone role.
+	ErrEveryoneRole  = errors.New("the @everyone role cannot be deleted or renamed")
+	ErrUnknownRole   = errors.New("role does not belong to this guild")
+	ErrDuplicateMove = errors.New("role listed more than once")
+	ErrManagedRole   = errors.New("a bot's managed role cannot be deleted or renamed")
 )
 
 type service struct {
-	repo *repositories.GuildRoleRepository
+	repo    *repositories.GuildRoleRepository
+	permSvc permissions.Service
+	audit   auditlog.Service
 }
 
-func NewService(repo *repositories.GuildRoleRepository) Service {
-	return &service{repo}
+func NewService(
+	repo *repositories.GuildRoleRepository,
+	permSvc permissions.Service,
+	audit auditlog.Service,
+) Service {
+	return &service{repo, permSvc, audit}
 }
 
-func (s *service) Create(ctx context.Context, role *guilds.GuildRole) error {
-	return s.repo.Create(ctx, role)
+func (s *service) Create(ctx context.Context, role *guilds.GuildRole, actorID string) error {
+	if err := s.requireBelow(ctx, role.GuildID, actorID, role.Position, role.Permissions); err != nil {
+		return err
+	}
+	role.Managed = false // only the bot authorization flow creates those
+	role.BotID = nil
+	if err := s.repo.Create(ctx, role); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    role.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditRoleCreate,
+		TargetType: guilds.AuditTargetRole,
+		TargetID:   role.ID,
+		After:      role,
+	})
 }
 
 func (s *service) Get(ctx context.Context, id string) (*guilds.GuildRole, error) {
@@ -27,10 +60,179 @@ func (s *service) List(ctx context.Context, guildID string) ([]guilds.GuildRole,
 	return s.repo.ListByGuild(ctx, guildID)
 }
 
-func (s *service) Update(ctx context.Context, role *guilds.GuildRole) error {
-	return s.repo.Update(ctx, role)
+func (s *service) Update(ctx context.Context, role *guilds.GuildRole, actorID string) error {
+	before, err := s.repo.Get(ctx, role.ID)
+	if err != nil {
+		return err
+	}
+	// bits the role already has may stay, new ones must be the actor's
+	added := role.Permissions &^ before.Permissions
+	if err := s.requireBelow(ctx, before.GuildID, actorID, before.Position, added); err != nil {
+		return err
+	}
+	if before.IsEveryone() {
+		// only its permissions and color may change
+		if role.Name != before.Name {
+			return ErrEveryoneRole
+		}
+		role.Hoist = false
+	}
+	// roles never change guild, and positions only change through Reorder,
+	// which enforces the hierarchy
+	role.GuildID = before.GuildID
+	role.Position = before.Position
+	if before.Managed && role.Name != before.Name {
+		return ErrManagedRole
+	}
+	role.Managed = before.Managed
+	role.BotID = before.BotID
+	if err := s.repo.Update(ctx, role); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    before.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditRoleUpdate,
+		TargetType: guilds.AuditTargetRole,
+		TargetID:   role.ID,
+		Before:     before,
+		After:      role,
+	})
 }
 
-func (s *service) Delete(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+func (s *service) Delete(ctx context.Context, id, actorID string) error {
+	before, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.requireBelow(ctx, before.GuildID, actorID, before.Position, 0); err != nil {
+		return err
+	}
+	if before.IsEveryone() {
+		return ErrEveryoneRole
+	}
+	if before.Managed {
+		return ErrManagedRole
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    before.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditRoleDelete,
+		TargetType: guilds.AuditTargetRole,
+		TargetID:   id,
+		Before:     before,
+	})
+}
+
+// requireBelow checks that the actor may manage roles in the guild, that
+// position sits below their own highest role and that they hold every bit
+// of grant. Owners and administrators hold them all.
+func (s *service) requireBelow(ctx context.Context, guildID, actorID string, position int, grant uint64) error {
+	perms, err := s.permSvc.GuildPermissions(ctx, guildID, actorID)
+	if err != nil {
+		return err
+	}
+	if perms&guilds.PermManageRoles == 0 {
+		return permissions.ErrMissingPermission
+	}
+	if grant&^perms != 0 {
+		return permissions.ErrCannotGrant
+	}
+	top, err := s.permSvc.HighestPosition(ctx, guildID, actorID)
+	if err != nil {
+		return err
+	}
+	if position >= top {
+		return permissions.ErrHierarchy
+	}
+	return nil
+}
+
+func (s *service) Reorder(ctx context.Context, guildID string, moves []Move, actorID string) error {
+	if err := s.permSvc.Require(ctx, guildID, actorID, guilds.PermManageRoles); err != nil {
+		return err
+	}
+	top, err := s.permSvc.HighestPosition(ctx, guildID, actorID)
+	if err != nil {
+		return err
+	}
+	requested := make(map[string]int, len(moves))
+	for _, m := range moves {
+		if _, dup := requested[m.ID]; dup {
+			return ErrDuplicateMove
+		}
+		if m.Position >= top {
+			return permissions.ErrHierarchy
+		}
+		requested[m.ID] = m.Position
+	}
+
+	var before, after []guilds.GuildRole
+	err = s.repo.Reorder(ctx, guildID, func(rows []guilds.GuildRole) ([]guilds.GuildRole, error) {
+		// Roles at or above the actor's top stay locked, in order, above
+		// everything the actor may arrange.
+		var movable, locked []utils.Positioned
+		index := make(map[string]guilds.GuildRole, len(rows))
+		seen := 0
+		for _, r := range rows {
+			if r.IsEveryone() {
+				if _, ok := requested[r.ID]; ok {
+					return nil, ErrEveryoneRole
+				}
+				continue
+			}
+			index[r.ID] = r
+			item := utils.Positioned{ID: r.ID, Position: r.Position}
+			pos, ok := requested[r.ID]
+			if r.Position >= top {
+				if ok {
+					return nil, permissions.ErrHierarchy
+				}
+				locked = append(locked, item)
+				continue
+			}
+			if ok {
+				seen++
+				item.Position = pos
+				item.Moved = true
+			}
+			movable = append(movable, item)
+		}
+		if seen != len(requested) {
+			return nil, ErrUnknownRole
+		}
+
+		order := append(utils.DenseOrder(movable), utils.DenseOrder(locked)...)
+		for i, id := range order {
+			r := index[id]
+			if r.Position == i+1 {
+				continue
+			}
+			before = append(before, r)
+			r.Position = i + 1
+			after = append(after, r)
+		}
+		return after, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range after {
+		if err := s.audit.Record(ctx, auditlog.Record{
+			GuildID:    guildID,
+			ActorID:    actorID,
+			Action:     guilds.AuditRoleUpdate,
+			TargetType: guilds.AuditTargetRole,
+			TargetID:   after[i].ID,
+			Before:     map[string]interface{}{"position": before[i].Position},
+			After:      map[string]interface{}{"position": after[i].Position},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -4,20 +4,43 @@ import (
 	"context"
 
 	mg "launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
 )
 
 // Service defines guild‐related business logic.
 type Service interface {
 	// Guild CRUD
-	CreateGuild(ctx context.Context, guild *mg.Guild, ownerID string) error
+	CreateGuild(ctx context.Context, guild *mg.Guild, templateID, ownerID string) error
 	ListGuilds(ctx context.Context) ([]mg.Guild, error)
 	GetGuild(ctx context.Context, guildID string) (*mg.Guild, error)
 	UpdateGuild(ctx context.Context, guildID string, update *mg.Guild, requesterID string) error
-	DeleteGuild(ctx context.Context, guildID string, requesterID string) error
+	// DeleteGuild cascades to everything in the guild; owner only, confirmed by password.
+	DeleteGuild(ctx context.Context, guildID, password, requesterID string) error
+	TransferOwnership(ctx context.Context, guildID, newOwnerID, requesterID string) error
 
 	// Membership management
 	AddMember(ctx context.Context, guildID, userID string, roleIDs []string, requesterID string) error
 	UpdateMemberRoles(ctx context.Context, guildID, userID string, roleIDs []string, requesterID string) error
 	RemoveMember(ctx context.Context, guildID, userID, requesterID string) error
 	ListMembers(ctx context.Context, guildID string) ([]mg.GuildMember, error)
+	// MutualGuilds lists the guilds viewerID and userID share.
+	MutualGuilds(ctx context.Context, viewerID, userID string) ([]mg.Guild, error)
+	// SetHideActivity lets a member keep their activity from the guild.
+	SetHideActivity(ctx context.Context, guildID, userID string, hide bool) error
+
+	// Single role assignment; role IDs must belong to the guild.
+	AddMemberRole(ctx context.Context, guildID, userID, roleID, requesterID string) error
+	RemoveMemberRole(ctx context.Context, guildID, userID, roleID, requesterID string) error
+	ListMembersByRole(ctx context.Context, guildID, roleID string) ([]mg.GuildMember, error)
+
+	// ListAuditLog pages through the guild's audit log; requires view-audit-log.
+	ListAuditLog(
+		ctx context.Context, guildID, requesterID string, f repositories.AuditLogFilter, page, limit int,
+	) (*auditlog.Page, error)
+
+	// Templates: built-ins plus structures exported from existing guilds.
+	ListTemplates(ctx context.Context, requesterID string) ([]Template, error)
+	GetTemplate(ctx context.Context, templateID string) (*Template, error)
+	ExportTemplate(ctx context.Context, guildID, name, description, requesterID string) (*Template, error)
 }
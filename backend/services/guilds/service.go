@@ -4,50 +4,215 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 
+	m "launay-dot-one/models"
 	"launay-dot-one/models/guilds"
 	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+	"launay-dot-one/services/events"
+	"launay-dot-one/services/permissions"
 )
 
-var ErrUnauthorized = errors.New("unauthorized")
+var (
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrInvalidRole     = errors.New("role does not belong to this guild")
+	ErrManagedRole     = errors.New("a bot's managed role can't be assigned or removed")
+	ErrBotMember       = errors.New("bots join guilds through bot authorization")
+	ErrUnknownTemplate = errors.New("unknown guild template")
+	ErrInvalidPassword = errors.New("invalid password")
+	ErrNotMember       = errors.New("user is not a member of this guild")
+	ErrRoleHierarchy   = errors.New("you can only manage members and roles below your highest role")
+)
 
 type service struct {
-	guildRepo  *repositories.GuildRepository
-	memberRepo *repositories.GuildMemberRepository
+	guildRepo     *repositories.GuildRepository
+	memberRepo    *repositories.GuildMemberRepository
+	roleRepo      *repositories.GuildRoleRepository
+	categoryRepo  *repositories.CategoryRepository
+	channelRepo   *repositories.ChannelRepository
+	overwriteRepo *repositories.PermissionOverwriteRepository
+	templateRepo  *repositories.GuildTemplateRepository
+	userRepo      *repositories.UserRepository
+	banRepo       *repositories.GuildBanRepository
+	permSvc       permissions.Service
+	audit         auditlog.Service
+	events        events.Publisher
 }
 
 // NewService constructs a guild service.
 func NewService(
 	guildRepo *repositories.GuildRepository,
 	memberRepo *repositories.GuildMemberRepository,
+	roleRepo *repositories.GuildRoleRepository,
+	categoryRepo *repositories.CategoryRepository,
+	channelRepo *repositories.ChannelRepository,
+	overwriteRepo *repositories.PermissionOverwriteRepository,
+	templateRepo *repositories.GuildTemplateRepository,
+	userRepo *repositories.UserRepository,
+	banRepo *repositories.GuildBanRepository,
+	permSvc permissions.Service,
+	audit auditlog.Service,
+	events events.Publisher,
 ) Service {
-	return &service{guildRepo: guildRepo, memberRepo: memberRepo}
+	return &service{
+		guildRepo:     guildRepo,
+		memberRepo:    memberRepo,
+		roleRepo:      roleRepo,
+		categoryRepo:  categoryRepo,
+		channelRepo:   channelRepo,
+		overwriteRepo: overwriteRepo,
+		templateRepo:  templateRepo,
+		userRepo:      userRepo,
+		banRepo:       banRepo,
+		permSvc:       permSvc,
+		audit:         audit,
+		events:        events,
+	}
 }
 
-func (s *service) CreateGuild(ctx context.Context, guild *guilds.Guild, ownerID string) error {
+// CreateGuild creates the guild, its @everyone role and the owner's
+// membership, plus the template's structure when templateID is set, all
+// in one transaction.
+func (s *service) CreateGuild(ctx context.Context, guild *guilds.Guild, templateID, ownerID string) error {
+	structure := blankStructure
+	if templateID != "" {
+		tpl, err := s.GetTemplate(ctx, templateID)
+		if err != nil {
+			return err
+		}
+		structure = tpl.Structure
+	}
+
 	guild.ID = uuid.NewString()
 	now := time.Now()
 	guild.OwnerID = ownerID
 	guild.CreatedAt = now
 	guild.UpdatedAt = now
 
-	if err := s.guildRepo.Create(ctx, guild); err != nil {
-		return err
-	}
-
-	// add owner as first member with no roles (roles can be assigned later)
-	mem := &guilds.GuildMember{
+	// the owner holds no explicit roles; ownership already grants everything
+	owner := &guilds.GuildMember{
 		GuildID:   guild.ID,
 		UserID:    ownerID,
-		RoleIDs:   datatypes.JSON([]byte("[]")),
 		JoinedAt:  now,
 		UpdatedAt: now,
 	}
-	return s.memberRepo.Add(ctx, mem)
+	if err := s.guildRepo.CreateSeeded(ctx, buildSeed(guild, owner, structure)); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    guild.ID,
+		ActorID:    ownerID,
+		Action:     guilds.AuditGuildCreate,
+		TargetType: guilds.AuditTargetGuild,
+		TargetID:   guild.ID,
+		After:      guild,
+		Reason:     templateReason(templateID),
+	})
+}
+
+func templateReason(templateID string) string {
+	if templateID == "" {
+		return ""
+	}
+	return "created from template " + templateID
+}
+
+// ListTemplates returns the built-in templates followed by those the
+// requester exported.
+func (s *service) ListTemplates(ctx context.Context, requesterID string) ([]Template, error) {
+	out := make([]Template, 0, len(builtInTemplates))
+	for _, id := range builtInTemplateIDs {
+		out = append(out, builtInTemplates[id])
+	}
+	stored, err := s.templateRepo.ListByCreator(ctx, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stored {
+		t, err := fromStored(&stored[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, nil
+}
+
+// GetTemplate resolves a built-in ID or a stored template's UUID.
+func (s *service) GetTemplate(ctx context.Context, templateID string) (*Template, error) {
+	if t, ok := builtInTemplates[templateID]; ok {
+		return &t, nil
+	}
+	if _, err := uuid.Parse(templateID); err != nil {
+		return nil, ErrUnknownTemplate
+	}
+	stored, err := s.templateRepo.Get(ctx, templateID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnknownTemplate
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromStored(stored)
+}
+
+// ExportTemplate snapshots the guild's roles, categories, channels and
+// role overwrites as a stored template; requires administrator.
+func (s *service) ExportTemplate(
+	ctx context.Context, guildID, name, description, requesterID string,
+) (*Template, error) {
+	if err := s.permSvc.Require(ctx, guildID, requesterID, guilds.PermAdministrator); err != nil {
+		return nil, ErrUnauthorized
+	}
+	roles, err := s.roleRepo.ListByGuild(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categoryRepo.ListByGuild(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	channels, err := s.channelRepo.ListByGuild(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	overwrites, err := s.overwriteRepo.List(ctx, guildID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	structure := exportStructure(guildID, roles, categories, channels, overwrites)
+	raw, err := json.Marshal(structure)
+	if err != nil {
+		return nil, err
+	}
+	stored := &guilds.GuildTemplate{
+		Name:          name,
+		Description:   description,
+		SourceGuildID: guildID,
+		CreatorID:     requesterID,
+		Structure:     datatypes.JSON(raw),
+		CreatedAt:     time.Now(),
+	}
+	if err := s.templateRepo.Create(ctx, stored); err != nil {
+		return nil, err
+	}
+	return &Template{ID: stored.ID, Name: name, Description: description, Structure: structure}, nil
+}
+
+func fromStored(t *guilds.GuildTemplate) (*Template, error) {
+	out := &Template{ID: t.ID, Name: t.Name, Description: t.Description}
+	if err := json.Unmarshal(t.Structure, &out.Structure); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
 func (s *service) ListGuilds(ctx context.Context) ([]guilds.Guild, error) {
@@ -58,12 +223,16 @@ func (s *service) GetGuild(ctx context.Context, guildID string) (*guilds.Guild,
 	return s.guildRepo.GetByID(ctx, guildID)
 }
 
+// UpdateGuild renames or re-describes the guild; requires manage-guild.
 func (s *service) UpdateGuild(ctx context.Context, guildID string, update *guilds.Guild, requesterID string) error {
-	// TODO: check requester permissions
+	if err := s.permSvc.Require(ctx, guildID, requesterID, guilds.PermManageGuild); err != nil {
+		return ErrUnauthorized
+	}
 	guild, err := s.guildRepo.GetByID(ctx, guildID)
 	if err != nil {
 		return err
 	}
+	before := *guild
 	if update.Name != "" {
 		guild.Name = update.Name
 	}
@@ -71,47 +240,413 @@ func (s *service) UpdateGuild(ctx context.Context, guildID string, update *guild
 		guild.Description = update.Description
 	}
 	guild.UpdatedAt = time.Now()
-	return s.guildRepo.Update(ctx, guild)
+	if err := s.guildRepo.Update(ctx, guild); err != nil {
+		return err
+	}
+	if err := s.audit.Record(ctx, auditlog.Record{
+		GuildID:    guildID,
+		ActorID:    requesterID,
+		Action:     guilds.AuditGuildUpdate,
+		TargetType: guilds.AuditTargetGuild,
+		TargetID:   guildID,
+		Before:     &before,
+		After:      guild,
+	}); err != nil {
+		return err
+	}
+	_ = s.events.Publish(ctx, guildID, m.EventGuildUpdated, guild)
+	return nil
 }
 
-func (s *service) DeleteGuild(ctx context.Context, guildID, requesterID string) error {
-	// TODO: check requester permissions
-	return s.guildRepo.Delete(ctx, guildID)
+// DeleteGuild permanently removes the guild and everything in it. Only
+// the owner may do so, and only after re-entering their password.
+func (s *service) DeleteGuild(ctx context.Context, guildID, password, requesterID string) error {
+	guild, err := s.guildRepo.GetByID(ctx, guildID)
+	if err != nil {
+		return err
+	}
+	if guild.OwnerID != requesterID {
+		return ErrUnauthorized
+	}
+	u, err := s.userRepo.GetByID(ctx, requesterID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
+		return ErrInvalidPassword
+	}
+	if err := s.guildRepo.Delete(ctx, guildID); err != nil {
+		return err
+	}
+	// the entry outlives the guild until retention prunes it
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    guildID,
+		ActorID:    requesterID,
+		Action:     guilds.AuditGuildDelete,
+		TargetType: guilds.AuditTargetGuild,
+		TargetID:   guildID,
+		Before:     guild,
+	})
 }
 
+// TransferOwnership hands the guild to another member; owner only.
+func (s *service) TransferOwnership(ctx context.Context, guildID, newOwnerID, requesterID string) error {
+	guild, err := s.guildRepo.GetByID(ctx, guildID)
+	if err != nil {
+		return err
+	}
+	if guild.OwnerID != requesterID {
+		return ErrUnauthorized
+	}
+	if newOwnerID == requesterID {
+		return nil
+	}
+	if _, err := s.memberRepo.Get(ctx, guildID, newOwnerID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotMember
+		}
+		return err
+	}
+	guild.OwnerID = newOwnerID
+	guild.UpdatedAt = time.Now()
+	if err := s.guildRepo.Update(ctx, guild); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    guildID,
+		ActorID:    requesterID,
+		Action:     guilds.AuditGuildOwnerTransfer,
+		TargetType: guilds.AuditTargetGuild,
+		TargetID:   guildID,
+		Before:     map[string]interface{}{"owner_id": requesterID},
+		After:      map[string]interface{}{"owner_id": newOwnerID},
+	})
+}
+
+// AddMember adds a user directly, bypassing invites; only members with
+// manage-guild may do so. Everyone else joins through an invite. Banned
+// users can't be added back either way.
 func (s *service) AddMember(ctx context.Context, guildID, userID string, roleIDs []string, requesterID string) error {
-	// TODO: check requester permissions
+	if err := s.permSvc.Require(ctx, guildID, requesterID, guilds.PermManageGuild); err != nil {
+		return ErrUnauthorized
+	}
 	now := time.Now()
-	b, _ := json.Marshal(roleIDs)
+	if _, err := s.banRepo.GetActive(ctx, guildID, userID, now); err == nil {
+		return repositories.ErrBannedFromGuild
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if u, err := s.userRepo.GetByID(ctx, userID); err == nil && u.Bot {
+		return ErrBotMember
+	}
+	roleIDs, err := s.validateRoles(ctx, guildID, roleIDs)
+	if err != nil {
+		return err
+	}
+	if err := s.requireAssignable(ctx, guildID, requesterID, "", roleIDs); err != nil {
+		return err
+	}
 	mem := &guilds.GuildMember{
 		GuildID:   guildID,
 		UserID:    userID,
-		RoleIDs:   datatypes.JSON(b),
+		RoleIDs:   roleIDs,
 		JoinedAt:  now,
 		UpdatedAt: now,
 	}
-	return s.memberRepo.Add(ctx, mem)
+	if err := s.memberRepo.Add(ctx, mem); err != nil {
+		return err
+	}
+	if err := s.audit.Record(ctx, auditlog.Record{
+		GuildID:    guildID,
+		ActorID:    requesterID,
+		Action:     guilds.AuditMemberAdd,
+		TargetType: guilds.AuditTargetMember,
+		TargetID:   userID,
+		After:      map[string]interface{}{"role_ids": roleIDs},
+	}); err != nil {
+		return err
+	}
+	_ = s.events.Publish(ctx, guildID, m.EventMemberJoined, events.MemberJoined{
+		UserID:   userID,
+		JoinedAt: now,
+		AddedBy:  requesterID,
+	})
+	return nil
 }
 
+// UpdateMemberRoles replaces the member's roles; requires manage-roles.
 func (s *service) UpdateMemberRoles(
 	ctx context.Context, guildID, userID string, roleIDs []string, requesterID string,
 ) error {
-	// TODO: check requester permissions
+	if err := s.permSvc.Require(ctx, guildID, requesterID, guilds.PermManageRoles); err != nil {
+		return ErrUnauthorized
+	}
 	mem, err := s.memberRepo.Get(ctx, guildID, userID)
 	if err != nil {
 		return err
 	}
-	b, _ := json.Marshal(roleIDs)
-	mem.RoleIDs = datatypes.JSON(b)
-	mem.UpdatedAt = time.Now()
-	return s.memberRepo.Update(ctx, mem)
+	// a bot keeps its managed role whatever the list says
+	managed, err := s.managedRoles(ctx, guildID, mem.RoleIDs)
+	if err != nil {
+		return err
+	}
+	requested := make([]string, 0, len(roleIDs))
+	for _, id := range roleIDs {
+		if !managed[id] {
+			requested = append(requested, id)
+		}
+	}
+	roleIDs, err = s.validateRoles(ctx, guildID, requested)
+	if err != nil {
+		return err
+	}
+	if err := s.requireAssignable(ctx, guildID, requesterID, userID, changedRoles(mem.RoleIDs, roleIDs, managed)); err != nil {
+		return err
+	}
+	for id := range managed {
+		roleIDs = append(roleIDs, id)
+	}
+	if err := s.memberRepo.SetRoles(ctx, guildID, userID, roleIDs); err != nil {
+		return err
+	}
+	return s.recordRoleChange(ctx, guildID, userID, requesterID, mem.RoleIDs, roleIDs)
 }
 
+// AddMemberRole assigns one role; requires manage-roles.
+func (s *service) AddMemberRole(ctx context.Context, guildID, userID, roleID, requesterID string) error {
+	if err := s.permSvc.Require(ctx, guildID, requesterID, guilds.PermManageRoles); err != nil {
+		return ErrUnauthorized
+	}
+	if _, err := s.validateRoles(ctx, guildID, []string{roleID}); err != nil {
+		return err
+	}
+	if err := s.requireAssignable(ctx, guildID, requesterID, userID, []string{roleID}); err != nil {
+		return err
+	}
+	mem, err := s.memberRepo.Get(ctx, guildID, userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range mem.RoleIDs {
+		if id == roleID {
+			return nil
+		}
+	}
+	if err := s.memberRepo.AddRole(ctx, guildID, userID, roleID); err != nil {
+		return err
+	}
+	after := append(append([]string{}, mem.RoleIDs...), roleID)
+	return s.recordRoleChange(ctx, guildID, userID, requesterID, mem.RoleIDs, after)
+}
+
+// RemoveMemberRole unassigns one role; requires manage-roles.
+func (s *service) RemoveMemberRole(ctx context.Context, guildID, userID, roleID, requesterID string) error {
+	if err := s.permSvc.Require(ctx, guildID, requesterID, guilds.PermManageRoles); err != nil {
+		return ErrUnauthorized
+	}
+	managed, err := s.managedRoles(ctx, guildID, []string{roleID})
+	if err != nil {
+		return err
+	}
+	if managed[roleID] {
+		return ErrManagedRole
+	}
+	if err := s.requireAssignable(ctx, guildID, requesterID, userID, []string{roleID}); err != nil {
+		return err
+	}
+	mem, err := s.memberRepo.Get(ctx, guildID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.memberRepo.RemoveRole(ctx, guildID, userID, roleID); err != nil {
+		return err
+	}
+	after := make([]string, 0, len(mem.RoleIDs))
+	for _, id := range mem.RoleIDs {
+		if id != roleID {
+			after = append(after, id)
+		}
+	}
+	return s.recordRoleChange(ctx, guildID, userID, requesterID, mem.RoleIDs, after)
+}
+
+// requireAssignable enforces the role hierarchy on changing a member's
+// roles: unless they own the guild, the requester must outrank the member
+// (userID, empty for someone joining) and every role given or taken must
+// sit below the requester's highest one.
+func (s *service) requireAssignable(ctx context.Context, guildID, requesterID, userID string, roleIDs []string) error {
+	top, err := s.permSvc.HighestPosition(ctx, guildID, requesterID)
+	if err != nil {
+		return err
+	}
+	if top == math.MaxInt { // the owner
+		return nil
+	}
+	if userID != "" {
+		err := s.permSvc.RequireAbove(ctx, guildID, requesterID, userID)
+		if errors.Is(err, permissions.ErrHierarchy) {
+			return ErrRoleHierarchy
+		}
+		if err != nil {
+			return err
+		}
+	}
+	roles, err := s.roleRepo.ListByIDs(ctx, guildID, roleIDs)
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		if r.Position >= top {
+			return ErrRoleHierarchy
+		}
+	}
+	return nil
+}
+
+// changedRoles lists the roles in exactly one of before and after,
+// leaving out managed ones, which never change.
+func changedRoles(before, after []string, managed map[string]bool) []string {
+	had := make(map[string]bool, len(before))
+	for _, id := range before {
+		had[id] = true
+	}
+	has := make(map[string]bool, len(after))
+	for _, id := range after {
+		has[id] = true
+	}
+	var out []string
+	for id := range had {
+		if !has[id] && !managed[id] {
+			out = append(out, id)
+		}
+	}
+	for id := range has {
+		if !had[id] && !managed[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// ListMembersByRole returns the members holding a role of this guild.
+func (s *service) ListMembersByRole(ctx context.Context, guildID, roleID string) ([]guilds.GuildMember, error) {
+	if roleID == guildID {
+		return s.memberRepo.ListByGuild(ctx, guildID) // @everyone
+	}
+	if _, err := s.validateRoles(ctx, guildID, []string{roleID}); err != nil {
+		return nil, err
+	}
+	return s.memberRepo.ListByRole(ctx, guildID, roleID)
+}
+
+// validateRoles de-duplicates ids and returns ErrInvalidRole unless every
+// one names an assignable role of this guild.
+func (s *service) validateRoles(ctx context.Context, guildID string, ids []string) ([]string, error) {
+	seen := make(map[string]bool, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == guildID {
+			return nil, ErrInvalidRole // @everyone is implicit, never assigned
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	roles, err := s.roleRepo.ListByIDs(ctx, guildID, out)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) != len(out) {
+		return nil, ErrInvalidRole
+	}
+	for _, r := range roles {
+		if r.Managed {
+			return nil, ErrManagedRole
+		}
+	}
+	return out, nil
+}
+
+// managedRoles returns which of ids are bots' managed roles.
+func (s *service) managedRoles(ctx context.Context, guildID string, ids []string) (map[string]bool, error) {
+	roles, err := s.roleRepo.ListByIDs(ctx, guildID, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool)
+	for _, r := range roles {
+		if r.Managed {
+			out[r.ID] = true
+		}
+	}
+	return out, nil
+}
+
+func (s *service) recordRoleChange(ctx context.Context, guildID, userID, actorID string, before, after []string) error {
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    guildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditMemberRoleUpdate,
+		TargetType: guilds.AuditTargetMember,
+		TargetID:   userID,
+		Before:     map[string]interface{}{"role_ids": before},
+		After:      map[string]interface{}{"role_ids": after},
+	})
+}
+
+// RemoveMember lets a member leave, or a member with kick-members remove
+// someone below them in the role hierarchy.
 func (s *service) RemoveMember(ctx context.Context, guildID, userID, requesterID string) error {
-	// TODO: check requester permissions
-	return s.memberRepo.Remove(ctx, guildID, userID)
+	action := guilds.AuditMemberLeave
+	if userID != requesterID {
+		if err := s.permSvc.Require(ctx, guildID, requesterID, guilds.PermKickMembers); err != nil {
+			return ErrUnauthorized
+		}
+		if err := s.permSvc.RequireAbove(ctx, guildID, requesterID, userID); err != nil {
+			return ErrUnauthorized
+		}
+		action = guilds.AuditMemberKick
+	}
+	if err := s.memberRepo.Remove(ctx, guildID, userID); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    guildID,
+		ActorID:    requesterID,
+		Action:     action,
+		TargetType: guilds.AuditTargetMember,
+		TargetID:   userID,
+	})
 }
 
 func (s *service) ListMembers(ctx context.Context, guildID string) ([]guilds.GuildMember, error) {
 	return s.memberRepo.ListByGuild(ctx, guildID)
 }
+
+func (s *service) MutualGuilds(ctx context.Context, viewerID, userID string) ([]guilds.Guild, error) {
+	return s.memberRepo.ListMutualGuilds(ctx, viewerID, userID)
+}
+
+func (s *service) SetHideActivity(ctx context.Context, guildID, userID string, hide bool) error {
+	ok, err := s.memberRepo.SetHideActivity(ctx, guildID, userID, hide)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotMember
+	}
+	return nil
+}
+
+func (s *service) ListAuditLog(
+	ctx context.Context,
+	guildID, requesterID string,
+	f repositories.AuditLogFilter,
+	page, limit int,
+) (*auditlog.Page, error) {
+	if err := s.permSvc.Require(ctx, guildID, requesterID, guilds.PermViewAuditLog); err != nil {
+		return nil, ErrUnauthorized
+	}
+	return s.audit.List(ctx, guildID, f, page, limit)
+}
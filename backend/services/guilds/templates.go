@@ -0,0 +1,331 @@
+package guilds
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
+)
+
+// Template is a guild skeleton that CreateGuild can start from: either
+// one of the built-ins below or one exported from an existing guild.
+type Template struct {
+	ID          string                   `json:"id"`
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	BuiltIn     bool                     `json:"built_in"`
+	Structure   guilds.TemplateStructure `json:"structure"`
+}
+
+const (
+	text  = string(guilds.ChannelText)
+	voice = string(guilds.ChannelVoice)
+
+	modPerms = guilds.PermKickMembers | guilds.PermBanMembers | guilds.PermModerateMembers |
+		guilds.PermManageMessages | guilds.PermViewAuditLog | guilds.PermMuteMembers
+	readOnly = int64(guilds.PermSendMessages)
+)
+
+// builtInTemplateIDs lists the built-in templates in display order.
+var builtInTemplateIDs = []string{"community", "study-group", "gaming"}
+
+// builtInTemplates are keyed by ID.
+var builtInTemplates = map[string]Template{
+	"community": {
+		ID:          "community",
+		Name:        "Community",
+		Description: "Announcements, open chat and a moderation team.",
+		BuiltIn:     true,
+		Structure: guilds.TemplateStructure{
+			EveryonePermissions: guilds.PermDefaultEveryone,
+			Roles: []guilds.TemplateRole{
+				{Key: "moderator", Name: "Moderator", Permissions: modPerms, Hoist: true, Position: 1},
+			},
+			Categories: []guilds.TemplateCategory{
+				{
+					Name: "Information", Position: 0,
+					Overwrites: []guilds.TemplateOverwrite{
+						{Role: guilds.TemplateEveryone, Deny: readOnly},
+						{Role: "moderator", Allow: readOnly},
+					},
+					Channels: []guilds.TemplateChannel{
+						{Name: "welcome", Type: text, Position: 0},
+						{Name: "rules", Type: text, Position: 1},
+						{Name: "announcements", Type: text, Position: 2},
+					},
+				},
+				{
+					Name: "Text Channels", Position: 1,
+					Channels: []guilds.TemplateChannel{
+						{Name: "general", Type: text, Position: 0},
+						{Name: "off-topic", Type: text, Position: 1},
+					},
+				},
+				{
+					Name: "Voice Channels", Position: 2,
+					Channels: []guilds.TemplateChannel{
+						{Name: "Lounge", Type: voice, Position: 0},
+					},
+				},
+			},
+		},
+	},
+	"study-group": {
+		ID:          "study-group",
+		Name:        "Study group",
+		Description: "Shared resources, homework help and quiet study rooms.",
+		BuiltIn:     true,
+		Structure: guilds.TemplateStructure{
+			EveryonePermissions: guilds.PermDefaultEveryone,
+			Roles: []guilds.TemplateRole{
+				{Key: "tutor", Name: "Tutor", Permissions: guilds.PermManageMessages | guilds.PermMuteMembers, Hoist: true, Position: 1},
+			},
+			Categories: []guilds.TemplateCategory{
+				{
+					Name: "Study", Position: 0,
+					Channels: []guilds.TemplateChannel{
+						{Name: "general", Type: text, Position: 0},
+						{
+							Name: "resources", Type: text, Position: 1,
+							Overwrites: []guilds.TemplateOverwrite{
+								{Role: guilds.TemplateEveryone, Deny: readOnly},
+								{Role: "tutor", Allow: readOnly},
+							},
+						},
+						{Name: "homework-help", Type: text, Position: 2},
+					},
+				},
+				{
+					Name: "Study Rooms", Position: 1,
+					Channels: []guilds.TemplateChannel{
+						{Name: "Room 1", Type: voice, Position: 0},
+						{Name: "Room 2", Type: voice, Position: 1},
+					},
+				},
+			},
+		},
+	},
+	"gaming": {
+		ID:          "gaming",
+		Name:        "Gaming",
+		Description: "Clips, looking-for-group and squad voice channels.",
+		BuiltIn:     true,
+		Structure: guilds.TemplateStructure{
+			EveryonePermissions: guilds.PermDefaultEveryone,
+			Roles: []guilds.TemplateRole{
+				{Key: "moderator", Name: "Moderator", Permissions: modPerms, Hoist: true, Position: 2},
+				{Key: "streamer", Name: "Streamer", Hoist: true, Position: 1},
+			},
+			Categories: []guilds.TemplateCategory{
+				{
+					Name: "Text Channels", Position: 0,
+					Channels: []guilds.TemplateChannel{
+						{Name: "general", Type: text, Position: 0},
+						{Name: "clips", Type: text, Position: 1},
+						{Name: "looking-for-group", Type: text, Position: 2},
+					},
+				},
+				{
+					Name: "Voice Channels", Position: 1,
+					Channels: []guilds.TemplateChannel{
+						{Name: "Lobby", Type: voice, Position: 0},
+						{Name: "Squad 1", Type: voice, Position: 1},
+						{Name: "Squad 2", Type: voice, Position: 2},
+					},
+				},
+			},
+		},
+	},
+}
+
+// blankStructure is used when CreateGuild gets no template: just @everyone.
+var blankStructure = guilds.TemplateStructure{EveryonePermissions: guilds.PermDefaultEveryone}
+
+// buildSeed turns a template structure into rows for a new guild. Every
+// row gets a fresh ID; role keys resolve to those IDs, and @everyone to
+// the guild ID. Overwrites naming an unknown role key are dropped.
+func buildSeed(guild *guilds.Guild, owner *guilds.GuildMember, st guilds.TemplateStructure) *repositories.GuildSeed {
+	now := time.Now()
+	seed := &repositories.GuildSeed{Guild: guild, Owner: owner}
+
+	roleIDs := map[string]string{guilds.TemplateEveryone: guild.ID}
+	seed.Roles = append(seed.Roles, guilds.GuildRole{
+		ID:          guild.ID,
+		GuildID:     guild.ID,
+		Name:        guilds.EveryoneRoleName,
+		Permissions: st.EveryonePermissions,
+		Position:    0,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	for i, r := range st.Roles {
+		id := uuid.NewString()
+		roleIDs[r.Key] = id
+		pos := r.Position
+		if pos <= 0 {
+			pos = i + 1 // position 0 is reserved for @everyone
+		}
+		seed.Roles = append(seed.Roles, guilds.GuildRole{
+			ID:          id,
+			GuildID:     guild.ID,
+			Name:        r.Name,
+			Permissions: r.Permissions,
+			Color:       r.Color,
+			Hoist:       r.Hoist,
+			Position:    pos,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+
+	overwrite := func(ow guilds.TemplateOverwrite, categoryID, channelID string) {
+		roleID, ok := roleIDs[ow.Role]
+		if !ok {
+			return
+		}
+		seed.Overwrites = append(seed.Overwrites, guilds.PermissionOverwrite{
+			ID:            uuid.NewString(),
+			GuildID:       guild.ID,
+			CategoryID:    categoryID,
+			ChannelID:     channelID,
+			OverwriteType: guilds.OverwriteRole,
+			TargetID:      roleID,
+			Allow:         ow.Allow,
+			Deny:          ow.Deny,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+	// a categorised channel without overwrites of its own is synced to its
+	// category and gets copies of the category's
+	channel := func(tc guilds.TemplateChannel, categoryID *string, inherited []guilds.TemplateOverwrite) {
+		ch := guilds.Channel{
+			ID:         uuid.NewString(),
+			GuildID:    guild.ID,
+			CategoryID: categoryID,
+			Name:       tc.Name,
+			Type:       tc.Type,
+			Position:   tc.Position,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		if ch.Type == "" {
+			ch.Type = text
+		}
+		if guilds.ChannelType(ch.Type).AudioBased() {
+			ch.Bitrate = guilds.DefaultBitrate
+		}
+		own := tc.Overwrites
+		if categoryID != nil && len(own) == 0 {
+			ch.PermissionsSynced = true
+			own = inherited
+		}
+		seed.Channels = append(seed.Channels, ch)
+		for _, ow := range own {
+			overwrite(ow, "", ch.ID)
+		}
+	}
+
+	for _, tc := range st.Categories {
+		cat := guilds.Category{
+			ID:        uuid.NewString(),
+			GuildID:   guild.ID,
+			Name:      tc.Name,
+			Position:  tc.Position,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		seed.Categories = append(seed.Categories, cat)
+		for _, ow := range tc.Overwrites {
+			overwrite(ow, cat.ID, "")
+		}
+		catID := cat.ID
+		for _, ch := range tc.Channels {
+			channel(ch, &catID, tc.Overwrites)
+		}
+	}
+	for _, ch := range st.Channels {
+		channel(ch, nil, nil)
+	}
+	return seed
+}
+
+// exportStructure captures a guild's current layout. Role IDs become keys;
+// member-specific overwrites are not portable and are left out.
+func exportStructure(
+	guildID string,
+	roles []guilds.GuildRole,
+	categories []guilds.Category,
+	channels []guilds.Channel,
+	overwrites []guilds.PermissionOverwrite,
+) guilds.TemplateStructure {
+	st := guilds.TemplateStructure{
+		Roles:      []guilds.TemplateRole{},
+		Categories: []guilds.TemplateCategory{},
+		Channels:   []guilds.TemplateChannel{},
+	}
+
+	keys := map[string]string{guildID: guilds.TemplateEveryone}
+	for _, r := range roles {
+		if r.IsEveryone() {
+			st.EveryonePermissions = r.Permissions
+			continue
+		}
+		keys[r.ID] = r.ID
+		st.Roles = append(st.Roles, guilds.TemplateRole{
+			Key:         r.ID,
+			Name:        r.Name,
+			Permissions: r.Permissions,
+			Color:       r.Color,
+			Hoist:       r.Hoist,
+			Position:    r.Position,
+		})
+	}
+
+	byCategory := map[string][]guilds.TemplateOverwrite{}
+	byChannel := map[string][]guilds.TemplateOverwrite{}
+	for _, o := range overwrites {
+		key, ok := keys[o.TargetID]
+		if o.OverwriteType != guilds.OverwriteRole || !ok {
+			continue
+		}
+		tow := guilds.TemplateOverwrite{Role: key, Allow: o.Allow, Deny: o.Deny}
+		switch {
+		case o.ChannelID != "":
+			byChannel[o.ChannelID] = append(byChannel[o.ChannelID], tow)
+		case o.CategoryID != "":
+			byCategory[o.CategoryID] = append(byCategory[o.CategoryID], tow)
+		}
+	}
+
+	catIndex := map[string]int{}
+	for _, c := range categories {
+		catIndex[c.ID] = len(st.Categories)
+		st.Categories = append(st.Categories, guilds.TemplateCategory{
+			Name:       c.Name,
+			Position:   c.Position,
+			Overwrites: byCategory[c.ID],
+			Channels:   []guilds.TemplateChannel{},
+		})
+	}
+	for _, ch := range channels {
+		tc := guilds.TemplateChannel{
+			Name:     ch.Name,
+			Type:     ch.Type,
+			Position: ch.Position,
+		}
+		if !ch.PermissionsSynced {
+			tc.Overwrites = byChannel[ch.ID] // synced copies are rebuilt on import
+		}
+		if ch.CategoryID != nil {
+			if i, ok := catIndex[*ch.CategoryID]; ok {
+				st.Categories[i].Channels = append(st.Categories[i].Channels, tc)
+				continue
+			}
+		}
+		st.Channels = append(st.Channels, tc)
+	}
+	return st
+}
@@ -0,0 +1,79 @@
+package interactions
+
+import (
+	"context"
+	"encoding/json"
+
+	m "launay-dot-one/models"
+)
+
+type Service interface {
+	// CreateCommand, ListCommands, UpdateCommand and DeleteCommand manage a
+	// bot's commands. The bot itself or its owner may call them. guildID
+	// scopes a command to one guild the bot is in; "" makes it global.
+	CreateCommand(ctx context.Context, botID, guildID string, in Command, actorID string) (*m.ApplicationCommand, error)
+	ListCommands(ctx context.Context, botID, guildID, actorID string) ([]m.ApplicationCommand, error)
+
+	// UpdateCommand replaces a command's name, description and options;
+	// its scope can't change.
+	UpdateCommand(ctx context.Context, botID, commandID string, in Command, actorID string) (*m.ApplicationCommand, error)
+	DeleteCommand(ctx context.Context, botID, commandID, actorID string) error
+
+	// GuildCommands lists the commands a member can invoke in a guild.
+	GuildCommands(ctx context.Context, guildID, userID string) ([]m.ApplicationCommand, error)
+
+	// Invoke validates the options against the command's schema and hands
+	// the interaction to its bot: POSTed to the bot's interactions URL when
+	// it has one, otherwise sent as INTERACTION_CREATE on its socket. The
+	// invoker needs use-application-commands in the channel's guild.
+	Invoke(ctx context.Context, userID string, in Invocation) (*m.Interaction, error)
+
+	// Respond is the bot's answer: a message, or a deferral that promises
+	// follow-ups. It must arrive within the response deadline and only
+	// once. It needs no session: the interaction token authorises it.
+	Respond(ctx context.Context, id, token string, resp Response) (*m.Message, error)
+
+	// Followup posts another message for an interaction the bot already
+	// answered or deferred, within the follow-up window.
+	Followup(ctx context.Context, id, token string, msg Message) (*m.Message, error)
+}
+
+// Command is what a bot registers.
+type Command struct {
+	Name        string
+	Description string
+	Options     []m.CommandOption
+}
+
+// Invocation is a user running a command in a channel.
+type Invocation struct {
+	CommandID string        `json:"command_id" binding:"required"`
+	ChannelID string        `json:"channel_id" binding:"required"`
+	Options   []OptionValue `json:"options"`
+}
+
+// OptionValue is one raw option value as the invoking client sent it.
+type OptionValue struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Response is a bot's answer to an interaction. Data is required for
+// message responses.
+type Response struct {
+	Type m.InteractionResponseType `json:"type"`
+	Data *Message                  `json:"data,omitempty"`
+}
+
+// Message is what a bot posts in answer to an interaction.
+type Message struct {
+	Content     string          `json:"content"`
+	Attachments json.RawMessage `json:"attachments,omitempty"`
+}
+
+// Update is the INTERACTION_UPDATE payload sent to the invoking user when
+// the bot answers, defers or follows up. Message is nil for a deferral.
+type Update struct {
+	Interaction *m.Interaction `json:"interaction"`
+	Message     *m.Message     `json:"message,omitempty"`
+}
@@ -0,0 +1,732 @@
+package interactions
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/realtime"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/messaging"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/utils"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotBotOrOwner      = errors.New("only the bot or its owner can manage its commands")
+	ErrBotNotInGuild      = errors.New("bot is not a member of this guild")
+	ErrInvalidCommand     = errors.New("invalid command")
+	ErrCommandNameTaken   = errors.New("the bot already has a command with this name here")
+	ErrTooManyCommands    = fmt.Errorf("a bot can have at most %d commands per scope", m.MaxCommandsPerScope)
+	ErrUnknownCommand     = errors.New("unknown command")
+	ErrInvalidChannel     = errors.New("commands can only be used in text or announcement channels")
+	ErrInvalidOptions     = errors.New("invalid command options")
+	ErrTimedOut           = errors.New("you are timed out in this guild")
+	ErrBotUnavailable     = errors.New("the bot did not answer")
+	ErrInvalidToken       = errors.New("invalid interaction token")
+	ErrAlreadyResponded   = errors.New("interaction was already answered")
+	ErrDeadlinePassed     = errors.New("the response deadline has passed")
+	ErrNotAnswered        = errors.New("interaction must be answered before following up")
+	ErrInvalidResponse    = errors.New("invalid interaction response")
+	ErrInteractionExpired = errors.New("interaction has expired")
+)
+
+// Headers on interactions POSTed to a bot. The signature is the hex
+// Ed25519 signature of timestamp + body, checked with the bot's public key.
+const (
+	HeaderSignature = "X-Signature-Ed25519"
+	HeaderTimestamp = "X-Signature-Timestamp"
+)
+
+const (
+	tokenBytes = 32
+
+	// responseDeadline bounds the bot's first answer.
+	responseDeadline = 3 * time.Second
+	// followupWindow bounds follow-ups, and how long interactions are kept.
+	followupWindow = 15 * time.Minute
+
+	maxResponseBody = 64 << 10
+)
+
+var commandNameRe = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+type service struct {
+	repo        *repositories.ApplicationCommandRepository
+	botRepo     *repositories.BotRepository
+	channelRepo *repositories.ChannelRepository
+	memberRepo  *repositories.GuildMemberRepository
+	roleRepo    *repositories.GuildRoleRepository
+	permSvc     permissions.Service
+	msgSvc      messaging.Service
+	gateway     realtime.Gateway
+	redisClient *redis.Client
+	client      *http.Client
+}
+
+func NewService(
+	repo *repositories.ApplicationCommandRepository,
+	botRepo *repositories.BotRepository,
+	channelRepo *repositories.ChannelRepository,
+	memberRepo *repositories.GuildMemberRepository,
+	roleRepo *repositories.GuildRoleRepository,
+	permSvc permissions.Service,
+	msgSvc messaging.Service,
+	gateway realtime.Gateway,
+	redisClient *redis.Client,
+) Service {
+	return &service{
+		repo:        repo,
+		botRepo:     botRepo,
+		channelRepo: channelRepo,
+		memberRepo:  memberRepo,
+		roleRepo:    roleRepo,
+		permSvc:     permSvc,
+		msgSvc:      msgSvc,
+		gateway:     gateway,
+		redisClient: redisClient,
+		// a redirect is an answer, not a response
+		client: utils.PublicHTTPClient(responseDeadline, false),
+	}
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// tokensEqual compares tokens in constant time; hashing first makes the
+// lengths match.
+func tokensEqual(a, b string) bool {
+	ha, hb := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+func invalidCommand(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCommand, fmt.Sprintf(format, args...))
+}
+
+func invalidOptions(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidOptions, fmt.Sprintf(format, args...))
+}
+
+func validDescription(what, desc string) error {
+	if n := len([]rune(strings.TrimSpace(desc))); n == 0 || n > m.MaxCommandDescription {
+		return invalidCommand("%s description must be 1 to %d characters", what, m.MaxCommandDescription)
+	}
+	return nil
+}
+
+// validCommand checks a command's schema and normalises it.
+func validCommand(in *Command) error {
+	if !commandNameRe.MatchString(in.Name) {
+		return invalidCommand("name must be 1 to %d lowercase letters, digits, - or _", m.MaxCommandName)
+	}
+	if err := validDescription("command", in.Description); err != nil {
+		return err
+	}
+	if len(in.Options) > m.MaxCommandOptions {
+		return invalidCommand("at most %d options", m.MaxCommandOptions)
+	}
+	seen := make(map[string]bool, len(in.Options))
+	optional := false
+	for i := range in.Options {
+		o := &in.Options[i]
+		if !commandNameRe.MatchString(o.Name) {
+			return invalidCommand("option name %q is invalid", o.Name)
+		}
+		if seen[o.Name] {
+			return invalidCommand("option %q is declared twice", o.Name)
+		}
+		seen[o.Name] = true
+		if err := validDescription("option "+o.Name, o.Description); err != nil {
+			return err
+		}
+		if o.Required && optional {
+			return invalidCommand("required option %q must come before optional ones", o.Name)
+		}
+		optional = optional || !o.Required
+		if err := validOption(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validOption(o *m.CommandOption) error {
+	switch o.Type {
+	case m.OptionString, m.OptionInteger:
+	case m.OptionUser, m.OptionChannel, m.OptionRole:
+		if len(o.Choices) > 0 {
+			return invalidCommand("%s options can't have choices", o.Type)
+		}
+	default:
+		return invalidCommand("option %q has unknown type %q", o.Name, o.Type)
+	}
+	if o.Type != m.OptionInteger && (o.MinValue != nil || o.MaxValue != nil) {
+		return invalidCommand("only integer options take a min or max value")
+	}
+	if o.MinValue != nil && o.MaxValue != nil && *o.MinValue > *o.MaxValue {
+		return invalidCommand("option %q has min_value above max_value", o.Name)
+	}
+	if len(o.Choices) > m.MaxOptionChoices {
+		return invalidCommand("option %q has more than %d choices", o.Name, m.MaxOptionChoices)
+	}
+	for i, c := range o.Choices {
+		if n := len([]rune(c.Name)); n == 0 || n > m.MaxCommandDescription {
+			return invalidCommand("choice names must be 1 to %d characters", m.MaxCommandDescription)
+		}
+		switch v := c.Value.(type) {
+		case string:
+			if o.Type != m.OptionString || len([]rune(v)) > m.MaxCommandDescription {
+				return invalidCommand("choice %q has an invalid value", c.Name)
+			}
+		case float64: // how JSON decodes every number
+			if o.Type != m.OptionInteger || v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+				return invalidCommand("choice %q has an invalid value", c.Name)
+			}
+			o.Choices[i].Value = int64(v)
+		default:
+			return invalidCommand("choice %q has an invalid value", c.Name)
+		}
+	}
+	return nil
+}
+
+// managed loads a bot and checks that the actor is the bot or its owner.
+func (s *service) managed(ctx context.Context, botID, actorID string) (*m.Bot, error) {
+	b, err := s.botRepo.GetByID(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	if actorID != b.UserID && actorID != b.OwnerID {
+		return nil, ErrNotBotOrOwner
+	}
+	return b, nil
+}
+
+func (s *service) requireMember(ctx context.Context, guildID, botID string) error {
+	_, err := s.memberRepo.Get(ctx, guildID, botID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrBotNotInGuild
+	}
+	return err
+}
+
+func (s *service) CreateCommand(
+	ctx context.Context,
+	botID, guildID string,
+	in Command,
+	actorID string,
+) (*m.ApplicationCommand, error) {
+	if _, err := s.managed(ctx, botID, actorID); err != nil {
+		return nil, err
+	}
+	if guildID != "" {
+		if err := s.requireMember(ctx, guildID, botID); err != nil {
+			return nil, err
+		}
+	}
+	if err := validCommand(&in); err != nil {
+		return nil, err
+	}
+	n, err := s.repo.Count(ctx, botID, guildID)
+	if err != nil {
+		return nil, err
+	}
+	if n >= m.MaxCommandsPerScope {
+		return nil, ErrTooManyCommands
+	}
+	taken, err := s.repo.NameTaken(ctx, botID, guildID, in.Name, "")
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrCommandNameTaken
+	}
+	cmd := &m.ApplicationCommand{
+		BotID:       botID,
+		Name:        in.Name,
+		Description: strings.TrimSpace(in.Description),
+		Options:     datatypes.JSONSlice[m.CommandOption](in.Options),
+	}
+	if guildID != "" {
+		cmd.GuildID = &guildID
+	}
+	if cmd.Options == nil {
+		cmd.Options = datatypes.JSONSlice[m.CommandOption]{}
+	}
+	if err := s.repo.Create(ctx, cmd); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func (s *service) ListCommands(ctx context.Context, botID, guildID, actorID string) ([]m.ApplicationCommand, error) {
+	if _, err := s.managed(ctx, botID, actorID); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, botID, guildID)
+}
+
+// command loads one of the bot's commands.
+func (s *service) command(ctx context.Context, botID, commandID string) (*m.ApplicationCommand, error) {
+	cmd, err := s.repo.GetByID(ctx, commandID)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.BotID != botID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return cmd, nil
+}
+
+func (s *service) UpdateCommand(
+	ctx context.Context,
+	botID, commandID string,
+	in Command,
+	actorID string,
+) (*m.ApplicationCommand, error) {
+	if _, err := s.managed(ctx, botID, actorID); err != nil {
+		return nil, err
+	}
+	cmd, err := s.command(ctx, botID, commandID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validCommand(&in); err != nil {
+		return nil, err
+	}
+	var guildID string
+	if cmd.GuildID != nil {
+		guildID = *cmd.GuildID
+	}
+	taken, err := s.repo.NameTaken(ctx, botID, guildID, in.Name, cmd.ID)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrCommandNameTaken
+	}
+	cmd.Name = in.Name
+	cmd.Description = strings.TrimSpace(in.Description)
+	cmd.Options = datatypes.JSONSlice[m.CommandOption](in.Options)
+	if cmd.Options == nil {
+		cmd.Options = datatypes.JSONSlice[m.CommandOption]{}
+	}
+	if err := s.repo.Update(ctx, cmd); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func (s *service) DeleteCommand(ctx context.Context, botID, commandID, actorID string) error {
+	if _, err := s.managed(ctx, botID, actorID); err != nil {
+		return err
+	}
+	if _, err := s.command(ctx, botID, commandID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, commandID)
+}
+
+func (s *service) GuildCommands(ctx context.Context, guildID, userID string) ([]m.ApplicationCommand, error) {
+	if _, err := s.permSvc.GuildPermissions(ctx, guildID, userID); err != nil {
+		return nil, err // not a member
+	}
+	return s.repo.ListForGuild(ctx, guildID)
+}
+
+// resolveOptions checks the invocation's options against the command's
+// schema and returns them typed, in schema order.
+func (s *service) resolveOptions(
+	ctx context.Context,
+	cmd *m.ApplicationCommand,
+	guildID string,
+	given []OptionValue,
+) ([]m.InteractionOption, error) {
+	values := make(map[string]json.RawMessage, len(given))
+	for _, o := range given {
+		if _, dup := values[o.Name]; dup {
+			return nil, invalidOptions("option %q given twice", o.Name)
+		}
+		values[o.Name] = o.Value
+	}
+	out := make([]m.InteractionOption, 0, len(given))
+	for _, opt := range cmd.Options {
+		raw, ok := values[opt.Name]
+		delete(values, opt.Name)
+		if !ok || string(raw) == "null" {
+			if opt.Required {
+				return nil, invalidOptions("option %q is required", opt.Name)
+			}
+			continue
+		}
+		v, err := s.resolveOption(ctx, opt, guildID, raw)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m.InteractionOption{Name: opt.Name, Type: opt.Type, Value: v})
+	}
+	for name := range values {
+		return nil, invalidOptions("unknown option %q", name)
+	}
+	return out, nil
+}
+
+func (s *service) resolveOption(
+	ctx context.Context,
+	opt m.CommandOption,
+	guildID string,
+	raw json.RawMessage,
+) (interface{}, error) {
+	if opt.Type == m.OptionInteger {
+		var n int64
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return nil, invalidOptions("option %q must be an integer", opt.Name)
+		}
+		if (opt.MinValue != nil && n < *opt.MinValue) || (opt.MaxValue != nil && n > *opt.MaxValue) {
+			return nil, invalidOptions("option %q is out of range", opt.Name)
+		}
+		if !hasChoice(opt, func(v interface{}) bool { f, ok := v.(float64); return ok && int64(f) == n }) {
+			return nil, invalidOptions("option %q must be one of its choices", opt.Name)
+		}
+		return n, nil
+	}
+
+	var str string
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return nil, invalidOptions("option %q must be a string", opt.Name)
+	}
+	var err error
+	switch opt.Type {
+	case m.OptionString:
+		if len([]rune(str)) > m.MaxStringOptionValue {
+			return nil, invalidOptions("option %q is longer than %d characters", opt.Name, m.MaxStringOptionValue)
+		}
+		if !hasChoice(opt, func(v interface{}) bool { return v == str }) {
+			return nil, invalidOptions("option %q must be one of its choices", opt.Name)
+		}
+		return str, nil
+	case m.OptionUser:
+		_, err = s.memberRepo.Get(ctx, guildID, str)
+	case m.OptionChannel:
+		var ch *guilds.Channel
+		if ch, err = s.channelRepo.GetByID(ctx, str); err == nil && ch.GuildID != guildID {
+			err = gorm.ErrRecordNotFound
+		}
+	case m.OptionRole:
+		var role *guilds.GuildRole
+		if role, err = s.roleRepo.Get(ctx, str); err == nil && role.GuildID != guildID {
+			err = gorm.ErrRecordNotFound
+		}
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, invalidOptions("option %q must be a %s of this guild", opt.Name, opt.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return str, nil
+}
+
+// hasChoice reports whether match accepts one of the option's choices.
+// Options without choices take any value. Stored integer choices come
+// back from JSON as float64.
+func hasChoice(opt m.CommandOption, match func(interface{}) bool) bool {
+	if len(opt.Choices) == 0 {
+		return true
+	}
+	for _, c := range opt.Choices {
+		if match(c.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) Invoke(ctx context.Context, userID string, in Invocation) (*m.Interaction, error) {
+	cmd, err := s.repo.GetByID(ctx, in.CommandID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnknownCommand
+	}
+	if err != nil {
+		return nil, err
+	}
+	ch, err := s.channelRepo.GetByID(ctx, in.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	if ch.Type != string(guilds.ChannelText) && ch.Type != string(guilds.ChannelAnnouncement) {
+		return nil, ErrInvalidChannel
+	}
+	if cmd.GuildID != nil && *cmd.GuildID != ch.GuildID {
+		return nil, ErrUnknownCommand
+	}
+	if err := s.requireMember(ctx, ch.GuildID, cmd.BotID); err != nil {
+		if errors.Is(err, ErrBotNotInGuild) {
+			return nil, ErrUnknownCommand
+		}
+		return nil, err
+	}
+	if err := s.permSvc.RequireChannel(ctx, ch, userID, guilds.PermUseApplicationCommands); err != nil {
+		return nil, err
+	}
+	mem, err := s.memberRepo.Get(ctx, ch.GuildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mem.TimedOut(time.Now()) {
+		return nil, ErrTimedOut
+	}
+	opts, err := s.resolveOptions(ctx, cmd, ch.GuildID, in.Options)
+	if err != nil {
+		return nil, err
+	}
+	bot, err := s.botRepo.GetByID(ctx, cmd.BotID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	it := &m.Interaction{
+		ID:          uuid.NewString(),
+		Type:        m.InteractionCommand,
+		Token:       token,
+		BotID:       cmd.BotID,
+		CommandID:   cmd.ID,
+		CommandName: cmd.Name,
+		GuildID:     ch.GuildID,
+		ChannelID:   ch.ID,
+		UserID:      userID,
+		Options:     opts,
+		Status:      m.InteractionPending,
+		CreatedAt:   time.Now(),
+	}
+
+	if bot.InteractionsURL == "" {
+		if !s.gateway.Connected(bot.UserID) {
+			return nil, fmt.Errorf("%w: bot is offline", ErrBotUnavailable)
+		}
+		if err := s.store(ctx, it, followupWindow); err != nil {
+			return nil, err
+		}
+		s.gateway.SendToUsers([]string{bot.UserID}, realtime.Event{
+			Type:    realtime.EventInteractionCreate,
+			GuildID: it.GuildID,
+			Data:    it,
+		})
+		return redacted(it), nil
+	}
+
+	if err := s.store(ctx, it, followupWindow); err != nil {
+		return nil, err
+	}
+	resp, err := s.post(ctx, bot, it)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.respond(ctx, it, *resp); err != nil {
+		return nil, err
+	}
+	return redacted(it), nil
+}
+
+// redacted copies an interaction without its token, for the invoker.
+func redacted(it *m.Interaction) *m.Interaction {
+	out := *it
+	out.Token = ""
+	return &out
+}
+
+func interactionKey(id string) string {
+	return "interaction:" + id
+}
+
+// store writes an interaction; ttl is followupWindow for new ones and
+// redis.KeepTTL for updates.
+func (s *service) store(ctx context.Context, it *m.Interaction, ttl time.Duration) error {
+	raw, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	return s.redisClient.Set(ctx, interactionKey(it.ID), raw, ttl).Err()
+}
+
+// load fetches an interaction and checks its token.
+func (s *service) load(ctx context.Context, id, token string) (*m.Interaction, error) {
+	raw, err := s.redisClient.Get(ctx, interactionKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInteractionExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+	var it m.Interaction
+	if err := json.Unmarshal(raw, &it); err != nil {
+		return nil, err
+	}
+	if !tokensEqual(token, it.Token) {
+		return nil, ErrInvalidToken
+	}
+	return &it, nil
+}
+
+// post sends the interaction to the bot's endpoint and decodes its answer.
+func (s *service) post(ctx context.Context, bot *m.Bot, it *m.Interaction) (*Response, error) {
+	key, err := hex.DecodeString(bot.PrivateKey)
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: bot has no signing key", ErrBotUnavailable)
+	}
+	body, err := json.Marshal(it)
+	if err != nil {
+		return nil, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	sig := ed25519.Sign(ed25519.PrivateKey(key), append([]byte(ts), body...))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, bot.InteractionsURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, hex.EncodeToString(sig))
+	req.Header.Set(HeaderTimestamp, ts)
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBotUnavailable, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("%w: endpoint answered %d", ErrBotUnavailable, res.StatusCode)
+	}
+	var resp Response
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseBody)).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	return &resp, nil
+}
+
+func (s *service) Respond(ctx context.Context, id, token string, resp Response) (*m.Message, error) {
+	it, err := s.load(ctx, id, token)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(it.CreatedAt) > responseDeadline {
+		return nil, ErrDeadlinePassed
+	}
+	return s.respond(ctx, it, resp)
+}
+
+// respond applies the bot's first answer. An ack key makes sure only one
+// answer wins when several race.
+func (s *service) respond(ctx context.Context, it *m.Interaction, resp Response) (*m.Message, error) {
+	switch resp.Type {
+	case m.ResponseMessage:
+		if resp.Data == nil {
+			return nil, fmt.Errorf("%w: message responses need data", ErrInvalidResponse)
+		}
+	case m.ResponseDeferred:
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidResponse, resp.Type)
+	}
+	ok, err := s.redisClient.SetNX(ctx, interactionKey(it.ID)+":ack", 1, followupWindow).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrAlreadyResponded
+	}
+
+	var msg *m.Message
+	it.Status = m.InteractionDeferred
+	if resp.Type == m.ResponseMessage {
+		if msg, err = s.send(ctx, it, *resp.Data); err != nil {
+			_ = s.redisClient.Del(ctx, interactionKey(it.ID)+":ack").Err() // let the bot retry
+			return nil, err
+		}
+		it.Status = m.InteractionResponded
+	}
+	if err := s.store(ctx, it, redis.KeepTTL); err != nil {
+		return nil, err
+	}
+	s.gateway.SendToUsers([]string{it.UserID}, realtime.Event{
+		Type:    realtime.EventInteractionUpdate,
+		GuildID: it.GuildID,
+		Data:    Update{Interaction: redacted(it), Message: msg},
+	})
+	return msg, nil
+}
+
+func (s *service) Followup(ctx context.Context, id, token string, msg Message) (*m.Message, error) {
+	it, err := s.load(ctx, id, token)
+	if err != nil {
+		return nil, err
+	}
+	if it.Status == m.InteractionPending {
+		return nil, ErrNotAnswered
+	}
+	if time.Since(it.CreatedAt) > followupWindow {
+		return nil, ErrInteractionExpired
+	}
+	out, err := s.send(ctx, it, msg)
+	if err != nil {
+		return nil, err
+	}
+	if it.Status == m.InteractionDeferred {
+		it.Status = m.InteractionResponded
+		if err := s.store(ctx, it, redis.KeepTTL); err != nil {
+			return nil, err
+		}
+	}
+	s.gateway.SendToUsers([]string{it.UserID}, realtime.Event{
+		Type:    realtime.EventInteractionUpdate,
+		GuildID: it.GuildID,
+		Data:    Update{Interaction: redacted(it), Message: out},
+	})
+	return out, nil
+}
+
+// send posts a message as the bot into the interaction's channel.
+func (s *service) send(ctx context.Context, it *m.Interaction, in Message) (*m.Message, error) {
+	if strings.TrimSpace(in.Content) == "" && len(in.Attachments) == 0 {
+		return nil, fmt.Errorf("%w: message needs content or attachments", ErrInvalidResponse)
+	}
+	msg := &m.Message{
+		ChannelID:   it.ChannelID,
+		Content:     in.Content,
+		Attachments: datatypes.JSON(in.Attachments),
+	}
+	if err := s.msgSvc.SendInteractionMessage(ctx, it.BotID, it.ID, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,53 @@
+package invites
+
+import (
+	"context"
+	"time"
+
+	mg "launay-dot-one/models/guilds"
+)
+
+// Service manages guild invite codes.
+type Service interface {
+	// Create issues a new invite for a guild. Requires the create-invite permission.
+	Create(ctx context.Context, guildID, creatorID string, opts CreateOptions) (*mg.GuildInvite, error)
+
+	// Preview returns public information about the invite's guild.
+	Preview(ctx context.Context, code string) (*PreviewDTO, error)
+
+	// Accept joins the user to the invite's guild, consuming one use.
+	Accept(ctx context.Context, code, userID string) (*mg.GuildInvite, error)
+
+	// ListByGuild returns all invites of a guild. Requires manage-guild.
+	ListByGuild(ctx context.Context, guildID, requesterID string) ([]mg.GuildInvite, error)
+
+	// Revoke deletes an invite. Allowed for its creator or with manage-guild.
+	Revoke(ctx context.Context, code, requesterID string) error
+
+	// DropTemporaryMemberships removes the user from guilds joined through
+	// temporary invites; called when their last connection closes.
+	DropTemporaryMemberships(ctx context.Context, userID string) error
+
+	// DeleteExpired sweeps invites that are expired or used up.
+	DeleteExpired(ctx context.Context) error
+}
+
+// CreateOptions controls the limits of a new invite.
+type CreateOptions struct {
+	MaxUses   int           // 0 = unlimited
+	MaxAge    time.Duration // 0 = never expires
+	Temporary bool
+
+	// TargetUserID, when set, is sent the invite as a notification.
+	TargetUserID string
+}
+
+// PreviewDTO is what anyone holding the code may see.
+type PreviewDTO struct {
+	Code        string     `json:"code"`
+	GuildID     string     `json:"guild_id"`
+	GuildName   string     `json:"guild_name"`
+	GuildIcon   string     `json:"guild_icon"`
+	MemberCount int64      `json:"member_count"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
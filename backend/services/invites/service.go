@@ -0,0 +1,197 @@
+package invites
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/events"
+	"launay-dot-one/services/notifications"
+	"launay-dot-one/services/permissions"
+)
+
+var (
+	ErrInvalidOptions = errors.New("max_uses and max_age must not be negative")
+	ErrNotFound       = errors.New("invite not found")
+)
+
+const (
+	codeLength   = 8
+	codeAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	codeAttempts = 5
+)
+
+type service struct {
+	repo       *repositories.GuildInviteRepository
+	guildRepo  *repositories.GuildRepository
+	memberRepo *repositories.GuildMemberRepository
+	permSvc    permissions.Service
+	events     events.Publisher
+	notifier   notifications.Service
+}
+
+// NewService constructs the invite service.
+func NewService(
+	repo *repositories.GuildInviteRepository,
+	guildRepo *repositories.GuildRepository,
+	memberRepo *repositories.GuildMemberRepository,
+	permSvc permissions.Service,
+	events events.Publisher,
+	notifier notifications.Service,
+) Service {
+	return &service{
+		repo:       repo,
+		guildRepo:  guildRepo,
+		memberRepo: memberRepo,
+		permSvc:    permSvc,
+		events:     events,
+		notifier:   notifier,
+	}
+}
+
+func (s *service) Create(ctx context.Context, guildID, creatorID string, opts CreateOptions) (*guilds.GuildInvite, error) {
+	if opts.MaxUses < 0 || opts.MaxAge < 0 {
+		return nil, ErrInvalidOptions
+	}
+	if err := s.permSvc.Require(ctx, guildID, creatorID, guilds.PermCreateInvite); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	inv := &guilds.GuildInvite{
+		GuildID:   guildID,
+		CreatorID: creatorID,
+		MaxUses:   opts.MaxUses,
+		Temporary: opts.Temporary,
+		CreatedAt: now,
+	}
+	if opts.MaxAge > 0 {
+		exp := now.Add(opts.MaxAge)
+		inv.ExpiresAt = &exp
+	}
+
+	// codes are short, so retry the rare primary-key collision
+	var err error
+	for i := 0; i < codeAttempts; i++ {
+		if inv.Code, err = generateCode(); err != nil {
+			return nil, err
+		}
+		if err = s.repo.Create(ctx, inv); err == nil {
+			s.notifyTarget(ctx, inv, opts.TargetUserID)
+			return inv, nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// notifyTarget sends the invite to the user it was made for, if any.
+func (s *service) notifyTarget(ctx context.Context, inv *guilds.GuildInvite, userID string) {
+	if userID == "" {
+		return
+	}
+	g, err := s.guildRepo.GetByID(ctx, inv.GuildID)
+	if err != nil {
+		return
+	}
+	data, _ := json.Marshal(map[string]string{"code": inv.Code, "guild_name": g.Name})
+	_ = s.notifier.Notify(ctx, m.Notification{
+		UserID:  userID,
+		Type:    m.NotificationGuildInvite,
+		ActorID: inv.CreatorID,
+		GuildID: &inv.GuildID,
+		Data:    data,
+	})
+}
+
+func (s *service) Preview(ctx context.Context, code string) (*PreviewDTO, error) {
+	inv, err := s.repo.Get(ctx, code)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if !inv.Usable(time.Now()) {
+		return nil, repositories.ErrInviteUnusable
+	}
+	g, err := s.guildRepo.GetByID(ctx, inv.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.memberRepo.CountByGuild(ctx, inv.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	return &PreviewDTO{
+		Code:        inv.Code,
+		GuildID:     g.ID,
+		GuildName:   g.Name,
+		GuildIcon:   g.Icon,
+		MemberCount: count,
+		ExpiresAt:   inv.ExpiresAt,
+	}, nil
+}
+
+func (s *service) Accept(ctx context.Context, code, userID string) (*guilds.GuildInvite, error) {
+	now := time.Now()
+	inv, err := s.repo.Accept(ctx, code, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.events.Publish(ctx, inv.GuildID, m.EventMemberJoined, events.MemberJoined{
+		UserID:     userID,
+		JoinedAt:   now,
+		InviteCode: inv.Code,
+		Temporary:  inv.Temporary,
+	})
+	return inv, nil
+}
+
+func (s *service) ListByGuild(ctx context.Context, guildID, requesterID string) ([]guilds.GuildInvite, error) {
+	if err := s.permSvc.Require(ctx, guildID, requesterID, guilds.PermManageGuild); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByGuild(ctx, guildID)
+}
+
+func (s *service) Revoke(ctx context.Context, code, requesterID string) error {
+	inv, err := s.repo.Get(ctx, code)
+	if err != nil {
+		return ErrNotFound
+	}
+	if inv.CreatorID != requesterID {
+		if err := s.permSvc.Require(ctx, inv.GuildID, requesterID, guilds.PermManageGuild); err != nil {
+			return err
+		}
+	}
+	return s.repo.Delete(ctx, code)
+}
+
+func (s *service) DropTemporaryMemberships(ctx context.Context, userID string) error {
+	return s.memberRepo.RemoveTemporary(ctx, userID)
+}
+
+func (s *service) DeleteExpired(ctx context.Context) error {
+	return s.repo.DeleteExpired(ctx, time.Now())
+}
+
+func generateCode() (string, error) {
+	b := make([]byte, codeLength)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = codeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
@@ -11,6 +11,18 @@ type Service interface {
 	// SendMessage enqueues a new message (in Redis) before persistence.
 	SendMessage(ctx context.Context, msg *m.Message) error
 
+	// SendWebhookMessage enqueues a message posted by a webhook, which
+	// becomes its author.
+	SendWebhookMessage(ctx context.Context, webhookID string, msg *m.Message) error
+
+	// SendInteractionMessage enqueues a bot's answer to an interaction,
+	// linked to it.
+	SendInteractionMessage(ctx context.Context, botID, interactionID string, msg *m.Message) error
+
+	// Crosspost copies an announcement message into every channel following
+	// its channel and returns the copies.
+	Crosspost(ctx context.Context, messageID, userID string) ([]m.Message, error)
+
 	// AddReaction adds a reaction to an in-flight message in Redis.
 	AddReaction(ctx context.Context, messageID, reaction, userID string) error
 
@@ -4,33 +4,268 @@ package messaging
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"time"
 
 	m "launay-dot-one/models"
+	"launay-dot-one/models/guilds"
 	"launay-dot-one/repositories"
+	"launay-dot-one/services/events"
+	"launay-dot-one/services/friendships"
+	"launay-dot-one/services/notifications"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/services/push"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+var (
+	ErrNotGuildMember = errors.New("not a member of this guild")
+	ErrTimedOut       = errors.New("you are timed out in this guild")
+	ErrSlowmode       = errors.New("slowmode is active in this channel")
+	ErrForumPostOnly  = errors.New("forum channels only accept messages inside a post")
+	ErrUnknownChannel = errors.New("channel does not exist")
+	ErrInvalidReply   = errors.New("replies must answer a message of the same channel")
+
+	ErrNotAnnouncement    = errors.New("only announcement messages can be cross-posted")
+	ErrAlreadyCrossposted = errors.New("message was already cross-posted")
+)
+
+// crosspostMarkerTTL bounds how long a published message is remembered to
+// guard against publishing it twice.
+const crosspostMarkerTTL = 30 * 24 * time.Hour
+
 type service struct {
-	redisClient *redis.Client
-	repo        *repositories.MessagingRepository
+	redisClient  *redis.Client
+	repo         *repositories.MessagingRepository
+	channelRepo  *repositories.ChannelRepository
+	memberRepo   *repositories.GuildMemberRepository
+	forumRepo    *repositories.ForumRepository
+	followerRepo *repositories.ChannelFollowerRepository
+	permSvc      permissions.Service
+	events       events.Publisher
+	friends      friendships.Service
+	notifier     notifications.Service
+	pusher       push.Service
 }
 
 // NewService wires up Redis + GORM for messaging.
 func NewService(
 	redisClient *redis.Client,
 	repo *repositories.MessagingRepository,
+	channelRepo *repositories.ChannelRepository,
+	memberRepo *repositories.GuildMemberRepository,
+	forumRepo *repositories.ForumRepository,
+	followerRepo *repositories.ChannelFollowerRepository,
+	permSvc permissions.Service,
+	events events.Publisher,
+	friends friendships.Service,
+	notifier notifications.Service,
+	pusher push.Service,
 ) Service {
-	return &service{redisClient: redisClient, repo: repo}
+	return &service{
+		redisClient:  redisClient,
+		repo:         repo,
+		channelRepo:  channelRepo,
+		memberRepo:   memberRepo,
+		forumRepo:    forumRepo,
+		followerRepo: followerRepo,
+		permSvc:      permSvc,
+		events:       events,
+		friends:      friends,
+		notifier:     notifier,
+		pusher:       pusher,
+	}
+}
+
+// guildChannel resolves a message target to the guild channel it lives in.
+// Messages in a forum post resolve to the forum, with inPost set. Targets
+// that aren't guild channels (DMs, groups) resolve to nil.
+func (s *service) guildChannel(ctx context.Context, targetID string) (ch *guilds.Channel, inPost bool, err error) {
+	ch, err = s.channelRepo.GetByID(ctx, targetID)
+	if err == nil {
+		return ch, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+	post, err := s.forumRepo.GetPost(ctx, targetID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	ch, err = s.channelRepo.GetByID(ctx, post.ChannelID)
+	if err != nil {
+		return nil, false, err
+	}
+	return ch, true, nil
+}
+
+// checkGuildWrite rejects writes to a guild channel from non-members and
+// timed-out members. Targets that aren't guild channels (DMs, groups) pass.
+func (s *service) checkGuildWrite(ctx context.Context, targetID, userID string) error {
+	ch, _, err := s.guildChannel(ctx, targetID)
+	if err != nil || ch == nil {
+		return err
+	}
+	return s.checkMember(ctx, ch, userID)
+}
+
+func (s *service) checkMember(ctx context.Context, ch *guilds.Channel, userID string) error {
+	mem, err := s.memberRepo.Get(ctx, ch.GuildID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotGuildMember
+	}
+	if err != nil {
+		return err
+	}
+	if mem.TimedOut(time.Now()) {
+		return ErrTimedOut
+	}
+	return nil
+}
+
+// enforceSlowmode lets a user post in a slowmode channel once per interval.
+// Members who can manage messages or the channel are exempt.
+func (s *service) enforceSlowmode(ctx context.Context, ch *guilds.Channel, userID string) error {
+	if ch.SlowmodeSeconds <= 0 {
+		return nil
+	}
+	perms, err := s.permSvc.ChannelPermissions(ctx, ch, userID)
+	if err != nil {
+		return err
+	}
+	if perms&(guilds.PermManageMessages|guilds.PermManageChannels) != 0 {
+		return nil
+	}
+	key := "slowmode:" + ch.ID + ":" + userID
+	interval := time.Duration(ch.SlowmodeSeconds) * time.Second
+	ok, err := s.redisClient.SetNX(ctx, key, 1, interval).Result()
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+	ttl, err := s.redisClient.TTL(ctx, key).Result()
+	if err != nil || ttl < 0 {
+		ttl = interval
+	}
+	return fmt.Errorf("%w: retry in %d seconds", ErrSlowmode, int(math.Ceil(ttl.Seconds())))
 }
 
 // SendMessage marshals the message into Redis with a 3-minute TTL.
 func (s *service) SendMessage(ctx context.Context, msg *m.Message) error {
+	ch, inPost, err := s.guildChannel(ctx, msg.ChannelID)
+	if err != nil {
+		return err
+	}
+	if ch != nil {
+		if err := s.checkMember(ctx, ch, msg.AuthorID); err != nil {
+			return err
+		}
+		if ch.Type == string(guilds.ChannelForum) && !inPost {
+			return ErrForumPostOnly
+		}
+		if err := s.enforceSlowmode(ctx, ch, msg.AuthorID); err != nil {
+			return err
+		}
+	} else if err := s.friends.CheckDM(ctx, msg.AuthorID, msg.ChannelID); err != nil {
+		return err
+	}
+	var repliedTo string
+	if msg.ReplyToID != nil {
+		parent, err := s.findMessage(ctx, *msg.ReplyToID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && parent.ChannelID != msg.ChannelID) {
+			return ErrInvalidReply
+		}
+		if err != nil {
+			return err
+		}
+		repliedTo = parent.AuthorID
+	}
 	msg.ID = uuid.NewString()
 	msg.CreatedAt = time.Now()
+	msg.CrosspostedFrom = nil
+	msg.WebhookID = nil
+	msg.InteractionID = nil
+	if err := s.enqueue(ctx, msg); err != nil {
+		return err
+	}
+	if ch != nil {
+		_ = s.events.Publish(ctx, ch.GuildID, m.EventMessageCreated, msg)
+		_ = s.notifier.MessageCreated(ctx, ch.GuildID, ch.ID, msg, repliedTo)
+	} else {
+		_ = s.pusher.DirectMessage(ctx, msg)
+	}
+	return nil
+}
+
+// SendWebhookMessage posts on behalf of a webhook. The webhook's token is
+// its authorisation, so membership, timeouts and slowmode don't apply.
+func (s *service) SendWebhookMessage(ctx context.Context, webhookID string, msg *m.Message) error {
+	ch, inPost, err := s.guildChannel(ctx, msg.ChannelID)
+	if err != nil {
+		return err
+	}
+	if ch == nil {
+		return ErrUnknownChannel
+	}
+	if ch.Type == string(guilds.ChannelForum) && !inPost {
+		return ErrForumPostOnly
+	}
+	msg.ID = uuid.NewString()
+	msg.AuthorID = webhookID
+	msg.WebhookID = &webhookID
+	msg.CrosspostedFrom = nil
+	msg.InteractionID = nil
+	msg.ReplyToID = nil
+	msg.CreatedAt = time.Now()
+	if err := s.enqueue(ctx, msg); err != nil {
+		return err
+	}
+	_ = s.events.Publish(ctx, ch.GuildID, m.EventMessageCreated, msg)
+	return nil
+}
+
+// SendInteractionMessage posts a bot's answer to an interaction. The bot
+// must still be a member, but answers aren't held to slowmode: the user
+// who invoked the command already was.
+func (s *service) SendInteractionMessage(ctx context.Context, botID, interactionID string, msg *m.Message) error {
+	ch, inPost, err := s.guildChannel(ctx, msg.ChannelID)
+	if err != nil {
+		return err
+	}
+	if ch == nil {
+		return ErrUnknownChannel
+	}
+	if err := s.checkMember(ctx, ch, botID); err != nil {
+		return err
+	}
+	if ch.Type == string(guilds.ChannelForum) && !inPost {
+		return ErrForumPostOnly
+	}
+	msg.ID = uuid.NewString()
+	msg.AuthorID = botID
+	msg.InteractionID = &interactionID
+	msg.WebhookID = nil
+	msg.CrosspostedFrom = nil
+	msg.ReplyToID = nil
+	msg.CreatedAt = time.Now()
+	if err := s.enqueue(ctx, msg); err != nil {
+		return err
+	}
+	_ = s.events.Publish(ctx, ch.GuildID, m.EventMessageCreated, msg)
+	return nil
+}
 
+func (s *service) enqueue(ctx context.Context, msg *m.Message) error {
 	raw, err := json.Marshal(msg)
 	if err != nil {
 		return err
@@ -38,6 +273,74 @@ func (s *service) SendMessage(ctx context.Context, msg *m.Message) error {
 	return s.redisClient.Set(ctx, "message:"+msg.ID, raw, 3*time.Minute).Err()
 }
 
+// findMessage looks a message up in Redis first, then in PostgreSQL.
+func (s *service) findMessage(ctx context.Context, messageID string) (*m.Message, error) {
+	raw, err := s.redisClient.Get(ctx, "message:"+messageID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return s.repo.GetByID(ctx, messageID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var msg m.Message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// Crosspost publishes an announcement message to every following channel.
+// The author or anyone who can manage messages may publish, once.
+func (s *service) Crosspost(ctx context.Context, messageID, userID string) ([]m.Message, error) {
+	msg, err := s.findMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	ch, inPost, err := s.guildChannel(ctx, msg.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	if ch == nil || inPost || ch.Type != string(guilds.ChannelAnnouncement) {
+		return nil, ErrNotAnnouncement
+	}
+	if msg.AuthorID != userID {
+		if err := s.permSvc.RequireChannel(ctx, ch, userID, guilds.PermManageMessages); err != nil {
+			return nil, err
+		}
+	}
+	fresh, err := s.redisClient.SetNX(ctx, "crossposted:"+msg.ID, userID, crosspostMarkerTTL).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrAlreadyCrossposted
+	}
+
+	followers, err := s.followerRepo.ListByChannel(ctx, ch.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := make([]m.Message, 0, len(followers))
+	for _, f := range followers {
+		cp := m.Message{
+			ID:              uuid.NewString(),
+			ChannelID:       f.TargetChannelID,
+			AuthorID:        msg.AuthorID,
+			Content:         msg.Content,
+			Attachments:     msg.Attachments,
+			CrosspostedFrom: &msg.ID,
+			CreatedAt:       now,
+		}
+		if err := s.enqueue(ctx, &cp); err != nil {
+			return out, err
+		}
+		_ = s.events.Publish(ctx, f.GuildID, m.EventMessageCreated, &cp)
+		out = append(out, cp)
+	}
+	return out, nil
+}
+
 // AddReaction pulls the JSON message from Redis, updates its Reactions,
 // then writes it back (resetting TTL to 3m).
 func (s *service) AddReaction(ctx context.Context, messageID, reaction, userID string) error {
@@ -51,6 +354,9 @@ func (s *service) AddReaction(ctx context.Context, messageID, reaction, userID s
 	if err := json.Unmarshal(raw, &msg); err != nil {
 		return err
 	}
+	if err := s.checkGuildWrite(ctx, msg.ChannelID, userID); err != nil {
+		return err
+	}
 
 	// existing reactions → map[string][]string
 	var rm map[string][]string
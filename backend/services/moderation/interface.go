@@ -0,0 +1,34 @@
+package moderation
+
+import (
+	"context"
+	"time"
+
+	mg "launay-dot-one/models/guilds"
+)
+
+// Service implements guild moderation: bans, kicks and timeouts. Every
+// action emits a gateway event and an audit log entry.
+type Service interface {
+	// Ban removes the user from the guild and blocks rejoining until
+	// expiresAt (nil = permanent). Requires ban-members.
+	Ban(ctx context.Context, guildID, actorID, targetID, reason string, expiresAt *time.Time) (*mg.GuildBan, error)
+
+	// Unban lifts a ban. Requires ban-members.
+	Unban(ctx context.Context, guildID, actorID, targetID, reason string) error
+
+	// ListBans returns the guild's bans. Requires ban-members.
+	ListBans(ctx context.Context, guildID, requesterID string) ([]mg.GuildBan, error)
+
+	// Kick removes the member; they may rejoin with an invite. Requires kick-members.
+	Kick(ctx context.Context, guildID, actorID, targetID, reason string) error
+
+	// Timeout makes the member read-only until the given time. Requires moderate-members.
+	Timeout(ctx context.Context, guildID, actorID, targetID string, until time.Time, reason string) error
+
+	// RemoveTimeout ends a timeout early. Requires moderate-members.
+	RemoveTimeout(ctx context.Context, guildID, actorID, targetID, reason string) error
+
+	// LiftExpired clears lapsed bans and timeouts; run periodically.
+	LiftExpired(ctx context.Context) error
+}
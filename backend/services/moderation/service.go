@@ -0,0 +1,274 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"launay-dot-one/models"
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/realtime"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+	"launay-dot-one/services/notifications"
+	"launay-dot-one/services/permissions"
+	"launay-dot-one/services/voice"
+)
+
+// MaxTimeout caps how long a member can be timed out.
+const MaxTimeout = 28 * 24 * time.Hour
+
+var (
+	ErrInvalidDuration = errors.New("timeout must end in the future and last at most 28 days")
+	ErrInvalidExpiry   = errors.New("ban expiry must be in the future")
+	ErrNotMember       = errors.New("user is not a member of this guild")
+	ErrNotBanned       = errors.New("user is not banned")
+)
+
+type service struct {
+	banRepo    *repositories.GuildBanRepository
+	memberRepo *repositories.GuildMemberRepository
+	audit      auditlog.Service
+	permSvc    permissions.Service
+	gateway    realtime.Gateway
+	voiceSvc   voice.Service
+	notifier   notifications.Service
+}
+
+// NewService constructs the moderation service.
+func NewService(
+	banRepo *repositories.GuildBanRepository,
+	memberRepo *repositories.GuildMemberRepository,
+	audit auditlog.Service,
+	permSvc permissions.Service,
+	gateway realtime.Gateway,
+	voiceSvc voice.Service,
+	notifier notifications.Service,
+) Service {
+	return &service{
+		banRepo:    banRepo,
+		memberRepo: memberRepo,
+		audit:      audit,
+		permSvc:    permSvc,
+		gateway:    gateway,
+		voiceSvc:   voiceSvc,
+		notifier:   notifier,
+	}
+}
+
+// authorize checks both the permission bit and the role hierarchy.
+func (s *service) authorize(ctx context.Context, guildID, actorID, targetID string, perm uint64) error {
+	if err := s.permSvc.Require(ctx, guildID, actorID, perm); err != nil {
+		return err
+	}
+	return s.permSvc.RequireAbove(ctx, guildID, actorID, targetID)
+}
+
+func (s *service) Ban(
+	ctx context.Context,
+	guildID, actorID, targetID, reason string,
+	expiresAt *time.Time,
+) (*guilds.GuildBan, error) {
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, ErrInvalidExpiry
+	}
+	if err := s.authorize(ctx, guildID, actorID, targetID, guilds.PermBanMembers); err != nil {
+		return nil, err
+	}
+
+	ban := &guilds.GuildBan{
+		GuildID:     guildID,
+		UserID:      targetID,
+		Reason:      reason,
+		ModeratorID: actorID,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   now,
+	}
+	if err := s.banRepo.Ban(ctx, ban); err != nil {
+		return nil, err
+	}
+
+	var after interface{}
+	if expiresAt != nil {
+		after = map[string]interface{}{"expires_at": expiresAt}
+	}
+	if err := s.record(ctx, guildID, actorID, guilds.AuditMemberBan, targetID, reason, nil, after); err != nil {
+		return nil, err
+	}
+	s.emit(ctx, guildID, realtime.EventGuildBanAdd, ban, targetID)
+	s.dropVoice(ctx, guildID, targetID)
+	s.notify(ctx, guildID, targetID, guilds.AuditMemberBan, reason, expiresAt)
+	return ban, nil
+}
+
+func (s *service) Unban(ctx context.Context, guildID, actorID, targetID, reason string) error {
+	if err := s.permSvc.Require(ctx, guildID, actorID, guilds.PermBanMembers); err != nil {
+		return err
+	}
+	n, err := s.banRepo.Delete(ctx, guildID, targetID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotBanned
+	}
+	if err := s.record(ctx, guildID, actorID, guilds.AuditMemberUnban, targetID, reason, nil, nil); err != nil {
+		return err
+	}
+	s.emit(ctx, guildID, realtime.EventGuildBanRemove, map[string]string{"user_id": targetID}, targetID)
+	return nil
+}
+
+func (s *service) ListBans(ctx context.Context, guildID, requesterID string) ([]guilds.GuildBan, error) {
+	if err := s.permSvc.Require(ctx, guildID, requesterID, guilds.PermBanMembers); err != nil {
+		return nil, err
+	}
+	return s.banRepo.ListByGuild(ctx, guildID)
+}
+
+func (s *service) Kick(ctx context.Context, guildID, actorID, targetID, reason string) error {
+	if err := s.authorize(ctx, guildID, actorID, targetID, guilds.PermKickMembers); err != nil {
+		return err
+	}
+	if _, err := s.memberRepo.Get(ctx, guildID, targetID); err != nil {
+		return ErrNotMember
+	}
+	if err := s.memberRepo.Remove(ctx, guildID, targetID); err != nil {
+		return err
+	}
+	if err := s.record(ctx, guildID, actorID, guilds.AuditMemberKick, targetID, reason, nil, nil); err != nil {
+		return err
+	}
+	s.emit(ctx, guildID, realtime.EventGuildMemberRemove, map[string]string{"user_id": targetID}, targetID)
+	s.dropVoice(ctx, guildID, targetID)
+	s.notify(ctx, guildID, targetID, guilds.AuditMemberKick, reason, nil)
+	return nil
+}
+
+func (s *service) Timeout(
+	ctx context.Context,
+	guildID, actorID, targetID string,
+	until time.Time,
+	reason string,
+) error {
+	now := time.Now()
+	if !until.After(now) || until.Sub(now) > MaxTimeout {
+		return ErrInvalidDuration
+	}
+	if err := s.authorize(ctx, guildID, actorID, targetID, guilds.PermModerateMembers); err != nil {
+		return err
+	}
+	return s.setTimeout(ctx, guildID, actorID, targetID, &until, reason, guilds.AuditMemberTimeout)
+}
+
+func (s *service) RemoveTimeout(ctx context.Context, guildID, actorID, targetID, reason string) error {
+	if err := s.authorize(ctx, guildID, actorID, targetID, guilds.PermModerateMembers); err != nil {
+		return err
+	}
+	return s.setTimeout(ctx, guildID, actorID, targetID, nil, reason, guilds.AuditMemberTimeoutRemove)
+}
+
+func (s *service) setTimeout(
+	ctx context.Context,
+	guildID, actorID, targetID string,
+	until *time.Time,
+	reason string,
+	action guilds.AuditAction,
+) error {
+	mem, err := s.memberRepo.Get(ctx, guildID, targetID)
+	if err != nil {
+		return ErrNotMember
+	}
+	if err := s.memberRepo.SetTimeout(ctx, guildID, targetID, until); err != nil {
+		return err
+	}
+	before := map[string]interface{}{"timeout_until": mem.TimeoutUntil}
+	after := map[string]interface{}{"timeout_until": until}
+	if err := s.record(ctx, guildID, actorID, action, targetID, reason, before, after); err != nil {
+		return err
+	}
+	mem.TimeoutUntil = until
+	s.emit(ctx, guildID, realtime.EventGuildMemberUpdate, mem)
+	if until != nil {
+		s.dropVoice(ctx, guildID, targetID)
+		s.notify(ctx, guildID, targetID, action, reason, until)
+	}
+	return nil
+}
+
+func (s *service) LiftExpired(ctx context.Context) error {
+	now := time.Now()
+	bans, err := s.banRepo.DeleteExpired(ctx, now)
+	if err != nil {
+		return fmt.Errorf("expire bans: %w", err)
+	}
+	for _, b := range bans {
+		if err := s.record(ctx, b.GuildID, "", guilds.AuditMemberUnban, b.UserID, "ban expired", nil, nil); err != nil {
+			return err
+		}
+		s.emit(ctx, b.GuildID, realtime.EventGuildBanRemove, map[string]string{"user_id": b.UserID}, b.UserID)
+	}
+
+	members, err := s.memberRepo.ClearExpiredTimeouts(ctx, now)
+	if err != nil {
+		return fmt.Errorf("expire timeouts: %w", err)
+	}
+	for i := range members {
+		m := &members[i]
+		if err := s.record(ctx, m.GuildID, "", guilds.AuditMemberTimeoutRemove, m.UserID, "timeout expired", nil, nil); err != nil {
+			return err
+		}
+		s.emit(ctx, m.GuildID, realtime.EventGuildMemberUpdate, m)
+	}
+	return nil
+}
+
+func (s *service) record(
+	ctx context.Context,
+	guildID, actorID string,
+	action guilds.AuditAction,
+	targetID, reason string,
+	before, after interface{},
+) error {
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    guildID,
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: guilds.AuditTargetMember,
+		TargetID:   targetID,
+		Before:     before,
+		After:      after,
+		Reason:     reason,
+	})
+}
+
+// emit is best-effort: a failed fan-out must not undo the moderation action.
+func (s *service) emit(ctx context.Context, guildID, eventType string, data interface{}, extra ...string) {
+	_ = s.gateway.SendToGuild(ctx, guildID, realtime.Event{Type: eventType, Data: data}, extra...)
+}
+
+// notify tells the member what was done to them. Moderators stay
+// anonymous, so blocking one doesn't hide it either.
+func (s *service) notify(
+	ctx context.Context,
+	guildID, targetID string,
+	action guilds.AuditAction,
+	reason string,
+	until *time.Time,
+) {
+	data, _ := json.Marshal(map[string]interface{}{"action": action, "reason": reason, "until": until})
+	_ = s.notifier.Notify(ctx, models.Notification{
+		UserID:  targetID,
+		Type:    models.NotificationModeration,
+		GuildID: &guildID,
+		Data:    data,
+	})
+}
+
+// dropVoice is best-effort like emit: the member is already out either way.
+func (s *service) dropVoice(ctx context.Context, guildID, userID string) {
+	_ = s.voiceSvc.DisconnectMember(ctx, guildID, userID)
+}
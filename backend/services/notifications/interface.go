@@ -0,0 +1,63 @@
+package notifications
+
+import (
+	"context"
+	"time"
+
+	m "launay-dot-one/models"
+)
+
+// Service keeps each user's notification inbox and decides, from their
+// guild and channel settings, whom a message notifies.
+type Service interface {
+	// Notify records notifications and pushes each to its user's sockets.
+	// Notifications between users where one blocked the other are dropped.
+	Notify(ctx context.Context, ns ...m.Notification) error
+
+	// MessageCreated notifies the users a new guild message concerns: the
+	// ones it mentions, the author of the message it replies to, and those
+	// following every message of the channel. Their guild and channel
+	// settings decide whether they hear about it.
+	MessageCreated(ctx context.Context, guildID, channelID string, msg *m.Message, repliedToID string) error
+
+	// List pages through the user's notifications, newest first.
+	List(ctx context.Context, userID string, unreadOnly bool, page, limit int) (*Page, error)
+
+	MarkRead(ctx context.Context, userID, notificationID string) error
+
+	// MarkAllRead marks every notification of the user read and returns
+	// how many were unread.
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+
+	// Settings returns the user's settings for a guild and its channels.
+	Settings(ctx context.Context, userID, guildID string) (*GuildSettings, error)
+
+	UpdateGuildSettings(ctx context.Context, userID, guildID string, in Settings) (*m.NotificationSetting, error)
+	UpdateChannelSettings(ctx context.Context, userID, channelID string, in Settings) (*m.NotificationSetting, error)
+
+	// PruneRead deletes notifications read longer ago than the retention.
+	PruneRead(ctx context.Context) (int64, error)
+}
+
+// Page is one page of a user's inbox.
+type Page struct {
+	Notifications []m.Notification `json:"notifications"`
+	Unread        int64            `json:"unread"`
+	Total         int64            `json:"total"`
+	Page          int              `json:"page"`
+	Limit         int              `json:"limit"`
+}
+
+// Settings is what a user sets for a guild or channel. An empty level
+// follows the guild (for channels) or the default (for guilds).
+type Settings struct {
+	Level      m.NotificationLevel `json:"level"`
+	MutedUntil *time.Time          `json:"muted_until"`
+}
+
+// GuildSettings are a user's settings for a guild and those of its
+// channels they changed.
+type GuildSettings struct {
+	Guild    m.NotificationSetting   `json:"guild"`
+	Channels []m.NotificationSetting `json:"channels"`
+}
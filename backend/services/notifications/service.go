@@ -0,0 +1,308 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/realtime"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/push"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidSettings = errors.New("level must be all, mentions, nothing or empty, and muted_until in the future")
+	ErrNotMember       = errors.New("not a member of this guild")
+)
+
+type service struct {
+	repo        *repositories.NotificationRepository
+	memberRepo  *repositories.GuildMemberRepository
+	channelRepo *repositories.ChannelRepository
+	friendRepo  *repositories.FriendRequestRepository
+	gateway     realtime.Gateway
+	pusher      push.Service
+	retention   time.Duration
+}
+
+// NewService constructs the notification service. Mentions and replies
+// also go out as Web Push to users with no socket open. Read
+// notifications are kept for retention; 0 keeps them forever.
+func NewService(
+	repo *repositories.NotificationRepository,
+	memberRepo *repositories.GuildMemberRepository,
+	channelRepo *repositories.ChannelRepository,
+	friendRepo *repositories.FriendRequestRepository,
+	gateway realtime.Gateway,
+	pusher push.Service,
+	retention time.Duration,
+) Service {
+	return &service{
+		repo:        repo,
+		memberRepo:  memberRepo,
+		channelRepo: channelRepo,
+		friendRepo:  friendRepo,
+		gateway:     gateway,
+		pusher:      pusher,
+		retention:   retention,
+	}
+}
+
+func (s *service) Notify(ctx context.Context, ns ...m.Notification) error {
+	keep := ns[:0:0]
+	for _, n := range ns {
+		if n.ActorID == n.UserID {
+			continue
+		}
+		if n.ActorID != "" {
+			blocked, err := s.friendRepo.IsBlocked(ctx, n.ActorID, n.UserID)
+			if err != nil {
+				return err
+			}
+			if blocked {
+				continue
+			}
+		}
+		keep = append(keep, n)
+	}
+	return s.store(ctx, keep)
+}
+
+// store saves the notifications and sends them to their users' sockets,
+// or as Web Push when they have none open.
+func (s *service) store(ctx context.Context, ns []m.Notification) error {
+	if err := s.repo.CreateMany(ctx, ns); err != nil {
+		return err
+	}
+	for _, n := range ns {
+		s.gateway.SendToUsers([]string{n.UserID}, realtime.Event{Type: realtime.EventNotification, Data: n})
+		_ = s.pusher.Notified(ctx, n)
+	}
+	return nil
+}
+
+func (s *service) MessageCreated(
+	ctx context.Context, guildID, channelID string, msg *m.Message, repliedToID string,
+) error {
+	var candidates []string
+	seen := map[string]bool{"": true, msg.AuthorID: true}
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			candidates = append(candidates, id)
+		}
+	}
+	mentioned := make(map[string]bool)
+	for _, id := range msg.Mentions() {
+		add(id)
+		mentioned[id] = true
+	}
+	add(repliedToID)
+	followers, err := s.repo.ListAllMessagesSubscribers(ctx, guildID, channelID)
+	if err != nil {
+		return err
+	}
+	for _, id := range followers {
+		add(id)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	members, err := s.memberRepo.FilterMemberIDs(ctx, guildID, candidates)
+	if err != nil {
+		return err
+	}
+	blockedIDs, err := s.friendRepo.ListBlockerIDs(ctx, msg.AuthorID, members)
+	if err != nil {
+		return err
+	}
+	blocked := make(map[string]bool, len(blockedIDs))
+	for _, id := range blockedIDs {
+		blocked[id] = true
+	}
+	settings, err := s.repo.ListSettingsFor(ctx, members, []string{guildID, channelID})
+	if err != nil {
+		return err
+	}
+	guildSet := make(map[string]*m.NotificationSetting)
+	channelSet := make(map[string]*m.NotificationSetting)
+	for i := range settings {
+		if settings[i].ScopeID == guildID {
+			guildSet[settings[i].UserID] = &settings[i]
+		} else {
+			channelSet[settings[i].UserID] = &settings[i]
+		}
+	}
+
+	now := time.Now()
+	data := excerpt(msg.Content)
+	var ns []m.Notification
+	for _, id := range members {
+		if blocked[id] {
+			continue // blocking silences mentions and replies too
+		}
+		level, muted := effective(guildSet[id], channelSet[id], now)
+		if muted || level == m.NotifyNothing {
+			continue
+		}
+		var typ m.NotificationType
+		switch {
+		case mentioned[id]:
+			typ = m.NotificationMention
+		case id == repliedToID:
+			typ = m.NotificationReply
+		case level == m.NotifyAll:
+			typ = m.NotificationMessage
+		default:
+			continue
+		}
+		ns = append(ns, m.Notification{
+			UserID:    id,
+			Type:      typ,
+			ActorID:   msg.AuthorID,
+			GuildID:   &guildID,
+			ChannelID: &msg.ChannelID,
+			MessageID: &msg.ID,
+			Data:      data,
+			CreatedAt: now,
+		})
+	}
+	return s.store(ctx, ns)
+}
+
+// effective resolves a user's level for a channel: the channel's own,
+// else the guild's, else the default. Either scope being muted mutes it.
+func effective(guild, channel *m.NotificationSetting, now time.Time) (m.NotificationLevel, bool) {
+	level := m.DefaultNotificationLevel
+	muted := false
+	for _, set := range []*m.NotificationSetting{guild, channel} {
+		if set == nil {
+			continue
+		}
+		if set.Level != m.NotifyInherit {
+			level = set.Level
+		}
+		muted = muted || set.Muted(now)
+	}
+	return level, muted
+}
+
+// excerpt is the start of a message's text, as notification data.
+func excerpt(content string) datatypes.JSON {
+	if r := []rune(content); len(r) > m.MaxNotificationExcerpt {
+		content = string(r[:m.MaxNotificationExcerpt]) + "…"
+	}
+	raw, _ := json.Marshal(map[string]string{"excerpt": content})
+	return raw
+}
+
+func (s *service) List(ctx context.Context, userID string, unreadOnly bool, page, limit int) (*Page, error) {
+	list, total, err := s.repo.List(ctx, userID, unreadOnly, (page-1)*limit, limit)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.repo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{Notifications: list, Unread: unread, Total: total, Page: page, Limit: limit}, nil
+}
+
+func (s *service) MarkRead(ctx context.Context, userID, notificationID string) error {
+	ok, err := s.repo.MarkRead(ctx, userID, notificationID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *service) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	return s.repo.MarkAllRead(ctx, userID)
+}
+
+func (s *service) Settings(ctx context.Context, userID, guildID string) (*GuildSettings, error) {
+	if err := s.requireMember(ctx, guildID, userID); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListSettings(ctx, userID, guildID)
+	if err != nil {
+		return nil, err
+	}
+	out := &GuildSettings{
+		Guild:    m.NotificationSetting{UserID: userID, ScopeID: guildID, GuildID: guildID},
+		Channels: []m.NotificationSetting{},
+	}
+	for _, set := range list {
+		if set.ScopeID == guildID {
+			out.Guild = set
+		} else {
+			out.Channels = append(out.Channels, set)
+		}
+	}
+	return out, nil
+}
+
+func (s *service) UpdateGuildSettings(
+	ctx context.Context, userID, guildID string, in Settings,
+) (*m.NotificationSetting, error) {
+	return s.save(ctx, userID, guildID, guildID, in)
+}
+
+func (s *service) UpdateChannelSettings(
+	ctx context.Context, userID, channelID string, in Settings,
+) (*m.NotificationSetting, error) {
+	ch, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	return s.save(ctx, userID, ch.GuildID, ch.ID, in)
+}
+
+func (s *service) save(ctx context.Context, userID, guildID, scopeID string, in Settings) (*m.NotificationSetting, error) {
+	switch in.Level {
+	case m.NotifyInherit, m.NotifyAll, m.NotifyMentions, m.NotifyNothing:
+	default:
+		return nil, ErrInvalidSettings
+	}
+	if in.MutedUntil != nil && !in.MutedUntil.After(time.Now()) {
+		return nil, ErrInvalidSettings
+	}
+	if err := s.requireMember(ctx, guildID, userID); err != nil {
+		return nil, err
+	}
+	set := &m.NotificationSetting{
+		UserID:     userID,
+		ScopeID:    scopeID,
+		GuildID:    guildID,
+		Level:      in.Level,
+		MutedUntil: in.MutedUntil,
+	}
+	if err := s.repo.SaveSetting(ctx, set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+func (s *service) requireMember(ctx context.Context, guildID, userID string) error {
+	_, err := s.memberRepo.Get(ctx, guildID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotMember
+	}
+	return err
+}
+
+func (s *service) PruneRead(ctx context.Context) (int64, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	return s.repo.DeleteReadBefore(ctx, time.Now().Add(-s.retention))
+}
@@ -7,8 +7,37 @@ import (
 )
 
 type Service interface {
-	Create(ctx context.Context, o *m.PermissionOverwrite) error
-	Update(ctx context.Context, o *m.PermissionOverwrite) error
-	Delete(ctx context.Context, id string) error
+	// Create, Update and Delete require manage-roles in the overwrite's
+	// guild. An overwrite targets one channel or category of that guild
+	// and allows no bit the actor lacks.
+	Create(ctx context.Context, o *m.PermissionOverwrite, actorID string) error
+	Update(ctx context.Context, o *m.PermissionOverwrite, actorID string) error
+	Delete(ctx context.Context, guildID, id, actorID string) error
 	List(ctx context.Context, guildID string, categoryID, channelID *string) ([]m.PermissionOverwrite, error)
+
+	// GuildPermissions resolves a member's guild-wide permission bitfield:
+	// the owner and administrators get every bit, others the union of
+	// their roles.
+	GuildPermissions(ctx context.Context, guildID, userID string) (uint64, error)
+
+	// Require returns ErrMissingPermission unless the user holds perm.
+	Require(ctx context.Context, guildID, userID string, perm uint64) error
+
+	// ChannelPermissions resolves a member's permissions in a channel: the
+	// guild-wide bits with the channel's overwrites applied in turn for
+	// @everyone, the member's roles and the member, each denying before it
+	// allows. The owner and administrators still get every bit.
+	ChannelPermissions(ctx context.Context, ch *m.Channel, userID string) (uint64, error)
+
+	// RequireChannel is Require against ChannelPermissions.
+	RequireChannel(ctx context.Context, ch *m.Channel, userID string, perm uint64) error
+
+	// RequireAbove returns ErrHierarchy unless the actor outranks the
+	// target: the owner outranks everyone, otherwise the actor's highest
+	// role must sit above the target's.
+	RequireAbove(ctx context.Context, guildID, actorID, targetID string) error
+
+	// HighestPosition returns the position of the member's top role. The
+	// owner ranks above every role and gets math.MaxInt.
+	HighestPosition(ctx context.Context, guildID, userID string) (int, error)
 }
@@ -2,29 +2,188 @@ package permissions
 
 import (
 	"context"
+	"errors"
+	"math"
+
+	"gorm.io/gorm"
 
 	"launay-dot-one/models/guilds"
 	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+)
+
+var (
+	ErrNotMember         = errors.New("not a member of this guild")
+	ErrMissingPermission = errors.New("missing permission")
+	ErrHierarchy         = errors.New("target's highest role is not below yours")
+	ErrCannotGrant       = errors.New("cannot grant permissions you don't hold")
+	ErrInvalidOverwrite  = errors.New("overwrite must target a channel or category of this guild")
+	ErrUnknownOverwrite  = errors.New("overwrite does not belong to this guild")
 )
 
 type service struct {
-	repo *repositories.PermissionOverwriteRepository
+	repo       *repositories.PermissionOverwriteRepository
+	guildRepo  *repositories.GuildRepository
+	memberRepo *repositories.GuildMemberRepository
+	roleRepo   *repositories.GuildRoleRepository
+	audit      auditlog.Service
+
+	channelRepo  *repositories.ChannelRepository
+	categoryRepo *repositories.CategoryRepository
+}
+
+func NewService(
+	repo *repositories.PermissionOverwriteRepository,
+	guildRepo *repositories.GuildRepository,
+	memberRepo *repositories.GuildMemberRepository,
+	roleRepo *repositories.GuildRoleRepository,
+	channelRepo *repositories.ChannelRepository,
+	categoryRepo *repositories.CategoryRepository,
+	audit auditlog.Service,
+) Service {
+	return &service{repo, guildRepo, memberRepo, roleRepo, audit, channelRepo, categoryRepo}
+}
+
+// authorize checks that the actor may write the overwrite: it needs
+// manage-roles, must target a channel or category of its own guild, and can
+// only allow bits the actor holds.
+func (s *service) authorize(ctx context.Context, o *guilds.PermissionOverwrite, actorID string) error {
+	perms, err := s.GuildPermissions(ctx, o.GuildID, actorID)
+	if err != nil {
+		return err
+	}
+	if perms&guilds.PermManageRoles == 0 {
+		return ErrMissingPermission
+	}
+	if (o.ChannelID == "") == (o.CategoryID == "") {
+		return ErrInvalidOverwrite
+	}
+	var targetGuild string
+	if o.ChannelID != "" {
+		ch, err := s.channelRepo.GetByID(ctx, o.ChannelID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidOverwrite
+		}
+		if err != nil {
+			return err
+		}
+		targetGuild = ch.GuildID
+	} else {
+		c, err := s.categoryRepo.GetByID(ctx, o.CategoryID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidOverwrite
+		}
+		if err != nil {
+			return err
+		}
+		targetGuild = c.GuildID
+	}
+	if targetGuild != o.GuildID {
+		return ErrInvalidOverwrite
+	}
+	if uint64(o.Allow)&^perms != 0 {
+		return ErrCannotGrant
+	}
+	return nil
 }
 
-func NewService(repo *repositories.PermissionOverwriteRepository) Service {
-	return &service{repo}
+// stored loads an overwrite, hiding those of other guilds.
+func (s *service) stored(ctx context.Context, guildID, id string) (*guilds.PermissionOverwrite, error) {
+	o, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if o.GuildID != guildID {
+		return nil, ErrUnknownOverwrite
+	}
+	return o, nil
 }
 
-func (s *service) Create(ctx context.Context, o *guilds.PermissionOverwrite) error {
-	return s.repo.Create(ctx, o)
+func (s *service) Create(ctx context.Context, o *guilds.PermissionOverwrite, actorID string) error {
+	if err := s.authorize(ctx, o, actorID); err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, o); err != nil {
+		return err
+	}
+	if err := s.propagate(ctx, o); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    o.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditOverwriteCreate,
+		TargetType: guilds.AuditTargetOverwrite,
+		TargetID:   o.ID,
+		After:      o,
+	})
 }
 
-func (s *service) Update(ctx context.Context, o *guilds.PermissionOverwrite) error {
-	return s.repo.Update(ctx, o)
+func (s *service) Update(ctx context.Context, o *guilds.PermissionOverwrite, actorID string) error {
+	before, err := s.stored(ctx, o.GuildID, o.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.authorize(ctx, o, actorID); err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, o); err != nil {
+		return err
+	}
+	if err := s.propagate(ctx, before); err != nil {
+		return err
+	}
+	if err := s.propagate(ctx, o); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    before.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditOverwriteUpdate,
+		TargetType: guilds.AuditTargetOverwrite,
+		TargetID:   o.ID,
+		Before:     before,
+		After:      o,
+	})
 }
 
-func (s *service) Delete(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+func (s *service) Delete(ctx context.Context, guildID, id, actorID string) error {
+	before, err := s.stored(ctx, guildID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.Require(ctx, guildID, actorID, guilds.PermManageRoles); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := s.propagate(ctx, before); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    before.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditOverwriteDelete,
+		TargetType: guilds.AuditTargetOverwrite,
+		TargetID:   id,
+		Before:     before,
+	})
+}
+
+// propagate keeps channel sync state consistent after an overwrite changed:
+// editing a channel's own overwrites breaks its sync, editing a category's
+// re-copies them onto the channels still synced to it.
+func (s *service) propagate(ctx context.Context, o *guilds.PermissionOverwrite) error {
+	if o.ChannelID != "" {
+		if err := s.channelRepo.SetPermissionsSynced(ctx, o.ChannelID, false); err != nil {
+			return err
+		}
+	}
+	if o.CategoryID != "" {
+		return s.repo.ResyncCategory(ctx, o.CategoryID)
+	}
+	return nil
 }
 
 func (s *service) List(
@@ -34,3 +193,163 @@ func (s *service) List(
 ) ([]guilds.PermissionOverwrite, error) {
 	return s.repo.List(ctx, guildID, categoryID, channelID)
 }
+
+func (s *service) GuildPermissions(ctx context.Context, guildID, userID string) (uint64, error) {
+	perms, _, err := s.guildPermissions(ctx, guildID, userID)
+	return perms, err
+}
+
+// guildPermissions also returns the roles the member holds, unless they
+// get every bit anyway.
+func (s *service) guildPermissions(ctx context.Context, guildID, userID string) (uint64, []guilds.GuildRole, error) {
+	g, err := s.guildRepo.GetByID(ctx, guildID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if g.OwnerID == userID {
+		return guilds.PermAll, nil, nil
+	}
+
+	roles, err := s.memberRoles(ctx, guildID, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	var perms uint64
+	for _, r := range roles {
+		perms |= r.Permissions
+	}
+	if perms&guilds.PermAdministrator != 0 {
+		return guilds.PermAll, nil, nil
+	}
+	return perms, roles, nil
+}
+
+func (s *service) ChannelPermissions(ctx context.Context, ch *guilds.Channel, userID string) (uint64, error) {
+	perms, roles, err := s.guildPermissions(ctx, ch.GuildID, userID)
+	if err != nil || perms == guilds.PermAll {
+		return perms, err
+	}
+	// a synced channel holds copies of its category's overwrites, so its
+	// own are all that apply
+	overwrites, err := s.repo.List(ctx, ch.GuildID, nil, &ch.ID)
+	if err != nil {
+		return 0, err
+	}
+	held := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		held[r.ID] = true
+	}
+	var everyone, fromRoles, member [2]uint64 // allow, deny
+	for _, o := range overwrites {
+		bits := [2]uint64{uint64(o.Allow), uint64(o.Deny)}
+		switch {
+		case o.OverwriteType == guilds.OverwriteMember:
+			if o.TargetID == userID {
+				member = bits
+			}
+		case o.TargetID == ch.GuildID:
+			everyone = bits
+		case held[o.TargetID]:
+			fromRoles[0] |= bits[0]
+			fromRoles[1] |= bits[1]
+		}
+	}
+	for _, ow := range [][2]uint64{everyone, fromRoles, member} {
+		perms = perms&^ow[1] | ow[0]
+	}
+	return perms, nil
+}
+
+func (s *service) RequireChannel(ctx context.Context, ch *guilds.Channel, userID string, perm uint64) error {
+	perms, err := s.ChannelPermissions(ctx, ch, userID)
+	if err != nil {
+		return err
+	}
+	if perms&perm != perm {
+		return ErrMissingPermission
+	}
+	return nil
+}
+
+func (s *service) Require(ctx context.Context, guildID, userID string, perm uint64) error {
+	perms, err := s.GuildPermissions(ctx, guildID, userID)
+	if err != nil {
+		return err
+	}
+	if perms&perm != perm {
+		return ErrMissingPermission
+	}
+	return nil
+}
+
+func (s *service) RequireAbove(ctx context.Context, guildID, actorID, targetID string) error {
+	if actorID == targetID {
+		return ErrHierarchy
+	}
+	g, err := s.guildRepo.GetByID(ctx, guildID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case g.OwnerID == actorID:
+		return nil
+	case g.OwnerID == targetID:
+		return ErrHierarchy
+	}
+
+	actorTop, err := s.highestPosition(ctx, guildID, actorID)
+	if err != nil {
+		return err
+	}
+	targetTop, err := s.highestPosition(ctx, guildID, targetID)
+	if errors.Is(err, ErrNotMember) {
+		return nil // e.g. banning someone who already left
+	}
+	if err != nil {
+		return err
+	}
+	if actorTop <= targetTop {
+		return ErrHierarchy
+	}
+	return nil
+}
+
+// memberRoles loads the roles a guild member holds, @everyone included.
+func (s *service) memberRoles(ctx context.Context, guildID, userID string) ([]guilds.GuildRole, error) {
+	mem, err := s.memberRepo.Get(ctx, guildID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	// every member implicitly holds @everyone, whose ID is the guild ID
+	ids := append([]string{guildID}, mem.RoleIDs...)
+	return s.roleRepo.ListByIDs(ctx, guildID, ids)
+}
+
+func (s *service) HighestPosition(ctx context.Context, guildID, userID string) (int, error) {
+	g, err := s.guildRepo.GetByID(ctx, guildID)
+	if err != nil {
+		return 0, err
+	}
+	if g.OwnerID == userID {
+		return math.MaxInt, nil
+	}
+	return s.highestPosition(ctx, guildID, userID)
+}
+
+// highestPosition returns the position of the member's top role, or -1.
+func (s *service) highestPosition(ctx context.Context, guildID, userID string) (int, error) {
+	roles, err := s.memberRoles(ctx, guildID, userID)
+	if err != nil {
+		return 0, err
+	}
+	top := -1
+	for _, r := range roles {
+		if r.Position > top {
+			top = r.Position
+		}
+	}
+	return top, nil
+}
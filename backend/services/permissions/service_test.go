@@ -0,0 +1,135 @@
+package permissions
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+const testGuild = "guild-1"
+
+func newTestService(t *testing.T) (*service, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "permissions.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the models' uuid_generate_v4() defaults are Postgres only
+	for _, ddl := range []string{
+		`CREATE TABLE guilds (
+			id TEXT PRIMARY KEY, name TEXT, description TEXT, owner_id TEXT NOT NULL,
+			icon TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE guild_roles (
+			id TEXT PRIMARY KEY, guild_id TEXT NOT NULL, name TEXT, permissions BIGINT,
+			color INTEGER, hoist BOOLEAN, position INTEGER, created_at DATETIME,
+			updated_at DATETIME, managed BOOLEAN, bot_id TEXT)`,
+		`CREATE TABLE channels (
+			id TEXT PRIMARY KEY, guild_id TEXT NOT NULL, category_id TEXT, name TEXT,
+			type TEXT, position INTEGER, created_at DATETIME, updated_at DATETIME,
+			permissions_synced BOOLEAN, topic TEXT, nsfw BOOLEAN, slowmode_seconds INTEGER,
+			bitrate INTEGER, user_limit INTEGER)`,
+	} {
+		if err := db.Exec(ddl).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AutoMigrate(&guilds.GuildMember{}, &guilds.GuildMemberRole{}, &guilds.PermissionOverwrite{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(
+		repositories.NewPermissionOverwriteRepository(db), repositories.NewGuildRepository(db),
+		repositories.NewGuildMemberRepository(db), repositories.NewGuildRoleRepository(db),
+		repositories.NewChannelRepository(db), repositories.NewCategoryRepository(db), nil,
+	).(*service)
+	return svc, db
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, rows ...interface{}) {
+	t.Helper()
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestChannelPermissionsApplyOverwrites(t *testing.T) {
+	ctx := context.Background()
+	svc, db := newTestService(t)
+
+	mustCreate(t, db,
+		&guilds.Guild{ID: testGuild, Name: "Guild", OwnerID: "owner"},
+		&guilds.GuildRole{ID: testGuild, GuildID: testGuild, Name: "@everyone", Permissions: guilds.PermDefaultEveryone},
+		&guilds.GuildRole{ID: "mods", GuildID: testGuild, Name: "Mods", Permissions: guilds.PermManageMessages, Position: 1},
+		&guilds.GuildRole{ID: "admins", GuildID: testGuild, Name: "Admins", Permissions: guilds.PermAdministrator, Position: 2},
+		&guilds.Channel{ID: "open", GuildID: testGuild, Name: "open", Type: string(guilds.ChannelText)},
+		&guilds.Channel{ID: "secret", GuildID: testGuild, Name: "secret", Type: string(guilds.ChannelText)},
+	)
+	for user, roles := range map[string][]string{"owner": nil, "bob": nil, "alice": {"mods"}, "dave": {"mods"}, "carol": {"admins"}} {
+		mustCreate(t, db, &guilds.GuildMember{GuildID: testGuild, UserID: user})
+		for _, role := range roles {
+			mustCreate(t, db, &guilds.GuildMemberRole{GuildID: testGuild, UserID: user, RoleID: role})
+		}
+	}
+	overwrite := func(id string, typ guilds.OverwriteType, target string, allow, deny uint64) *guilds.PermissionOverwrite {
+		return &guilds.PermissionOverwrite{
+			ID: id, GuildID: testGuild, ChannelID: "secret", OverwriteType: typ, TargetID: target,
+			Allow: int64(allow), Deny: int64(deny),
+		}
+	}
+	mustCreate(t, db,
+		// hidden from @everyone, visible to mods, only alice may post
+		overwrite("ow-everyone", guilds.OverwriteRole, testGuild, 0, guilds.PermViewChannel|guilds.PermSendMessages),
+		overwrite("ow-mods", guilds.OverwriteRole, "mods", guilds.PermViewChannel, 0),
+		overwrite("ow-alice", guilds.OverwriteMember, "alice", guilds.PermSendMessages, 0),
+		overwrite("ow-dave", guilds.OverwriteMember, "dave", 0, guilds.PermViewChannel),
+	)
+
+	channel := func(id string) *guilds.Channel {
+		ch, err := svc.channelRepo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ch
+	}
+	open, secret := channel("open"), channel("secret")
+
+	tests := []struct {
+		user    string
+		ch      *guilds.Channel
+		has     uint64
+		hasNone uint64
+	}{
+		{"bob", open, guilds.PermViewChannel | guilds.PermSendMessages, guilds.PermManageMessages},
+		{"bob", secret, guilds.PermAddReactions, guilds.PermViewChannel | guilds.PermSendMessages},
+		{"alice", secret, guilds.PermViewChannel | guilds.PermSendMessages | guilds.PermManageMessages, 0},
+		// the member's own overwrite beats their roles'
+		{"dave", secret, guilds.PermManageMessages, guilds.PermViewChannel | guilds.PermSendMessages},
+		{"carol", secret, guilds.PermAll, 0},
+		{"owner", secret, guilds.PermAll, 0},
+	}
+	for _, tt := range tests {
+		perms, err := svc.ChannelPermissions(ctx, tt.ch, tt.user)
+		if err != nil {
+			t.Fatalf("%s in %s: %v", tt.user, tt.ch.ID, err)
+		}
+		if perms&tt.has != tt.has || perms&tt.hasNone != 0 {
+			t.Errorf("%s in %s: permissions %b, want %b set and %b clear", tt.user, tt.ch.ID, perms, tt.has, tt.hasNone)
+		}
+	}
+
+	if err := svc.RequireChannel(ctx, secret, "bob", guilds.PermViewChannel); !errors.Is(err, ErrMissingPermission) {
+		t.Errorf("RequireChannel for bob in secret: %v, want ErrMissingPermission", err)
+	}
+	// guild-wide permissions ignore channel overwrites
+	if err := svc.Require(ctx, testGuild, "bob", guilds.PermViewChannel); err != nil {
+		t.Errorf("Require for bob: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package push
+
+import (
+	"context"
+
+	m "launay-dot-one/models"
+)
+
+// Service delivers direct messages, mentions and replies as Web Push
+// notifications to users who have no socket open. Users in do not
+// disturb get none, and messages arriving in quick succession from the
+// same conversation are collapsed into a single follow-up push.
+type Service interface {
+	// PublicKey is the VAPID key browsers subscribe with, base64url.
+	PublicKey() string
+
+	// Subscribe registers a browser's push subscription for the user.
+	Subscribe(ctx context.Context, userID string, in Subscription) (*m.PushSubscription, error)
+
+	ListSubscriptions(ctx context.Context, userID string) ([]m.PushSubscription, error)
+	Unsubscribe(ctx context.Context, userID, subscriptionID string) error
+
+	// Notified pushes a mention or reply notification to its user; other
+	// notification types aren't pushed.
+	Notified(ctx context.Context, n m.Notification) error
+
+	// DirectMessage pushes a direct message to its recipient. Messages to
+	// groups aren't pushed.
+	DirectMessage(ctx context.Context, msg *m.Message) error
+
+	// FlushBursts sends one push for each conversation whose collapse
+	// window closed with messages held back, and returns how many it sent.
+	FlushBursts(ctx context.Context) (int, error)
+}
+
+// Subscription is what a browser's PushSubscription serialises to, plus
+// an optional name for the device.
+type Subscription struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
+	} `json:"keys" binding:"required"`
+	DeviceName string `json:"device_name"`
+}
+
+// Payload is the JSON a service worker receives.
+type Payload struct {
+	Type      string `json:"type"`       // "dm", "mention" or "reply"
+	ChannelID string `json:"channel_id"` // for direct messages, the sender
+	GuildID   string `json:"guild_id,omitempty"`
+	MessageID string `json:"message_id"` // the latest one
+	ActorID   string `json:"actor_id"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Count     int    `json:"count"` // messages the push stands for
+}
@@ -0,0 +1,439 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/realtime"
+	"launay-dot-one/repositories"
+	"launay-dot-one/utils"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidSubscription = fmt.Errorf(
+		"subscription needs an https endpoint of at most %d characters, a P-256 p256dh key, "+
+			"a 16-byte auth secret and a device name of at most %d characters",
+		m.MaxPushEndpoint, m.MaxPushDeviceName,
+	)
+	ErrTooManySubscriptions = fmt.Errorf("at most %d devices can receive pushes", m.MaxPushSubscriptions)
+)
+
+// Payload types.
+const (
+	TypeDirectMessage = "dm"
+	TypeMention       = "mention"
+	TypeReply         = "reply"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	// pushTTL is how long a push service holds a push for an offline device.
+	pushTTL = 24 * time.Hour
+)
+
+// Redis layout: while a conversation's collapse window is open for a user
+// its burst key exists, messages held back are counted (with the latest
+// one's payload) in its pending hash, and the window sits in one sorted
+// set scored by when it closes, which FlushBursts walks.
+const burstDueKey = "push:bursts"
+
+func burstKey(member string) string   { return "push:burst:" + member }
+func pendingKey(member string) string { return "push:pending:" + member }
+
+type service struct {
+	repo        *repositories.PushSubscriptionRepository
+	userRepo    *repositories.UserRepository
+	redisClient *redis.Client
+	gateway     realtime.Gateway
+	presence    realtime.PresenceService
+	key         *VAPIDKey
+	subject     string
+	client      *http.Client
+	window      time.Duration
+	allowLocal  bool
+}
+
+// NewService wires up Web Push. Pushes are signed with key, naming
+// subject (a mailto: or https: URL) as who to contact; messages following
+// a push within window are held back and collapsed. Endpoints must be
+// public https URLs unless allowLocal, meant for development against a
+// local push service, also lets them be plain http to this machine.
+func NewService(
+	repo *repositories.PushSubscriptionRepository,
+	userRepo *repositories.UserRepository,
+	redisClient *redis.Client,
+	gateway realtime.Gateway,
+	presence realtime.PresenceService,
+	key *VAPIDKey,
+	subject string,
+	window time.Duration,
+	allowLocal bool,
+) Service {
+	client := utils.PublicHTTPClient(requestTimeout, false)
+	if allowLocal {
+		client = &http.Client{
+			Timeout:       requestTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+	}
+	return &service{
+		repo:        repo,
+		userRepo:    userRepo,
+		redisClient: redisClient,
+		gateway:     gateway,
+		presence:    presence,
+		key:         key,
+		subject:     subject,
+		client:      client,
+		window:      window,
+		allowLocal:  allowLocal,
+	}
+}
+
+// LoadVAPIDKey parses the configured private key or, when there is none,
+// returns the one stored in the database, generating it on first start.
+func LoadVAPIDKey(ctx context.Context, repo *repositories.PushSubscriptionRepository, configured string) (*VAPIDKey, error) {
+	if configured != "" {
+		return ParseVAPIDKey(configured)
+	}
+	private, public, err := GenerateVAPIDKey()
+	if err != nil {
+		return nil, err
+	}
+	stored, err := repo.EnsureVAPIDKey(ctx, &m.VAPIDKey{PrivateKey: private, PublicKey: public})
+	if err != nil {
+		return nil, err
+	}
+	return ParseVAPIDKey(stored.PrivateKey)
+}
+
+func (s *service) PublicKey() string { return s.key.public }
+
+func (s *service) Subscribe(ctx context.Context, userID string, in Subscription) (*m.PushSubscription, error) {
+	if !s.validEndpoint(in.Endpoint) || !validKeys(in.Keys.P256dh, in.Keys.Auth) ||
+		utf8.RuneCountInString(in.DeviceName) > m.MaxPushDeviceName {
+		return nil, ErrInvalidSubscription
+	}
+	n, err := s.repo.CountByUser(ctx, userID, in.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if n >= m.MaxPushSubscriptions {
+		return nil, ErrTooManySubscriptions
+	}
+	sub := &m.PushSubscription{
+		UserID:     userID,
+		Endpoint:   in.Endpoint,
+		P256dh:     in.Keys.P256dh,
+		Auth:       in.Keys.Auth,
+		DeviceName: strings.TrimSpace(in.DeviceName),
+	}
+	if err := s.repo.Save(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// validEndpoint accepts https URLs to public hosts and, with allowLocal,
+// plain http to this machine.
+func (s *service) validEndpoint(raw string) bool {
+	if raw == "" || len(raw) > m.MaxPushEndpoint {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if s.allowLocal && u.Scheme == "http" {
+		if u.Hostname() == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(u.Hostname())
+		return ip != nil && ip.IsLoopback()
+	}
+	return u.Scheme == "https" && (s.allowLocal || !utils.IsInternalHost(u.Hostname()))
+}
+
+// validKeys checks a browser's keys are a P-256 point and a 16-byte
+// secret.
+func validKeys(p256dh, auth string) bool {
+	point, err := decodeKey(p256dh)
+	if err != nil {
+		return false
+	}
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return false
+	}
+	secret, err := decodeKey(auth)
+	return err == nil && len(secret) == authSize
+}
+
+func (s *service) ListSubscriptions(ctx context.Context, userID string) ([]m.PushSubscription, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *service) Unsubscribe(ctx context.Context, userID, subscriptionID string) error {
+	ok, err := s.repo.Delete(ctx, userID, subscriptionID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *service) Notified(ctx context.Context, n m.Notification) error {
+	var typ, verb string
+	switch n.Type {
+	case m.NotificationMention:
+		typ, verb = TypeMention, "mentioned you"
+	case m.NotificationReply:
+		typ, verb = TypeReply, "replied to you"
+	default:
+		return nil
+	}
+	if n.ChannelID == nil || n.MessageID == nil {
+		return nil
+	}
+	var data struct {
+		Excerpt string `json:"excerpt"`
+	}
+	_ = json.Unmarshal(n.Data, &data)
+	p := Payload{
+		Type:      typ,
+		ChannelID: *n.ChannelID,
+		MessageID: *n.MessageID,
+		ActorID:   n.ActorID,
+		Title:     s.actorName(ctx, n.ActorID) + " " + verb,
+		Body:      data.Excerpt,
+		Count:     1,
+	}
+	if n.GuildID != nil {
+		p.GuildID = *n.GuildID
+	}
+	return s.offer(ctx, n.UserID, p)
+}
+
+func (s *service) DirectMessage(ctx context.Context, msg *m.Message) error {
+	recipient := msg.ChannelID
+	if recipient == msg.AuthorID || s.gateway.Connected(recipient) {
+		return nil
+	}
+	_, err := s.userRepo.GetByID(ctx, recipient)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // a group
+	}
+	if err != nil {
+		return err
+	}
+	return s.offer(ctx, recipient, Payload{
+		Type:      TypeDirectMessage,
+		ChannelID: msg.AuthorID,
+		MessageID: msg.ID,
+		ActorID:   msg.AuthorID,
+		Title:     s.actorName(ctx, msg.AuthorID),
+		Body:      excerpt(msg.Content),
+		Count:     1,
+	})
+}
+
+func (s *service) actorName(ctx context.Context, userID string) string {
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "Someone"
+	}
+	return u.Username
+}
+
+func excerpt(content string) string {
+	if r := []rune(content); len(r) > m.MaxNotificationExcerpt {
+		return string(r[:m.MaxNotificationExcerpt]) + "…"
+	}
+	return content
+}
+
+// reachable reports whether a push should go to the user: they have no
+// socket open and aren't in do not disturb.
+func (s *service) reachable(ctx context.Context, userID string) (bool, error) {
+	if s.gateway.Connected(userID) {
+		return false, nil
+	}
+	dnd, err := s.presence.DoNotDisturb(ctx, userID)
+	return !dnd, err
+}
+
+// offer pushes p right away when it opens a collapse window for its
+// conversation, and otherwise holds it back for FlushBursts.
+func (s *service) offer(ctx context.Context, userID string, p Payload) error {
+	if ok, err := s.reachable(ctx, userID); err != nil || !ok {
+		return err
+	}
+	member := userID + ":" + p.ChannelID
+	// the window outlives its due time so it only closes through FlushBursts
+	opened, err := s.redisClient.SetNX(ctx, burstKey(member), 1, 2*s.window).Result()
+	if err != nil {
+		return err
+	}
+	if opened {
+		due := float64(time.Now().Add(s.window).Unix())
+		if err := s.redisClient.ZAdd(ctx, burstDueKey, &redis.Z{Score: due, Member: member}).Err(); err != nil {
+			return err
+		}
+		go s.send(context.Background(), userID, p)
+		return nil
+	}
+	last, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	pipe := s.redisClient.TxPipeline()
+	pipe.HIncrBy(ctx, pendingKey(member), "count", 1)
+	pipe.HSet(ctx, pendingKey(member), "last", last)
+	pipe.Expire(ctx, pendingKey(member), 2*s.window)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *service) FlushBursts(ctx context.Context) (int, error) {
+	due, err := s.redisClient.ZRangeByScore(ctx, burstDueKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Unix(), 10),
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, member := range due {
+		// whoever removes the entry flushes it, so replicas don't both push
+		n, err := s.redisClient.ZRem(ctx, burstDueKey, member).Result()
+		if err != nil {
+			return sent, err
+		}
+		if n == 0 {
+			continue
+		}
+		pipe := s.redisClient.TxPipeline()
+		pending := pipe.HGetAll(ctx, pendingKey(member))
+		pipe.Del(ctx, pendingKey(member))
+		if _, err := pipe.Exec(ctx); err != nil {
+			return sent, err
+		}
+		userID, _, _ := strings.Cut(member, ":")
+		count, _ := strconv.Atoi(pending.Val()["count"])
+		var p Payload
+		ok := count > 0 && json.Unmarshal([]byte(pending.Val()["last"]), &p) == nil
+		if ok {
+			if ok, err = s.reachable(ctx, userID); err != nil {
+				return sent, err
+			}
+		}
+		if !ok {
+			// the burst is over: the next message pushes right away
+			if err := s.redisClient.Del(ctx, burstKey(member)).Err(); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		// still busy: keep collapsing for another window
+		pipe = s.redisClient.TxPipeline()
+		pipe.Expire(ctx, burstKey(member), 2*s.window)
+		pipe.ZAdd(ctx, burstDueKey, &redis.Z{Score: float64(time.Now().Add(s.window).Unix()), Member: member})
+		if _, err := pipe.Exec(ctx); err != nil {
+			return sent, err
+		}
+		p.Count = count
+		if count > 1 {
+			p.Body = fmt.Sprintf("%d new messages", count)
+		}
+		s.send(ctx, userID, p)
+		sent++
+	}
+	return sent, nil
+}
+
+// send pushes p to each of the user's devices. Pushes are best effort:
+// a device that misses one will catch up when the app next opens.
+func (s *service) send(ctx context.Context, userID string, p Payload) {
+	subs, err := s.repo.ListByUser(ctx, userID)
+	if err != nil || len(subs) == 0 {
+		return
+	}
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+	for i := range subs {
+		_ = s.sendTo(ctx, &subs[i], payload, topic(p.ChannelID))
+	}
+}
+
+// topic names a conversation for the Topic header (RFC 8030), so a push
+// service replaces a device's undelivered push with the newer one.
+func topic(conversation string) string {
+	sum := sha256.Sum256([]byte(conversation))
+	return b64.EncodeToString(sum[:24]) // 32 characters, the most allowed
+}
+
+// sendTo delivers one encrypted push and drops the subscription when the
+// push service says it's gone.
+func (s *service) sendTo(ctx context.Context, sub *m.PushSubscription, payload []byte, topic string) error {
+	uaPublic, err := decodeKey(sub.P256dh)
+	if err != nil {
+		return err
+	}
+	auth, err := decodeKey(sub.Auth)
+	if err != nil {
+		return err
+	}
+	body, err := encrypt(payload, uaPublic, auth)
+	if err != nil {
+		return err
+	}
+	authorization, err := s.key.authorization(sub.Endpoint, s.subject)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(pushTTL/time.Second)))
+	req.Header.Set("Urgency", "high")
+	req.Header.Set("Topic", topic)
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusGone, resp.StatusCode == http.StatusNotFound:
+		return s.repo.DeleteByID(ctx, sub.ID)
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return s.repo.MarkPushed(ctx, sub.ID, time.Now())
+	default:
+		return fmt.Errorf("push service answered %d", resp.StatusCode)
+	}
+}
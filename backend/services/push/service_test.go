@@ -0,0 +1,257 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/realtime"
+	"launay-dot-one/repositories"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// fakePushService stands in for a browser vendor's push service: it
+// answers each endpoint path with a fixed status and keeps what it got.
+type fakePushService struct {
+	*httptest.Server
+	mu       sync.Mutex
+	received []*http.Request
+	bodies   [][]byte
+	got      chan struct{}
+}
+
+func newFakePushService(t *testing.T, statuses map[string]int) *fakePushService {
+	f := &fakePushService{got: make(chan struct{}, 16)}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.received = append(f.received, r)
+		f.bodies = append(f.bodies, body)
+		f.mu.Unlock()
+		w.WriteHeader(statuses[r.URL.Path])
+		f.got <- struct{}{}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// wait blocks until n more pushes arrived.
+func (f *fakePushService) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-f.got:
+		case <-time.After(5 * time.Second):
+			t.Fatal("push never arrived")
+		}
+	}
+}
+
+func (f *fakePushService) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.received)
+}
+
+type fakeGateway struct{ realtime.Gateway }
+
+func (fakeGateway) Connected(string) bool { return false }
+
+type fakePresence struct{ realtime.PresenceService }
+
+func (fakePresence) DoNotDisturb(context.Context, string) (bool, error) { return false, nil }
+
+type testEnv struct {
+	svc   *service
+	db    *gorm.DB
+	redis *redis.Client
+	push  *fakePushService
+	ua    *ecdh.PrivateKey
+	auth  []byte
+}
+
+func newTestEnv(t *testing.T, statuses map[string]int) *testEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "push.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the model's uuid_generate_v4() default is Postgres only
+	if err := db.Exec(`CREATE TABLE push_subscriptions (
+		id TEXT PRIMARY KEY, user_id TEXT NOT NULL, endpoint TEXT NOT NULL UNIQUE,
+		p256dh TEXT NOT NULL, auth TEXT NOT NULL, device_name TEXT,
+		created_at DATETIME, last_push_at DATETIME)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	private, _, err := GenerateVAPIDKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseVAPIDKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ua, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, authSize)
+	if _, err := rand.Read(auth); err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(
+		repositories.NewPushSubscriptionRepository(db), nil, rdb,
+		fakeGateway{}, fakePresence{}, key, "mailto:test@example.com", time.Minute, true,
+	).(*service)
+	return &testEnv{svc, db, rdb, newFakePushService(t, statuses), ua, auth}
+}
+
+// subscribe registers a device of userID whose endpoint is path on the
+// fake push service.
+func (e *testEnv) subscribe(t *testing.T, id, userID, path string) {
+	t.Helper()
+	if err := e.db.Create(&m.PushSubscription{
+		ID:       id,
+		UserID:   userID,
+		Endpoint: e.push.URL + path,
+		P256dh:   b64.EncodeToString(e.ua.PublicKey().Bytes()),
+		Auth:     b64.EncodeToString(e.auth),
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// payload decrypts the i-th push the fake service received.
+func (e *testEnv) payload(t *testing.T, i int) Payload {
+	t.Helper()
+	e.push.mu.Lock()
+	body := e.push.bodies[i]
+	e.push.mu.Unlock()
+	plain, err := decrypt(body, e.ua, e.auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p Payload
+	if err := json.Unmarshal(plain, &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestSendDropsGoneSubscriptions(t *testing.T) {
+	e := newTestEnv(t, map[string]int{"/ok": http.StatusCreated, "/gone": http.StatusGone, "/missing": http.StatusNotFound})
+	e.subscribe(t, "sub-ok", "user-1", "/ok")
+	e.subscribe(t, "sub-gone", "user-1", "/gone")
+	e.subscribe(t, "sub-missing", "user-1", "/missing")
+
+	e.svc.send(context.Background(), "user-1", Payload{Type: TypeDirectMessage, ChannelID: "user-2", Body: "hi", Count: 1})
+	e.push.wait(t, 3)
+
+	subs, err := e.svc.repo.ListByUser(context.Background(), "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || subs[0].ID != "sub-ok" {
+		t.Fatalf("subscriptions left = %+v, want only sub-ok", subs)
+	}
+	if subs[0].LastPushAt == nil {
+		t.Error("delivered subscription was not marked pushed")
+	}
+
+	for _, r := range e.push.received {
+		if r.Header.Get("Content-Encoding") != "aes128gcm" {
+			t.Errorf("Content-Encoding = %q", r.Header.Get("Content-Encoding"))
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "vapid t=") {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("TTL") == "" || r.Header.Get("Topic") != topic("user-2") {
+			t.Errorf("TTL = %q, Topic = %q", r.Header.Get("TTL"), r.Header.Get("Topic"))
+		}
+	}
+	if p := e.payload(t, 0); p.Body != "hi" || p.ChannelID != "user-2" {
+		t.Errorf("payload = %+v", p)
+	}
+}
+
+func TestFlushBurstsCollapses(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t, map[string]int{"/ok": http.StatusCreated})
+	e.subscribe(t, "sub-ok", "user-1", "/ok")
+	dm := func(body string) Payload {
+		return Payload{Type: TypeDirectMessage, ChannelID: "user-2", Title: "bob", Body: body, Count: 1}
+	}
+	member := "user-1:user-2"
+	closeWindow := func() {
+		// as if the window had run out
+		if err := e.redis.ZAdd(ctx, burstDueKey, &redis.Z{Score: 0, Member: member}).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first message opens the window and pushes at once
+	if err := e.svc.offer(ctx, "user-1", dm("one")); err != nil {
+		t.Fatal(err)
+	}
+	e.push.wait(t, 1)
+	// the next ones are held back
+	for _, body := range []string{"two", "three"} {
+		if err := e.svc.offer(ctx, "user-1", dm(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := e.push.count(); n != 1 {
+		t.Fatalf("pushes during the window = %d, want 1", n)
+	}
+
+	closeWindow()
+	sent, err := e.svc.FlushBursts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 {
+		t.Fatalf("FlushBursts sent %d, want 1", sent)
+	}
+	e.push.wait(t, 1)
+	if p := e.payload(t, 1); p.Count != 2 || p.Body != "2 new messages" {
+		t.Errorf("collapsed payload = %+v, want 2 new messages", p)
+	}
+	if e.redis.Exists(ctx, pendingKey(member)).Val() != 0 {
+		t.Error("pending messages were not cleared")
+	}
+
+	// a quiet window ends the burst: nothing is sent and the next
+	// message pushes right away again
+	closeWindow()
+	if sent, err := e.svc.FlushBursts(ctx); err != nil || sent != 0 {
+		t.Fatalf("FlushBursts on a quiet window = %d, %v", sent, err)
+	}
+	if e.redis.Exists(ctx, burstKey(member)).Val() != 0 {
+		t.Error("quiet window stayed open")
+	}
+	if err := e.svc.offer(ctx, "user-1", dm("four")); err != nil {
+		t.Fatal(err)
+	}
+	e.push.wait(t, 1)
+	if p := e.payload(t, 2); p.Body != "four" || p.Count != 1 {
+		t.Errorf("payload after the burst = %+v", p)
+	}
+}
@@ -0,0 +1,167 @@
+package push
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// recordSize is the aes128gcm record size; pushes fit in one record.
+	recordSize = 4096
+	saltSize   = 16
+	authSize   = 16
+	// headerSize is salt, record size, key id length and the key id, our
+	// uncompressed P-256 public key.
+	headerSize = saltSize + 4 + 1 + 65
+	// maxPayload is what fits in one record next to its padding delimiter
+	// and the GCM tag, within the 4096 bytes push services accept.
+	maxPayload = recordSize - headerSize - 1 - 16
+
+	vapidTokenTTL = 12 * time.Hour
+)
+
+var b64 = base64.RawURLEncoding
+
+// decodeKey reads a base64url key, padded or not.
+func decodeKey(s string) ([]byte, error) {
+	for len(s)%4 != 0 {
+		s += "="
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
+
+// encrypt seals payload for a subscription's keys as a single-record
+// aes128gcm body (RFC 8188), keyed the way RFC 8291 describes.
+func encrypt(payload, uaPublic, authSecret []byte) ([]byte, error) {
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return seal(payload, uaPublic, authSecret, asKey, salt)
+}
+
+// seal is encrypt with the ephemeral key and salt chosen by the caller.
+func seal(payload, uaPublic, authSecret []byte, asKey *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(payload) > maxPayload {
+		return nil, fmt.Errorf("push payload of %d bytes exceeds %d", len(payload), maxPayload)
+	}
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, authSecret, keyInfo), ikm); err != nil {
+		return nil, err
+	}
+
+	prk := hkdf.Extract(sha256.New, ikm, salt)
+	cek := make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: aes128gcm\x00")), cek); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 12)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: nonce\x00")), nonce); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, 0, headerSize+len(payload)+1+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, byte(len(asPublic)))
+	body = append(body, asPublic...)
+	record := append(append(make([]byte, 0, len(payload)+1), payload...), 0x02) // last record
+	return gcm.Seal(body, nonce, record, nil), nil
+}
+
+// VAPIDKey is the key pair the server identifies itself to push services
+// with (RFC 8292).
+type VAPIDKey struct {
+	private *ecdsa.PrivateKey
+	public  string // base64url, what browsers pass as applicationServerKey
+}
+
+// ParseVAPIDKey reads a base64url P-256 private scalar.
+func ParseVAPIDKey(raw string) (*VAPIDKey, error) {
+	d, err := decodeKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID private key: %w", err)
+	}
+	pub := key.PublicKey().Bytes() // 0x04 || X || Y
+	return &VAPIDKey{
+		private: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(pub[1:33]),
+				Y:     new(big.Int).SetBytes(pub[33:]),
+			},
+			D: new(big.Int).SetBytes(d),
+		},
+		public: b64.EncodeToString(pub),
+	}, nil
+}
+
+// GenerateVAPIDKey returns a new key pair, base64url encoded.
+func GenerateVAPIDKey() (private, public string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return b64.EncodeToString(key.Bytes()), b64.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// authorization is the VAPID Authorization header for a push to endpoint.
+func (k *VAPIDKey) authorization(endpoint, subject string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", errors.New("push endpoint is not a URL")
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(vapidTokenTTL).Unix(),
+		"sub": subject,
+	}).SignedString(k.private)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + token + ", k=" + k.public, nil
+}
@@ -0,0 +1,157 @@
+package push
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// The example from RFC 8291, Appendix A.
+const (
+	rfcPlaintext  = "When I grow up, I want to be a watermelon"
+	rfcASPrivate  = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfcASPublic   = "BP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A8"
+	rfcUAPrivate  = "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"
+	rfcUAPublic   = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfcSalt       = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfcAuthSecret = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfcBody       = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := decodeKey(s)
+	if err != nil {
+		t.Fatalf("decode %q: %v", s, err)
+	}
+	return b
+}
+
+func TestSealRFC8291(t *testing.T) {
+	asKey, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcASPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(asKey.PublicKey().Bytes(), mustDecode(t, rfcASPublic)) {
+		t.Fatal("application server key pair does not match the RFC")
+	}
+
+	body, err := seal([]byte(rfcPlaintext), mustDecode(t, rfcUAPublic), mustDecode(t, rfcAuthSecret),
+		asKey, mustDecode(t, rfcSalt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b64.EncodeToString(body); got != rfcBody {
+		t.Errorf("body\n got %s\nwant %s", got, rfcBody)
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	uaKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := bytes.Repeat([]byte{7}, authSize)
+	payload := []byte(`{"type":"dm","body":"hi"}`)
+
+	first, err := encrypt(payload, uaKey.PublicKey().Bytes(), auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := encrypt(payload, uaKey.PublicKey().Bytes(), auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first[:headerSize], second[:headerSize]) {
+		t.Error("two pushes share a salt and key")
+	}
+	for _, body := range [][]byte{first, second} {
+		got, err := decrypt(body, uaKey, auth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Errorf("decrypted %q, want %q", got, payload)
+		}
+	}
+
+	if _, err := encrypt(make([]byte, maxPayload+1), uaKey.PublicKey().Bytes(), auth); err == nil {
+		t.Error("oversized payload was accepted")
+	}
+}
+
+func TestDecryptRFC8291(t *testing.T) {
+	uaKey, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcUAPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decrypt(mustDecode(t, rfcBody), uaKey, mustDecode(t, rfcAuthSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != rfcPlaintext {
+		t.Errorf("decrypted %q, want %q", got, rfcPlaintext)
+	}
+}
+
+// decrypt opens a single-record aes128gcm body the way a browser holding
+// uaKey and authSecret would.
+func decrypt(body []byte, uaKey *ecdh.PrivateKey, authSecret []byte) ([]byte, error) {
+	if len(body) < headerSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	salt := body[:saltSize]
+	if rs := binary.BigEndian.Uint32(body[saltSize:]); rs != recordSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	asPublic := body[saltSize+5 : headerSize]
+	asKey, err := ecdh.P256().NewPublicKey(asPublic)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := uaKey.ECDH(asKey)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := append([]byte("WebPush: info\x00"), uaKey.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, authSecret, keyInfo), ikm); err != nil {
+		return nil, err
+	}
+	prk := hkdf.Extract(sha256.New, ikm, salt)
+	cek := make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: aes128gcm\x00")), cek); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 12)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: nonce\x00")), nonce); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	record, err := gcm.Open(nil, nonce, body[headerSize:], nil)
+	if err != nil {
+		return nil, err
+	}
+	// strip the padding delimiter of the last record
+	end := bytes.LastIndexByte(record, 0x02)
+	if end < 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return record[:end], nil
+}
@@ -11,12 +11,36 @@ type Service interface {
 	// GetByUser returns the resume (with all sub‐records) for the given user.
 	GetByUser(ctx context.Context, userID string) (*m.Resume, error)
 
-	// Create inserts a new resume record.
+	// Create inserts a new resume record. It starts out private.
 	Create(ctx context.Context, res *m.Resume) error
 
-	// Update modifies an existing resume.
+	// Update modifies an existing resume. Its sharing settings are kept.
 	Update(ctx context.Context, res *m.Resume) error
 
 	// DeleteByUser deletes the resume belonging to the given user.
 	DeleteByUser(ctx context.Context, userID string) error
+
+	// UpdateSharing changes who can read the user's resume, its vanity slug
+	// and the sections viewers don't see.
+	UpdateSharing(ctx context.Context, userID string, in Sharing) (*m.Resume, error)
+
+	// RotateShareLink replaces the secret slug of the unlisted link, so
+	// links handed out before stop working.
+	RotateShareLink(ctx context.Context, userID string) (*m.Resume, error)
+
+	// View returns userID's resume as viewerID (empty when anonymous) sees
+	// it. Resumes the viewer may not read aren't found.
+	View(ctx context.Context, viewerID, userID string) (*m.PublicResume, error)
+
+	// ViewBySlug does the same for the resume behind a vanity slug or the
+	// secret slug of an unlisted link.
+	ViewBySlug(ctx context.Context, viewerID, slug string) (*m.PublicResume, error)
+}
+
+// Sharing changes a resume's sharing settings; nil fields are left alone
+// and an empty slug removes the vanity one.
+type Sharing struct {
+	Visibility     *m.ResumeVisibility `json:"visibility"`
+	Slug           *string             `json:"slug"`
+	HiddenSections *[]m.ResumeSection  `json:"hidden_sections"`
 }
@@ -2,19 +2,47 @@ package resumes
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"launay-dot-one/models"
 	"launay-dot-one/repositories"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidVisibility = errors.New("visibility must be public, unlisted, friends or private")
+	ErrInvalidSlug       = fmt.Errorf(
+		"slug must be %d to %d lowercase letters, digits and single dashes between them",
+		models.MinResumeSlug, models.MaxResumeSlug,
+	)
+	ErrSlugTaken      = errors.New("slug is already taken")
+	ErrInvalidSection = errors.New(
+		"hidden sections must be among education, experience, projects, certifications, skills and interests",
+	)
 )
 
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
 type service struct {
-	repo *repositories.ResumeRepository
+	repo       *repositories.ResumeRepository
+	userRepo   *repositories.UserRepository
+	friendRepo *repositories.FriendRequestRepository
 }
 
 // NewService constructs the resume service.
-func NewService(repo *repositories.ResumeRepository) Service {
-	return &service{repo: repo}
+func NewService(
+	repo *repositories.ResumeRepository,
+	userRepo *repositories.UserRepository,
+	friendRepo *repositories.FriendRequestRepository,
+) Service {
+	return &service{repo: repo, userRepo: userRepo, friendRepo: friendRepo}
 }
 
 func (s *service) GetByUser(ctx context.Context, userID string) (*models.Resume, error) {
@@ -22,6 +50,14 @@ func (s *service) GetByUser(ctx context.Context, userID string) (*models.Resume,
 }
 
 func (s *service) Create(ctx context.Context, res *models.Resume) error {
+	shareSlug, err := newShareSlug()
+	if err != nil {
+		return err
+	}
+	res.Visibility = models.ResumePrivate
+	res.Slug = nil
+	res.ShareSlug = &shareSlug
+	res.HiddenSections = datatypes.JSONSlice[models.ResumeSection]{}
 	return s.repo.Create(ctx, res)
 }
 
@@ -32,6 +68,10 @@ func (s *service) Update(ctx context.Context, res *models.Resume) error {
 		return fmt.Errorf("resume not found: %w", err)
 	}
 	res.ID = existing.ID
+	res.Visibility = existing.Visibility
+	res.Slug = existing.Slug
+	res.ShareSlug = existing.ShareSlug
+	res.HiddenSections = existing.HiddenSections
 	return s.repo.Update(ctx, res)
 }
 
@@ -43,3 +83,146 @@ func (s *service) DeleteByUser(ctx context.Context, userID string) error {
 	}
 	return s.repo.Delete(ctx, existing.ID)
 }
+
+func (s *service) UpdateSharing(ctx context.Context, userID string, in Sharing) (*models.Resume, error) {
+	res, err := s.repo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if in.Visibility != nil {
+		switch *in.Visibility {
+		case models.ResumePublic, models.ResumeUnlisted, models.ResumeFriends, models.ResumePrivate:
+			res.Visibility = *in.Visibility
+		default:
+			return nil, ErrInvalidVisibility
+		}
+	}
+	if in.Slug != nil {
+		slug := strings.ToLower(strings.TrimSpace(*in.Slug))
+		switch {
+		case slug == "":
+			res.Slug = nil
+		case len(slug) < models.MinResumeSlug || len(slug) > models.MaxResumeSlug || !slugPattern.MatchString(slug):
+			return nil, ErrInvalidSlug
+		default:
+			res.Slug = &slug
+		}
+	}
+	if in.HiddenSections != nil {
+		hidden := datatypes.JSONSlice[models.ResumeSection]{}
+		for _, sec := range *in.HiddenSections {
+			switch sec {
+			case models.SectionEducation, models.SectionExperience, models.SectionProjects,
+				models.SectionCertifications, models.SectionSkills, models.SectionInterests:
+			default:
+				return nil, ErrInvalidSection
+			}
+			if !contains(hidden, sec) {
+				hidden = append(hidden, sec)
+			}
+		}
+		res.HiddenSections = hidden
+	}
+	if res.ShareSlug == nil { // resumes created before share links
+		shareSlug, err := newShareSlug()
+		if err != nil {
+			return nil, err
+		}
+		res.ShareSlug = &shareSlug
+	}
+	if err := s.repo.UpdateSharing(ctx, res); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrSlugTaken
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+func contains(list []models.ResumeSection, sec models.ResumeSection) bool {
+	for _, s := range list {
+		if s == sec {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) RotateShareLink(ctx context.Context, userID string) (*models.Resume, error) {
+	res, err := s.repo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	shareSlug, err := newShareSlug()
+	if err != nil {
+		return nil, err
+	}
+	res.ShareSlug = &shareSlug
+	if err := s.repo.UpdateSharing(ctx, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// newShareSlug returns a secret slug for an unlisted link, 128 random
+// bits.
+func newShareSlug() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *service) View(ctx context.Context, viewerID, userID string) (*models.PublicResume, error) {
+	res, err := s.repo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.view(ctx, viewerID, res, false)
+}
+
+func (s *service) ViewBySlug(ctx context.Context, viewerID, slug string) (*models.PublicResume, error) {
+	res, err := s.repo.GetBySlug(ctx, strings.ToLower(slug))
+	if err == nil {
+		return s.view(ctx, viewerID, res, false)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	res, err = s.repo.GetByShareSlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	return s.view(ctx, viewerID, res, true)
+}
+
+// view renders res for the viewer if they may read it; withSecret says
+// they came through the unlisted link. Otherwise, so as not to reveal
+// the resume exists, it isn't found.
+func (s *service) view(ctx context.Context, viewerID string, res *models.Resume, withSecret bool) (*models.PublicResume, error) {
+	allowed := viewerID == res.UserID
+	switch res.Visibility {
+	case models.ResumePublic:
+		allowed = true
+	case models.ResumeUnlisted:
+		allowed = allowed || withSecret
+	case models.ResumeFriends:
+		if !allowed && viewerID != "" {
+			friends, err := s.friendRepo.AreFriends(ctx, viewerID, res.UserID)
+			if err != nil {
+				return nil, err
+			}
+			allowed = friends
+		}
+	}
+	if !allowed {
+		return nil, gorm.ErrRecordNotFound
+	}
+	owner, err := s.userRepo.GetByID(ctx, res.UserID)
+	if err != nil {
+		return nil, err
+	}
+	out := res.ToPublic(owner.Username)
+	return &out, nil
+}
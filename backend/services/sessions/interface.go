@@ -0,0 +1,17 @@
+package sessions
+
+import (
+	"context"
+	"time"
+)
+
+// Service tracks server-side revocation of otherwise valid JWTs.
+type Service interface {
+	// RevokeAll invalidates every token issued to the user up to now and
+	// closes the sockets they authenticated.
+	RevokeAll(ctx context.Context, userID string) error
+
+	// IsRevoked reports whether a token issued at issuedAt has been revoked.
+	// Tokens issued within the second of a revocation count as revoked.
+	IsRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error)
+}
@@ -0,0 +1,54 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	connectionmanager "launay-dot-one/manager"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type service struct {
+	redisClient *redis.Client
+	tokenTTL    time.Duration
+}
+
+// NewService stores revocation cut-offs in Redis. tokenTTL should match the
+// JWT lifetime: once every older token has expired the marker is dropped.
+func NewService(redisClient *redis.Client, tokenTTL time.Duration) Service {
+	return &service{redisClient: redisClient, tokenTTL: tokenTTL}
+}
+
+func revokedKey(userID string) string {
+	return "session:revoked:" + userID
+}
+
+func (s *service) RevokeAll(ctx context.Context, userID string) error {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := s.redisClient.Set(ctx, revokedKey(userID), now, s.tokenTTL).Err(); err != nil {
+		return err
+	}
+	// open sockets were authenticated once, at the handshake
+	connectionmanager.ConnManager.CloseUser(userID)
+	return nil
+}
+
+func (s *service) IsRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error) {
+	raw, err := s.redisClient.Get(ctx, revokedKey(userID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	cutoff, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	// iat has second precision: a token from the second of the revocation
+	// may predate it, so it goes too
+	return issuedAt.Unix() <= cutoff, nil
+}
@@ -18,9 +18,28 @@ type Service interface {
 	// GetCurrent returns a public view of the authenticated user.
 	GetCurrent(ctx context.Context, userID string) (*m.PublicUser, error)
 
-	// List returns all users in public form.
-	List(ctx context.Context) ([]m.PublicUser, error)
+	// Search pages through users whose username starts with the prefix,
+	// which must be at least MinUsername characters long.
+	Search(ctx context.Context, prefix string, page, limit int) (*UserPage, error)
 
-	// UpdateProfile updates the user profile with the provided data.
+	// UpdateProfile updates the user profile with the provided data. A new
+	// username must be free, ignoring case.
 	UpdateProfile(ctx context.Context, userID string, updates map[string]interface{}) error
 }
+
+// SearchResult is what a user search shows of each account: enough to
+// pick someone out and send a friend request, nothing private.
+type SearchResult struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+	Bot      bool   `json:"bot"`
+}
+
+// UserPage is one page of a user search.
+type UserPage struct {
+	Users []SearchResult `json:"users"`
+	Total int64          `json:"total"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+}
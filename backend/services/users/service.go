@@ -2,6 +2,7 @@ package users
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"strings"
@@ -9,6 +10,14 @@ import (
 	m "launay-dot-one/models"
 	"launay-dot-one/repositories"
 	"launay-dot-one/storage"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidUsername = fmt.Errorf("username must be %d to %d characters", m.MinUsername, m.MaxUsername)
+	ErrUsernameTaken   = errors.New("username is already taken")
+	ErrQueryTooShort   = fmt.Errorf("search needs at least %d characters", m.MinUsername)
 )
 
 type service struct {
@@ -50,20 +59,43 @@ func (s *service) GetCurrent(ctx context.Context, userID string) (*m.PublicUser,
 	return s.GetByID(ctx, userID)
 }
 
-func (s *service) List(ctx context.Context) ([]m.PublicUser, error) {
-	users, err := s.userRepo.ListAll(ctx)
+func (s *service) Search(ctx context.Context, prefix string, page, limit int) (*UserPage, error) {
+	prefix = strings.TrimSpace(prefix)
+	if len([]rune(prefix)) < m.MinUsername {
+		return nil, ErrQueryTooShort
+	}
+	users, total, err := s.userRepo.SearchByUsername(ctx, prefix, (page-1)*limit, limit)
 	if err != nil {
 		return nil, err
 	}
-	out := make([]m.PublicUser, len(users))
+	out := make([]SearchResult, len(users))
 	for i, u := range users {
-		out[i] = u.ToPublic()
+		out[i] = SearchResult{ID: u.ID, Username: u.Username, Avatar: u.Avatar, Bot: u.Bot}
 	}
-	return out, nil
+	return &UserPage{Users: out, Total: total, Page: page, Limit: limit}, nil
 }
 
 func (s *service) UpdateProfile(ctx context.Context, userID string, updates map[string]interface{}) error {
+	if v, ok := updates["username"]; ok {
+		name, _ := v.(string)
+		name = strings.TrimSpace(name)
+		if n := len([]rune(name)); n < m.MinUsername || n > m.MaxUsername {
+			return ErrInvalidUsername
+		}
+		taken, err := s.userRepo.UsernameTaken(ctx, name, userID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrUsernameTaken
+		}
+		updates["username"] = name
+	}
 	if err := s.userRepo.UpdateFields(ctx, userID, updates); err != nil {
+		// lost a race for the name
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrUsernameTaken
+		}
 		return fmt.Errorf("update profile: %w", err)
 	}
 	return nil
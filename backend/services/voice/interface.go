@@ -0,0 +1,91 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Service tracks who is connected to which voice channel and relays WebRTC
+// signaling between them. Media never passes through the server; clients
+// (or an SFU acting as a peer) exchange SDP and ICE through Relay.
+type Service interface {
+	// Join connects the user to a voice or stage channel, leaving any
+	// channel they were in. Requires connect; members without speak join
+	// suppressed, as does the audience of a stage. socketID names the
+	// socket the call now belongs to.
+	Join(ctx context.Context, channelID, userID, socketID string, selfMute, selfDeaf bool) (*State, error)
+
+	// Leave disconnects the user from voice. Leaving while not connected is
+	// a no-op.
+	Leave(ctx context.Context, userID string) error
+
+	// LeaveSocket disconnects the user when their call belongs to the
+	// socket, e.g. once it closed; a call joined from another of their
+	// sockets carries on.
+	LeaveSocket(ctx context.Context, userID, socketID string) error
+
+	// UpdateSelf changes the user's own mute/deaf flags; nil leaves a flag
+	// unchanged.
+	UpdateSelf(ctx context.Context, userID string, selfMute, selfDeaf *bool) (*State, error)
+
+	// UpdateMember server-mutes or -deafens a connected member. Requires
+	// mute-members or deafen-members respectively.
+	UpdateMember(ctx context.Context, guildID, actorID, targetID string, mute, deaf *bool) (*State, error)
+
+	// DisconnectMember drops a member from the guild's voice, e.g. after
+	// a kick, ban or timeout.
+	DisconnectMember(ctx context.Context, guildID, userID string) error
+
+	// GetState returns the user's voice state, or nil when not connected.
+	GetState(ctx context.Context, userID string) (*State, error)
+
+	// ListChannel returns the states of everyone connected to a channel.
+	ListChannel(ctx context.Context, channelID string) ([]State, error)
+
+	// Relay forwards an offer, answer or ICE candidate to a peer in the
+	// same channel as VOICE_SIGNAL.
+	Relay(ctx context.Context, fromUserID string, sig Signal) error
+}
+
+// State is a user's connection to a voice channel.
+type State struct {
+	UserID     string    `json:"user_id"`
+	GuildID    string    `json:"guild_id"`
+	ChannelID  string    `json:"channel_id"` // empty once disconnected
+	SessionID  string    `json:"session_id"`
+	SelfMute   bool      `json:"self_mute"`
+	SelfDeaf   bool      `json:"self_deaf"`
+	ServerMute bool      `json:"mute"`
+	ServerDeaf bool      `json:"deaf"`
+	Suppress   bool      `json:"suppress"` // lacks speak, or audience on a stage
+	JoinedAt   time.Time `json:"joined_at"`
+
+	// SocketID is the socket the user joined from; stored, never sent.
+	SocketID string `json:"-"`
+}
+
+// Signal types relayed between peers.
+const (
+	SignalOffer     = "offer"
+	SignalAnswer    = "answer"
+	SignalCandidate = "ice_candidate"
+)
+
+// Signal is one WebRTC negotiation message addressed to a peer.
+type Signal struct {
+	Type      string          `json:"type"`
+	ToUserID  string          `json:"to_user_id"`
+	SDP       string          `json:"sdp,omitempty"`
+	Candidate json.RawMessage `json:"candidate,omitempty"`
+}
+
+// SignalEvent is what the receiving peer gets.
+type SignalEvent struct {
+	Type       string          `json:"type"`
+	FromUserID string          `json:"from_user_id"`
+	ChannelID  string          `json:"channel_id"`
+	SessionID  string          `json:"session_id"`
+	SDP        string          `json:"sdp,omitempty"`
+	Candidate  json.RawMessage `json:"candidate,omitempty"`
+}
@@ -0,0 +1,340 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/realtime"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+	"launay-dot-one/services/permissions"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotVoiceChannel = errors.New("channel is not a voice or stage channel")
+	ErrChannelFull     = errors.New("voice channel is full")
+	ErrTimedOut        = errors.New("you are timed out in this guild")
+	ErrNotConnected    = errors.New("user is not connected to voice")
+	ErrPeerNotInCall   = errors.New("peer is not connected to your voice channel")
+	ErrInvalidSignal   = errors.New("invalid signal")
+)
+
+// stateTTL bounds how long a state survives without being touched, so a
+// crashed node cannot leave users connected forever.
+const stateTTL = 12 * time.Hour
+
+type service struct {
+	redisClient *redis.Client
+	channelRepo *repositories.ChannelRepository
+	memberRepo  *repositories.GuildMemberRepository
+	permSvc     permissions.Service
+	audit       auditlog.Service
+	gateway     realtime.Gateway
+}
+
+func NewService(
+	redisClient *redis.Client,
+	channelRepo *repositories.ChannelRepository,
+	memberRepo *repositories.GuildMemberRepository,
+	permSvc permissions.Service,
+	audit auditlog.Service,
+	gateway realtime.Gateway,
+) Service {
+	return &service{
+		redisClient: redisClient,
+		channelRepo: channelRepo,
+		memberRepo:  memberRepo,
+		permSvc:     permSvc,
+		audit:       audit,
+		gateway:     gateway,
+	}
+}
+
+func userKey(userID string) string       { return "voice:user:" + userID }
+func channelKey(channelID string) string { return "voice:channel:" + channelID }
+
+func (s *service) Join(ctx context.Context, channelID, userID, socketID string, selfMute, selfDeaf bool) (*State, error) {
+	ch, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	t := guilds.ChannelType(ch.Type)
+	if !t.AudioBased() {
+		return nil, ErrNotVoiceChannel
+	}
+	perms, err := s.permSvc.ChannelPermissions(ctx, ch, userID)
+	if err != nil {
+		return nil, err
+	}
+	if perms&guilds.PermConnect == 0 {
+		return nil, permissions.ErrMissingPermission
+	}
+	mem, err := s.memberRepo.Get(ctx, ch.GuildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mem.TimedOut(time.Now()) {
+		return nil, ErrTimedOut
+	}
+
+	prev, err := s.GetState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if ch.UserLimit > 0 && (prev == nil || prev.ChannelID != ch.ID) && perms&guilds.PermManageChannels == 0 {
+		n, err := s.redisClient.SCard(ctx, channelKey(ch.ID)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if int(n) >= ch.UserLimit {
+			return nil, ErrChannelFull
+		}
+	}
+
+	st := &State{
+		UserID:    userID,
+		GuildID:   ch.GuildID,
+		ChannelID: ch.ID,
+		SessionID: uuid.NewString(),
+		SelfMute:  selfMute,
+		SelfDeaf:  selfDeaf,
+		JoinedAt:  time.Now(),
+		SocketID:  socketID,
+	}
+	st.Suppress = perms&guilds.PermSpeak == 0 ||
+		(t == guilds.ChannelStage && perms&guilds.PermManageChannels == 0)
+	if prev != nil {
+		// server-side moderation sticks while the user stays in the guild
+		if prev.GuildID == st.GuildID {
+			st.ServerMute, st.ServerDeaf = prev.ServerMute, prev.ServerDeaf
+		}
+		if err := s.redisClient.SRem(ctx, channelKey(prev.ChannelID), userID).Err(); err != nil {
+			return nil, err
+		}
+		if prev.GuildID != st.GuildID {
+			s.emit(ctx, &State{UserID: userID, GuildID: prev.GuildID, SessionID: prev.SessionID})
+		}
+	}
+	if err := s.save(ctx, st); err != nil {
+		return nil, err
+	}
+	if err := s.redisClient.SAdd(ctx, channelKey(ch.ID), userID).Err(); err != nil {
+		return nil, err
+	}
+	s.emit(ctx, st)
+	return st, nil
+}
+
+func (s *service) Leave(ctx context.Context, userID string) error {
+	st, err := s.GetState(ctx, userID)
+	if err != nil || st == nil {
+		return err
+	}
+	return s.disconnect(ctx, st)
+}
+
+func (s *service) LeaveSocket(ctx context.Context, userID, socketID string) error {
+	st, err := s.GetState(ctx, userID)
+	if err != nil || st == nil || st.SocketID != socketID {
+		return err
+	}
+	return s.disconnect(ctx, st)
+}
+
+func (s *service) DisconnectMember(ctx context.Context, guildID, userID string) error {
+	st, err := s.GetState(ctx, userID)
+	if err != nil || st == nil || st.GuildID != guildID {
+		return err
+	}
+	return s.disconnect(ctx, st)
+}
+
+func (s *service) disconnect(ctx context.Context, st *State) error {
+	if err := s.redisClient.SRem(ctx, channelKey(st.ChannelID), st.UserID).Err(); err != nil {
+		return err
+	}
+	if err := s.redisClient.Del(ctx, userKey(st.UserID)).Err(); err != nil {
+		return err
+	}
+	st.ChannelID = ""
+	s.emit(ctx, st)
+	return nil
+}
+
+func (s *service) UpdateSelf(ctx context.Context, userID string, selfMute, selfDeaf *bool) (*State, error) {
+	st, err := s.GetState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		return nil, ErrNotConnected
+	}
+	if selfMute != nil {
+		st.SelfMute = *selfMute
+	}
+	if selfDeaf != nil {
+		st.SelfDeaf = *selfDeaf
+	}
+	if err := s.save(ctx, st); err != nil {
+		return nil, err
+	}
+	s.emit(ctx, st)
+	return st, nil
+}
+
+func (s *service) UpdateMember(
+	ctx context.Context,
+	guildID, actorID, targetID string,
+	mute, deaf *bool,
+) (*State, error) {
+	if mute != nil {
+		if err := s.permSvc.Require(ctx, guildID, actorID, guilds.PermMuteMembers); err != nil {
+			return nil, err
+		}
+	}
+	if deaf != nil {
+		if err := s.permSvc.Require(ctx, guildID, actorID, guilds.PermDeafenMembers); err != nil {
+			return nil, err
+		}
+	}
+	st, err := s.GetState(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil || st.GuildID != guildID {
+		return nil, ErrNotConnected
+	}
+
+	before := map[string]bool{"mute": st.ServerMute, "deaf": st.ServerDeaf}
+	if mute != nil {
+		st.ServerMute = *mute
+	}
+	if deaf != nil {
+		st.ServerDeaf = *deaf
+	}
+	if err := s.save(ctx, st); err != nil {
+		return nil, err
+	}
+	if err := s.audit.Record(ctx, auditlog.Record{
+		GuildID:    guildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditMemberVoiceUpdate,
+		TargetType: guilds.AuditTargetMember,
+		TargetID:   targetID,
+		Before:     before,
+		After:      map[string]bool{"mute": st.ServerMute, "deaf": st.ServerDeaf},
+	}); err != nil {
+		return nil, err
+	}
+	s.emit(ctx, st)
+	return st, nil
+}
+
+func (s *service) GetState(ctx context.Context, userID string) (*State, error) {
+	raw, err := s.redisClient.Get(ctx, userKey(userID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st State
+	rec := record{State: &st}
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return nil, err
+	}
+	st.SocketID = rec.SocketID
+	return &st, nil
+}
+
+// ListChannel also prunes members whose state expired or moved elsewhere.
+func (s *service) ListChannel(ctx context.Context, channelID string) ([]State, error) {
+	ids, err := s.redisClient.SMembers(ctx, channelKey(channelID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]State, 0, len(ids))
+	for _, id := range ids {
+		st, err := s.GetState(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if st == nil || st.ChannelID != channelID {
+			_ = s.redisClient.SRem(ctx, channelKey(channelID), id).Err()
+			continue
+		}
+		out = append(out, *st)
+	}
+	return out, nil
+}
+
+func (s *service) Relay(ctx context.Context, fromUserID string, sig Signal) error {
+	switch sig.Type {
+	case SignalOffer, SignalAnswer:
+		if sig.SDP == "" {
+			return ErrInvalidSignal
+		}
+	case SignalCandidate:
+		if len(sig.Candidate) == 0 {
+			return ErrInvalidSignal
+		}
+	default:
+		return ErrInvalidSignal
+	}
+	if sig.ToUserID == "" || sig.ToUserID == fromUserID {
+		return ErrInvalidSignal
+	}
+
+	from, err := s.GetState(ctx, fromUserID)
+	if err != nil {
+		return err
+	}
+	if from == nil {
+		return ErrNotConnected
+	}
+	to, err := s.GetState(ctx, sig.ToUserID)
+	if err != nil {
+		return err
+	}
+	if to == nil || to.ChannelID != from.ChannelID {
+		return ErrPeerNotInCall
+	}
+	s.gateway.SendToUsers([]string{to.UserID}, realtime.Event{
+		Type:    realtime.EventVoiceSignal,
+		GuildID: from.GuildID,
+		Data: SignalEvent{
+			Type:       sig.Type,
+			FromUserID: fromUserID,
+			ChannelID:  from.ChannelID,
+			SessionID:  from.SessionID,
+			SDP:        sig.SDP,
+			Candidate:  sig.Candidate,
+		},
+	})
+	return nil
+}
+
+// record is a state as stored: with the socket, which clients don't see.
+type record struct {
+	*State
+	SocketID string `json:"socket_id"`
+}
+
+func (s *service) save(ctx context.Context, st *State) error {
+	raw, err := json.Marshal(record{st, st.SocketID})
+	if err != nil {
+		return err
+	}
+	return s.redisClient.Set(ctx, userKey(st.UserID), raw, stateTTL).Err()
+}
+
+// emit is best-effort: a failed fan-out must not undo the state change.
+func (s *service) emit(ctx context.Context, st *State) {
+	_ = s.gateway.SendToGuild(ctx, st.GuildID, realtime.Event{Type: realtime.EventVoiceStateUpdate, Data: st}, st.UserID)
+}
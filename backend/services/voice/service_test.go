@@ -0,0 +1,204 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/realtime"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/permissions"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const (
+	testGuild   = "guild-1"
+	testChannel = "voice-1"
+)
+
+// fakePeers stands in for the clients on the gateway: it keeps the
+// signals each user was sent.
+type fakePeers struct {
+	realtime.Gateway
+	signals map[string][]SignalEvent
+}
+
+func (f *fakePeers) SendToUsers(userIDs []string, evt realtime.Event) {
+	if evt.Type != realtime.EventVoiceSignal {
+		return
+	}
+	for _, id := range userIDs {
+		f.signals[id] = append(f.signals[id], evt.Data.(SignalEvent))
+	}
+}
+
+func (f *fakePeers) SendToGuild(context.Context, string, realtime.Event, ...string) error {
+	return nil
+}
+
+// take returns and forgets the signals userID was sent.
+func (f *fakePeers) take(userID string) []SignalEvent {
+	out := f.signals[userID]
+	delete(f.signals, userID)
+	return out
+}
+
+type fakePermissions struct{ permissions.Service }
+
+func (fakePermissions) ChannelPermissions(context.Context, *guilds.Channel, string) (uint64, error) {
+	return guilds.PermConnect | guilds.PermSpeak, nil
+}
+
+func newTestService(t *testing.T, members ...string) (*service, *fakePeers) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "voice.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the channel's uuid_generate_v4() default is Postgres only
+	if err := db.Exec(`CREATE TABLE channels (
+		id TEXT PRIMARY KEY, guild_id TEXT NOT NULL, category_id TEXT, name TEXT,
+		type TEXT, position INTEGER, created_at DATETIME, updated_at DATETIME,
+		permissions_synced BOOLEAN, topic TEXT, nsfw BOOLEAN, slowmode_seconds INTEGER,
+		bitrate INTEGER, user_limit INTEGER)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&guilds.GuildMember{}, &guilds.GuildMemberRole{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&guilds.Channel{
+		ID: testChannel, GuildID: testGuild, Name: "General", Type: string(guilds.ChannelVoice),
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range members {
+		if err := db.Create(&guilds.GuildMember{GuildID: testGuild, UserID: id}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	peers := &fakePeers{signals: map[string][]SignalEvent{}}
+	svc := NewService(
+		rdb, repositories.NewChannelRepository(db), repositories.NewGuildMemberRepository(db),
+		fakePermissions{}, nil, peers,
+	).(*service)
+	return svc, peers
+}
+
+func TestSignalingBetweenPeers(t *testing.T) {
+	ctx := context.Background()
+	svc, peers := newTestService(t, "alice", "bob", "carol")
+
+	alice, err := svc.Join(ctx, testChannel, "alice", "alice-socket", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Join(ctx, testChannel, "bob", "bob-socket", false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// alice offers, bob answers, and both trickle a candidate
+	if err := svc.Relay(ctx, "alice", Signal{Type: SignalOffer, ToUserID: "bob", SDP: "offer-sdp"}); err != nil {
+		t.Fatal(err)
+	}
+	got := peers.take("bob")
+	if len(got) != 1 || got[0].Type != SignalOffer || got[0].FromUserID != "alice" ||
+		got[0].SDP != "offer-sdp" || got[0].ChannelID != testChannel || got[0].SessionID != alice.SessionID {
+		t.Fatalf("bob got %+v", got)
+	}
+	if err := svc.Relay(ctx, "bob", Signal{Type: SignalAnswer, ToUserID: "alice", SDP: "answer-sdp"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := peers.take("alice"); len(got) != 1 || got[0].Type != SignalAnswer || got[0].SDP != "answer-sdp" {
+		t.Fatalf("alice got %+v", got)
+	}
+	candidate := json.RawMessage(`{"candidate":"candidate:1 1 udp 1 192.0.2.1 5000 typ host"}`)
+	if err := svc.Relay(ctx, "alice", Signal{Type: SignalCandidate, ToUserID: "bob", Candidate: candidate}); err != nil {
+		t.Fatal(err)
+	}
+	if got := peers.take("bob"); len(got) != 1 || string(got[0].Candidate) != string(candidate) {
+		t.Fatalf("bob got %+v", got)
+	}
+
+	// nobody outside the call is reachable, and malformed signals go nowhere
+	if err := svc.Relay(ctx, "alice", Signal{Type: SignalOffer, ToUserID: "carol", SDP: "x"}); !errors.Is(err, ErrPeerNotInCall) {
+		t.Errorf("offer to carol: %v, want ErrPeerNotInCall", err)
+	}
+	if err := svc.Relay(ctx, "carol", Signal{Type: SignalOffer, ToUserID: "alice", SDP: "x"}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("offer from carol: %v, want ErrNotConnected", err)
+	}
+	if err := svc.Relay(ctx, "alice", Signal{Type: SignalOffer, ToUserID: "bob"}); !errors.Is(err, ErrInvalidSignal) {
+		t.Errorf("offer without sdp: %v, want ErrInvalidSignal", err)
+	}
+	if len(peers.signals) != 0 {
+		t.Errorf("stray signals %+v", peers.signals)
+	}
+}
+
+func TestLeaveSocket(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t, "alice", "bob")
+	for _, id := range []string{"alice", "bob"} {
+		if _, err := svc.Join(ctx, testChannel, id, id+"-socket", false, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// closing another of alice's sockets leaves the call alone
+	if err := svc.LeaveSocket(ctx, "alice", "alice-other-socket"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Relay(ctx, "bob", Signal{Type: SignalOffer, ToUserID: "alice", SDP: "x"}); err != nil {
+		t.Fatalf("call ended by an unrelated socket: %v", err)
+	}
+
+	// the socket the call was joined from ends it
+	if err := svc.LeaveSocket(ctx, "alice", "alice-socket"); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := svc.GetState(ctx, "alice"); err != nil || st != nil {
+		t.Fatalf("state after leaving = %+v, %v", st, err)
+	}
+	if err := svc.Relay(ctx, "bob", Signal{Type: SignalOffer, ToUserID: "alice", SDP: "x"}); !errors.Is(err, ErrPeerNotInCall) {
+		t.Errorf("offer to alice after leaving: %v, want ErrPeerNotInCall", err)
+	}
+
+	// rejoining from a new socket moves the call there
+	if _, err := svc.Join(ctx, testChannel, "alice", "alice-new-socket", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.LeaveSocket(ctx, "alice", "alice-socket"); err != nil {
+		t.Fatal(err)
+	}
+	st, err := svc.GetState(ctx, "alice")
+	if err != nil || st == nil || st.ChannelID != testChannel {
+		t.Fatalf("state after the old socket closed = %+v, %v", st, err)
+	}
+	if st.SocketID != "alice-new-socket" {
+		t.Errorf("SocketID = %q", st.SocketID)
+	}
+
+	// the socket never reaches clients
+	raw, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["socket_id"]; ok {
+		t.Errorf("state JSON carries the socket: %s", raw)
+	}
+}
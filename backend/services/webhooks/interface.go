@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/models/guilds"
+)
+
+type Service interface {
+	// Create, List*, Update, Delete and RotateToken require manage-webhooks
+	// in the webhook's guild. Create and RotateToken return the new token,
+	// which is never shown again.
+	Create(ctx context.Context, w *guilds.Webhook, actorID string) (string, error)
+	ListByChannel(ctx context.Context, channelID, actorID string) ([]guilds.Webhook, error)
+	ListByGuild(ctx context.Context, guildID, actorID string) ([]guilds.Webhook, error)
+	Update(ctx context.Context, id string, patch Patch, actorID string) (*guilds.Webhook, error)
+	Delete(ctx context.Context, id, actorID string) error
+	RotateToken(ctx context.Context, id, actorID string) (string, error)
+
+	// Execute posts a message as the webhook. It needs no session: the
+	// token authorises it.
+	Execute(ctx context.Context, id, token string, in Message) (*m.Message, error)
+}
+
+// Patch lists the webhook fields an update may change; nil leaves a field
+// as it is.
+type Patch struct {
+	Name      *string
+	Avatar    *string
+	ChannelID *string
+}
+
+// Message is what a webhook caller posts.
+type Message struct {
+	Content     string          `json:"content"`
+	Attachments json.RawMessage `json:"attachments,omitempty"`
+}
@@ -0,0 +1,312 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	m "launay-dot-one/models"
+	"launay-dot-one/models/guilds"
+	"launay-dot-one/repositories"
+	"launay-dot-one/services/auditlog"
+	"launay-dot-one/services/messaging"
+	"launay-dot-one/services/permissions"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidToken   = errors.New("invalid webhook token")
+	ErrInvalidName    = errors.New("webhook name must be 1 to 80 characters")
+	ErrInvalidAvatar  = errors.New("webhook avatar must be an http(s) URL")
+	ErrInvalidChannel = errors.New("webhooks can only post into text or announcement channels of their guild")
+	ErrEmptyMessage   = errors.New("message needs content or attachments")
+	ErrRateLimited    = errors.New("webhook is sending too fast")
+)
+
+const (
+	tokenBytes = 32
+
+	// executeLimit messages per executeWindow, per webhook
+	executeLimit  = 30
+	executeWindow = time.Minute
+)
+
+type service struct {
+	repo        *repositories.WebhookRepository
+	channelRepo *repositories.ChannelRepository
+	msgSvc      messaging.Service
+	permSvc     permissions.Service
+	audit       auditlog.Service
+	redisClient *redis.Client
+}
+
+func NewService(
+	repo *repositories.WebhookRepository,
+	channelRepo *repositories.ChannelRepository,
+	msgSvc messaging.Service,
+	permSvc permissions.Service,
+	audit auditlog.Service,
+	redisClient *redis.Client,
+) Service {
+	return &service{
+		repo:        repo,
+		channelRepo: channelRepo,
+		msgSvc:      msgSvc,
+		permSvc:     permSvc,
+		audit:       audit,
+		redisClient: redisClient,
+	}
+}
+
+func generateToken() (token, hash string, err error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// postableChannel loads a channel a webhook may post into.
+func (s *service) postableChannel(ctx context.Context, channelID string) (*guilds.Channel, error) {
+	ch, err := s.channelRepo.GetByID(ctx, channelID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidChannel
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := guilds.ChannelType(ch.Type)
+	if t != guilds.ChannelText && t != guilds.ChannelAnnouncement {
+		return nil, ErrInvalidChannel
+	}
+	return ch, nil
+}
+
+func validate(w *guilds.Webhook) error {
+	w.Name = strings.TrimSpace(w.Name)
+	if w.Name == "" || len(w.Name) > guilds.MaxWebhookName {
+		return ErrInvalidName
+	}
+	if w.Avatar == "" {
+		return nil
+	}
+	u, err := url.Parse(w.Avatar)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(w.Avatar) > guilds.MaxWebhookAvatar {
+		return ErrInvalidAvatar
+	}
+	return nil
+}
+
+func (s *service) Create(ctx context.Context, w *guilds.Webhook, actorID string) (string, error) {
+	ch, err := s.postableChannel(ctx, w.ChannelID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.permSvc.RequireChannel(ctx, ch, actorID, guilds.PermManageWebhooks); err != nil {
+		return "", err
+	}
+	if err := validate(w); err != nil {
+		return "", err
+	}
+	token, hash, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	w.ID = ""
+	w.GuildID = ch.GuildID
+	w.TokenHash = hash
+	w.CreatorID = actorID
+	if err := s.repo.Create(ctx, w); err != nil {
+		return "", err
+	}
+	if err := s.audit.Record(ctx, auditlog.Record{
+		GuildID:    w.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditWebhookCreate,
+		TargetType: guilds.AuditTargetWebhook,
+		TargetID:   w.ID,
+		After:      w,
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *service) ListByChannel(ctx context.Context, channelID, actorID string) ([]guilds.Webhook, error) {
+	ch, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.permSvc.RequireChannel(ctx, ch, actorID, guilds.PermManageWebhooks); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByChannel(ctx, channelID)
+}
+
+func (s *service) ListByGuild(ctx context.Context, guildID, actorID string) ([]guilds.Webhook, error) {
+	if err := s.permSvc.Require(ctx, guildID, actorID, guilds.PermManageWebhooks); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByGuild(ctx, guildID)
+}
+
+// manageable loads a webhook the actor may manage.
+func (s *service) manageable(ctx context.Context, id, actorID string) (*guilds.Webhook, error) {
+	w, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.permSvc.Require(ctx, w.GuildID, actorID, guilds.PermManageWebhooks); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *service) Update(ctx context.Context, id string, patch Patch, actorID string) (*guilds.Webhook, error) {
+	w, err := s.manageable(ctx, id, actorID)
+	if err != nil {
+		return nil, err
+	}
+	before := *w
+	if patch.Name != nil {
+		w.Name = *patch.Name
+	}
+	if patch.Avatar != nil {
+		w.Avatar = *patch.Avatar
+	}
+	if patch.ChannelID != nil && *patch.ChannelID != w.ChannelID {
+		ch, err := s.postableChannel(ctx, *patch.ChannelID)
+		if err != nil {
+			return nil, err
+		}
+		if ch.GuildID != w.GuildID {
+			return nil, ErrInvalidChannel
+		}
+		w.ChannelID = ch.ID
+	}
+	if err := validate(w); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, w); err != nil {
+		return nil, err
+	}
+	if err := s.audit.Record(ctx, auditlog.Record{
+		GuildID:    w.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditWebhookUpdate,
+		TargetType: guilds.AuditTargetWebhook,
+		TargetID:   w.ID,
+		Before:     before,
+		After:      w,
+	}); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *service) Delete(ctx context.Context, id, actorID string) error {
+	w, err := s.manageable(ctx, id, actorID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, w.ID); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, auditlog.Record{
+		GuildID:    w.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditWebhookDelete,
+		TargetType: guilds.AuditTargetWebhook,
+		TargetID:   w.ID,
+		Before:     w,
+	})
+}
+
+func (s *service) RotateToken(ctx context.Context, id, actorID string) (string, error) {
+	w, err := s.manageable(ctx, id, actorID)
+	if err != nil {
+		return "", err
+	}
+	token, hash, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	w.TokenHash = hash
+	if err := s.repo.Update(ctx, w); err != nil {
+		return "", err
+	}
+	// the hash is not serialised, so the entry only says that it happened
+	if err := s.audit.Record(ctx, auditlog.Record{
+		GuildID:    w.GuildID,
+		ActorID:    actorID,
+		Action:     guilds.AuditWebhookTokenRotate,
+		TargetType: guilds.AuditTargetWebhook,
+		TargetID:   w.ID,
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *service) Execute(ctx context.Context, id, token string, in Message) (*m.Message, error) {
+	w, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidToken // don't reveal which webhook IDs exist
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(w.TokenHash)) != 1 {
+		return nil, ErrInvalidToken
+	}
+	if strings.TrimSpace(in.Content) == "" && len(in.Attachments) == 0 {
+		return nil, ErrEmptyMessage
+	}
+	if err := s.throttle(ctx, w.ID); err != nil {
+		return nil, err
+	}
+	msg := &m.Message{
+		ChannelID:   w.ChannelID,
+		Content:     in.Content,
+		Attachments: datatypes.JSON(in.Attachments),
+	}
+	if err := s.msgSvc.SendWebhookMessage(ctx, w.ID, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// throttle allows executeLimit messages per fixed executeWindow.
+func (s *service) throttle(ctx context.Context, webhookID string) error {
+	key := "webhook:rate:" + webhookID
+	n, err := s.redisClient.Incr(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if n == 1 {
+		if err := s.redisClient.Expire(ctx, key, executeWindow).Err(); err != nil {
+			return err
+		}
+	}
+	if n > executeLimit {
+		return fmt.Errorf("%w: at most %d messages per %s", ErrRateLimited, executeLimit, executeWindow)
+	}
+	return nil
+}
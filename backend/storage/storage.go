@@ -2,20 +2,28 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"launay-dot-one/utils"
 	"mime/multipart"
+	"net/url"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
 )
 
+// MaxPresignedTTL is the longest a presigned URL stays valid (S3 limit).
+const MaxPresignedTTL = 7 * 24 * time.Hour
+
 type StorageService struct {
-	client  *minio.Client
-	bucket  string
-	baseURL string
+	client    *minio.Client
+	presigner *minio.Client
+	bucket    string
+	baseURL   string
 }
 
 func NewStorageService(c *minio.Client, bucket string) *StorageService {
@@ -26,6 +34,15 @@ func NewStorageService(c *minio.Client, bucket string) *StorageService {
 	}
 }
 
+// NewPrivateStorageService stores objects in a bucket without public
+// access; they are handed out through PresignedGetURL only. presigner
+// signs for the public storage host (STORAGE_PUBLIC_URL).
+func NewPrivateStorageService(c, presigner *minio.Client, bucket string) *StorageService {
+	s := NewStorageService(c, bucket)
+	s.presigner = presigner
+	return s
+}
+
 func (s *StorageService) UploadFile(
 	ctx context.Context,
 	file multipart.File,
@@ -59,3 +76,77 @@ func (s *StorageService) UploadFile(
 	url := fmt.Sprintf("%s/%s/%s", s.baseURL, s.bucket, object)
 	return url, nil
 }
+
+// PutObject stores an arbitrary payload under the given object name and
+// returns its public URL.
+func (s *StorageService) PutObject(
+	ctx context.Context,
+	object string,
+	r io.Reader,
+	size int64,
+	contentType string,
+) (string, error) {
+	uploadCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	_, err := s.client.PutObject(uploadCtx, s.bucket, object, r, size,
+		minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s/%s", s.baseURL, s.bucket, object), nil
+}
+
+// PresignedGetURL returns a URL that downloads object until ttl, at most
+// MaxPresignedTTL, has passed.
+func (s *StorageService) PresignedGetURL(ctx context.Context, object string, ttl time.Duration) (string, error) {
+	if s.presigner == nil {
+		return "", errors.New("storage: no presigner configured")
+	}
+	u, err := s.presigner.PresignedGetObject(ctx, s.bucket, object, ttl, nil)
+	if err != nil {
+		return "", err
+	}
+	// STORAGE_PUBLIC_URL may carry a path prefix the proxy strips (/storage)
+	base, err := url.Parse(s.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse STORAGE_PUBLIC_URL: %w", err)
+	}
+	u.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+	return u.String(), nil
+}
+
+// DeletePrefix removes every object under prefix (e.g. "<userID>/").
+func (s *StorageService) DeletePrefix(ctx context.Context, prefix string) error {
+	return s.deleteMatching(ctx, prefix, func(minio.ObjectInfo) bool { return true })
+}
+
+// DeleteOlderThan removes objects under prefix last modified before cutoff.
+func (s *StorageService) DeleteOlderThan(ctx context.Context, prefix string, cutoff time.Time) error {
+	return s.deleteMatching(ctx, prefix, func(o minio.ObjectInfo) bool {
+		return o.LastModified.Before(cutoff)
+	})
+}
+
+func (s *StorageService) deleteMatching(
+	ctx context.Context,
+	prefix string,
+	match func(minio.ObjectInfo) bool,
+) error {
+	objects := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	})
+	for obj := range objects {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if !match(obj) {
+			continue
+		}
+		if err := s.client.RemoveObject(ctx, s.bucket, obj.Key, minio.RemoveObjectOptions{}); err != nil {
+			return fmt.Errorf("remove %s: %w", obj.Key, err)
+		}
+	}
+	return nil
+}
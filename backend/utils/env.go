@@ -4,6 +4,8 @@ import (
 	"errors"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -28,3 +30,25 @@ func GetEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// GetEnvDuration parses a Go duration (e.g. "72h") or returns fallback.
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists && value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		log.Printf("invalid duration for %s: %q, using %s", key, value, fallback)
+	}
+	return fallback
+}
+
+// GetEnvBool parses a boolean (e.g. "true", "1") or returns fallback.
+func GetEnvBool(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists && value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+		log.Printf("invalid boolean for %s: %q, using %t", key, value, fallback)
+	}
+	return fallback
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned when an outgoing request would reach a
+// loopback, private, link-local or otherwise internal address.
+var ErrPrivateAddress = errors.New("destination address is not public")
+
+// internalPrefixes are ranges IsPublicAddr refuses on top of what
+// netip.Addr reports as non-global or private.
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network", reaches the host on Linux
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which can embed any IPv4
+}
+
+// IsPublicAddr reports whether addr is a globally routable unicast address
+// that user-supplied URLs may point at.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, p := range internalPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsInternalHost reports whether a URL host (without port) is obviously
+// internal: localhost or a literal non-public IP. Names are only checked
+// when dialing, after resolution, by PublicHTTPClient.
+func IsInternalHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	addr, err := netip.ParseAddr(strings.Trim(host, "[]"))
+	return err == nil && !IsPublicAddr(addr)
+}
+
+// guardControl runs after DNS resolution, right before connecting, so a
+// name that resolves (or later rebinds) to an internal address is refused.
+func guardControl(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil || !IsPublicAddr(ap.Addr()) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// PublicHTTPClient returns a client for requests to user-supplied URLs. It
+// refuses to connect to internal addresses, ignores proxy settings (the
+// proxy would do the dialing) and, unless followRedirects is set, hands
+// redirects back as responses.
+func PublicHTTPClient(timeout time.Duration, followRedirects bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   guardControl,
+	}
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	client := &http.Client{Timeout: timeout, Transport: transport}
+	if !followRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
+	return client
+}
@@ -0,0 +1,28 @@
+package utils
+
+import "sort"
+
+// Positioned is one item of an ordered list being renumbered.
+type Positioned struct {
+	ID       string
+	Position int  // requested position if Moved, current position otherwise
+	Moved    bool // explicitly placed by the caller
+}
+
+// DenseOrder returns the IDs in their new order, ready to be numbered
+// 0..n-1. Items are sorted by position; on a tie an explicitly moved item
+// goes first, otherwise the input order is kept.
+func DenseOrder(items []Positioned) []string {
+	sorted := append([]Positioned(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Position != sorted[j].Position {
+			return sorted[i].Position < sorted[j].Position
+		}
+		return sorted[i].Moved && !sorted[j].Moved
+	})
+	ids := make([]string, len(sorted))
+	for i, it := range sorted {
+		ids[i] = it.ID
+	}
+	return ids
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pagination reads ?page= (1-based) and ?limit= from the query string,
+// falling back to page 1 and defaultLimit, and capping limit at maxLimit.
+func Pagination(c *gin.Context, defaultLimit, maxLimit int) (page, limit int) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err = strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return page, limit
+}